@@ -0,0 +1,276 @@
+package alerts
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/kylegk/sse-rest-server/config"
+	"github.com/kylegk/sse-rest-server/db"
+	"github.com/kylegk/sse-rest-server/models"
+)
+
+// ErrNotFound is returned when an alert does not exist
+var ErrNotFound = errors.New("alert not found")
+
+// ErrInvalidTransition is returned when an alert cannot move to the requested status
+var ErrInvalidTransition = errors.New("invalid alert status transition")
+
+var (
+	// Serializes evaluation so concurrent scores for the same student cannot raise duplicate alerts
+	evalMu    sync.Mutex
+	startOnce sync.Once
+)
+
+// Start evaluates the rules against the scores recorded by every committed write, whichever route or call made it
+// The rules are evaluated as the write is committed, so its alerts are raised before the write returns
+func Start() {
+	startOnce.Do(func() {
+		db.OnScoreChange(func(events []models.ScoreEvent) {
+			// The scores have been recorded, so a failure to evaluate the rules is logged rather than returned
+			_, err := EvaluateChanges(events)
+			if err != nil {
+				log.Println(err)
+			}
+		})
+	})
+}
+
+// Evaluate checks a newly recorded score against every rule, raising an alert for each rule it triggers
+// A rule does not raise another alert for a student while an earlier alert from that rule is unresolved
+func Evaluate(score models.StudentExam) ([]models.Alert, error) {
+	evalMu.Lock()
+	defer evalMu.Unlock()
+
+	agg, err := db.GetStudentAggregate(score.StudentID)
+	if err != nil || agg == nil {
+		return nil, err
+	}
+
+	return evaluate(score, *agg)
+}
+
+// EvaluateChanges checks every score recorded by a write transaction against every rule, in the order the scores were written
+// Each score is compared with the student's scores as they were when it was written, so the later scores of a batch
+// are not counted in the history of the earlier ones; a score whose raw value did not change, such as one re-curved, is not evaluated again
+func EvaluateChanges(events []models.ScoreEvent) ([]models.Alert, error) {
+	evalMu.Lock()
+	defer evalMu.Unlock()
+
+	type written struct {
+		score models.StudentExam
+		agg   models.Aggregate
+	}
+
+	// Roll each student's aggregate back from the latest change to the earliest, keeping the aggregate as it was after each score
+	aggs := make(map[string]*models.Aggregate)
+	scores := make([]written, 0, len(events))
+	for i := len(events) - 1; i >= 0; i-- {
+		keys := events[i].After
+		if keys == nil {
+			keys = events[i].Before
+		}
+
+		agg, ok := aggs[keys.StudentID]
+		if !ok {
+			var err error
+			agg, err = db.GetStudentAggregate(keys.StudentID)
+			if err != nil {
+				return nil, err
+			}
+			aggs[keys.StudentID] = agg
+		}
+
+		// A student without an aggregate has had their scores removed since, so there is nothing to evaluate
+		if agg == nil {
+			continue
+		}
+		if after := events[i].After; after != nil {
+			if before := events[i].Before; before == nil || before.Score != after.Score {
+				scores = append(scores, written{score: *after, agg: *agg})
+			}
+			remove(agg, after.Score)
+		}
+		if before := events[i].Before; before != nil {
+			agg.Add(before.Score)
+		}
+	}
+
+	raised := make([]models.Alert, 0)
+	for i := len(scores) - 1; i >= 0; i-- {
+		alerts, err := evaluate(scores[i].score, scores[i].agg)
+		raised = append(raised, alerts...)
+		if err != nil {
+			return raised, err
+		}
+	}
+
+	return raised, nil
+}
+
+// Check a score against every rule, given the student's aggregate just after the score was recorded
+func evaluate(score models.StudentExam, agg models.Aggregate) ([]models.Alert, error) {
+	// The student's history is everything recorded before this score
+	history := agg
+	remove(&history, score.Score)
+
+	raised := make([]models.Alert, 0)
+	for _, rule := range GetRules() {
+		var message string
+		exam := 0
+
+		switch rule.Type {
+		case AverageBelow:
+			if agg.Count >= rule.MinHistory && agg.Mean() < rule.Threshold {
+				message = fmt.Sprintf("average of %.3f is below the threshold of %.3f", agg.Mean(), rule.Threshold)
+			}
+		case BelowHistory:
+			limit := history.Mean() - rule.Deviations*history.StdDev()
+			if history.Count >= rule.MinHistory && history.Count > 0 && history.StdDev() > 0 && score.Score < limit {
+				exam = score.Exam
+				message = fmt.Sprintf("score of %.3f on exam %d is more than %.1f standard deviations below the previous average of %.3f", score.Score, score.Exam, rule.Deviations, history.Mean())
+			}
+		}
+
+		if message == "" {
+			continue
+		}
+
+		unresolved, err := hasUnresolved(score.StudentID, rule.Name, exam)
+		if err != nil {
+			return raised, err
+		}
+		if unresolved {
+			continue
+		}
+
+		alert := models.Alert{
+			ID:        db.NewID(),
+			StudentID: score.StudentID,
+			Exam:      score.Exam,
+			Rule:      rule.Name,
+			Message:   message,
+			Score:     score.Score,
+			Average:   agg.Mean(),
+			Status:    models.AlertOpen,
+			CreatedAt: time.Now().UTC(),
+		}
+
+		err = db.UpsertRow(config.AlertTable, alert)
+		if err != nil {
+			return raised, err
+		}
+		raised = append(raised, alert)
+	}
+
+	return raised, nil
+}
+
+// Remove a score from the count and sums of an aggregate; the rules do not use its min and max
+func remove(agg *models.Aggregate, score float64) {
+	agg.Count--
+	agg.Sum -= score
+	agg.SumSq -= score * score
+}
+
+// List retrieves the alerts, optionally filtered by status and student
+func List(status string, studentID string) ([]models.Alert, error) {
+	var res []interface{}
+	var err error
+	switch {
+	case studentID != "":
+		res, err = db.GetRows(config.AlertTable, config.StudentIdx, studentID)
+	case status != "":
+		res, err = db.GetRows(config.AlertTable, config.StatusIdx, status)
+	default:
+		res, err = db.GetRows(config.AlertTable, config.IdFld)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	alerts := make([]models.Alert, 0, len(res))
+	for _, row := range res {
+		alert := row.(models.Alert)
+		if status != "" && alert.Status != status {
+			continue
+		}
+		alerts = append(alerts, alert)
+	}
+
+	return alerts, nil
+}
+
+// Get retrieves a single alert
+func Get(id string) (models.Alert, error) {
+	res, err := db.GetRows(config.AlertTable, config.IdFld, id)
+	if err != nil {
+		return models.Alert{}, err
+	}
+	if len(res) == 0 {
+		return models.Alert{}, ErrNotFound
+	}
+
+	return res[0].(models.Alert), nil
+}
+
+// Acknowledge marks an open alert as seen by a counselor
+func Acknowledge(id string, auditor db.Auditor) (models.Alert, error) {
+	return transition(id, models.AlertAcknowledged, auditor)
+}
+
+// Resolve closes an open or acknowledged alert
+func Resolve(id string, auditor db.Auditor) (models.Alert, error) {
+	return transition(id, models.AlertResolved, auditor)
+}
+
+// Move an alert to a new status, recording when the change happened; the auditor, if any, records the change in the audit log
+func transition(id string, status string, auditor db.Auditor) (models.Alert, error) {
+	evalMu.Lock()
+	defer evalMu.Unlock()
+
+	var alert models.Alert
+	_, err := db.Update(auditor, func(txn *db.Txn) error {
+		res, err := txn.GetRows(config.AlertTable, config.IdFld, id)
+		if err != nil {
+			return err
+		}
+		if len(res) == 0 {
+			return ErrNotFound
+		}
+		alert = res[0].(models.Alert)
+
+		now := time.Now().UTC()
+		switch {
+		case status == models.AlertAcknowledged && alert.Status == models.AlertOpen:
+			alert.AcknowledgedAt = &now
+		case status == models.AlertResolved && alert.Status != models.AlertResolved:
+			alert.ResolvedAt = &now
+		default:
+			return ErrInvalidTransition
+		}
+		alert.Status = status
+
+		return txn.UpsertRow(config.AlertTable, alert)
+	})
+
+	return alert, err
+}
+
+// Check whether a rule already has an unresolved alert for a student (and exam, when the rule applies to a single exam)
+func hasUnresolved(studentID string, rule string, exam int) (bool, error) {
+	alerts, err := List("", studentID)
+	if err != nil {
+		return false, err
+	}
+
+	for _, alert := range alerts {
+		if alert.Rule == rule && alert.Status != models.AlertResolved && (exam == 0 || alert.Exam == exam) {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
@@ -0,0 +1,212 @@
+package alerts
+
+import (
+	"testing"
+
+	"github.com/kylegk/sse-rest-server/config"
+	"github.com/kylegk/sse-rest-server/db"
+	"github.com/kylegk/sse-rest-server/models"
+)
+
+func recordScores(t *testing.T, scores ...models.StudentExam) []models.Alert {
+	raised := make([]models.Alert, 0)
+	for _, score := range scores {
+		err := db.UpsertRow(config.ScoreTable, score)
+		if err != nil {
+			t.Fatalf("Failed to insert score")
+		}
+
+		alerts, err := Evaluate(score)
+		if err != nil {
+			t.Fatalf("Failed to evaluate score: %v", err)
+		}
+		raised = append(raised, alerts...)
+	}
+
+	return raised
+}
+
+// TestEvaluateAverageBelow validates that a low average raises a single alert until it is resolved
+func TestEvaluateAverageBelow(t *testing.T) {
+	err := db.InitDB(config.DBSchema)
+	if err != nil {
+		t.Errorf("The database failed to initialize")
+	}
+
+	raised := recordScores(t,
+		models.StudentExam{Exam: 1, StudentID: "test", Score: 0.5},
+		models.StudentExam{Exam: 2, StudentID: "test", Score: 0.4},
+	)
+
+	have := len(raised)
+	want := 1
+	if have != want {
+		t.Fatalf("Incorrect number of alerts raised; have: %v, want: %v", have, want)
+	}
+	if raised[0].Rule != "low_average" || raised[0].Status != models.AlertOpen {
+		t.Errorf("Incorrect alert raised; have: %+v", raised[0])
+	}
+
+	_, err = Resolve(raised[0].ID, nil)
+	if err != nil {
+		t.Errorf("Failed to resolve alert")
+	}
+
+	// Once resolved, the rule can raise another alert
+	raised = recordScores(t, models.StudentExam{Exam: 3, StudentID: "test", Score: 0.3})
+	have = len(raised)
+	if have != want {
+		t.Errorf("Incorrect number of alerts raised after resolving; have: %v, want: %v", have, want)
+	}
+}
+
+// TestEvaluateBelowHistory validates that a score far below the student's own history raises an alert
+func TestEvaluateBelowHistory(t *testing.T) {
+	err := db.InitDB(config.DBSchema)
+	if err != nil {
+		t.Errorf("The database failed to initialize")
+	}
+
+	raised := recordScores(t,
+		models.StudentExam{Exam: 1, StudentID: "test", Score: 0.9},
+		models.StudentExam{Exam: 2, StudentID: "test", Score: 0.92},
+		models.StudentExam{Exam: 3, StudentID: "test", Score: 0.88},
+		models.StudentExam{Exam: 4, StudentID: "test", Score: 0.7},
+	)
+
+	have := len(raised)
+	want := 1
+	if have != want {
+		t.Fatalf("Incorrect number of alerts raised; have: %v, want: %v", have, want)
+	}
+	if raised[0].Rule != "below_history" || raised[0].Exam != 4 {
+		t.Errorf("Incorrect alert raised; have: %+v", raised[0])
+	}
+}
+
+// TestEvaluateChanges validates that each score of a batch is compared with the history before it, not the later scores of the batch
+func TestEvaluateChanges(t *testing.T) {
+	err := db.InitDB(config.DBSchema)
+	if err != nil {
+		t.Errorf("The database failed to initialize")
+	}
+
+	// The low scores after exam 4 would hide its drop if they were counted in its history
+	events, err := db.UpsertScores([]models.StudentExam{
+		{Exam: 1, StudentID: "test", Score: 0.9},
+		{Exam: 2, StudentID: "test", Score: 0.92},
+		{Exam: 3, StudentID: "test", Score: 0.88},
+		{Exam: 4, StudentID: "test", Score: 0.7},
+		{Exam: 5, StudentID: "test", Score: 0.3},
+		{Exam: 6, StudentID: "test", Score: 0.3},
+	}, nil)
+	if err != nil {
+		t.Fatalf("Failed to insert scores")
+	}
+
+	raised, err := EvaluateChanges(events)
+	if err != nil {
+		t.Fatalf("Failed to evaluate scores: %v", err)
+	}
+
+	exams := make(map[int]bool)
+	for _, alert := range raised {
+		if alert.Rule == "below_history" {
+			exams[alert.Exam] = true
+		}
+	}
+	if !exams[4] || !exams[5] || exams[6] {
+		t.Errorf("Incorrect alerts raised; have: %+v", raised)
+	}
+}
+
+// TestTransition validates the acknowledge and resolve workflow
+func TestTransition(t *testing.T) {
+	err := db.InitDB(config.DBSchema)
+	if err != nil {
+		t.Errorf("The database failed to initialize")
+	}
+
+	raised := recordScores(t, models.StudentExam{Exam: 1, StudentID: "test", Score: 0.2})
+	if len(raised) != 1 {
+		t.Fatalf("An alert should have been raised")
+	}
+	id := raised[0].ID
+
+	alert, err := Acknowledge(id, nil)
+	if err != nil || alert.Status != models.AlertAcknowledged || alert.AcknowledgedAt == nil {
+		t.Errorf("Failed to acknowledge alert; have: %+v", alert)
+	}
+
+	_, err = Acknowledge(id, nil)
+	if err != ErrInvalidTransition {
+		t.Errorf("Acknowledging twice should have failed; have: %v", err)
+	}
+
+	alert, err = Resolve(id, nil)
+	if err != nil || alert.Status != models.AlertResolved || alert.ResolvedAt == nil {
+		t.Errorf("Failed to resolve alert; have: %+v", alert)
+	}
+
+	_, err = Resolve("does_not_exist", nil)
+	if err != ErrNotFound {
+		t.Errorf("Resolving a missing alert should have failed; have: %v", err)
+	}
+}
+
+// TestSetRules validates that invalid rules are rejected
+func TestSetRules(t *testing.T) {
+	invalid := [][]Rule{
+		{{Name: "", Type: AverageBelow, Threshold: 0.5}},
+		{{Name: "a", Type: "does_not_exist"}},
+		{{Name: "a", Type: AverageBelow}},
+		{{Name: "a", Type: BelowHistory}},
+		{{Name: "a", Type: AverageBelow, Threshold: 0.5}, {Name: "a", Type: AverageBelow, Threshold: 0.5}},
+	}
+
+	for _, r := range invalid {
+		err := SetRules(r)
+		if err == nil {
+			t.Errorf("The rules should have been rejected: %+v", r)
+		}
+	}
+}
+
+// TestStart validates that the rules are evaluated against every committed write, and that a re-curved score is not evaluated again
+// The listener stays registered once started, so this runs after the tests that evaluate the scores themselves
+func TestStart(t *testing.T) {
+	err := db.InitDB(config.DBSchema)
+	if err != nil {
+		t.Errorf("The database failed to initialize")
+	}
+
+	Start()
+
+	_, err = db.UpsertScores([]models.StudentExam{
+		{Exam: 1, StudentID: "test", Score: 0.5},
+		{Exam: 2, StudentID: "test", Score: 0.4},
+	}, nil)
+	if err != nil {
+		t.Fatalf("Failed to insert scores")
+	}
+
+	raised, _ := List("", "test")
+	if len(raised) != 1 || raised[0].Rule != "low_average" {
+		t.Fatalf("Incorrect alerts raised; have: %+v", raised)
+	}
+
+	_, err = Resolve(raised[0].ID, nil)
+	if err != nil {
+		t.Errorf("Failed to resolve alert")
+	}
+
+	_, err = db.ApplyCurve(models.Curve{Exam: 2, Method: models.CurveLinear, Factor: 1.1}, nil)
+	if err != nil {
+		t.Fatalf("Failed to curve exam")
+	}
+
+	raised, _ = List(models.AlertOpen, "test")
+	if len(raised) != 0 {
+		t.Errorf("A re-curved score raised an alert; have: %+v", raised)
+	}
+}
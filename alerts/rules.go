@@ -0,0 +1,94 @@
+package alerts
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"sync"
+)
+
+// Define the supported rule types
+const (
+	// AverageBelow is triggered when a student's average falls below the threshold
+	AverageBelow = "average_below"
+	// BelowHistory is triggered when a score falls the given number of standard deviations below the student's previous scores
+	BelowHistory = "below_history"
+)
+
+// Rule defines a condition that marks a student as at risk
+type Rule struct {
+	Name       string  `json:"name"`
+	Type       string  `json:"type"`
+	Threshold  float64 `json:"threshold,omitempty"`
+	Deviations float64 `json:"deviations,omitempty"`
+	MinHistory int     `json:"min_history,omitempty"`
+}
+
+// Rules evaluated when no rule file is provided
+var defaultRules = []Rule{
+	{Name: "low_average", Type: AverageBelow, Threshold: 0.6, MinHistory: 1},
+	{Name: "below_history", Type: BelowHistory, Deviations: 2, MinHistory: 3},
+}
+
+var (
+	mu    sync.RWMutex
+	rules = defaultRules
+)
+
+// LoadRules reads a JSON array of rules from a file, replacing the default rules
+func LoadRules(path string) error {
+	bytes, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	var loaded []Rule
+	err = json.Unmarshal(bytes, &loaded)
+	if err != nil {
+		return fmt.Errorf("unable to parse alert rules: %v", err)
+	}
+
+	return SetRules(loaded)
+}
+
+// SetRules validates and replaces the rules evaluated for every score
+func SetRules(r []Rule) error {
+	names := make(map[string]bool)
+	for _, rule := range r {
+		if rule.Name == "" || names[rule.Name] {
+			return fmt.Errorf("alert rule names must be unique and non-empty")
+		}
+		names[rule.Name] = true
+
+		switch rule.Type {
+		case AverageBelow:
+			if rule.Threshold <= 0 {
+				return fmt.Errorf("alert rule %s: threshold must be greater than 0", rule.Name)
+			}
+		case BelowHistory:
+			if rule.Deviations <= 0 {
+				return fmt.Errorf("alert rule %s: deviations must be greater than 0", rule.Name)
+			}
+		default:
+			return fmt.Errorf("alert rule %s: invalid type: %s", rule.Name, rule.Type)
+		}
+
+		if rule.MinHistory < 0 {
+			return fmt.Errorf("alert rule %s: min_history cannot be negative", rule.Name)
+		}
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	rules = r
+
+	return nil
+}
+
+// GetRules returns the rules evaluated for every score
+func GetRules() []Rule {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	return rules
+}
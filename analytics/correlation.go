@@ -0,0 +1,78 @@
+package analytics
+
+import (
+	"math"
+	"sort"
+)
+
+// Pearson returns the Pearson correlation coefficient of the paired values
+// The coefficient is undefined, and false is returned, when there are fewer than two pairs or either set of values is constant
+func Pearson(xs []float64, ys []float64) (float64, bool) {
+	n := len(xs)
+	if n < 2 || n != len(ys) {
+		return 0, false
+	}
+
+	var meanX, meanY float64
+	for i := range xs {
+		meanX += xs[i]
+		meanY += ys[i]
+	}
+	meanX /= float64(n)
+	meanY /= float64(n)
+
+	var sxx, sxy, syy float64
+	for i := range xs {
+		dx := xs[i] - meanX
+		dy := ys[i] - meanY
+		sxx += dx * dx
+		sxy += dx * dy
+		syy += dy * dy
+	}
+
+	if sxx == 0 || syy == 0 {
+		return 0, false
+	}
+
+	// Rounding can push a perfect correlation just outside of the valid range
+	r := sxy / math.Sqrt(sxx*syy)
+
+	return math.Max(-1, math.Min(1, r)), true
+}
+
+// Spearman returns the Spearman rank correlation coefficient of the paired values, averaging the ranks of tied values
+func Spearman(xs []float64, ys []float64) (float64, bool) {
+	if len(xs) != len(ys) {
+		return 0, false
+	}
+
+	return Pearson(Rank(xs), Rank(ys))
+}
+
+// Rank returns the rank of each value, starting at 1; tied values share the average of the ranks they span
+func Rank(values []float64) []float64 {
+	order := make([]int, len(values))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		return values[order[a]] < values[order[b]]
+	})
+
+	ranks := make([]float64, len(values))
+	for start := 0; start < len(order); {
+		end := start + 1
+		for end < len(order) && values[order[end]] == values[order[start]] {
+			end++
+		}
+
+		// Positions start through end-1 hold the ranks start+1 through end
+		rank := float64(start+end+1) / 2
+		for _, i := range order[start:end] {
+			ranks[i] = rank
+		}
+		start = end
+	}
+
+	return ranks
+}
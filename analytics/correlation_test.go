@@ -0,0 +1,59 @@
+package analytics
+
+import (
+	"testing"
+)
+
+// TestPearson validates the coefficient for perfectly correlated, anti-correlated and undefined input
+func TestPearson(t *testing.T) {
+	r, ok := Pearson([]float64{0.1, 0.2, 0.3}, []float64{0.5, 0.7, 0.9})
+	if !ok || !almostEqual(r, 1) {
+		t.Errorf("Incorrect coefficient; have: %v, %v, want: 1", r, ok)
+	}
+
+	r, ok = Pearson([]float64{0.1, 0.2, 0.3}, []float64{0.9, 0.7, 0.5})
+	if !ok || !almostEqual(r, -1) {
+		t.Errorf("Incorrect coefficient; have: %v, %v, want: -1", r, ok)
+	}
+
+	r, ok = Pearson([]float64{0.1, 0.2, 0.3, 0.4}, []float64{0.3, 0.1, 0.4, 0.2})
+	if !ok || !almostEqual(r, 0) {
+		t.Errorf("Incorrect coefficient; have: %v, %v, want: 0", r, ok)
+	}
+
+	// Constant values and single pairs have no correlation
+	if _, ok = Pearson([]float64{0.1, 0.2}, []float64{0.5, 0.5}); ok {
+		t.Errorf("The coefficient should be undefined for constant values")
+	}
+	if _, ok = Pearson([]float64{0.1}, []float64{0.5}); ok {
+		t.Errorf("The coefficient should be undefined for a single pair")
+	}
+}
+
+// TestSpearman validates that a monotonic but non-linear relationship is perfectly rank correlated
+func TestSpearman(t *testing.T) {
+	xs := []float64{0.1, 0.2, 0.3, 0.4}
+	ys := []float64{0.01, 0.04, 0.09, 0.8}
+
+	r, ok := Spearman(xs, ys)
+	if !ok || !almostEqual(r, 1) {
+		t.Errorf("Incorrect coefficient; have: %v, %v, want: 1", r, ok)
+	}
+
+	p, _ := Pearson(xs, ys)
+	if p >= 1 {
+		t.Errorf("The Pearson coefficient should be below 1 for a non-linear relationship; have: %v", p)
+	}
+}
+
+// TestRank validates that tied values share the average of their ranks
+func TestRank(t *testing.T) {
+	have := Rank([]float64{0.7, 0.5, 0.7, 0.9})
+	want := []float64{2.5, 1, 2.5, 4}
+	for i := range want {
+		if have[i] != want[i] {
+			t.Errorf("Incorrect ranks; have: %v, want: %v", have, want)
+			break
+		}
+	}
+}
@@ -0,0 +1,110 @@
+package analytics
+
+import "math"
+
+// Fit is the result of a least squares linear regression
+type Fit struct {
+	Slope       float64
+	Intercept   float64
+	RSquared    float64
+	TStat       float64
+	Significant bool
+}
+
+// Two-tailed critical values of the t distribution at the 95% confidence level, indexed by degrees of freedom
+var tCritical = []float64{
+	0, 12.706, 4.303, 3.182, 2.776, 2.571, 2.447, 2.365, 2.306, 2.262, 2.228,
+	2.201, 2.179, 2.160, 2.145, 2.131, 2.120, 2.110, 2.101, 2.093, 2.086,
+	2.080, 2.074, 2.069, 2.064, 2.060, 2.056, 2.052, 2.048, 2.045, 2.042,
+}
+
+// Critical value of the t distribution used when there are more degrees of freedom than the table covers
+const tCriticalLarge = 1.96
+
+// LinearFit fits a line to the points using least squares, and tests whether the slope is significantly different from zero
+// At least three points are required to test the slope, so fewer points are never significant
+func LinearFit(xs []float64, ys []float64) Fit {
+	fit := Fit{}
+	n := len(xs)
+	if n == 0 || n != len(ys) {
+		return fit
+	}
+
+	var meanX, meanY float64
+	for i := range xs {
+		meanX += xs[i]
+		meanY += ys[i]
+	}
+	meanX /= float64(n)
+	meanY /= float64(n)
+
+	var sxx, sxy, syy float64
+	for i := range xs {
+		dx := xs[i] - meanX
+		dy := ys[i] - meanY
+		sxx += dx * dx
+		sxy += dx * dy
+		syy += dy * dy
+	}
+
+	fit.Intercept = meanY
+	if sxx == 0 {
+		return fit
+	}
+
+	fit.Slope = sxy / sxx
+	fit.Intercept = meanY - fit.Slope*meanX
+	if syy > 0 {
+		fit.RSquared = (sxy * sxy) / (sxx * syy)
+	}
+
+	if n < 3 {
+		return fit
+	}
+
+	df := n - 2
+	sse := math.Max(syy-fit.Slope*sxy, 0)
+	stdErr := math.Sqrt(sse / float64(df) / sxx)
+	if stdErr == 0 {
+		// A perfect fit is significant as long as the line is not flat
+		fit.Significant = fit.Slope != 0
+		if fit.Significant {
+			fit.TStat = math.Inf(int(math.Copysign(1, fit.Slope)))
+		}
+		return fit
+	}
+
+	fit.TStat = fit.Slope / stdErr
+	critical := tCriticalLarge
+	if df < len(tCritical) {
+		critical = tCritical[df]
+	}
+	fit.Significant = math.Abs(fit.TStat) >= critical
+
+	return fit
+}
+
+// MovingAverage returns the trailing average of each value over the window
+// Values earlier than a full window are averaged over the values available so far
+func MovingAverage(values []float64, window int) []float64 {
+	averages := make([]float64, len(values))
+	if window < 1 {
+		window = 1
+	}
+
+	var sum float64
+	for i, value := range values {
+		sum += value
+		if i >= window {
+			sum -= values[i-window]
+		}
+
+		count := i + 1
+		if count > window {
+			count = window
+		}
+		averages[i] = sum / float64(count)
+	}
+
+	return averages
+}
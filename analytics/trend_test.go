@@ -0,0 +1,50 @@
+package analytics
+
+import (
+	"math"
+	"testing"
+)
+
+func almostEqual(a float64, b float64) bool {
+	return math.Abs(a-b) < 1e-9
+}
+
+// TestLinearFit validates the slope and significance of a fit against known points
+func TestLinearFit(t *testing.T) {
+	// A perfect line is always significant
+	fit := LinearFit([]float64{0, 1, 2, 3}, []float64{0.5, 0.6, 0.7, 0.8})
+	if !almostEqual(fit.Slope, 0.1) || !almostEqual(fit.Intercept, 0.5) || !fit.Significant {
+		t.Errorf("Incorrect fit for a perfect line; have: %+v", fit)
+	}
+
+	// Noisy points without a clear direction are not significant
+	fit = LinearFit([]float64{0, 1, 2, 3, 4}, []float64{0.7, 0.5, 0.8, 0.4, 0.7})
+	if fit.Significant {
+		t.Errorf("The fit should not be significant; have: %+v", fit)
+	}
+
+	// Two points can be fit but never tested
+	fit = LinearFit([]float64{0, 1}, []float64{0.2, 0.9})
+	if !almostEqual(fit.Slope, 0.7) || fit.Significant {
+		t.Errorf("Incorrect fit for two points; have: %+v", fit)
+	}
+
+	// Mismatched input produces an empty fit
+	fit = LinearFit([]float64{0, 1}, []float64{0.2})
+	if fit.Slope != 0 || fit.Significant {
+		t.Errorf("The fit should be empty; have: %+v", fit)
+	}
+}
+
+// TestMovingAverage validates the trailing average, including the partial windows at the start
+func TestMovingAverage(t *testing.T) {
+	have := MovingAverage([]float64{0.2, 0.4, 0.6, 0.8}, 2)
+	want := []float64{0.2, 0.3, 0.5, 0.7}
+
+	for i := range want {
+		if !almostEqual(have[i], want[i]) {
+			t.Errorf("Incorrect moving average; have: %v, want: %v", have, want)
+			break
+		}
+	}
+}
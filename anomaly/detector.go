@@ -0,0 +1,256 @@
+package anomaly
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/kylegk/sse-rest-server/config"
+	"github.com/kylegk/sse-rest-server/db"
+	"github.com/kylegk/sse-rest-server/models"
+)
+
+// Detection thresholds for the ingested score stream
+var (
+	// Scores outside of this range are quarantined
+	MinScore = 0.0
+	MaxScore = 1.0
+
+	// The same exam, student and score may be received this many times within the window before the repeats are quarantined
+	DuplicateLimit  = 3
+	DuplicateWindow = time.Minute
+
+	// A single student or exam may receive this many events within the window before further events are quarantined
+	StudentRateLimit = 10
+	ExamRateLimit    = 500
+	RateWindow       = time.Second
+
+	// A shift is reported when the mean of an exam's most recent ShiftWindow scores is more than ShiftZScore
+	// standard errors from the exam's overall mean, once the exam has at least ShiftMinHistory scores
+	ShiftWindow     = 20
+	ShiftZScore     = 4.0
+	ShiftMinHistory = 60
+)
+
+var (
+	mu         sync.Mutex
+	duplicates = make(map[string]*history)
+	students   = make(map[string]*history)
+	exams      = make(map[string]*history)
+	recent     = make(map[int][]float64)
+)
+
+// The times of the most recent events for a key, in a ring buffer holding no more events than the key's limit
+type history struct {
+	times []time.Time
+	next  int
+}
+
+// Inspect checks an ingested score for anomalies, recording any that are found
+// It returns true when the score should be quarantined rather than recorded
+func Inspect(score models.StudentExam) (bool, error) {
+	reason, detail := check(score, time.Now())
+	if reason == "" {
+		return false, nil
+	}
+
+	event := score
+	return true, record(models.Anomaly{
+		Reason:      reason,
+		Detail:      detail,
+		Exam:        score.Exam,
+		StudentID:   score.StudentID,
+		Event:       &event,
+		Quarantined: true,
+	})
+}
+
+// InspectMalformed quarantines an event that could not be parsed
+func InspectMalformed(raw []byte, parseErr error) error {
+	return record(models.Anomaly{
+		Reason:      models.AnomalyMalformed,
+		Detail:      parseErr.Error(),
+		Raw:         string(raw),
+		Quarantined: true,
+	})
+}
+
+// Observe tracks a recorded score for distribution shifts in its exam, recording an anomaly when one is found
+// Shifts describe the exam rather than a single event, so nothing is quarantined
+func Observe(score models.StudentExam) error {
+	mu.Lock()
+	window := append(recent[score.Exam], score.Score)
+	if len(window) < ShiftWindow {
+		recent[score.Exam] = window
+		mu.Unlock()
+		return nil
+	}
+	delete(recent, score.Exam)
+	mu.Unlock()
+
+	stats, err := db.GetExamAggregate(score.Exam)
+	if err != nil || stats == nil || stats.Count < ShiftMinHistory || stats.StdDev() == 0 {
+		return err
+	}
+
+	var sum float64
+	for _, s := range window {
+		sum += s
+	}
+	recentMean := sum / float64(len(window))
+	z := (recentMean - stats.Mean()) / (stats.StdDev() / math.Sqrt(float64(len(window))))
+	if math.Abs(z) < ShiftZScore {
+		return nil
+	}
+
+	return record(models.Anomaly{
+		Reason: models.AnomalyShift,
+		Detail: fmt.Sprintf("mean of the last %d scores (%.3f) is %.1f standard errors from the exam mean (%.3f)", len(window), recentMean, z, stats.Mean()),
+		Exam:   score.Exam,
+	})
+}
+
+// Ingest records a score that passed the per-event checks, or was released from quarantine, and follows it through the rest
+// of the ingest path: the score's exam is tracked for distribution shifts and the alert rules are evaluated against the score
+// The auditor, if any, records the change to the score in the audit log
+func Ingest(score models.StudentExam, auditor db.Auditor) error {
+	_, err := db.UpsertScores([]models.StudentExam{score}, auditor)
+	if err != nil {
+		return err
+	}
+
+	err = Observe(score)
+	if err != nil {
+		log.Println(err)
+	}
+
+	return nil
+}
+
+// Run the per-event checks, returning the reason and detail of the first check the score fails
+func check(score models.StudentExam, now time.Time) (string, string) {
+	if !InRange(score.Score) {
+		return models.AnomalyOutOfRange, fmt.Sprintf("score %v is outside of the range %v to %v", score.Score, MinScore, MaxScore)
+	}
+	if score.Exam <= 0 || score.StudentID == "" {
+		return models.AnomalyMalformed, "event is missing an exam or student id"
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	prune(now)
+
+	// Every event counts towards the limits, so a flood stays quarantined until it slows down
+	dupKey := fmt.Sprintf("%d|%s|%v", score.Exam, score.StudentID, score.Score)
+	if hit(duplicates, dupKey, now, DuplicateWindow, DuplicateLimit) {
+		return models.AnomalyDuplicateFlood, fmt.Sprintf("received more than %d times within %v", DuplicateLimit, DuplicateWindow)
+	}
+	if hit(students, score.StudentID, now, RateWindow, StudentRateLimit) {
+		return models.AnomalyStudentRate, fmt.Sprintf("student received more than %d events within %v", StudentRateLimit, RateWindow)
+	}
+	if hit(exams, strconv.Itoa(score.Exam), now, RateWindow, ExamRateLimit) {
+		return models.AnomalyExamRate, fmt.Sprintf("exam received more than %d events within %v", ExamRateLimit, RateWindow)
+	}
+
+	return "", ""
+}
+
+// Validate checks a score written through the REST or gRPC API, returning an error for each missing or invalid field
+// A score must be within the same range as the ingested scores, which are quarantined when they are outside of it
+func Validate(score models.StudentExam) []models.FieldError {
+	fields := make([]models.FieldError, 0)
+	if score.Exam == 0 {
+		fields = append(fields, models.FieldError{Field: "exam", Code: models.FieldRequired, Message: "invalid exam id"})
+	}
+	if score.Score == 0 {
+		fields = append(fields, models.FieldError{Field: "score", Code: models.FieldRequired, Message: "invalid score"})
+	} else if !InRange(score.Score) {
+		fields = append(fields, models.FieldError{Field: "score", Code: models.FieldInvalid, Message: fmt.Sprintf("invalid score: must be between %v and %v", MinScore, MaxScore)})
+	}
+	if score.StudentID == "" {
+		fields = append(fields, models.FieldError{Field: "studentid", Code: models.FieldRequired, Message: "invalid studentid"})
+	}
+
+	return fields
+}
+
+// InRange reports whether a score is within the range that can be recorded, from MinScore to MaxScore
+func InRange(score float64) bool {
+	return !math.IsNaN(score) && score >= MinScore && score <= MaxScore
+}
+
+// Record an event for a key and report whether more than limit events for the key fell within the window
+// Only the last limit events are kept, so the event that would be over the limit is the oldest one in the buffer
+func hit(counts map[string]*history, key string, now time.Time, window time.Duration, limit int) bool {
+	if limit <= 0 {
+		return true
+	}
+
+	h := counts[key]
+	if h == nil {
+		h = &history{}
+		counts[key] = h
+	}
+
+	if len(h.times) < limit {
+		h.times = append(h.times, now)
+		return false
+	}
+
+	oldest := h.times[h.next]
+	h.times[h.next] = now
+	h.next = (h.next + 1) % len(h.times)
+
+	return now.Sub(oldest) < window
+}
+
+// The time of the most recent event in a history
+func (h *history) last() time.Time {
+	if len(h.times) == 0 {
+		return time.Time{}
+	}
+
+	return h.times[(h.next+len(h.times)-1)%len(h.times)]
+}
+
+// Drop the keys whose events have all fallen out of their window, so the history does not grow without bound
+var lastPrune time.Time
+
+func prune(now time.Time) {
+	if now.Sub(lastPrune) < time.Minute {
+		return
+	}
+	lastPrune = now
+
+	window := DuplicateWindow
+	if RateWindow > window {
+		window = RateWindow
+	}
+
+	for _, counts := range []map[string]*history{duplicates, students, exams} {
+		for key, h := range counts {
+			if now.Sub(h.last()) >= window {
+				delete(counts, key)
+			}
+		}
+	}
+}
+
+// Reset clears the tracked event history and the counts of the anomalies stored for each reason
+func Reset() {
+	mu.Lock()
+	duplicates = make(map[string]*history)
+	students = make(map[string]*history)
+	exams = make(map[string]*history)
+	recent = make(map[int][]float64)
+	mu.Unlock()
+
+	samplesMu.Lock()
+	stored = make(map[string]int)
+	dropped = make(map[string]int)
+	samplesMu.Unlock()
+	db.Touch(config.AnomalyTable)
+}
@@ -0,0 +1,274 @@
+package anomaly
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/kylegk/sse-rest-server/config"
+	"github.com/kylegk/sse-rest-server/db"
+	"github.com/kylegk/sse-rest-server/models"
+)
+
+// TestCheck validates each of the per-event checks
+func TestCheck(t *testing.T) {
+	Reset()
+	now := time.Now()
+
+	reason, _ := check(models.StudentExam{Exam: 1, StudentID: "test", Score: 1.5}, now)
+	if reason != models.AnomalyOutOfRange {
+		t.Errorf("Incorrect reason; have: %v, want: %v", reason, models.AnomalyOutOfRange)
+	}
+
+	reason, _ = check(models.StudentExam{Exam: 0, StudentID: "test", Score: 0.5}, now)
+	if reason != models.AnomalyMalformed {
+		t.Errorf("Incorrect reason; have: %v, want: %v", reason, models.AnomalyMalformed)
+	}
+
+	// The same event is allowed up to the duplicate limit
+	dup := models.StudentExam{Exam: 1, StudentID: "dup", Score: 0.5}
+	for i := 0; i < DuplicateLimit; i++ {
+		reason, _ = check(dup, now)
+		if reason != "" {
+			t.Fatalf("The event should not have been flagged; have: %v", reason)
+		}
+	}
+	reason, _ = check(dup, now)
+	if reason != models.AnomalyDuplicateFlood {
+		t.Errorf("Incorrect reason; have: %v, want: %v", reason, models.AnomalyDuplicateFlood)
+	}
+
+	// Distinct events for one student are allowed up to the rate limit
+	for i := 0; i < StudentRateLimit; i++ {
+		reason, _ = check(models.StudentExam{Exam: 100 + i, StudentID: "busy", Score: 0.5}, now)
+		if reason != "" {
+			t.Fatalf("The event should not have been flagged; have: %v", reason)
+		}
+	}
+	reason, _ = check(models.StudentExam{Exam: 200, StudentID: "busy", Score: 0.5}, now)
+	if reason != models.AnomalyStudentRate {
+		t.Errorf("Incorrect reason; have: %v, want: %v", reason, models.AnomalyStudentRate)
+	}
+
+	// Once the window has passed the student is allowed again
+	reason, _ = check(models.StudentExam{Exam: 201, StudentID: "busy", Score: 0.5}, now.Add(RateWindow))
+	if reason != "" {
+		t.Errorf("The event should not have been flagged; have: %v", reason)
+	}
+}
+
+// TestInspectAndRelease validates that a quarantined event is reported and can be released into the data store
+func TestInspectAndRelease(t *testing.T) {
+	err := db.InitDB(config.DBSchema)
+	if err != nil {
+		t.Errorf("The database failed to initialize")
+	}
+	Reset()
+
+	quarantined, err := Inspect(models.StudentExam{Exam: 1, StudentID: "test", Score: 2})
+	if err != nil || !quarantined {
+		t.Fatalf("The event should have been quarantined")
+	}
+
+	err = InspectMalformed([]byte("{not json"), errors.New("unexpected end of JSON input"))
+	if err != nil {
+		t.Errorf("Failed to quarantine malformed event")
+	}
+
+	anomalies, err := List(models.AnomalyOutOfRange)
+	if err != nil || len(anomalies) != 1 {
+		t.Fatalf("Incorrect anomalies reported; have: %+v", anomalies)
+	}
+
+	// An event outside of the range cannot be recorded, even when it is released
+	_, err = Release(anomalies[0].ID, nil)
+	if err != ErrOutOfRange {
+		t.Errorf("Releasing an out of range event should have failed; have: %v", err)
+	}
+
+	// A student that sent too many events has the rest quarantined, and they can be released
+	for i := 0; i <= StudentRateLimit; i++ {
+		quarantined, err = Inspect(models.StudentExam{Exam: 10 + i, StudentID: "busy", Score: 0.5})
+	}
+	if err != nil || !quarantined {
+		t.Fatalf("The event should have been quarantined")
+	}
+	flooded, _ := List(models.AnomalyStudentRate)
+	if len(flooded) != 1 {
+		t.Fatalf("The flooded event was not reported; have: %+v", flooded)
+	}
+
+	score, err := Release(flooded[0].ID, nil)
+	if err != nil || score.Exam != 10+StudentRateLimit {
+		t.Errorf("Failed to release the event; have: %+v, %v", score, err)
+	}
+	res, _ := db.GetRows(config.ScoreTable, config.StudentIdx, "busy")
+	if len(res) != 1 {
+		t.Errorf("The released event was not recorded; have: %v", len(res))
+	}
+
+	_, err = Release(flooded[0].ID, nil)
+	if err != ErrNotReleasable {
+		t.Errorf("Releasing twice should have failed; have: %v", err)
+	}
+
+	malformed, _ := List(models.AnomalyMalformed)
+	if len(malformed) != 1 {
+		t.Fatalf("The malformed event was not reported")
+	}
+	_, err = Release(malformed[0].ID, nil)
+	if err != ErrNotReleasable {
+		t.Errorf("Releasing a malformed event should have failed; have: %v", err)
+	}
+}
+
+// TestReleaseConcurrently validates that an event released by several requests at once is only recorded once
+func TestReleaseConcurrently(t *testing.T) {
+	err := db.InitDB(config.DBSchema)
+	if err != nil {
+		t.Errorf("The database failed to initialize")
+	}
+	Reset()
+
+	var quarantined bool
+	for i := 0; i <= StudentRateLimit; i++ {
+		quarantined, err = Inspect(models.StudentExam{Exam: 10 + i, StudentID: "busy", Score: 0.5})
+	}
+	if err != nil || !quarantined {
+		t.Fatalf("The event should have been quarantined")
+	}
+	flooded, _ := List(models.AnomalyStudentRate)
+	if len(flooded) != 1 {
+		t.Fatalf("The flooded event was not reported; have: %+v", flooded)
+	}
+
+	var mu sync.Mutex
+	var released, audited int
+	auditor := func(events []models.ScoreEvent) []interface{} {
+		mu.Lock()
+		audited += len(events)
+		mu.Unlock()
+		return nil
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := Release(flooded[0].ID, auditor)
+			mu.Lock()
+			defer mu.Unlock()
+			if err == nil {
+				released++
+			} else if err != ErrNotReleasable {
+				t.Errorf("Releasing should have failed as already released; have: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	have := released
+	want := 1
+	if have != want {
+		t.Errorf("The event should have been released once; have: %v, want: %v", have, want)
+	}
+	have = audited
+	if have != want {
+		t.Errorf("The release should have been audited once; have: %v, want: %v", have, want)
+	}
+}
+
+// TestObserveShift validates that a sudden change in an exam's scores is reported
+func TestObserveShift(t *testing.T) {
+	err := db.InitDB(config.DBSchema)
+	if err != nil {
+		t.Errorf("The database failed to initialize")
+	}
+	Reset()
+
+	record := func(student int, score float64) {
+		s := models.StudentExam{Exam: 1, StudentID: fmt.Sprintf("student%d", student), Score: score}
+		err := db.UpsertRow(config.ScoreTable, s)
+		if err != nil {
+			t.Fatalf("Failed to insert score")
+		}
+		err = Observe(s)
+		if err != nil {
+			t.Fatalf("Failed to observe score: %v", err)
+		}
+	}
+
+	for i := 0; i < ShiftMinHistory; i++ {
+		record(i, 0.7+float64(i%3)*0.05)
+	}
+
+	shifts, _ := List(models.AnomalyShift)
+	if len(shifts) != 0 {
+		t.Fatalf("A stable exam should not report a shift; have: %+v", shifts)
+	}
+
+	for i := 0; i < ShiftWindow; i++ {
+		record(ShiftMinHistory+i, 0.1)
+	}
+
+	shifts, _ = List(models.AnomalyShift)
+	if len(shifts) != 1 {
+		t.Errorf("The shift was not reported; have: %+v", shifts)
+	}
+}
+
+// TestRecordSamples validates that quarantined anomalies are always stored, while the other anomalies beyond the samples for a reason are counted rather than stored
+func TestRecordSamples(t *testing.T) {
+	err := db.InitDB(config.DBSchema)
+	if err != nil {
+		t.Errorf("The database failed to initialize")
+	}
+	Reset()
+
+	limit := MaxSamples
+	MaxSamples = 3
+	defer func() { MaxSamples = limit }()
+
+	for i := 0; i < 5; i++ {
+		_, err = Inspect(models.StudentExam{Exam: 1, StudentID: fmt.Sprintf("student%d", i), Score: 2})
+		if err != nil {
+			t.Fatalf("Failed to quarantine the event: %v", err)
+		}
+		err = record(models.Anomaly{Reason: models.AnomalyShift, Exam: 1, Detail: fmt.Sprintf("shift %d", i)})
+		if err != nil {
+			t.Fatalf("Failed to record the anomaly: %v", err)
+		}
+	}
+
+	anomalies, _ := List(models.AnomalyOutOfRange)
+	if len(anomalies) != 5 {
+		t.Errorf("Incorrect number of quarantined anomalies stored; have: %v, want: %v", len(anomalies), 5)
+	}
+	if len(anomalies) > 0 && anomalies[0].StudentID != "student0" {
+		t.Errorf("The anomalies are not in the order they were detected; have: %+v", anomalies)
+	}
+
+	anomalies, _ = List(models.AnomalyShift)
+	if len(anomalies) != MaxSamples {
+		t.Errorf("Incorrect number of shift anomalies stored; have: %v, want: %v", len(anomalies), MaxSamples)
+	}
+
+	dropped := Dropped()
+	if dropped[models.AnomalyShift] != 2 || dropped[models.AnomalyOutOfRange] != 0 {
+		t.Errorf("Incorrect dropped counts; have: %v", dropped)
+	}
+
+	// A dropped anomaly changes the version of the anomaly table, as the dropped counts are reported with it
+	version, _ := db.TableVersion(config.AnomalyTable)
+	err = record(models.Anomaly{Reason: models.AnomalyShift, Exam: 1, Detail: "shift"})
+	if err != nil {
+		t.Fatalf("Failed to record the anomaly: %v", err)
+	}
+	have, _ := db.TableVersion(config.AnomalyTable)
+	if have == version {
+		t.Errorf("The anomaly table version should have changed; have: %v", have)
+	}
+}
@@ -0,0 +1,143 @@
+package anomaly
+
+import (
+	"errors"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/kylegk/sse-rest-server/config"
+	"github.com/kylegk/sse-rest-server/db"
+	"github.com/kylegk/sse-rest-server/models"
+)
+
+// ErrNotFound is returned when an anomaly does not exist
+var ErrNotFound = errors.New("anomaly not found")
+
+// ErrNotReleasable is returned when an anomaly has no quarantined event that can be recorded
+var ErrNotReleasable = errors.New("anomaly does not have a quarantined event to release")
+
+// ErrOutOfRange is returned when a quarantined event's score is outside of the range that can be recorded
+var ErrOutOfRange = errors.New("anomaly's event has a score outside of the range that can be recorded")
+
+// MaxSamples is the number of anomalies stored for each reason that does not quarantine its event
+// A flood of events would otherwise fill the data store, so the anomalies detected after that are only counted
+// Quarantined anomalies are always stored, since they hold the only copy of an event that may still be released
+var MaxSamples = 1000
+
+var (
+	samplesMu sync.Mutex
+	stored    = make(map[string]int)
+	dropped   = make(map[string]int)
+)
+
+// Store a detected anomaly, or count it when it was not quarantined and the samples for its reason are full
+func record(a models.Anomaly) error {
+	samplesMu.Lock()
+	defer samplesMu.Unlock()
+
+	if !a.Quarantined && stored[a.Reason] >= MaxSamples {
+		dropped[a.Reason]++
+		// The dropped counts are reported with the anomalies, so cached reports must be revalidated
+		db.Touch(config.AnomalyTable)
+		return nil
+	}
+
+	a.ID = db.NewID()
+	a.DetectedAt = time.Now().UTC()
+
+	err := db.UpsertRow(config.AnomalyTable, a)
+	if err != nil {
+		return err
+	}
+	stored[a.Reason]++
+
+	return nil
+}
+
+// Dropped reports the number of anomalies detected for each reason that were not stored, because the samples for the reason were full
+func Dropped() map[string]int {
+	samplesMu.Lock()
+	defer samplesMu.Unlock()
+
+	counts := make(map[string]int, len(dropped))
+	for reason, count := range dropped {
+		counts[reason] = count
+	}
+
+	return counts
+}
+
+// List retrieves the stored anomalies in the order they were detected, optionally filtered by reason
+func List(reason string) ([]models.Anomaly, error) {
+	var res []interface{}
+	var err error
+	if reason != "" {
+		res, err = db.GetRows(config.AnomalyTable, config.ReasonIdx, reason)
+	} else {
+		res, err = db.GetRows(config.AnomalyTable, config.IdFld)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	anomalies := make([]models.Anomaly, 0, len(res))
+	for _, row := range res {
+		anomalies = append(anomalies, row.(models.Anomaly))
+	}
+	sort.SliceStable(anomalies, func(i, j int) bool {
+		return anomalies[i].DetectedAt.Before(anomalies[j].DetectedAt)
+	})
+
+	return anomalies, nil
+}
+
+// Release records a quarantined event that was flagged incorrectly, returning the recorded score
+// The anomaly is checked and marked as released in the same transaction that records the score, so an event is only
+// recorded once when it is released concurrently; its score must still be in range
+// The auditor, if any, records the change to the score in the audit log
+func Release(id string, auditor db.Auditor) (models.StudentExam, error) {
+	var score models.StudentExam
+	_, err := db.Update(auditor, func(txn *db.Txn) error {
+		res, err := txn.GetRows(config.AnomalyTable, config.IdFld, id)
+		if err != nil {
+			return err
+		}
+		if len(res) == 0 {
+			return ErrNotFound
+		}
+
+		a := res[0].(models.Anomaly)
+		if !a.Quarantined || a.Event == nil || a.Reason == models.AnomalyMalformed {
+			return ErrNotReleasable
+		}
+
+		score = *a.Event
+		if !InRange(score.Score) {
+			return ErrOutOfRange
+		}
+
+		err = txn.UpsertRow(config.ScoreTable, score)
+		if err != nil {
+			return err
+		}
+
+		now := time.Now().UTC()
+		a.Quarantined = false
+		a.ReleasedAt = &now
+
+		return txn.UpsertRow(config.AnomalyTable, a)
+	})
+	if err != nil {
+		return models.StudentExam{}, err
+	}
+
+	// The released score goes through the rest of the ingest path, as the events that pass the checks do
+	err = Observe(score)
+	if err != nil {
+		log.Println(err)
+	}
+
+	return score, nil
+}
@@ -0,0 +1,238 @@
+package apikey
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/kylegk/sse-rest-server/config"
+	"github.com/kylegk/sse-rest-server/db"
+	"github.com/kylegk/sse-rest-server/models"
+)
+
+// The prefix of the generated keys, so a key is recognizable if it is leaked
+const keyPrefix = "sk_"
+
+// Define the errors returned when verifying and revoking keys
+var (
+	ErrInvalidKey = errors.New("invalid API key")
+	ErrNotFound   = errors.New("API key not found")
+	ErrLastAdmin  = errors.New("the last admin API key cannot be revoked")
+)
+
+// Set once a key has been stored, and cleared when the database is created
+var enabled int32
+
+func init() {
+	db.OnInit(func() {
+		atomic.StoreInt32(&enabled, 0)
+	})
+}
+
+// A key in a key file, which holds the hex encoded SHA-256 hash of each key rather than the key
+type fileKey struct {
+	Name  string `json:"name"`
+	Hash  string `json:"hash"`
+	Admin bool   `json:"admin"`
+}
+
+// Hash returns the hex encoded SHA-256 hash a key is stored by
+// The keys are random, so a fast unsalted hash is enough to keep them from being recovered from the store
+func Hash(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// LoadKeys stores the keys in a JSON file, an array of objects with the name, hash and admin fields of each key
+func LoadKeys(path string) error {
+	bytes, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	keys := make([]fileKey, 0)
+	err = json.Unmarshal(bytes, &keys)
+	if err != nil {
+		return fmt.Errorf("unable to parse API key file %s: %v", path, err)
+	}
+
+	for _, key := range keys {
+		hash := strings.ToLower(key.Hash)
+		if _, decodeErr := hex.DecodeString(hash); decodeErr != nil || len(hash) != sha256.Size*2 {
+			return fmt.Errorf("invalid API key %q: the hash must be a hex encoded SHA-256 hash", key.Name)
+		}
+
+		err = store(key.Name, hash, key.Admin)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// AddKey stores a key that was given in plain text, such as in the environment, by its hash
+func AddKey(name string, key string, admin bool) error {
+	if key == "" {
+		return fmt.Errorf("invalid API key %q: the key is empty", name)
+	}
+
+	return store(name, Hash(key), admin)
+}
+
+// Create generates and stores a new key, returning it with the key set; the key cannot be retrieved again
+// The auditor, if any, records the new key in the audit log
+func Create(name string, admin bool, auditor db.ResourceAuditor) (models.APIKey, error) {
+	b := make([]byte, 32)
+	_, err := rand.Read(b)
+	if err != nil {
+		return models.APIKey{}, err
+	}
+
+	key := keyPrefix + hex.EncodeToString(b)
+	apiKey, err := newKey(name, Hash(key), admin)
+	if err != nil {
+		return apiKey, err
+	}
+
+	var audited db.Auditor
+	if auditor != nil {
+		audited = auditor(apiKey.ID)
+	}
+	_, err = db.Update(audited, func(txn *db.Txn) error {
+		return txn.UpsertRow(config.APIKeyTable, apiKey)
+	})
+	if err != nil {
+		return models.APIKey{}, err
+	}
+	atomic.StoreInt32(&enabled, 1)
+
+	apiKey.Key = key
+	apiKey.Hash = ""
+
+	return apiKey, nil
+}
+
+// List retrieves every key, without their hashes
+func List() ([]models.APIKey, error) {
+	res, err := db.GetRows(config.APIKeyTable, config.IdFld)
+	if err != nil {
+		return nil, err
+	}
+
+	keys := make([]models.APIKey, 0, len(res))
+	for _, row := range res {
+		key := row.(models.APIKey)
+		key.Hash = ""
+		keys = append(keys, key)
+	}
+
+	return keys, nil
+}
+
+// Revoke removes a key, refusing to remove the last admin key so the keys can still be managed
+// The admin keys are counted in the same transaction as the key is removed, so concurrent revokes cannot remove every admin key
+// The auditor, if any, records the revoke in the audit log
+func Revoke(id string, auditor db.Auditor) error {
+	_, err := db.Update(auditor, func(txn *db.Txn) error {
+		res, err := txn.GetRows(config.APIKeyTable, config.IdFld, id)
+		if err != nil {
+			return err
+		}
+		if len(res) == 0 {
+			return ErrNotFound
+		}
+
+		key := res[0].(models.APIKey)
+		if key.Admin {
+			admins, err := countAdmins(txn)
+			if err != nil {
+				return err
+			}
+			if admins <= 1 {
+				return ErrLastAdmin
+			}
+		}
+
+		return txn.DeleteRow(config.APIKeyTable, key)
+	})
+
+	return err
+}
+
+// Verify finds the stored key matching a key sent by a client
+func Verify(key string) (models.APIKey, error) {
+	res, err := db.GetRows(config.APIKeyTable, config.HashIdx, Hash(key))
+	if err != nil {
+		return models.APIKey{}, err
+	}
+	if len(res) == 0 {
+		return models.APIKey{}, ErrInvalidKey
+	}
+
+	found := res[0].(models.APIKey)
+	found.Hash = ""
+
+	return found, nil
+}
+
+// Enabled reports whether a key has been stored since the database was created; the API only requires a key once one has been configured
+// Revoking keys does not turn the keys off again, so removing every non-admin key never opens up the API
+func Enabled() bool {
+	return atomic.LoadInt32(&enabled) == 1
+}
+
+func store(name string, hash string, admin bool) error {
+	key, err := newKey(name, hash, admin)
+	if err != nil {
+		return err
+	}
+
+	// The hash index is unique, so the same key cannot be stored under two names
+	existing, err := db.GetRows(config.APIKeyTable, config.HashIdx, hash)
+	if err != nil {
+		return err
+	}
+	if len(existing) > 0 {
+		return fmt.Errorf("invalid API key %q: the key is already stored as %q", name, existing[0].(models.APIKey).Name)
+	}
+
+	err = db.UpsertRow(config.APIKeyTable, key)
+	if err != nil {
+		return err
+	}
+	atomic.StoreInt32(&enabled, 1)
+
+	return nil
+}
+
+func newKey(name string, hash string, admin bool) (models.APIKey, error) {
+	if strings.TrimSpace(name) == "" {
+		return models.APIKey{}, fmt.Errorf("invalid name: a name is required")
+	}
+
+	return models.APIKey{ID: db.NewID(), Name: name, Admin: admin, Hash: hash, CreatedAt: time.Now().UTC()}, nil
+}
+
+func countAdmins(txn *db.Txn) (int, error) {
+	res, err := txn.GetRows(config.APIKeyTable, config.IdFld)
+	if err != nil {
+		return 0, err
+	}
+
+	admins := 0
+	for _, row := range res {
+		if row.(models.APIKey).Admin {
+			admins++
+		}
+	}
+
+	return admins, nil
+}
@@ -0,0 +1,179 @@
+package apikey
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sync"
+	"testing"
+
+	"github.com/kylegk/sse-rest-server/config"
+	"github.com/kylegk/sse-rest-server/db"
+)
+
+func setupTestDB(t *testing.T) {
+	err := db.InitDB(config.DBSchema)
+	if err != nil {
+		t.Fatalf("Failed to initialize the database")
+	}
+}
+
+func TestLoadKeys(t *testing.T) {
+	setupTestDB(t)
+
+	file, err := ioutil.TempFile("", "keys*.json")
+	if err != nil {
+		t.Fatalf("Unable to create the key file")
+	}
+	defer os.Remove(file.Name())
+
+	_, _ = file.WriteString(`[{"name": "dashboard", "hash": "` + Hash("dashboard-key") + `"}, {"name": "ops", "hash": "` + Hash("ops-key") + `", "admin": true}]`)
+	file.Close()
+
+	if Enabled() {
+		t.Errorf("Keys are required before any key is stored")
+	}
+
+	err = LoadKeys(file.Name())
+	if err != nil {
+		t.Errorf("Unable to load the keys; %v", err)
+	}
+	if !Enabled() {
+		t.Errorf("Keys are not required once a key is stored")
+	}
+
+	key, err := Verify("ops-key")
+	if err != nil || key.Name != "ops" || !key.Admin || key.Hash != "" {
+		t.Errorf("Incorrect key verified; have: %+v, %v", key, err)
+	}
+
+	_, err = Verify(Hash("ops-key"))
+	have := err
+	want := ErrInvalidKey
+	if have != want {
+		t.Errorf("The hash of a key was accepted as the key; have: %v, want: %v", have, want)
+	}
+
+	// Verify a file with a key that is not a hash, or a key that is already stored, is rejected
+	for _, contents := range []string{`[{"name": "plain", "hash": "not-a-hash"}]`, `[{"name": "again", "hash": "` + Hash("dashboard-key") + `"}]`} {
+		_ = ioutil.WriteFile(file.Name(), []byte(contents), 0600)
+		err = LoadKeys(file.Name())
+		if err == nil {
+			t.Errorf("Invalid key file was loaded; %v", contents)
+		}
+	}
+}
+
+func TestCreateAndRevoke(t *testing.T) {
+	setupTestDB(t)
+
+	err := AddKey("ADMIN_API_KEY", "admin-key", true)
+	if err != nil {
+		t.Errorf("Unable to add the key; %v", err)
+	}
+
+	created, err := Create("dashboard", false, nil)
+	if err != nil {
+		t.Errorf("Unable to create the key; %v", err)
+	}
+	if created.Key == "" || created.ID == "" || created.Hash != "" {
+		t.Errorf("Incorrect key created; have: %+v", created)
+	}
+
+	key, err := Verify(created.Key)
+	if err != nil || key.ID != created.ID || key.Key != "" {
+		t.Errorf("Created key was not verified; have: %+v, %v", key, err)
+	}
+
+	keys, err := List()
+	if err != nil || len(keys) != 2 {
+		t.Errorf("Incorrect keys listed; have: %+v, %v", keys, err)
+	}
+	for _, key := range keys {
+		if key.Hash != "" || key.Key != "" {
+			t.Errorf("Listed key includes its secret; have: %+v", key)
+		}
+	}
+
+	err = Revoke(created.ID, nil)
+	if err != nil {
+		t.Errorf("Unable to revoke the key; %v", err)
+	}
+	_, err = Verify(created.Key)
+	if err != ErrInvalidKey {
+		t.Errorf("Revoked key was verified; have: %v", err)
+	}
+
+	admin, _ := Verify("admin-key")
+	have := Revoke(admin.ID, nil)
+	want := ErrLastAdmin
+	if have != want {
+		t.Errorf("Last admin key was revoked; have: %v, want: %v", have, want)
+	}
+
+	have = Revoke("missing", nil)
+	want = ErrNotFound
+	if have != want {
+		t.Errorf("Incorrect error revoking a missing key; have: %v, want: %v", have, want)
+	}
+}
+
+func TestRevokeConcurrently(t *testing.T) {
+	setupTestDB(t)
+
+	var ids []string
+	for i := 0; i < 5; i++ {
+		key, err := Create(fmt.Sprintf("admin%d", i), true, nil)
+		if err != nil {
+			t.Fatalf("Unable to create the key; %v", err)
+		}
+		ids = append(ids, key.ID)
+	}
+
+	// Verify concurrent revokes never remove the last admin key
+	var wg sync.WaitGroup
+	for _, id := range ids {
+		wg.Add(1)
+		go func(id string) {
+			defer wg.Done()
+			Revoke(id, nil)
+		}(id)
+	}
+	wg.Wait()
+
+	keys, _ := List()
+	have := len(keys)
+	want := 1
+	if have != want {
+		t.Errorf("Incorrect number of admin keys left; have: %v, want: %v", have, want)
+	}
+}
+
+func TestEnabled(t *testing.T) {
+	setupTestDB(t)
+	if Enabled() {
+		t.Errorf("Keys are enabled before any key is stored")
+	}
+
+	created, err := Create("dashboard", false, nil)
+	if err != nil {
+		t.Fatalf("Unable to create the key; %v", err)
+	}
+	if !Enabled() {
+		t.Errorf("Keys are not enabled after a key is stored")
+	}
+
+	// Verify revoking every key does not turn the keys off
+	err = Revoke(created.ID, nil)
+	if err != nil {
+		t.Errorf("Unable to revoke the key; %v", err)
+	}
+	if !Enabled() {
+		t.Errorf("Keys are not enabled after the keys are revoked")
+	}
+
+	setupTestDB(t)
+	if Enabled() {
+		t.Errorf("Keys are enabled after the database is created")
+	}
+}
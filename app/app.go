@@ -3,15 +3,30 @@ package app
 import (
 	"fmt"
 	"github.com/gorilla/mux"
+	"github.com/kylegk/sse-rest-server/alerts"
+	"github.com/kylegk/sse-rest-server/apikey"
+	"github.com/kylegk/sse-rest-server/auth"
 	"github.com/kylegk/sse-rest-server/config"
 	"github.com/kylegk/sse-rest-server/db"
+	"github.com/kylegk/sse-rest-server/grading"
 	"github.com/kylegk/sse-rest-server/handler"
+	"github.com/kylegk/sse-rest-server/ratelimit"
+	"github.com/kylegk/sse-rest-server/rpc"
 	"github.com/kylegk/sse-rest-server/sse"
+	"github.com/kylegk/sse-rest-server/webhook"
 	"log"
 	"net/http"
 	"os"
+	"strconv"
+	"time"
 )
 
+// The number of goroutines delivering webhook payloads
+const webhookWorkers = 4
+
+// How often deleted exams whose grace period has expired are purged
+const purgeInterval = time.Minute
+
 // Init creates the database, adds the routes to be handled and performs any other initial setup required
 func Init(c config.Config) {
 	var err error
@@ -33,7 +48,71 @@ func Init(c config.Config) {
 		return
 	}
 
+	err = setupGrading(c)
+	if err != nil {
+		log.Println(err)
+		return
+	}
+
+	if c.AlertRuleFile != "" {
+		err = alerts.LoadRules(c.AlertRuleFile)
+		if err != nil {
+			log.Println(err)
+			return
+		}
+	}
+
+	if c.DeleteGracePeriod != "" {
+		db.GracePeriod, err = time.ParseDuration(c.DeleteGracePeriod)
+		if err != nil || db.GracePeriod < 0 {
+			err = fmt.Errorf("invalid %s: must be a non-negative duration, such as 72h", config.EnvDeleteGracePeriod)
+			log.Println(err)
+			return
+		}
+	}
+	db.StartPurge(purgeInterval)
+
+	if c.IdempotencyWindow != "" {
+		handler.IdempotencyWindow, err = time.ParseDuration(c.IdempotencyWindow)
+		if err != nil || handler.IdempotencyWindow <= 0 {
+			err = fmt.Errorf("invalid %s: must be a positive duration, such as 1h", config.EnvIdempotencyWindow)
+			log.Println(err)
+			return
+		}
+	}
+
+	if c.LegacySunset != "" {
+		handler.LegacySunset, err = time.Parse("2006-01-02", c.LegacySunset)
+		if err != nil {
+			err = fmt.Errorf("invalid %s: must be a date, such as 2027-06-30", config.EnvLegacySunset)
+			log.Println(err)
+			return
+		}
+	}
+
+	err = setupAuth(c)
+	if err != nil {
+		log.Println(err)
+		return
+	}
+
+	err = setupRateLimit(c)
+	if err != nil {
+		log.Println(err)
+		return
+	}
+
+	alerts.Start()
+	webhook.Start(webhookWorkers)
 	sse.IngestData(c.SSEServerUrl)
+
+	// The gRPC server shares the datastore with the REST API, on its own port
+	if c.GRPCPort != "" {
+		go func() {
+			log.Fatal(rpc.Serve(c.GRPCPort))
+		}()
+	}
+
 	addRoutes(c.PORT)
 }
 
@@ -42,28 +121,221 @@ func validateConfig(c config.Config) error {
 		return fmt.Errorf("invalid configuration")
 	}
 
+	if c.GRPCPort != "" && c.GRPCPort == c.PORT {
+		return fmt.Errorf("invalid %s: must differ from %s", config.EnvGRPCPort, config.EnvPort)
+	}
+
 	return nil
 }
 
+// Load any custom grading policies and scales, and select the defaults
+func setupGrading(c config.Config) error {
+	if c.GradingPolicyFile != "" {
+		err := grading.LoadPolicies(c.GradingPolicyFile)
+		if err != nil {
+			return err
+		}
+	}
+
+	if c.DefaultGradingPolicy != "" {
+		err := grading.SetDefaultPolicy(c.DefaultGradingPolicy)
+		if err != nil {
+			return err
+		}
+	}
+
+	if c.GradingScaleFile != "" {
+		err := grading.LoadScales(c.GradingScaleFile)
+		if err != nil {
+			return err
+		}
+	}
+
+	if c.DefaultGradingScale != "" {
+		return grading.SetDefaultScale(c.DefaultGradingScale)
+	}
+
+	return nil
+}
+
+// Load the API keys, the key set bearer tokens are verified with and the cohorts of the teachers
+// The API is left open when neither keys nor a key set are configured
+func setupAuth(c config.Config) error {
+	if c.APIKeyFile != "" {
+		err := apikey.LoadKeys(c.APIKeyFile)
+		if err != nil {
+			return err
+		}
+	}
+
+	if c.AdminAPIKey != "" {
+		err := apikey.AddKey(config.EnvAdminAPIKey, c.AdminAPIKey, true)
+		if err != nil {
+			return err
+		}
+	}
+
+	if c.JWKSFile != "" {
+		err := auth.LoadJWKS(c.JWKSFile)
+		if err != nil {
+			return err
+		}
+		auth.Issuer = c.JWTIssuer
+		auth.Audience = c.JWTAudience
+	}
+
+	if c.CohortFile != "" {
+		err := auth.LoadCohorts(c.CohortFile)
+		if err != nil {
+			return err
+		}
+	}
+
+	if !auth.Required() {
+		log.Printf("no API keys or JWKS are configured, so the API does not require authentication; set %s, %s or %s to require it\n", config.EnvAdminAPIKey, config.EnvAPIKeyFile, config.EnvJWKSFile)
+	}
+
+	return nil
+}
+
+// Limit the rate of requests of every client, with the default limits unless they are configured
+func setupRateLimit(c config.Config) error {
+	perMinute, size := ratelimit.DefaultRate, ratelimit.DefaultBurst
+
+	var err error
+	if c.RateLimit != "" {
+		perMinute, err = strconv.Atoi(c.RateLimit)
+		if err != nil || perMinute < 0 {
+			return fmt.Errorf("invalid %s: must be a number of requests a minute, or 0 to turn the limits off", config.EnvRateLimit)
+		}
+	}
+
+	if c.RateLimitBurst != "" {
+		size, err = strconv.Atoi(c.RateLimitBurst)
+		if err != nil || size <= 0 {
+			return fmt.Errorf("invalid %s: must be a positive number of requests", config.EnvRateLimitBurst)
+		}
+	}
+
+	ratelimit.Setup(perMinute, size)
+	if perMinute == 0 {
+		log.Printf("%s is 0, so requests are not rate limited\n", config.EnvRateLimit)
+	}
+
+	return nil
+}
+
+// The number of tokens of the client's rate limit each route costs; the routes that are not listed cost one token
+// The routes that read every score, or write many, cost more than the routes that read a single student or exam
+var routeCosts = map[string]int{
+	"GET /students":              5,
+	"GET /students/compare":      2,
+	"GET /exams":                 5,
+	"GET /exams/all":             20,
+	"GET /analytics/correlation": 10,
+	"POST /import":               20,
+	"GET /admin/audit":           10,
+	"GET /graphql":               5,
+	"POST /graphql":              5,
+}
+
 // Initialize the routes to be served and setup any middleware applied to the routes
 func addRoutes(port string) {
 	router := mux.NewRouter()
 	router.NotFoundHandler = http.HandlerFunc(handler.SendGenericNotFoundResponse)
 	router.MethodNotAllowedHandler = http.HandlerFunc(handler.SendGenericNotAllowedResponse)
 
-	// Student route handlers
-	router.HandleFunc("/students", handler.GetAllStudents).Methods("GET")
-	router.HandleFunc("/students/{id}", handler.GetStudentByID).Methods("GET")
+	// Each version of the API is mounted under its own prefix, so a new version can change its handlers
+	// while the routes of the older versions keep serving the same responses
+	addV1Routes(router.PathPrefix("/v1").Subrouter())
 
-	// Exam route handlers
-	router.HandleFunc("/exams", handler.GetAllUniqueExamIDs).Methods("GET")
-	router.HandleFunc("/exams/all", handler.GetAllExams).Methods("GET")
-	router.HandleFunc("/exams/{id}", handler.GetExamByID).Methods("GET")
-	router.HandleFunc("/exams/{id}", handler.DeleteExam).Methods("DELETE")
-	router.HandleFunc("/exams", handler.AddExam).Methods("POST")
+	// The description of the API, and a page to browse it
+	router.HandleFunc("/openapi.json", handler.GetOpenAPISpec).Methods("GET")
+	router.HandleFunc("/docs", handler.GetAPIDocs).Methods("GET")
+
+	// GraphQL is not versioned with the REST routes; its schema evolves by adding fields
+	router.Handle("/graphql", handler.Authorize(handler.StaffOnly, http.HandlerFunc(handler.GraphQL))).Methods("GET", "POST")
+
+	// The unversioned paths are deprecated aliases of /v1, registered after the versions so they never shadow them
+	legacy := router.NewRoute().Subrouter()
+	legacy.Use(handler.Deprecated("/v1"))
+	addV1Routes(legacy)
 
 	// Add panic middleware
 	router.Use(handler.PanicRecovery)
 
-	log.Fatal(http.ListenAndServe(port, handler.LogRequest(router)))
+	// Require an API key or bearer token, except to read the description of the API
+	// Authentication runs before idempotency, so a cached response is never replayed to an unauthenticated client
+	router.Use(handler.Authenticate("/openapi.json", "/docs"))
+
+	// Limit the rate of requests of each client, once it has been identified
+	router.Use(handler.RateLimit("/v1", routeCosts))
+
+	// Replay the responses to retried write requests
+	router.Use(handler.Idempotency)
+
+	// Identify every request, including those that match no route, so the changes it made can be found in the audit log
+	log.Fatal(http.ListenAndServe(port, handler.LogRequest(handler.RequestID(router))))
+}
+
+// Add the routes of version 1 of the API
+// Every route is wrapped with the policy deciding which roles can use it: students can only read their own scores,
+// teachers can read the scores of their cohorts and add scores, and only admins can delete or change anything else
+func addV1Routes(router *mux.Router) {
+	// Read handlers are wrapped with the tables they read, to support conditional requests
+
+	// Student route handlers
+	router.Handle("/students", handler.Authorize(handler.StaffOnly, handler.Conditional(handler.GetAllStudents, config.ScoreTable))).Methods("GET")
+	// Registered before /students/{id} so "compare" is not treated as a student id
+	router.Handle("/students/compare", handler.Authorize(handler.StaffStudentsInQuery("ids"), handler.Conditional(handler.CompareStudents, config.ScoreTable))).Methods("GET")
+	router.Handle("/students/{id}", handler.Authorize(handler.StudentInPath("id"), handler.Conditional(handler.GetStudentByID, config.ScoreTable, config.ExamMetaTable))).Methods("GET")
+	router.Handle("/students/{id}", handler.Authorize(handler.AdminOnly, http.HandlerFunc(handler.DeleteStudent))).Methods("DELETE")
+	router.Handle("/students/{id}/trend", handler.Authorize(handler.StudentInPath("id"), handler.Conditional(handler.GetStudentTrend, config.ScoreTable))).Methods("GET")
+
+	// Exam route handlers
+	router.Handle("/exams", handler.Authorize(handler.StaffOnly, handler.Conditional(handler.GetAllUniqueExamIDs, config.ScoreTable))).Methods("GET")
+	router.Handle("/exams/all", handler.Authorize(handler.StaffOnly, handler.Conditional(handler.GetAllExams, config.ScoreTable))).Methods("GET")
+	router.Handle("/exams/{id}", handler.Authorize(handler.StaffOnly, handler.Conditional(handler.GetExamByID, config.ScoreTable))).Methods("GET")
+	router.Handle("/exams/{id}", handler.Authorize(handler.AdminOnly, http.HandlerFunc(handler.DeleteExam))).Methods("DELETE")
+	router.Handle("/exams/{id}/restore", handler.Authorize(handler.AdminOnly, http.HandlerFunc(handler.RestoreExam))).Methods("POST")
+	// The students of the scores in the body are checked against the teacher's cohorts by the handler
+	router.Handle("/exams", handler.Authorize(handler.StaffOnly, http.HandlerFunc(handler.AddExam))).Methods("POST")
+	router.Handle("/exams/{id}/curve", handler.Authorize(handler.AdminOnly, http.HandlerFunc(handler.CurveExam))).Methods("POST")
+	router.Handle("/exams/{id}/grade-distribution", handler.Authorize(handler.StaffOnly, handler.Conditional(handler.GetExamGradeDistribution, config.ScoreTable))).Methods("GET")
+	router.Handle("/exams/{id}/metadata", handler.Authorize(handler.StaffOnly, handler.Conditional(handler.GetExamMetadata, config.ExamMetaTable))).Methods("GET")
+	router.Handle("/exams/{id}/metadata", handler.Authorize(handler.AdminOnly, http.HandlerFunc(handler.PutExamMetadata))).Methods("PUT")
+	router.Handle("/exams/{id}/students/{student}", handler.Authorize(handler.StaffStudentInPath("student"), http.HandlerFunc(handler.PutScore))).Methods("PUT")
+	router.Handle("/exams/{id}/students/{student}", handler.Authorize(handler.StaffStudentInPath("student"), http.HandlerFunc(handler.PatchScore))).Methods("PATCH")
+	router.Handle("/exams/{id}/students/{student}", handler.Authorize(handler.AdminOnly, http.HandlerFunc(handler.DeleteScore))).Methods("DELETE")
+
+	// Import route handlers
+	router.Handle("/import", handler.Authorize(handler.StaffOnly, http.HandlerFunc(handler.ImportScores))).Methods("POST")
+
+	// Analytics route handlers
+	router.Handle("/analytics/correlation", handler.Authorize(handler.StaffOnly, handler.Conditional(handler.GetExamCorrelation, config.ScoreTable))).Methods("GET")
+
+	// Alert route handlers
+	router.Handle("/alerts", handler.Authorize(handler.StaffOnly, handler.Conditional(handler.GetAlerts, config.AlertTable))).Methods("GET")
+	router.Handle("/alerts/{id}", handler.Authorize(handler.StaffOnly, handler.Conditional(handler.GetAlertByID, config.AlertTable))).Methods("GET")
+	router.Handle("/alerts/{id}/acknowledge", handler.Authorize(handler.AdminOnly, http.HandlerFunc(handler.AcknowledgeAlert))).Methods("POST")
+	router.Handle("/alerts/{id}/resolve", handler.Authorize(handler.AdminOnly, http.HandlerFunc(handler.ResolveAlert))).Methods("POST")
+
+	// Anomaly route handlers
+	router.Handle("/anomalies", handler.Authorize(handler.StaffOnly, handler.Conditional(handler.GetAnomalyReport, config.AnomalyTable))).Methods("GET")
+	router.Handle("/anomalies/{id}/release", handler.Authorize(handler.AdminOnly, http.HandlerFunc(handler.ReleaseAnomaly))).Methods("POST")
+
+	// Webhook route handlers
+	router.Handle("/webhooks", handler.Authorize(handler.AdminOnly, handler.Conditional(handler.GetWebhooks, config.WebhookTable))).Methods("GET")
+	router.Handle("/webhooks", handler.Authorize(handler.AdminOnly, http.HandlerFunc(handler.AddWebhook))).Methods("POST")
+	router.Handle("/webhooks/{id}", handler.Authorize(handler.AdminOnly, handler.Conditional(handler.GetWebhookByID, config.WebhookTable))).Methods("GET")
+	router.Handle("/webhooks/{id}", handler.Authorize(handler.AdminOnly, http.HandlerFunc(handler.DeleteWebhook))).Methods("DELETE")
+	router.Handle("/webhooks/{id}/deliveries", handler.Authorize(handler.AdminOnly, handler.Conditional(handler.GetWebhookDeliveries, config.WebhookTable, config.WebhookDeliveryTable))).Methods("GET")
+
+	// API key route handlers, which require an admin even before any credentials are configured
+	router.Handle("/admin/keys", handler.RequireAdmin(handler.Conditional(handler.GetAPIKeys, config.APIKeyTable))).Methods("GET")
+	router.Handle("/admin/keys", handler.RequireAdmin(http.HandlerFunc(handler.AddAPIKey))).Methods("POST")
+	router.Handle("/admin/keys/{id}", handler.RequireAdmin(http.HandlerFunc(handler.DeleteAPIKey))).Methods("DELETE")
+
+	// Audit log route handlers
+	router.Handle("/admin/audit", handler.RequireAdmin(handler.Conditional(handler.GetAuditLog, config.AuditTable))).Methods("GET")
 }
@@ -0,0 +1,68 @@
+package app
+
+import (
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/kylegk/sse-rest-server/openapi"
+)
+
+func TestRoutesDocumented(t *testing.T) {
+	router := mux.NewRouter()
+	addV1Routes(router)
+
+	registered := make(map[string]bool)
+	err := router.Walk(func(route *mux.Route, router *mux.Router, ancestors []*mux.Route) error {
+		path, err := route.GetPathTemplate()
+		if err != nil {
+			return err
+		}
+		methods, err := route.GetMethods()
+		if err != nil {
+			return err
+		}
+
+		for _, method := range methods {
+			registered[method+" "+path] = true
+			if openapi.Spec().Operation(method, path) == nil {
+				t.Errorf("Route is not documented; %v %v", method, path)
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		t.Errorf("Unable to walk the routes; %v", err)
+	}
+
+	// Verify the document does not describe routes that are not registered
+	for _, route := range openapi.Spec().Routes() {
+		if !registered[route] {
+			t.Errorf("Documented route is not registered; %v", route)
+		}
+	}
+}
+
+func TestRouteCostsRegistered(t *testing.T) {
+	router := mux.NewRouter()
+	addV1Routes(router)
+	router.HandleFunc("/graphql", nil).Methods("GET", "POST")
+
+	registered := make(map[string]bool)
+	_ = router.Walk(func(route *mux.Route, router *mux.Router, ancestors []*mux.Route) error {
+		path, _ := route.GetPathTemplate()
+		methods, _ := route.GetMethods()
+		for _, method := range methods {
+			registered[method+" "+path] = true
+		}
+
+		return nil
+	})
+
+	// A cost for a route that is not registered, such as one with a typo in its path, would never be charged
+	for route := range routeCosts {
+		if !registered[route] {
+			t.Errorf("Route with a cost is not registered; %v", route)
+		}
+	}
+}
@@ -0,0 +1,155 @@
+package audit
+
+import (
+	"fmt"
+	"sort"
+	"sync/atomic"
+	"time"
+
+	"github.com/kylegk/sse-rest-server/config"
+	"github.com/kylegk/sse-rest-server/db"
+	"github.com/kylegk/sse-rest-server/models"
+)
+
+// IngestionActor is the actor of the changes made by the scores ingested from the SSE server
+const IngestionActor = "ingestion"
+
+// AnonymousActor is the actor of the changes made by requests without credentials, before authentication is configured
+const AnonymousActor = "anonymous"
+
+// Source identifies who made a change and the request it was made in
+type Source struct {
+	Actor     string
+	Role      string
+	IP        string
+	RequestID string
+}
+
+// Filter limits the entries returned by List; an empty field matches every entry
+type Filter struct {
+	Action     string
+	Actor      string
+	Exam       int
+	StudentID  string
+	Resource   string
+	ResourceID string
+	RequestID  string
+	Since      time.Time
+	Until      time.Time
+}
+
+// The sequence of the last recorded entry; entry ids are built from it so the id index lists the entries in the order they were recorded
+var sequence uint64
+
+// Auditor builds an entry of the audit log for each change a write makes to the scores, to be passed to the db write
+// so the entries are written in the same transaction as the changes
+// The log is append-only: entries are never updated or removed
+func Auditor(action string, source Source) db.Auditor {
+	return func(events []models.ScoreEvent) []interface{} {
+		entries := make([]interface{}, 0, len(events))
+		for _, event := range events {
+			entry := models.AuditEntry{
+				ID:        fmt.Sprintf("%016x", atomic.AddUint64(&sequence, 1)),
+				Action:    action,
+				Actor:     source.Actor,
+				Role:      source.Role,
+				IP:        source.IP,
+				RequestID: source.RequestID,
+				Before:    event.Before,
+				After:     event.After,
+				Time:      event.Time,
+			}
+
+			score := event.After
+			if score == nil {
+				score = event.Before
+			}
+			if score != nil {
+				entry.Exam = score.Exam
+				entry.StudentID = score.StudentID
+			}
+
+			entries = append(entries, entry)
+		}
+
+		return entries
+	}
+}
+
+// ResourceAuditor builds the entry of the audit log for a change to a resource other than the scores, such as an API key or webhook,
+// to be passed to the db write so the entry is written in the same transaction as the change
+// The entry only identifies the resource, so secrets such as keys are never written to the log
+func ResourceAuditor(action string, source Source, resource string, id string) db.Auditor {
+	return func([]models.ScoreEvent) []interface{} {
+		return []interface{}{models.AuditEntry{
+			ID:         fmt.Sprintf("%016x", atomic.AddUint64(&sequence, 1)),
+			Action:     action,
+			Actor:      source.Actor,
+			Role:       source.Role,
+			IP:         source.IP,
+			RequestID:  source.RequestID,
+			Resource:   resource,
+			ResourceID: id,
+			Time:       time.Now().UTC(),
+		}}
+	}
+}
+
+// List returns the entries matching the filter, in the order they were recorded
+func List(filter Filter) ([]models.AuditEntry, error) {
+	var res []interface{}
+	var err error
+	switch {
+	case filter.Exam != 0:
+		res, err = db.GetRows(config.AuditTable, config.ExamIdx, filter.Exam)
+	case filter.StudentID != "":
+		res, err = db.GetRows(config.AuditTable, config.StudentIdx, filter.StudentID)
+	case filter.Actor != "":
+		res, err = db.GetRows(config.AuditTable, config.ActorIdx, filter.Actor)
+	default:
+		res, err = db.GetRows(config.AuditTable, config.IdFld)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]models.AuditEntry, 0, len(res))
+	for _, row := range res {
+		entry := row.(models.AuditEntry)
+		if filter.matches(entry) {
+			entries = append(entries, entry)
+		}
+	}
+
+	// The secondary indexes order the entries by their exam, student or actor first, so restore the order they were recorded in
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].ID < entries[j].ID
+	})
+
+	return entries, nil
+}
+
+func (f Filter) matches(entry models.AuditEntry) bool {
+	switch {
+	case f.Action != "" && entry.Action != f.Action:
+		return false
+	case f.Actor != "" && entry.Actor != f.Actor:
+		return false
+	case f.Exam != 0 && entry.Exam != f.Exam:
+		return false
+	case f.StudentID != "" && entry.StudentID != f.StudentID:
+		return false
+	case f.Resource != "" && entry.Resource != f.Resource:
+		return false
+	case f.ResourceID != "" && entry.ResourceID != f.ResourceID:
+		return false
+	case f.RequestID != "" && entry.RequestID != f.RequestID:
+		return false
+	case !f.Since.IsZero() && entry.Time.Before(f.Since):
+		return false
+	case !f.Until.IsZero() && !entry.Time.Before(f.Until):
+		return false
+	}
+
+	return true
+}
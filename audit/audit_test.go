@@ -0,0 +1,88 @@
+package audit
+
+import (
+	"testing"
+	"time"
+
+	"github.com/kylegk/sse-rest-server/config"
+	"github.com/kylegk/sse-rest-server/db"
+	"github.com/kylegk/sse-rest-server/models"
+)
+
+func TestAuditor(t *testing.T) {
+	err := db.InitDB(config.DBSchema)
+	if err != nil {
+		t.Fatalf("Failed to initialize the database")
+	}
+
+	_, err = db.UpsertScores([]models.StudentExam{{Exam: 1, StudentID: "test.person1", Score: 0.5}, {Exam: 1, StudentID: "test.person2", Score: 0.7}}, Auditor(models.AuditScoreIngested, Source{Actor: IngestionActor}))
+	if err != nil {
+		t.Fatalf("Failed to record the scores; %v", err)
+	}
+
+	teacher := Source{Actor: "sub:ms.teacher", Role: "teacher", IP: "10.0.0.1", RequestID: "req-1"}
+	_, err = db.UpsertScores([]models.StudentExam{{Exam: 1, StudentID: "test.person1", Score: 0.9}}, Auditor(models.AuditExamAdded, teacher))
+	if err != nil {
+		t.Errorf("Unable to record the added score; %v", err)
+	}
+
+	admin := Source{Actor: "key:admin", Role: "admin", IP: "10.0.0.2", RequestID: "req-2"}
+	_, _, err = db.SoftDeleteExam(1, Auditor(models.AuditExamDeleted, admin))
+	if err != nil {
+		t.Errorf("Unable to delete the exam; %v", err)
+	}
+
+	// A write that changes no scores adds no entries
+	_, _, err = db.SoftDeleteExam(1, Auditor(models.AuditExamDeleted, admin))
+	if err != nil {
+		t.Errorf("Unable to delete the exam again; %v", err)
+	}
+
+	all, err := List(Filter{})
+	if err != nil {
+		t.Fatalf("Unable to list the entries; %v", err)
+	}
+	have := len(all)
+	want := 5
+	if have != want {
+		t.Fatalf("Incorrect number of entries; have: %v, want: %v", have, want)
+	}
+
+	// The entries are listed in the order they were recorded
+	if all[0].Action != models.AuditScoreIngested || all[2].Action != models.AuditExamAdded || all[4].Action != models.AuditExamDeleted {
+		t.Errorf("Entries are not in the order they were recorded; have: %+v", all)
+	}
+
+	added := all[2]
+	if added.Actor != "sub:ms.teacher" || added.IP != "10.0.0.1" || added.RequestID != "req-1" || added.Before == nil || added.Before.Score != 0.5 || added.After.Score != 0.9 {
+		t.Errorf("Incorrect entry for the added score; have: %+v", added)
+	}
+	for _, deleted := range all[3:] {
+		if deleted.Exam != 1 || deleted.Actor != "key:admin" || deleted.After != nil || deleted.Before == nil {
+			t.Errorf("Incorrect entry for the deleted score; have: %+v", deleted)
+		}
+	}
+
+	tests := []struct {
+		filter Filter
+		want   int
+	}{
+		{Filter{Action: models.AuditExamDeleted, Exam: 1}, 2},
+		{Filter{Actor: "key:admin"}, 2},
+		{Filter{StudentID: "test.person1"}, 3},
+		{Filter{StudentID: "test.person1", Actor: IngestionActor}, 1},
+		{Filter{RequestID: "req-1"}, 1},
+		{Filter{Exam: 2}, 0},
+		{Filter{Since: time.Now().Add(time.Hour)}, 0},
+		{Filter{Until: time.Now().Add(time.Hour)}, 5},
+	}
+	for _, test := range tests {
+		res, err := List(test.filter)
+		if err != nil {
+			t.Errorf("Unable to list the entries; %v", err)
+		}
+		if len(res) != test.want {
+			t.Errorf("Incorrect number of entries for %+v; have: %v, want: %v", test.filter, len(res), test.want)
+		}
+	}
+}
@@ -0,0 +1,205 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+)
+
+// The signing algorithms accepted in a token; symmetric algorithms are never accepted, so a public key cannot be used as an HMAC secret
+var validMethods = []string{"RS256", "RS384", "RS512", "PS256", "PS384", "PS512", "ES256", "ES384", "ES512"}
+
+// ErrInvalidToken is returned for a token that cannot be verified, or whose claims are invalid
+var ErrInvalidToken = errors.New("invalid bearer token")
+
+// Issuer and Audience, when set, must match the iss and aud claims of every token
+var (
+	Issuer   string
+	Audience string
+)
+
+// A key of a JSON Web Key Set, as described by RFC 7517
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// A verification key, with the algorithm it is limited to when the key set names one
+type verificationKey struct {
+	key crypto.PublicKey
+	alg string
+}
+
+var (
+	keysMu sync.RWMutex
+	// The verification keys, keyed by their key id
+	keys map[string]verificationKey
+)
+
+// LoadJWKS reads the keys tokens are verified with from a JSON Web Key Set file; only the RSA and EC signing keys are used
+func LoadJWKS(path string) error {
+	bytes, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	set := struct {
+		Keys []jwk `json:"keys"`
+	}{}
+	err = json.Unmarshal(bytes, &set)
+	if err != nil {
+		return fmt.Errorf("unable to parse JWKS file %s: %v", path, err)
+	}
+
+	loaded := make(map[string]verificationKey)
+	for _, k := range set.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+
+		key, err := k.publicKey()
+		if err != nil {
+			return fmt.Errorf("invalid key %q in JWKS file %s: %v", k.Kid, path, err)
+		}
+		if _, exists := loaded[k.Kid]; exists {
+			return fmt.Errorf("invalid JWKS file %s: the key id %q is used more than once", path, k.Kid)
+		}
+		loaded[k.Kid] = verificationKey{key: key, alg: k.Alg}
+	}
+	if len(loaded) == 0 {
+		return fmt.Errorf("invalid JWKS file %s: no signing keys", path)
+	}
+
+	keysMu.Lock()
+	defer keysMu.Unlock()
+	keys = loaded
+
+	return nil
+}
+
+// TokensEnabled reports whether a key set has been loaded, so bearer tokens are accepted
+func TokensEnabled() bool {
+	keysMu.RLock()
+	defer keysMu.RUnlock()
+
+	return len(keys) > 0
+}
+
+// Convert a JSON Web Key to the public key it describes
+func (k jwk) publicKey() (crypto.PublicKey, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := decodeInt(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeInt(k.E)
+		if err != nil || !e.IsInt64() {
+			return nil, fmt.Errorf("invalid exponent")
+		}
+
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+
+		x, err := decodeInt(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeInt(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		if !curve.IsOnCurve(x, y) {
+			return nil, fmt.Errorf("the point is not on the curve")
+		}
+
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+	default:
+		return nil, fmt.Errorf("unsupported key type %q", k.Kty)
+	}
+}
+
+func decodeInt(value string) (*big.Int, error) {
+	bytes, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(value, "="))
+	if err != nil || len(bytes) == 0 {
+		return nil, fmt.Errorf("invalid base64url integer")
+	}
+
+	return new(big.Int).SetBytes(bytes), nil
+}
+
+// Find the key a token was signed with by its key id; a token without a key id can only be verified when the set has a single key
+func tokenKey(token *jwt.Token) (interface{}, error) {
+	keysMu.RLock()
+	defer keysMu.RUnlock()
+
+	kid, _ := token.Header["kid"].(string)
+	key, ok := keys[kid]
+	if !ok && kid == "" && len(keys) == 1 {
+		for _, only := range keys {
+			key, ok = only, true
+		}
+	}
+	if !ok {
+		return nil, fmt.Errorf("unknown key id %q", kid)
+	}
+	if key.alg != "" && key.alg != token.Method.Alg() {
+		return nil, fmt.Errorf("the key does not allow %s", token.Method.Alg())
+	}
+
+	return key.key, nil
+}
+
+// Verify a token's signature and its exp, nbf, iss and aud claims, returning its claims
+// Tokens must expire, so a leaked token is not valid forever
+func verifyToken(token string) (jwt.MapClaims, error) {
+	claims := jwt.MapClaims{}
+	parser := &jwt.Parser{ValidMethods: validMethods}
+	_, err := parser.ParseWithClaims(token, claims, tokenKey)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+
+	now := time.Now().Unix()
+	if !claims.VerifyExpiresAt(now, true) {
+		return nil, fmt.Errorf("%w: the token must have an exp claim", ErrInvalidToken)
+	}
+	if Issuer != "" && !claims.VerifyIssuer(Issuer, true) {
+		return nil, fmt.Errorf("%w: the token was not issued by %s", ErrInvalidToken, Issuer)
+	}
+	if Audience != "" && !claims.VerifyAudience(Audience, true) {
+		return nil, fmt.Errorf("%w: the token is not intended for %s", ErrInvalidToken, Audience)
+	}
+
+	return claims, nil
+}
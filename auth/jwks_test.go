@@ -0,0 +1,155 @@
+package auth
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+)
+
+// The keys the test tokens are signed with
+var (
+	testRSAKey, _ = rsa.GenerateKey(rand.Reader, 2048)
+	testECKey, _  = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+)
+
+func encodeInt(i *big.Int) string {
+	return base64.RawURLEncoding.EncodeToString(i.Bytes())
+}
+
+// Write a key set holding the public halves of the test keys, and load it
+func loadTestJWKS(t *testing.T) {
+	dir, err := ioutil.TempDir("", "jwks")
+	if err != nil {
+		t.Fatalf("Unable to create a directory; %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	set := map[string][]jwk{"keys": {
+		{Kty: "RSA", Kid: "rsa", Use: "sig", Alg: "RS256", N: encodeInt(testRSAKey.N), E: encodeInt(big.NewInt(int64(testRSAKey.E)))},
+		{Kty: "EC", Kid: "ec", Crv: "P-256", X: encodeInt(testECKey.X), Y: encodeInt(testECKey.Y)},
+		{Kty: "RSA", Kid: "encryption", Use: "enc", N: encodeInt(testRSAKey.N), E: encodeInt(big.NewInt(int64(testRSAKey.E)))},
+	}}
+	bytes, _ := json.Marshal(set)
+	path := filepath.Join(dir, "jwks.json")
+	err = ioutil.WriteFile(path, bytes, 0600)
+	if err != nil {
+		t.Fatalf("Unable to write the key set; %v", err)
+	}
+
+	err = LoadJWKS(path)
+	if err != nil {
+		t.Fatalf("Unable to load the key set; %v", err)
+	}
+}
+
+// Clear the loaded key set and claim checks, so the other tests run without tokens
+func resetTokens() {
+	keysMu.Lock()
+	keys = nil
+	keysMu.Unlock()
+	Issuer = ""
+	Audience = ""
+}
+
+func signTestToken(t *testing.T, method jwt.SigningMethod, kid string, claims jwt.MapClaims) string {
+	token := jwt.NewWithClaims(method, claims)
+	if kid != "" {
+		token.Header["kid"] = kid
+	}
+
+	var key interface{} = testRSAKey
+	if method == jwt.SigningMethodES256 {
+		key = testECKey
+	}
+	signed, err := token.SignedString(key)
+	if err != nil {
+		t.Fatalf("Unable to sign the token; %v", err)
+	}
+
+	return signed
+}
+
+func TestLoadJWKS(t *testing.T) {
+	defer resetTokens()
+
+	if TokensEnabled() {
+		t.Errorf("Tokens are enabled before a key set is loaded")
+	}
+
+	loadTestJWKS(t)
+	if !TokensEnabled() {
+		t.Errorf("Tokens are not enabled after a key set is loaded")
+	}
+
+	// The encryption key is not used to verify tokens
+	have := len(keys)
+	want := 2
+	if have != want {
+		t.Errorf("Incorrect number of keys loaded; have: %v, want: %v", have, want)
+	}
+
+	err := LoadJWKS(filepath.Join(os.TempDir(), "missing-jwks.json"))
+	if err == nil {
+		t.Errorf("Missing key set was loaded")
+	}
+}
+
+func TestVerifyToken(t *testing.T) {
+	defer resetTokens()
+	loadTestJWKS(t)
+	Issuer = "https://issuer.test"
+	Audience = "scores"
+
+	exp := time.Now().Add(time.Hour).Unix()
+	valid := func() jwt.MapClaims {
+		return jwt.MapClaims{"sub": "test.person1", "iss": "https://issuer.test", "aud": "scores", "exp": exp}
+	}
+	expired := valid()
+	expired["exp"] = time.Now().Add(-time.Hour).Unix()
+	noExp := valid()
+	delete(noExp, "exp")
+	otherIssuer := valid()
+	otherIssuer["iss"] = "https://other.test"
+	otherAudience := valid()
+	otherAudience["aud"] = "other"
+
+	hmac, _ := jwt.NewWithClaims(jwt.SigningMethodHS256, valid()).SignedString([]byte("secret"))
+
+	tests := []struct {
+		name  string
+		token string
+		valid bool
+	}{
+		{"RSA", signTestToken(t, jwt.SigningMethodRS256, "rsa", valid()), true},
+		{"EC", signTestToken(t, jwt.SigningMethodES256, "ec", valid()), true},
+		{"expired", signTestToken(t, jwt.SigningMethodRS256, "rsa", expired), false},
+		{"no exp", signTestToken(t, jwt.SigningMethodRS256, "rsa", noExp), false},
+		{"other issuer", signTestToken(t, jwt.SigningMethodRS256, "rsa", otherIssuer), false},
+		{"other audience", signTestToken(t, jwt.SigningMethodRS256, "rsa", otherAudience), false},
+		{"unknown key id", signTestToken(t, jwt.SigningMethodRS256, "other", valid()), false},
+		{"no key id with several keys", signTestToken(t, jwt.SigningMethodRS256, "", valid()), false},
+		{"algorithm not allowed by the key", signTestToken(t, jwt.SigningMethodRS384, "rsa", valid()), false},
+		{"HMAC", hmac, false},
+		{"malformed", "not.a.token", false},
+	}
+	for _, test := range tests {
+		_, err := verifyToken(test.token)
+		if test.valid && err != nil {
+			t.Errorf("Valid %s token was rejected; %v", test.name, err)
+		}
+		if !test.valid && err == nil {
+			t.Errorf("Invalid %s token was accepted", test.name)
+		}
+	}
+}
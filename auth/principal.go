@@ -0,0 +1,175 @@
+package auth
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"sync"
+
+	"github.com/kylegk/sse-rest-server/apikey"
+)
+
+// Define the roles a client can have
+const (
+	// A student can only read their own scores
+	RoleStudent = "student"
+	// A teacher can read and add the scores of the students in their cohorts
+	RoleTeacher = "teacher"
+	// An admin can do anything, including deleting scores and exams
+	RoleAdmin = "admin"
+)
+
+// ErrNoCredentials is returned when a request has neither an API key nor a bearer token
+var ErrNoCredentials = errors.New("no credentials")
+
+// Principal is the authenticated client of a request
+type Principal struct {
+	// ID identifies the client across its requests, such as to scope its idempotency keys
+	ID string
+	// Subject is the student id of a student, or the name of a teacher, admin or API key
+	Subject string
+	Role    string
+	Cohorts []string
+	// AllStudents is set for clients that are not limited to their cohorts, such as API keys
+	AllStudents bool
+}
+
+var (
+	cohortsMu sync.RWMutex
+	// The students of each cohort, keyed by the cohort name and the student id
+	cohorts = make(map[string]map[string]bool)
+)
+
+// LoadCohorts reads the students of each cohort from a JSON file, an object mapping each cohort name to a list of student ids
+func LoadCohorts(path string) error {
+	bytes, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	file := make(map[string][]string)
+	err = json.Unmarshal(bytes, &file)
+	if err != nil {
+		return fmt.Errorf("unable to parse cohort file %s: %v", path, err)
+	}
+
+	loaded := make(map[string]map[string]bool, len(file))
+	for name, students := range file {
+		loaded[name] = make(map[string]bool, len(students))
+		for _, student := range students {
+			loaded[name][student] = true
+		}
+	}
+
+	cohortsMu.Lock()
+	defer cohortsMu.Unlock()
+	cohorts = loaded
+
+	return nil
+}
+
+// Required reports whether requests must be authenticated, which they must once an API key or a key set has been configured
+func Required() bool {
+	return TokensEnabled() || apikey.Enabled()
+}
+
+// Authenticate identifies the client from a bearer token in the Authorization value, or from an API key
+// A non-admin API key acts as a teacher of every student, as keys are issued to services rather than people
+func Authenticate(key string, authorization string) (Principal, error) {
+	if authorization != "" {
+		const prefix = "bearer "
+		if len(authorization) <= len(prefix) || strings.ToLower(authorization[:len(prefix)]) != prefix {
+			return Principal{}, fmt.Errorf("%w: the Authorization header must be a Bearer token", ErrInvalidToken)
+		}
+		if !TokensEnabled() {
+			return Principal{}, fmt.Errorf("%w: bearer tokens are not accepted", ErrInvalidToken)
+		}
+
+		return tokenPrincipal(strings.TrimSpace(authorization[len(prefix):]))
+	}
+
+	if key != "" {
+		found, err := apikey.Verify(key)
+		if err != nil {
+			return Principal{}, err
+		}
+
+		role := RoleTeacher
+		if found.Admin {
+			role = RoleAdmin
+		}
+
+		return Principal{ID: "key:" + found.ID, Subject: found.Name, Role: role, AllStudents: true}, nil
+	}
+
+	return Principal{}, ErrNoCredentials
+}
+
+// Identify the client of a verified token from its sub, role and cohorts claims
+func tokenPrincipal(token string) (Principal, error) {
+	claims, err := verifyToken(token)
+	if err != nil {
+		return Principal{}, err
+	}
+
+	subject, _ := claims["sub"].(string)
+	role, _ := claims["role"].(string)
+	if subject == "" {
+		return Principal{}, fmt.Errorf("%w: the token must have a sub claim", ErrInvalidToken)
+	}
+	if role != RoleStudent && role != RoleTeacher && role != RoleAdmin {
+		return Principal{}, fmt.Errorf("%w: the role claim must be student, teacher or admin", ErrInvalidToken)
+	}
+
+	principal := Principal{ID: "sub:" + subject, Subject: subject, Role: role, AllStudents: role == RoleAdmin}
+	if list, ok := claims["cohorts"].([]interface{}); ok {
+		for _, cohort := range list {
+			if name, ok := cohort.(string); ok {
+				principal.Cohorts = append(principal.Cohorts, name)
+			}
+		}
+	}
+
+	return principal, nil
+}
+
+// IsStaff reports whether the client is a teacher or an admin
+func (p Principal) IsStaff() bool {
+	return p.Role == RoleTeacher || p.Role == RoleAdmin
+}
+
+// CanAccessStudent reports whether the client can see the scores of a student:
+// students can only see their own, and teachers the students of their cohorts
+func (p Principal) CanAccessStudent(student string) bool {
+	switch {
+	case p.Role == RoleStudent:
+		return student == p.Subject
+	case p.AllStudents:
+		return true
+	case p.Role != RoleTeacher:
+		return false
+	}
+
+	cohortsMu.RLock()
+	defer cohortsMu.RUnlock()
+
+	for _, cohort := range p.Cohorts {
+		if cohorts[cohort][student] {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Visible returns the filter of the students whose scores the client can see when listing scores,
+// which is nil for clients that can see every student
+func (p Principal) Visible() func(student string) bool {
+	if p.AllStudents {
+		return nil
+	}
+
+	return p.CanAccessStudent
+}
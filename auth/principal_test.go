@@ -0,0 +1,122 @@
+package auth
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+	"github.com/kylegk/sse-rest-server/apikey"
+	"github.com/kylegk/sse-rest-server/config"
+	"github.com/kylegk/sse-rest-server/db"
+)
+
+func loadTestCohorts(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cohorts")
+	if err != nil {
+		t.Fatalf("Unable to create a directory; %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "cohorts.json")
+	err = ioutil.WriteFile(path, []byte(`{"math-101": ["test.person1", "test.person2"], "math-102": ["test.person3"]}`), 0600)
+	if err != nil {
+		t.Fatalf("Unable to write the cohorts; %v", err)
+	}
+
+	err = LoadCohorts(path)
+	if err != nil {
+		t.Fatalf("Unable to load the cohorts; %v", err)
+	}
+}
+
+func TestAuthenticate(t *testing.T) {
+	defer resetTokens()
+	err := db.InitDB(config.DBSchema)
+	if err != nil {
+		t.Fatalf("Failed to initialize the database")
+	}
+
+	_, err = Authenticate("", "")
+	if err != ErrNoCredentials {
+		t.Errorf("Incorrect error without credentials; have: %v, want: %v", err, ErrNoCredentials)
+	}
+
+	// Keys act as admins or as teachers of every student
+	err = apikey.AddKey("dashboard", "dashboard-key", false)
+	if err != nil {
+		t.Errorf("Unable to add the key; %v", err)
+	}
+	principal, err := Authenticate("dashboard-key", "")
+	if err != nil || principal.Role != RoleTeacher || !principal.AllStudents || principal.Subject != "dashboard" {
+		t.Errorf("Incorrect principal for an API key; have: %+v, %v", principal, err)
+	}
+	_, err = Authenticate("wrong-key", "")
+	if err != apikey.ErrInvalidKey {
+		t.Errorf("Incorrect error for a wrong API key; have: %v, want: %v", err, apikey.ErrInvalidKey)
+	}
+
+	// Bearer tokens are rejected until a key set is loaded
+	claims := jwt.MapClaims{"sub": "ms.teacher", "role": RoleTeacher, "cohorts": []string{"math-101"}, "exp": time.Now().Add(time.Hour).Unix()}
+	token := signTestToken(t, jwt.SigningMethodRS256, "rsa", claims)
+	_, err = Authenticate("", "Bearer "+token)
+	if err == nil {
+		t.Errorf("Bearer token was accepted without a key set")
+	}
+
+	loadTestJWKS(t)
+	principal, err = Authenticate("", "Bearer "+token)
+	if err != nil {
+		t.Errorf("Valid bearer token was rejected; %v", err)
+	}
+	if principal.ID != "sub:ms.teacher" || principal.Role != RoleTeacher || len(principal.Cohorts) != 1 || principal.Cohorts[0] != "math-101" {
+		t.Errorf("Incorrect principal for a bearer token; have: %+v", principal)
+	}
+
+	// A bearer token takes precedence over an API key
+	principal, _ = Authenticate("dashboard-key", "bearer "+token)
+	if principal.Subject != "ms.teacher" {
+		t.Errorf("Bearer token was not used; have: %+v", principal)
+	}
+
+	invalid := []string{
+		"Basic " + token,
+		"Bearer ",
+		"Bearer " + signTestToken(t, jwt.SigningMethodRS256, "rsa", jwt.MapClaims{"sub": "someone", "role": "janitor", "exp": claims["exp"]}),
+		"Bearer " + signTestToken(t, jwt.SigningMethodRS256, "rsa", jwt.MapClaims{"role": RoleAdmin, "exp": claims["exp"]}),
+	}
+	for _, authorization := range invalid {
+		_, err = Authenticate("", authorization)
+		if err == nil {
+			t.Errorf("Invalid Authorization value was accepted; %v", authorization)
+		}
+	}
+}
+
+func TestCanAccessStudent(t *testing.T) {
+	loadTestCohorts(t)
+
+	tests := []struct {
+		principal Principal
+		student   string
+		want      bool
+	}{
+		{Principal{Subject: "test.person1", Role: RoleStudent}, "test.person1", true},
+		{Principal{Subject: "test.person1", Role: RoleStudent}, "test.person2", false},
+		// A student is never widened to other students
+		{Principal{Subject: "test.person1", Role: RoleStudent, AllStudents: true}, "test.person2", false},
+		{Principal{Subject: "ms.teacher", Role: RoleTeacher, Cohorts: []string{"math-101"}}, "test.person2", true},
+		{Principal{Subject: "ms.teacher", Role: RoleTeacher, Cohorts: []string{"math-101"}}, "test.person3", false},
+		{Principal{Subject: "ms.teacher", Role: RoleTeacher, Cohorts: []string{"unknown"}}, "test.person1", false},
+		{Principal{Subject: "dashboard", Role: RoleTeacher, AllStudents: true}, "test.person3", true},
+		{Principal{Subject: "root", Role: RoleAdmin, AllStudents: true}, "test.person3", true},
+	}
+	for _, test := range tests {
+		have := test.principal.CanAccessStudent(test.student)
+		if have != test.want {
+			t.Errorf("Incorrect access for %+v to %v; have: %v, want: %v", test.principal, test.student, have, test.want)
+		}
+	}
+}
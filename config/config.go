@@ -3,13 +3,47 @@ package config
 import "github.com/hashicorp/go-memdb"
 
 type Config struct {
-	MemDBSchema  *memdb.DBSchema
-	SSEServerUrl string
-	PORT string
+	MemDBSchema          *memdb.DBSchema
+	SSEServerUrl         string
+	PORT                 string
+	GradingPolicyFile    string
+	DefaultGradingPolicy string
+	GradingScaleFile     string
+	DefaultGradingScale  string
+	AlertRuleFile        string
+	DeleteGracePeriod    string
+	IdempotencyWindow    string
+	LegacySunset         string
+	GRPCPort             string
+	APIKeyFile           string
+	AdminAPIKey          string
+	JWKSFile             string
+	JWTIssuer            string
+	JWTAudience          string
+	CohortFile           string
+	RateLimit            string
+	RateLimitBurst       string
 }
 
 const EnvURL = "SSE_SERVER_URL"
 const EnvPort = "APPLICATION_PORT"
+const EnvGradingPolicyFile = "GRADING_POLICY_FILE"
+const EnvDefaultGradingPolicy = "DEFAULT_GRADING_POLICY"
+const EnvGradingScaleFile = "GRADING_SCALE_FILE"
+const EnvDefaultGradingScale = "DEFAULT_GRADING_SCALE"
+const EnvAlertRuleFile = "ALERT_RULE_FILE"
+const EnvDeleteGracePeriod = "DELETE_GRACE_PERIOD"
+const EnvIdempotencyWindow = "IDEMPOTENCY_WINDOW"
+const EnvLegacySunset = "LEGACY_API_SUNSET"
+const EnvGRPCPort = "GRPC_PORT"
+const EnvAPIKeyFile = "API_KEY_FILE"
+const EnvAdminAPIKey = "ADMIN_API_KEY"
+const EnvJWKSFile = "JWKS_FILE"
+const EnvJWTIssuer = "JWT_ISSUER"
+const EnvJWTAudience = "JWT_AUDIENCE"
+const EnvCohortFile = "COHORT_FILE"
+const EnvRateLimit = "RATE_LIMIT"
+const EnvRateLimitBurst = "RATE_LIMIT_BURST"
 
 // Define the table name, fields, and indexes for the in-memory data store
 const (
@@ -23,6 +57,68 @@ const (
 	StudentFld        = "StudentID"
 )
 
+// Define the table name, fields, and indexes for the at-risk student alerts
+const (
+	AlertTable = "alert"
+	StatusIdx  = "status_idx"
+	IDFld      = "ID"
+	StatusFld  = "Status"
+)
+
+// Define the table names, fields, and indexes for the outbound webhooks and their delivery log
+const (
+	WebhookTable         = "webhook"
+	WebhookDeliveryTable = "webhook_delivery"
+	WebhookIdx           = "webhook_idx"
+	WebhookFld           = "WebhookID"
+)
+
+// Define the table name, fields, and indexes for the anomalies detected in the ingested events
+const (
+	AnomalyTable = "anomaly"
+	ReasonIdx    = "reason_idx"
+	ReasonFld    = "Reason"
+)
+
+// Define the table name, fields, and kinds for the running score aggregates
+const (
+	AggregateTable   = "aggregate"
+	KindFld          = "Kind"
+	KeyFld           = "Key"
+	StudentAggregate = "student"
+	ExamAggregate    = "exam"
+)
+
+// Define the table names for the exam metadata (category, weight) used by the grading policies, and the exam curves
+const (
+	ExamMetaTable = "exam_meta"
+	CurveTable    = "curve"
+)
+
+// Define the table name for the responses cached for requests with an Idempotency-Key
+const (
+	IdempotencyTable = "idempotency"
+)
+
+// Define the table name, fields, and indexes for the API keys, which are stored and looked up by their hash
+const (
+	APIKeyTable = "api_key"
+	HashIdx     = "hash_idx"
+	HashFld     = "Hash"
+)
+
+// Define the table name, fields, and indexes for the audit log of the changes made to the scores
+const (
+	AuditTable = "audit"
+	ActorIdx   = "actor_idx"
+	ActorFld   = "Actor"
+)
+
+// Define the table name for the scores of deleted exams, which are kept until their grace period expires
+const (
+	TombstoneTable = "tombstone"
+)
+
 // DBSchema Define the schema used for the scores in-memory database
 var DBSchema = &memdb.DBSchema{
 	Tables: map[string]*memdb.TableSchema{
@@ -62,5 +158,161 @@ var DBSchema = &memdb.DBSchema{
 				},
 			},
 		},
+		ExamMetaTable: {
+			Name: ExamMetaTable,
+			Indexes: map[string]*memdb.IndexSchema{
+				IdFld: {
+					Name:    IdFld,
+					Unique:  true,
+					Indexer: &memdb.IntFieldIndex{Field: ExamFld},
+				},
+			},
+		},
+		CurveTable: {
+			Name: CurveTable,
+			Indexes: map[string]*memdb.IndexSchema{
+				IdFld: {
+					Name:    IdFld,
+					Unique:  true,
+					Indexer: &memdb.IntFieldIndex{Field: ExamFld},
+				},
+			},
+		},
+		IdempotencyTable: {
+			Name: IdempotencyTable,
+			Indexes: map[string]*memdb.IndexSchema{
+				IdFld: {
+					Name:    IdFld,
+					Unique:  true,
+					Indexer: &memdb.StringFieldIndex{Field: KeyFld},
+				},
+			},
+		},
+		TombstoneTable: {
+			Name: TombstoneTable,
+			Indexes: map[string]*memdb.IndexSchema{
+				IdFld: {
+					Name:    IdFld,
+					Unique:  true,
+					Indexer: &memdb.IntFieldIndex{Field: ExamFld},
+				},
+			},
+		},
+		AlertTable: {
+			Name: AlertTable,
+			Indexes: map[string]*memdb.IndexSchema{
+				IdFld: {
+					Name:    IdFld,
+					Unique:  true,
+					Indexer: &memdb.StringFieldIndex{Field: IDFld},
+				},
+				StudentIdx: {
+					Name:    StudentIdx,
+					Unique:  false,
+					Indexer: &memdb.StringFieldIndex{Field: StudentFld},
+				},
+				StatusIdx: {
+					Name:    StatusIdx,
+					Unique:  false,
+					Indexer: &memdb.StringFieldIndex{Field: StatusFld},
+				},
+			},
+		},
+		WebhookTable: {
+			Name: WebhookTable,
+			Indexes: map[string]*memdb.IndexSchema{
+				IdFld: {
+					Name:    IdFld,
+					Unique:  true,
+					Indexer: &memdb.StringFieldIndex{Field: IDFld},
+				},
+			},
+		},
+		APIKeyTable: {
+			Name: APIKeyTable,
+			Indexes: map[string]*memdb.IndexSchema{
+				IdFld: {
+					Name:    IdFld,
+					Unique:  true,
+					Indexer: &memdb.StringFieldIndex{Field: IDFld},
+				},
+				HashIdx: {
+					Name:    HashIdx,
+					Unique:  true,
+					Indexer: &memdb.StringFieldIndex{Field: HashFld},
+				},
+			},
+		},
+		WebhookDeliveryTable: {
+			Name: WebhookDeliveryTable,
+			Indexes: map[string]*memdb.IndexSchema{
+				IdFld: {
+					Name:    IdFld,
+					Unique:  true,
+					Indexer: &memdb.StringFieldIndex{Field: IDFld},
+				},
+				WebhookIdx: {
+					Name:    WebhookIdx,
+					Unique:  false,
+					Indexer: &memdb.StringFieldIndex{Field: WebhookFld},
+				},
+			},
+		},
+		AnomalyTable: {
+			Name: AnomalyTable,
+			Indexes: map[string]*memdb.IndexSchema{
+				IdFld: {
+					Name:    IdFld,
+					Unique:  true,
+					Indexer: &memdb.StringFieldIndex{Field: IDFld},
+				},
+				ReasonIdx: {
+					Name:    ReasonIdx,
+					Unique:  false,
+					Indexer: &memdb.StringFieldIndex{Field: ReasonFld},
+				},
+			},
+		},
+		AuditTable: {
+			Name: AuditTable,
+			Indexes: map[string]*memdb.IndexSchema{
+				IdFld: {
+					Name:    IdFld,
+					Unique:  true,
+					Indexer: &memdb.StringFieldIndex{Field: IDFld},
+				},
+				ExamIdx: {
+					Name:    ExamIdx,
+					Unique:  false,
+					Indexer: &memdb.IntFieldIndex{Field: ExamFld},
+				},
+				StudentIdx: {
+					Name:         StudentIdx,
+					Unique:       false,
+					AllowMissing: true,
+					Indexer:      &memdb.StringFieldIndex{Field: StudentFld},
+				},
+				ActorIdx: {
+					Name:    ActorIdx,
+					Unique:  false,
+					Indexer: &memdb.StringFieldIndex{Field: ActorFld},
+				},
+			},
+		},
+		AggregateTable: {
+			Name: AggregateTable,
+			Indexes: map[string]*memdb.IndexSchema{
+				IdFld: {
+					Name:   IdFld,
+					Unique: true,
+					Indexer: &memdb.CompoundIndex{
+						Indexes: []memdb.Indexer{
+							&memdb.StringFieldIndex{Field: KindFld},
+							&memdb.StringFieldIndex{Field: KeyFld},
+						},
+					},
+				},
+			},
+		},
 	},
 }
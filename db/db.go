@@ -1,13 +1,22 @@
 package db
 
 import (
+	"errors"
 	"log"
 
 	"github.com/hashicorp/go-memdb"
+	"github.com/kylegk/sse-rest-server/config"
+	"github.com/kylegk/sse-rest-server/models"
 )
 
 var db *memdb.MemDB
 
+// The listeners called whenever the database is created
+var initListeners []func()
+
+// ErrNoScore is returned when a score that should be updated does not exist
+var ErrNoScore = errors.New("score not found")
+
 // InitDB initializes the datastore
 func InitDB(schema *memdb.DBSchema) error {
 	if schema == nil {
@@ -20,10 +29,19 @@ func InitDB(schema *memdb.DBSchema) error {
 	}
 
 	db = conn
+	resetVersions()
+	for _, listener := range initListeners {
+		listener()
+	}
 
 	return nil
 }
 
+// OnInit registers a listener that is called whenever the database is created, so state kept alongside the database is reset with it
+func OnInit(listener func()) {
+	initListeners = append(initListeners, listener)
+}
+
 // UpsertRow inserts a row into the database if it doesn't exist, or updates the existing value(s) if it does
 func UpsertRow(table string, record interface{}) error {
 	if db == nil {
@@ -31,34 +49,162 @@ func UpsertRow(table string, record interface{}) error {
 	}
 
 	txn := db.Txn(true)
+	txn.TrackChanges()
 	defer txn.Abort()
 
-	err := txn.Insert(table, record)
+	err := upsert(txn, table, record)
 	if err != nil {
 		return err
 	}
 
-	txn.Commit()
+	commit(txn)
+
+	return nil
+}
+
+// UpsertRows inserts or updates multiple rows in a single transaction; if any row fails, none of the rows are written
+func UpsertRows(table string, records []interface{}) error {
+	if db == nil {
+		panic("database connection has not been initialized")
+	}
+
+	txn := db.Txn(true)
+	txn.TrackChanges()
+	defer txn.Abort()
+
+	for _, record := range records {
+		err := upsert(txn, table, record)
+		if err != nil {
+			return err
+		}
+	}
+
+	commit(txn)
+
+	return nil
+}
+
+// UpsertScores inserts or updates multiple scores in a single transaction, returning the change made to each score
+// If any score fails, none of the scores are written; the auditor, if any, records the changes in the same transaction
+func UpsertScores(scores []models.StudentExam, auditor Auditor) ([]models.ScoreEvent, error) {
+	if db == nil {
+		panic("database connection has not been initialized")
+	}
+
+	txn := db.Txn(true)
+	txn.TrackChanges()
+	defer txn.Abort()
+
+	for _, score := range scores {
+		err := upsert(txn, config.ScoreTable, score)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err := writeAudit(txn, auditor)
+	if err != nil {
+		return nil, err
+	}
+
+	return commit(txn), nil
+}
+
+// UpdateScore updates an existing score, returning the change made to it, or ErrNoScore if the student has no score for the exam
+// The score is looked up and written in one transaction, so a score deleted concurrently is not created again
+// The auditor, if any, records the change in the same transaction
+func UpdateScore(score models.StudentExam, auditor Auditor) ([]models.ScoreEvent, error) {
+	if db == nil {
+		panic("database connection has not been initialized")
+	}
+
+	txn := db.Txn(true)
+	txn.TrackChanges()
+	defer txn.Abort()
+
+	existing, err := existingScore(txn, config.ScoreTable, score)
+	if err != nil {
+		return nil, err
+	}
+	if existing == nil {
+		return nil, ErrNoScore
+	}
+
+	err = upsert(txn, config.ScoreTable, score)
+	if err != nil {
+		return nil, err
+	}
+
+	err = writeAudit(txn, auditor)
+	if err != nil {
+		return nil, err
+	}
+
+	return commit(txn), nil
+}
+
+// Insert or update a row within a write transaction, keeping the score aggregates up to date
+func upsert(txn *memdb.Txn, table string, record interface{}) error {
+	old, err := existingScore(txn, table, record)
+	if err != nil {
+		return err
+	}
+
+	record, err = prepareScore(txn, table, record)
+	if err != nil {
+		return err
+	}
+
+	err = txn.Insert(table, record)
+	if err != nil {
+		return err
+	}
+
+	if score, ok := record.(models.StudentExam); ok && table == config.ScoreTable {
+		err = updateAggregates(txn, old, &score)
+		if err != nil {
+			return err
+		}
+	}
 
 	return nil
 }
 
 // DeleteRow removes a single row from the database
-// Not currently used
 func DeleteRow(table string, record interface{}) error {
 	if db == nil {
 		panic("database connection has not been initialized")
 	}
 
 	txn := db.Txn(true)
+	txn.TrackChanges()
 	defer txn.Abort()
 
-	err := txn.Delete(table, record)
+	err := remove(txn, table, record)
 	if err != nil {
 		return err
 	}
 
-	txn.Commit()
+	commit(txn)
+
+	return nil
+}
+
+// Remove a row within a write transaction, keeping the score aggregates up to date
+func remove(txn *memdb.Txn, table string, record interface{}) error {
+	old, err := existingScore(txn, table, record)
+	if err != nil {
+		return err
+	}
+
+	err = txn.Delete(table, record)
+	if err != nil {
+		return err
+	}
+
+	if old != nil {
+		return updateAggregates(txn, old, nil)
+	}
 
 	return nil
 }
@@ -70,28 +216,80 @@ func DeleteRows(table string, idx string, args ...interface{}) (int, error) {
 	}
 
 	txn := db.Txn(true)
+	txn.TrackChanges()
 	defer txn.Abort()
 
+	removed, err := collectScores(txn, table, idx, args...)
+	if err != nil {
+		return 0, err
+	}
+
 	count, err := txn.DeleteAll(table, idx, args...)
 	if err != nil {
 		return 0, err
 	}
 
+	for i := range removed {
+		err = updateAggregates(txn, &removed[i], nil)
+		if err != nil {
+			return 0, err
+		}
+	}
+
 	log.Println("delete rows: ", count)
 
-	txn.Commit()
+	commit(txn)
 
 	return count, nil
 }
 
+// DeleteScores removes the scores matching an index lookup, returning the change made to each score,
+// which is none when no scores matched; the auditor, if any, records the changes in the same transaction
+func DeleteScores(auditor Auditor, idx string, args ...interface{}) ([]models.ScoreEvent, error) {
+	if db == nil {
+		panic("database connection has not been initialized")
+	}
+
+	txn := db.Txn(true)
+	txn.TrackChanges()
+	defer txn.Abort()
+
+	removed, err := collectScores(txn, config.ScoreTable, idx, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = txn.DeleteAll(config.ScoreTable, idx, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range removed {
+		err = updateAggregates(txn, &removed[i], nil)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err = writeAudit(txn, auditor)
+	if err != nil {
+		return nil, err
+	}
+
+	return commit(txn), nil
+}
+
 // GetRows retrieves all the rows in the database
 func GetRows(table string, idx string, args ...interface{}) ([]interface{}, error) {
 	if db == nil {
 		panic("database connection has not been initialized")
 	}
 
-	txn := db.Txn(false)
+	return getRows(db.Txn(false), table, idx, args...)
+}
 
+// Retrieve the rows matching the index within a transaction
+func getRows(txn *memdb.Txn, table string, idx string, args ...interface{}) ([]interface{}, error) {
 	it, err := txn.Get(table, idx, args...)
 	if err != nil {
 		return nil, err
@@ -104,3 +302,27 @@ func GetRows(table string, idx string, args ...interface{}) ([]interface{}, erro
 
 	return results, nil
 }
+
+// EachRow calls fn for every row matching the index, reading from a single snapshot of the database without collecting the rows in memory
+// Iteration stops at the first error returned by fn, which is returned to the caller
+func EachRow(table string, idx string, fn func(row interface{}) error, args ...interface{}) error {
+	if db == nil {
+		panic("database connection has not been initialized")
+	}
+
+	txn := db.Txn(false)
+
+	it, err := txn.Get(table, idx, args...)
+	if err != nil {
+		return err
+	}
+
+	for obj := it.Next(); obj != nil; obj = it.Next() {
+		err = fn(obj)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -1,6 +1,7 @@
 package db
 
 import (
+	"errors"
 	"github.com/hashicorp/go-memdb"
 	"github.com/kylegk/sse-rest-server/config"
 	"github.com/kylegk/sse-rest-server/models"
@@ -154,6 +155,135 @@ func TestUpsertRow(t *testing.T) {
 	}
 }
 
+// TestUpsertScores validates the change made to each score is returned
+func TestUpsertScores(t *testing.T) {
+	err := InitDB(validSchema)
+	if err != nil {
+		t.Errorf("The database failed to initialize")
+	}
+
+	err = UpsertRow(validTable, models.StudentExam{Exam: 111, StudentID: "test", Score: 0.5})
+	if err != nil {
+		t.Errorf("The insert should have succeeded")
+	}
+
+	events, err := UpsertScores([]models.StudentExam{{Exam: 111, StudentID: "test", Score: 0.75}, {Exam: 111, StudentID: "test2", Score: 0.9}}, nil)
+	if err != nil {
+		t.Errorf("The upsert should have succeeded; %v", err)
+	}
+	if len(events) != 2 {
+		t.Fatalf("Incorrect number of changes returned; have: %v, want: %v", len(events), 2)
+	}
+
+	for _, event := range events {
+		switch event.After.StudentID {
+		case "test":
+			if event.Before == nil || event.Before.Score != 0.5 || event.After.Score != 0.75 {
+				t.Errorf("Incorrect change to an existing score; have: %+v", event)
+			}
+		case "test2":
+			if event.Before != nil || event.After.Score != 0.9 {
+				t.Errorf("Incorrect change to a new score; have: %+v", event)
+			}
+		}
+	}
+}
+
+// TestUpsertScoresAudited validates the audit entries are written with the scores, and that neither is written when the entries fail
+func TestUpsertScoresAudited(t *testing.T) {
+	err := InitDB(validSchema)
+	if err != nil {
+		t.Errorf("The database failed to initialize")
+	}
+
+	auditor := func(events []models.ScoreEvent) []interface{} {
+		entries := make([]interface{}, 0, len(events))
+		for _, event := range events {
+			entries = append(entries, models.AuditEntry{ID: event.After.StudentID, Actor: "test", Exam: event.After.Exam, StudentID: event.After.StudentID, After: event.After})
+		}
+		return entries
+	}
+	_, err = UpsertScores([]models.StudentExam{{Exam: 111, StudentID: "test", Score: 0.75}}, auditor)
+	if err != nil {
+		t.Errorf("The upsert should have succeeded; %v", err)
+	}
+
+	entries, _ := GetRows(config.AuditTable, config.IdFld)
+	if len(entries) != 1 {
+		t.Errorf("Incorrect number of audit entries; have: %v, want: %v", len(entries), 1)
+	}
+
+	// An entry that cannot be written aborts the whole transaction
+	failing := func(events []models.ScoreEvent) []interface{} {
+		return []interface{}{models.StudentExam{}}
+	}
+	_, err = UpsertScores([]models.StudentExam{{Exam: 111, StudentID: "test2", Score: 0.9}}, failing)
+	if err == nil {
+		t.Errorf("The upsert should have failed")
+	}
+
+	scores, _ := GetRows(validTable, validIdx, 111)
+	if len(scores) != 1 {
+		t.Errorf("The score was written without its audit entry; have: %v, want: %v", len(scores), 1)
+	}
+}
+
+// TestUpdateScore validates an existing score is updated, and that a missing score is not created
+func TestUpdateScore(t *testing.T) {
+	err := InitDB(validSchema)
+	if err != nil {
+		t.Errorf("The database failed to initialize")
+	}
+
+	_, err = UpsertScores([]models.StudentExam{{Exam: 111, StudentID: "test", Score: 0.5}}, nil)
+	if err != nil {
+		t.Errorf("The upsert should have succeeded; %v", err)
+	}
+
+	events, err := UpdateScore(models.StudentExam{Exam: 111, StudentID: "test", Score: 0.8}, nil)
+	if err != nil || len(events) != 1 || events[0].Before.Score != 0.5 || events[0].After.Score != 0.8 {
+		t.Errorf("Incorrect change returned for the updated score; have: %+v, %v", events, err)
+	}
+
+	events, err = UpdateScore(models.StudentExam{Exam: 222, StudentID: "test", Score: 0.8}, nil)
+	if err != ErrNoScore || len(events) != 0 {
+		t.Errorf("A missing score should not be updated; have: %+v, %v", events, err)
+	}
+
+	res, _ := GetRows(config.ScoreTable, config.StudentIdx, "test")
+	if len(res) != 1 {
+		t.Errorf("A missing score was created; have: %v", len(res))
+	}
+}
+
+// TestDeleteScores validates the removed scores are returned, and that nothing is returned when no scores match
+func TestDeleteScores(t *testing.T) {
+	err := InitDB(validSchema)
+	if err != nil {
+		t.Errorf("The database failed to initialize")
+	}
+
+	_, err = UpsertScores([]models.StudentExam{{Exam: 111, StudentID: "test", Score: 0.5}, {Exam: 222, StudentID: "test", Score: 0.7}}, nil)
+	if err != nil {
+		t.Errorf("The upsert should have succeeded; %v", err)
+	}
+
+	events, err := DeleteScores(nil, config.IdFld, 111, "test")
+	if err != nil || len(events) != 1 || events[0].Type != models.ScoreDeleted || events[0].Before.Exam != 111 {
+		t.Errorf("Incorrect change returned for the deleted score; have: %+v, %v", events, err)
+	}
+
+	events, err = DeleteScores(nil, config.IdFld, 111, "test")
+	if err != nil || len(events) != 0 {
+		t.Errorf("A missing score should not be deleted; have: %+v, %v", events, err)
+	}
+
+	stats, _ := GetStudentAggregate("test")
+	if stats == nil || stats.Count != 1 {
+		t.Errorf("The aggregate was not updated; have: %+v", stats)
+	}
+}
+
 // TestDeleteRowsInvalidTable validates delete will fail when provided an invalid table
 func TestDeleteRowsInvalidTable(t *testing.T) {
 	err := InitDB(validSchema)
@@ -254,3 +384,84 @@ func TestGetRows(t *testing.T) {
 		t.Errorf("Failed to retrieve the correct number of records; have: %v, want %v", have, want)
 	}
 }
+
+// TestEachRow validates every matching row is visited, and that iteration stops at the first error
+func TestEachRow(t *testing.T) {
+	err := InitDB(validSchema)
+	if err != nil {
+		t.Errorf("The database failed to initialize")
+	}
+
+	for _, student := range []string{"test1", "test2", "test3"} {
+		err = UpsertRow(validTable, models.StudentExam{Exam: 111, StudentID: student, Score: 0.5})
+		if err != nil {
+			t.Errorf("Failed to insert prior to lookup")
+		}
+	}
+
+	have := 0
+	err = EachRow(validTable, validIdx, func(row interface{}) error {
+		have++
+		return nil
+	}, 111)
+	want := 3
+	if err != nil || have != want {
+		t.Errorf("Failed to visit the correct number of records; have: %v, want %v", have, want)
+	}
+
+	// Iteration stops at the first error
+	stop := errors.New("stop")
+	have = 0
+	err = EachRow(validTable, validIdx, func(row interface{}) error {
+		have++
+		return stop
+	}, 111)
+	want = 1
+	if err != stop || have != want {
+		t.Errorf("Iteration should have stopped; have: %v, want %v", have, want)
+	}
+}
+
+// TestUpsertRows validates that multiple rows are inserted in a single transaction
+func TestUpsertRows(t *testing.T) {
+	err := InitDB(validSchema)
+	if err != nil {
+		t.Errorf("The database failed to initialize")
+	}
+
+	records := []interface{}{
+		models.StudentExam{Exam: 111, StudentID: "test1", Score: 0.5},
+		models.StudentExam{Exam: 111, StudentID: "test2", Score: 0.7},
+	}
+	err = UpsertRows(validTable, records)
+	if err != nil {
+		t.Errorf("Failed to insert the rows")
+	}
+
+	rows, _ := GetRows(validTable, validIdx, 111)
+	have := len(rows)
+	want := 2
+	if have != want {
+		t.Errorf("Failed to retrieve the correct number of records; have: %v, want %v", have, want)
+	}
+
+	// A failing row rolls back the whole batch
+	type Foo struct {
+		Bar string
+	}
+	records = []interface{}{
+		models.StudentExam{Exam: 222, StudentID: "test1", Score: 0.5},
+		Foo{Bar: "fail"},
+	}
+	err = UpsertRows(validTable, records)
+	if err == nil {
+		t.Errorf("The insert should have failed")
+	}
+
+	rows, _ = GetRows(validTable, validIdx, 222)
+	have = len(rows)
+	want = 0
+	if have != want {
+		t.Errorf("The batch should have been rolled back; have: %v, want %v", have, want)
+	}
+}
@@ -0,0 +1,107 @@
+package db
+
+import (
+	"sync"
+	"time"
+
+	"github.com/hashicorp/go-memdb"
+	"github.com/kylegk/sse-rest-server/config"
+	"github.com/kylegk/sse-rest-server/models"
+)
+
+// ScoreListener is called with the changes made to the scores by each committed write transaction
+type ScoreListener func(events []models.ScoreEvent)
+
+var (
+	listenersMu sync.RWMutex
+	listeners   []ScoreListener
+)
+
+// Auditor builds the audit log entries of the changes a write transaction made; it is passed the changes made to the scores, if any
+// The entries are written in the same transaction as the changes, so they are only kept when the changes are
+type Auditor func(events []models.ScoreEvent) []interface{}
+
+// ResourceAuditor builds the Auditor of a change to a row created by the write, once the id the row is created with is known
+type ResourceAuditor func(id string) Auditor
+
+// OnScoreChange registers a listener to be notified of every committed change to the scores
+// Listeners are called synchronously after the commit, so any slow work should be handed off to another goroutine
+func OnScoreChange(listener ScoreListener) {
+	listenersMu.Lock()
+	defer listenersMu.Unlock()
+
+	listeners = append(listeners, listener)
+}
+
+// Commit a write transaction, advance the versions of the tables it changed and notify the listeners of any changes it made to the scores
+// The changes to the scores are returned, for callers that report what they changed
+func commit(txn *memdb.Txn) []models.ScoreEvent {
+	changes := txn.Changes()
+	txn.Commit()
+	recordVersions(changes)
+
+	events := scoreEvents(changes)
+	if len(events) == 0 {
+		return events
+	}
+
+	listenersMu.RLock()
+	defer listenersMu.RUnlock()
+
+	for _, listener := range listeners {
+		listener(events)
+	}
+
+	return events
+}
+
+// Write the audit log entries of the changes a transaction has made, before it is committed
+// Nothing is written without an auditor, or when the transaction has not changed anything
+func writeAudit(txn *memdb.Txn, auditor Auditor) error {
+	if auditor == nil {
+		return nil
+	}
+
+	changes := txn.Changes()
+	if len(changes) == 0 {
+		return nil
+	}
+
+	for _, entry := range auditor(scoreEvents(changes)) {
+		err := txn.Insert(config.AuditTable, entry)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Convert the changes to the score table into score events
+func scoreEvents(changes memdb.Changes) []models.ScoreEvent {
+	now := time.Now().UTC()
+	events := make([]models.ScoreEvent, 0)
+
+	for _, change := range changes {
+		if change.Table != config.ScoreTable {
+			continue
+		}
+
+		event := models.ScoreEvent{Type: models.ScoreUpserted, Time: now}
+		if change.Before != nil {
+			before := change.Before.(models.StudentExam)
+			event.Before = &before
+		}
+		if change.After != nil {
+			after := change.After.(models.StudentExam)
+			event.After = &after
+		}
+		if change.Deleted() {
+			event.Type = models.ScoreDeleted
+		}
+
+		events = append(events, event)
+	}
+
+	return events
+}
@@ -0,0 +1,17 @@
+package db
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+)
+
+// NewID generates a random identifier for a row
+func NewID() string {
+	b := make([]byte, 8)
+	_, err := rand.Read(b)
+	if err != nil {
+		panic("unable to generate id: " + err.Error())
+	}
+
+	return hex.EncodeToString(b)
+}
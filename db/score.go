@@ -0,0 +1,242 @@
+package db
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/hashicorp/go-memdb"
+	"github.com/kylegk/sse-rest-server/config"
+	"github.com/kylegk/sse-rest-server/models"
+)
+
+// GetAggregate retrieves the running statistics for a student or exam, returning nil if no scores have been recorded
+func GetAggregate(kind string, key string) (*models.Aggregate, error) {
+	if db == nil {
+		panic("database connection has not been initialized")
+	}
+
+	return getAggregate(db.Txn(false), kind, key)
+}
+
+// Retrieve the running statistics for a student or exam within a transaction
+func getAggregate(txn *memdb.Txn, kind string, key string) (*models.Aggregate, error) {
+	obj, err := txn.First(config.AggregateTable, config.IdFld, kind, key)
+	if err != nil {
+		return nil, err
+	}
+	if obj == nil {
+		return nil, nil
+	}
+
+	agg := obj.(models.Aggregate)
+
+	return &agg, nil
+}
+
+// GetStudentAggregate retrieves the running statistics for a single student
+func GetStudentAggregate(studentID string) (*models.Aggregate, error) {
+	return GetAggregate(config.StudentAggregate, studentID)
+}
+
+// GetExamAggregate retrieves the running statistics for a single exam
+func GetExamAggregate(examID int) (*models.Aggregate, error) {
+	return GetAggregate(config.ExamAggregate, strconv.Itoa(examID))
+}
+
+// Look up the currently stored score matching the exam and student of the record, if the record is a score
+func existingScore(txn *memdb.Txn, table string, record interface{}) (*models.StudentExam, error) {
+	score, ok := record.(models.StudentExam)
+	if table != config.ScoreTable || !ok {
+		return nil, nil
+	}
+
+	obj, err := txn.First(table, config.IdFld, score.Exam, score.StudentID)
+	if err != nil || obj == nil {
+		return nil, err
+	}
+
+	old := obj.(models.StudentExam)
+
+	return &old, nil
+}
+
+// Apply a change to a score (insert, update or delete) to the student and exam aggregates within the same transaction
+func updateAggregates(txn *memdb.Txn, before *models.StudentExam, after *models.StudentExam) error {
+	keys := before
+	if keys == nil {
+		keys = after
+	}
+	if keys == nil {
+		return nil
+	}
+
+	err := updateAggregate(txn, config.StudentAggregate, keys.StudentID, config.StudentIdx, keys.StudentID, before, after)
+	if err != nil {
+		return err
+	}
+
+	return updateAggregate(txn, config.ExamAggregate, strconv.Itoa(keys.Exam), config.ExamIdx, keys.Exam, before, after)
+}
+
+// Adjust a single aggregate row, recalculating the min and max from the score index only when an extreme value is removed
+func updateAggregate(txn *memdb.Txn, kind string, key string, idx string, arg interface{}, before *models.StudentExam, after *models.StudentExam) error {
+	agg := models.Aggregate{Kind: kind, Key: key}
+	obj, err := txn.First(config.AggregateTable, config.IdFld, kind, key)
+	if err != nil {
+		return err
+	}
+	if obj != nil {
+		agg = obj.(models.Aggregate)
+	}
+
+	rescan := false
+	if before != nil {
+		agg.Count--
+		agg.Sum -= before.Score
+		agg.SumSq -= before.Score * before.Score
+		rescan = before.Score <= agg.Min || before.Score >= agg.Max
+	}
+
+	if after != nil {
+		if agg.Count == 0 && !rescan {
+			agg.Min, agg.Max = after.Score, after.Score
+		}
+		agg.Count++
+		agg.Sum += after.Score
+		agg.SumSq += after.Score * after.Score
+		if after.Score < agg.Min {
+			agg.Min = after.Score
+		}
+		if after.Score > agg.Max {
+			agg.Max = after.Score
+		}
+	}
+
+	if agg.Count <= 0 {
+		if obj == nil {
+			return nil
+		}
+		return txn.Delete(config.AggregateTable, agg)
+	}
+
+	if rescan {
+		err = rescanExtremes(txn, &agg, idx, arg)
+		if err != nil {
+			return err
+		}
+	}
+
+	return txn.Insert(config.AggregateTable, agg)
+}
+
+// Recalculate the min and max of an aggregate from the rows currently visible to the transaction
+func rescanExtremes(txn *memdb.Txn, agg *models.Aggregate, idx string, arg interface{}) error {
+	it, err := txn.Get(config.ScoreTable, idx, arg)
+	if err != nil {
+		return err
+	}
+
+	first := true
+	for obj := it.Next(); obj != nil; obj = it.Next() {
+		score := obj.(models.StudentExam).Score
+		if first || score < agg.Min {
+			agg.Min = score
+		}
+		if first || score > agg.Max {
+			agg.Max = score
+		}
+		first = false
+	}
+
+	return nil
+}
+
+// Collect the scores matching an index lookup so their aggregates can be adjusted once they are deleted
+func collectScores(txn *memdb.Txn, table string, idx string, args ...interface{}) ([]models.StudentExam, error) {
+	if table != config.ScoreTable {
+		return nil, nil
+	}
+
+	it, err := txn.Get(table, idx, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	scores := make([]models.StudentExam, 0)
+	for obj := it.Next(); obj != nil; obj = it.Next() {
+		scores = append(scores, obj.(models.StudentExam))
+	}
+
+	return scores, nil
+}
+
+// ApplyCurve stores the curve for an exam and records the curved value of every existing score for that exam
+// The auditor, if any, records the curved scores in the same transaction
+func ApplyCurve(curve models.Curve, auditor Auditor) (int, error) {
+	if db == nil {
+		panic("database connection has not been initialized")
+	}
+
+	txn := db.Txn(true)
+	txn.TrackChanges()
+	defer txn.Abort()
+
+	err := txn.Insert(config.CurveTable, curve)
+	if err != nil {
+		return 0, err
+	}
+
+	scores, err := collectScores(txn, config.ScoreTable, config.ExamIdx, curve.Exam)
+	if err != nil {
+		return 0, err
+	}
+
+	// The raw scores are unchanged, so the aggregates do not need to be updated
+	for _, score := range scores {
+		curved := curve.Apply(score.Score)
+		score.Curved = &curved
+		err = txn.Insert(config.ScoreTable, score)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	err = writeAudit(txn, auditor)
+	if err != nil {
+		return 0, err
+	}
+
+	commit(txn)
+
+	return len(scores), nil
+}
+
+// Record the time a score was first recorded, and set its curved value from the curve stored for its exam if there is one
+// An update keeps the time of the score it replaces, so correcting a score does not move the exam in the student's history
+func prepareScore(txn *memdb.Txn, table string, record interface{}) (interface{}, error) {
+	score, ok := record.(models.StudentExam)
+	if table != config.ScoreTable || !ok {
+		return record, nil
+	}
+
+	existing, err := existingScore(txn, table, score)
+	if err != nil {
+		return nil, err
+	}
+	score.RecordedAt = time.Now().UTC()
+	if existing != nil {
+		score.RecordedAt = existing.RecordedAt
+	}
+
+	score.Curved = nil
+	obj, err := txn.First(config.CurveTable, config.IdFld, score.Exam)
+	if err != nil {
+		return nil, err
+	}
+	if obj != nil {
+		curved := obj.(models.Curve).Apply(score.Score)
+		score.Curved = &curved
+	}
+
+	return score, nil
+}
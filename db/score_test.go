@@ -0,0 +1,128 @@
+package db
+
+import (
+	"testing"
+
+	"github.com/kylegk/sse-rest-server/models"
+)
+
+// TestAggregatesOnUpsert validates that inserting and updating scores keeps the student and exam aggregates current
+func TestAggregatesOnUpsert(t *testing.T) {
+	err := InitDB(validSchema)
+	if err != nil {
+		t.Errorf("The database failed to initialize")
+	}
+
+	records := []models.StudentExam{
+		{Exam: 1, StudentID: "test", Score: 0.5},
+		{Exam: 2, StudentID: "test", Score: 0.9},
+		{Exam: 1, StudentID: "test2", Score: 0.7},
+	}
+	for _, record := range records {
+		err = UpsertRow(validTable, record)
+		if err != nil {
+			t.Errorf("Failed to insert prior to lookup")
+		}
+	}
+
+	agg, err := GetStudentAggregate("test")
+	if err != nil || agg == nil {
+		t.Fatalf("Failed to retrieve the student aggregate")
+	}
+	if agg.Count != 2 || agg.Min != 0.5 || agg.Max != 0.9 {
+		t.Errorf("Incorrect student aggregate; have: %+v", agg)
+	}
+
+	// Updating an existing score should replace, not add to, the aggregate
+	err = UpsertRow(validTable, models.StudentExam{Exam: 1, StudentID: "test", Score: 0.3})
+	if err != nil {
+		t.Errorf("Failed to update score")
+	}
+
+	agg, err = GetExamAggregate(1)
+	if err != nil || agg == nil {
+		t.Fatalf("Failed to retrieve the exam aggregate")
+	}
+	have := agg.Mean()
+	want := 0.5
+	if agg.Count != 2 || have != want || agg.Min != 0.3 || agg.Max != 0.7 {
+		t.Errorf("Incorrect exam aggregate; have: %+v, want mean: %v", agg, want)
+	}
+}
+
+// TestAggregatesOnDelete validates that deleting scores removes them from the aggregates, recalculating extremes
+func TestAggregatesOnDelete(t *testing.T) {
+	err := InitDB(validSchema)
+	if err != nil {
+		t.Errorf("The database failed to initialize")
+	}
+
+	records := []models.StudentExam{
+		{Exam: 1, StudentID: "test", Score: 0.2},
+		{Exam: 2, StudentID: "test", Score: 0.6},
+		{Exam: 3, StudentID: "test", Score: 0.8},
+	}
+	for _, record := range records {
+		err = UpsertRow(validTable, record)
+		if err != nil {
+			t.Errorf("Failed to insert prior to delete")
+		}
+	}
+
+	_, err = DeleteRows(validTable, validIdx, 1)
+	if err != nil {
+		t.Errorf("Failed to delete rows")
+	}
+
+	agg, err := GetStudentAggregate("test")
+	if err != nil || agg == nil {
+		t.Fatalf("Failed to retrieve the student aggregate")
+	}
+	if agg.Count != 2 || agg.Min != 0.6 || agg.Max != 0.8 {
+		t.Errorf("Incorrect student aggregate after delete; have: %+v", agg)
+	}
+
+	// The exam aggregate should be removed once the exam has no scores left
+	agg, err = GetExamAggregate(1)
+	if err != nil {
+		t.Errorf("Failed to retrieve the exam aggregate")
+	}
+	if agg != nil {
+		t.Errorf("The exam aggregate should have been removed; have: %+v", agg)
+	}
+}
+
+// TestApplyCurve validates that existing and subsequently inserted scores for a curved exam are curved
+func TestApplyCurve(t *testing.T) {
+	err := InitDB(validSchema)
+	if err != nil {
+		t.Errorf("The database failed to initialize")
+	}
+
+	err = UpsertRow(validTable, models.StudentExam{Exam: 1, StudentID: "test", Score: 0.5})
+	if err != nil {
+		t.Errorf("Failed to insert prior to curve")
+	}
+
+	count, err := ApplyCurve(models.Curve{Exam: 1, Method: models.CurveLinear, Factor: 1.5}, nil)
+	if err != nil || count != 1 {
+		t.Errorf("Failed to curve the existing scores; have: %v, want: %v", count, 1)
+	}
+
+	err = UpsertRow(validTable, models.StudentExam{Exam: 1, StudentID: "test2", Score: 0.6})
+	if err != nil {
+		t.Errorf("Failed to insert after curve")
+	}
+
+	rows, err := GetRows(validTable, validIdx, 1)
+	if err != nil {
+		t.Errorf("Failed to retrieve the curved scores")
+	}
+
+	for _, row := range rows {
+		score := row.(models.StudentExam)
+		if score.Curved == nil || *score.Curved != score.Score*1.5 {
+			t.Errorf("The score was not curved; have: %+v", score)
+		}
+	}
+}
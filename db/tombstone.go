@@ -0,0 +1,213 @@
+package db
+
+import (
+	"errors"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/go-memdb"
+	"github.com/kylegk/sse-rest-server/config"
+	"github.com/kylegk/sse-rest-server/models"
+)
+
+// ErrNoTombstone is returned when a deleted exam cannot be restored, because it was never deleted or its grace period has expired
+var ErrNoTombstone = errors.New("no deleted scores to restore")
+
+// GracePeriod is how long the scores of a deleted exam can be restored before they are purged
+var GracePeriod = 24 * time.Hour
+
+var purgeOnce sync.Once
+
+// SoftDeleteExam removes the scores of an exam, keeping them in a tombstone until the grace period expires
+// The tombstone of an exam that is deleted again within its grace period also keeps the scores deleted earlier
+// The auditor, if any, records the removed scores in the same transaction
+func SoftDeleteExam(exam int, auditor Auditor) (int, *models.Tombstone, error) {
+	if db == nil {
+		panic("database connection has not been initialized")
+	}
+
+	txn := db.Txn(true)
+	txn.TrackChanges()
+	defer txn.Abort()
+
+	removed, err := collectScores(txn, config.ScoreTable, config.ExamIdx, exam)
+	if err != nil {
+		return 0, nil, err
+	}
+	if len(removed) == 0 {
+		return 0, nil, nil
+	}
+
+	_, err = txn.DeleteAll(config.ScoreTable, config.ExamIdx, exam)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	for i := range removed {
+		err = updateAggregates(txn, &removed[i], nil)
+		if err != nil {
+			return 0, nil, err
+		}
+	}
+
+	now := time.Now().UTC()
+	tombstone := models.Tombstone{Exam: exam, Scores: removed, DeletedAt: now, ExpiresAt: now.Add(GracePeriod)}
+
+	obj, err := txn.First(config.TombstoneTable, config.IdFld, exam)
+	if err != nil {
+		return 0, nil, err
+	}
+	if obj != nil && obj.(models.Tombstone).ExpiresAt.After(now) {
+		deleted := make(map[string]bool, len(removed))
+		for _, score := range removed {
+			deleted[score.StudentID] = true
+		}
+
+		// A student's most recently deleted score takes precedence over one deleted earlier
+		for _, score := range obj.(models.Tombstone).Scores {
+			if !deleted[score.StudentID] {
+				tombstone.Scores = append(tombstone.Scores, score)
+			}
+		}
+	}
+
+	err = txn.Insert(config.TombstoneTable, tombstone)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	err = writeAudit(txn, auditor)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	commit(txn)
+
+	return len(removed), &tombstone, nil
+}
+
+// RestoreExam returns the scores of a deleted exam to the datastore and removes its tombstone
+// Scores that have been recorded again since the exam was deleted are newer, so they are kept and the deleted score is skipped
+// The auditor, if any, records the restored scores in the same transaction
+func RestoreExam(exam int, auditor Auditor) (int, int, error) {
+	if db == nil {
+		panic("database connection has not been initialized")
+	}
+
+	txn := db.Txn(true)
+	txn.TrackChanges()
+	defer txn.Abort()
+
+	obj, err := txn.First(config.TombstoneTable, config.IdFld, exam)
+	if err != nil {
+		return 0, 0, err
+	}
+	if obj == nil || !obj.(models.Tombstone).ExpiresAt.After(time.Now()) {
+		return 0, 0, ErrNoTombstone
+	}
+	tombstone := obj.(models.Tombstone)
+
+	restored, skipped := 0, 0
+	for _, score := range tombstone.Scores {
+		existing, err := existingScore(txn, config.ScoreTable, score)
+		if err != nil {
+			return 0, 0, err
+		}
+		if existing != nil {
+			skipped++
+			continue
+		}
+
+		err = restoreScore(txn, score)
+		if err != nil {
+			return 0, 0, err
+		}
+		restored++
+	}
+
+	err = txn.Delete(config.TombstoneTable, tombstone)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	err = writeAudit(txn, auditor)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	commit(txn)
+
+	return restored, skipped, nil
+}
+
+// PurgeTombstones permanently removes the tombstones whose grace period has expired
+func PurgeTombstones(now time.Time) (int, error) {
+	if db == nil {
+		panic("database connection has not been initialized")
+	}
+
+	txn := db.Txn(true)
+	txn.TrackChanges()
+	defer txn.Abort()
+
+	it, err := txn.Get(config.TombstoneTable, config.IdFld)
+	if err != nil {
+		return 0, err
+	}
+
+	expired := make([]models.Tombstone, 0)
+	for obj := it.Next(); obj != nil; obj = it.Next() {
+		if tombstone := obj.(models.Tombstone); !tombstone.ExpiresAt.After(now) {
+			expired = append(expired, tombstone)
+		}
+	}
+
+	for _, tombstone := range expired {
+		err = txn.Delete(config.TombstoneTable, tombstone)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	commit(txn)
+
+	return len(expired), nil
+}
+
+// StartPurge purges the expired tombstones on an interval for as long as the server runs
+func StartPurge(interval time.Duration) {
+	purgeOnce.Do(func() {
+		go func() {
+			for now := range time.Tick(interval) {
+				count, err := PurgeTombstones(now)
+				if err != nil {
+					log.Println(err)
+					continue
+				}
+				if count > 0 {
+					log.Println("purged deleted exams: ", count)
+				}
+			}
+		}()
+	})
+}
+
+// Insert a deleted score, keeping the time it was originally recorded and curving it with the exam's current curve
+func restoreScore(txn *memdb.Txn, score models.StudentExam) error {
+	recordedAt := score.RecordedAt
+
+	record, err := prepareScore(txn, config.ScoreTable, score)
+	if err != nil {
+		return err
+	}
+	score = record.(models.StudentExam)
+	score.RecordedAt = recordedAt
+
+	err = txn.Insert(config.ScoreTable, score)
+	if err != nil {
+		return err
+	}
+
+	return updateAggregates(txn, nil, &score)
+}
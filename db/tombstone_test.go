@@ -0,0 +1,130 @@
+package db
+
+import (
+	"testing"
+	"time"
+
+	"github.com/kylegk/sse-rest-server/config"
+	"github.com/kylegk/sse-rest-server/models"
+)
+
+func seedTombstoneScores(t *testing.T) {
+	err := InitDB(config.DBSchema)
+	if err != nil {
+		t.Errorf("The database failed to initialize")
+	}
+
+	for _, score := range []models.StudentExam{
+		{Exam: 1, StudentID: "test1", Score: 0.5},
+		{Exam: 1, StudentID: "test2", Score: 0.7},
+		{Exam: 2, StudentID: "test1", Score: 0.9},
+	} {
+		err = UpsertRow(config.ScoreTable, score)
+		if err != nil {
+			t.Errorf("Failed to insert score")
+		}
+	}
+}
+
+// TestSoftDeleteAndRestore validates that a deleted exam is restored with its aggregates and original record times
+func TestSoftDeleteAndRestore(t *testing.T) {
+	seedTombstoneScores(t)
+	before, _ := GetRows(config.ScoreTable, config.IdFld, 1, "test1")
+
+	count, tombstone, err := SoftDeleteExam(1, nil)
+	if err != nil || count != 2 || tombstone == nil || len(tombstone.Scores) != 2 {
+		t.Fatalf("Failed to delete the exam; have: %v, %+v, %v", count, tombstone, err)
+	}
+
+	rows, _ := GetRows(config.ScoreTable, config.ExamIdx, 1)
+	stats, _ := GetExamAggregate(1)
+	if len(rows) != 0 || stats != nil {
+		t.Errorf("The exam's scores and aggregate should have been removed")
+	}
+
+	// A score recorded again after the deletion is newer than the deleted score
+	err = UpsertRow(config.ScoreTable, models.StudentExam{Exam: 1, StudentID: "test2", Score: 0.8})
+	if err != nil {
+		t.Errorf("Failed to insert score")
+	}
+
+	restored, skipped, err := RestoreExam(1, nil)
+	if err != nil || restored != 1 || skipped != 1 {
+		t.Errorf("Incorrect restore; have: %v restored, %v skipped, %v", restored, skipped, err)
+	}
+
+	after, _ := GetRows(config.ScoreTable, config.IdFld, 1, "test1")
+	if len(after) != 1 || !after[0].(models.StudentExam).RecordedAt.Equal(before[0].(models.StudentExam).RecordedAt) {
+		t.Errorf("The score was not restored with its original record time")
+	}
+
+	stats, _ = GetExamAggregate(1)
+	if stats == nil || stats.Count != 2 || stats.Min != 0.5 || stats.Max != 0.8 {
+		t.Errorf("Incorrect aggregate after restore; have: %+v", stats)
+	}
+
+	// The tombstone is removed by the restore
+	_, _, err = RestoreExam(1, nil)
+	if err != ErrNoTombstone {
+		t.Errorf("The exam should not be restorable twice; have: %v", err)
+	}
+}
+
+// TestSoftDeleteMerge validates that deleting an exam again keeps the scores deleted earlier
+func TestSoftDeleteMerge(t *testing.T) {
+	seedTombstoneScores(t)
+
+	_, _, err := SoftDeleteExam(1, nil)
+	if err != nil {
+		t.Errorf("Failed to delete the exam")
+	}
+	err = UpsertRow(config.ScoreTable, models.StudentExam{Exam: 1, StudentID: "test3", Score: 0.6})
+	if err != nil {
+		t.Errorf("Failed to insert score")
+	}
+
+	_, tombstone, err := SoftDeleteExam(1, nil)
+	if err != nil || tombstone == nil || len(tombstone.Scores) != 3 {
+		t.Errorf("The tombstone should hold every deleted score; have: %+v", tombstone)
+	}
+
+	// Deleting an exam without scores leaves the tombstone in place
+	count, tombstone, err := SoftDeleteExam(1, nil)
+	if err != nil || count != 0 || tombstone != nil {
+		t.Errorf("Nothing should have been deleted; have: %v, %+v", count, tombstone)
+	}
+
+	restored, _, err := RestoreExam(1, nil)
+	if err != nil || restored != 3 {
+		t.Errorf("Incorrect number of scores restored; have: %v, %v", restored, err)
+	}
+}
+
+// TestPurgeTombstones validates that expired tombstones are purged and can no longer be restored
+func TestPurgeTombstones(t *testing.T) {
+	seedTombstoneScores(t)
+
+	_, _, err := SoftDeleteExam(1, nil)
+	if err != nil {
+		t.Errorf("Failed to delete the exam")
+	}
+	_, _, err = SoftDeleteExam(2, nil)
+	if err != nil {
+		t.Errorf("Failed to delete the exam")
+	}
+
+	count, err := PurgeTombstones(time.Now())
+	if err != nil || count != 0 {
+		t.Errorf("Nothing should have been purged within the grace period; have: %v", count)
+	}
+
+	count, err = PurgeTombstones(time.Now().Add(GracePeriod))
+	if err != nil || count != 2 {
+		t.Errorf("Incorrect number of tombstones purged; have: %v, want: 2", count)
+	}
+
+	_, _, err = RestoreExam(1, nil)
+	if err != ErrNoTombstone {
+		t.Errorf("A purged exam should not be restorable; have: %v", err)
+	}
+}
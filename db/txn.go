@@ -0,0 +1,66 @@
+package db
+
+import (
+	"github.com/hashicorp/go-memdb"
+	"github.com/kylegk/sse-rest-server/models"
+)
+
+// Txn is a transaction passed to the functions run by Update and View
+// Rows written through it keep the score aggregates up to date, as they are when written by UpsertRow and DeleteRow
+type Txn struct {
+	txn *memdb.Txn
+}
+
+// Update runs fn in a write transaction, committing the rows it wrote when it returns nil and discarding them when it returns an error
+// Nothing else is written while fn runs, so a row it checks cannot change before its writes are committed
+// The changes made to the scores are returned, and the auditor, if any, records them in the same transaction
+func Update(auditor Auditor, fn func(txn *Txn) error) ([]models.ScoreEvent, error) {
+	if db == nil {
+		panic("database connection has not been initialized")
+	}
+
+	txn := db.Txn(true)
+	txn.TrackChanges()
+	defer txn.Abort()
+
+	err := fn(&Txn{txn: txn})
+	if err != nil {
+		return nil, err
+	}
+
+	err = writeAudit(txn, auditor)
+	if err != nil {
+		return nil, err
+	}
+
+	return commit(txn), nil
+}
+
+// View runs fn in a read transaction, so every row it reads comes from the same snapshot of the database
+func View(fn func(txn *Txn) error) error {
+	if db == nil {
+		panic("database connection has not been initialized")
+	}
+
+	return fn(&Txn{txn: db.Txn(false)})
+}
+
+// GetRows retrieves the rows matching the index
+func (t *Txn) GetRows(table string, idx string, args ...interface{}) ([]interface{}, error) {
+	return getRows(t.txn, table, idx, args...)
+}
+
+// GetAggregate retrieves the running statistics for a student or exam, returning nil if no scores have been recorded
+func (t *Txn) GetAggregate(kind string, key string) (*models.Aggregate, error) {
+	return getAggregate(t.txn, kind, key)
+}
+
+// UpsertRow inserts a row, or updates it if it exists; it fails in a transaction run by View
+func (t *Txn) UpsertRow(table string, record interface{}) error {
+	return upsert(t.txn, table, record)
+}
+
+// DeleteRow removes a single row; it fails in a transaction run by View
+func (t *Txn) DeleteRow(table string, record interface{}) error {
+	return remove(t.txn, table, record)
+}
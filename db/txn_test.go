@@ -0,0 +1,78 @@
+package db
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/kylegk/sse-rest-server/config"
+	"github.com/kylegk/sse-rest-server/models"
+)
+
+// TestUpdate validates that the rows written by an update are committed together, and discarded when it fails
+func TestUpdate(t *testing.T) {
+	err := InitDB(config.DBSchema)
+	if err != nil {
+		t.Errorf("The database failed to initialize")
+	}
+
+	failed := errors.New("failed")
+	_, err = Update(nil, func(txn *Txn) error {
+		err := txn.UpsertRow(config.ScoreTable, models.StudentExam{Exam: 1, StudentID: "test", Score: 0.5})
+		if err != nil {
+			return err
+		}
+		return failed
+	})
+	if err != failed {
+		t.Errorf("Incorrect error returned; have: %v, want: %v", err, failed)
+	}
+
+	res, _ := GetRows(config.ScoreTable, config.IdFld)
+	if len(res) != 0 {
+		t.Errorf("The rows of a failed update were written; have: %v", res)
+	}
+
+	events, err := Update(nil, func(txn *Txn) error {
+		return txn.UpsertRow(config.ScoreTable, models.StudentExam{Exam: 1, StudentID: "test", Score: 0.5})
+	})
+	if err != nil || len(events) != 1 || events[0].Before != nil {
+		t.Errorf("Incorrect changes returned; have: %+v, %v", events, err)
+	}
+
+	agg, _ := GetStudentAggregate("test")
+	if agg == nil || agg.Count != 1 {
+		t.Errorf("The aggregate was not updated; have: %+v", agg)
+	}
+}
+
+// TestView validates that every read made in a view comes from the same snapshot
+func TestView(t *testing.T) {
+	err := InitDB(config.DBSchema)
+	if err != nil {
+		t.Errorf("The database failed to initialize")
+	}
+
+	err = View(func(txn *Txn) error {
+		err := UpsertRow(config.ScoreTable, models.StudentExam{Exam: 1, StudentID: "test", Score: 0.5})
+		if err != nil {
+			return err
+		}
+
+		res, err := txn.GetRows(config.ScoreTable, config.IdFld)
+		if err != nil {
+			return err
+		}
+		agg, err := txn.GetAggregate(config.StudentAggregate, "test")
+		if err != nil {
+			return err
+		}
+		if len(res) != 0 || agg != nil {
+			t.Errorf("The view read a score written after it started; have: %v, %+v", res, agg)
+		}
+
+		return nil
+	})
+	if err != nil {
+		t.Errorf("Failed to read the view: %v", err)
+	}
+}
@@ -0,0 +1,89 @@
+package db
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/go-memdb"
+)
+
+// The modification index of a table: the index of the last transaction that changed it, and when it was committed
+type tableVersion struct {
+	index    uint64
+	modified time.Time
+}
+
+var (
+	versionMu sync.RWMutex
+	// Identifies this database, so versions are not mistaken for those of a database created earlier
+	epoch     string
+	lastIndex uint64
+	created   time.Time
+	versions  = make(map[string]tableVersion)
+)
+
+// TableVersion returns a version identifying the current contents of the tables, and when the tables were last modified
+// The version changes whenever a committed transaction changes any of the tables
+func TableVersion(tables ...string) (string, time.Time) {
+	versionMu.RLock()
+	defer versionMu.RUnlock()
+
+	var index uint64
+	modified := created
+	for _, table := range tables {
+		v, ok := versions[table]
+		if !ok {
+			continue
+		}
+		if v.index > index {
+			index = v.index
+		}
+		if v.modified.After(modified) {
+			modified = v.modified
+		}
+	}
+
+	return fmt.Sprintf("%s-%d", epoch, index), modified
+}
+
+// Start a new set of versions for a newly created database
+func resetVersions() {
+	versionMu.Lock()
+	defer versionMu.Unlock()
+
+	b := make([]byte, 4)
+	rand.Read(b)
+	epoch = hex.EncodeToString(b)
+	lastIndex = 0
+	created = time.Now().UTC()
+	versions = make(map[string]tableVersion)
+}
+
+// Advance the version of every table changed by a committed transaction
+func recordVersions(changes memdb.Changes) {
+	if len(changes) == 0 {
+		return
+	}
+
+	tables := make([]string, 0, len(changes))
+	for _, change := range changes {
+		tables = append(tables, change.Table)
+	}
+	Touch(tables...)
+}
+
+// Touch advances the version of the tables without changing them
+// This is for state reported alongside a table but kept outside of it, so the table's version still changes when that state does
+func Touch(tables ...string) {
+	versionMu.Lock()
+	defer versionMu.Unlock()
+
+	lastIndex++
+	v := tableVersion{index: lastIndex, modified: time.Now().UTC()}
+	for _, table := range tables {
+		versions[table] = v
+	}
+}
@@ -0,0 +1,78 @@
+package db
+
+import (
+	"testing"
+
+	"github.com/kylegk/sse-rest-server/config"
+	"github.com/kylegk/sse-rest-server/models"
+)
+
+// TestTableVersion validates that a table's version only changes when the table is changed
+func TestTableVersion(t *testing.T) {
+	err := InitDB(config.DBSchema)
+	if err != nil {
+		t.Errorf("The database failed to initialize")
+	}
+
+	scores, _ := TableVersion(config.ScoreTable)
+	alerts, _ := TableVersion(config.AlertTable)
+
+	err = UpsertRow(config.ScoreTable, models.StudentExam{Exam: 1, StudentID: "test", Score: 0.5})
+	if err != nil {
+		t.Errorf("Failed to insert score")
+	}
+
+	have, _ := TableVersion(config.ScoreTable)
+	if have == scores {
+		t.Errorf("The score table version should have changed; have: %v", have)
+	}
+
+	have, _ = TableVersion(config.AlertTable)
+	if have != alerts {
+		t.Errorf("The alert table version should not have changed; have: %v, want: %v", have, alerts)
+	}
+
+	// A combined version changes when any of its tables changes
+	combined, _ := TableVersion(config.ScoreTable, config.AlertTable)
+	err = UpsertRow(config.AlertTable, models.Alert{ID: "1", StudentID: "test", Status: models.AlertOpen})
+	if err != nil {
+		t.Errorf("Failed to insert alert")
+	}
+	have, _ = TableVersion(config.ScoreTable, config.AlertTable)
+	if have == combined {
+		t.Errorf("The combined version should have changed; have: %v", have)
+	}
+
+	// A new database does not reuse the versions of the previous one
+	err = InitDB(config.DBSchema)
+	if err != nil {
+		t.Errorf("The database failed to initialize")
+	}
+	have, _ = TableVersion(config.ScoreTable)
+	if have == scores {
+		t.Errorf("The version of a new database should differ; have: %v", have)
+	}
+}
+
+// TestTouch validates that touching a table changes its version without changing the table
+func TestTouch(t *testing.T) {
+	err := InitDB(config.DBSchema)
+	if err != nil {
+		t.Errorf("The database failed to initialize")
+	}
+
+	anomalies, _ := TableVersion(config.AnomalyTable)
+	scores, _ := TableVersion(config.ScoreTable)
+
+	Touch(config.AnomalyTable)
+
+	have, _ := TableVersion(config.AnomalyTable)
+	if have == anomalies {
+		t.Errorf("The anomaly table version should have changed; have: %v", have)
+	}
+
+	have, _ = TableVersion(config.ScoreTable)
+	if have != scores {
+		t.Errorf("The score table version should not have changed; have: %v, want: %v", have, scores)
+	}
+}
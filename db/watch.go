@@ -0,0 +1,78 @@
+package db
+
+import (
+	"context"
+	"log"
+	"sync"
+
+	"github.com/kylegk/sse-rest-server/models"
+)
+
+// The number of events that can be waiting for a watcher before further events to it are dropped
+const watcherBuffer = 100
+
+// A watcher of the score events, limited to an exam or student when they are set
+type watcher struct {
+	events  chan models.ScoreEvent
+	exam    int
+	student string
+}
+
+var (
+	watchersMu sync.Mutex
+	watchers   = make(map[*watcher]bool)
+	watchOnce  sync.Once
+)
+
+// WatchScores sends the score events matching the filters, a zero exam or empty student matching any, until the context is done, when the channel is closed
+// Events are dropped for a watcher that falls behind, so a slow reader cannot hold up the commits
+func WatchScores(ctx context.Context, exam int, student string) <-chan models.ScoreEvent {
+	watchOnce.Do(func() {
+		OnScoreChange(broadcast)
+	})
+
+	w := &watcher{events: make(chan models.ScoreEvent, watcherBuffer), exam: exam, student: student}
+	watchersMu.Lock()
+	watchers[w] = true
+	watchersMu.Unlock()
+
+	go func() {
+		<-ctx.Done()
+
+		watchersMu.Lock()
+		delete(watchers, w)
+		close(w.events)
+		watchersMu.Unlock()
+	}()
+
+	return w.events
+}
+
+// Hand the events to every matching watcher without blocking the commit
+func broadcast(events []models.ScoreEvent) {
+	watchersMu.Lock()
+	defer watchersMu.Unlock()
+
+	for w := range watchers {
+		for _, event := range events {
+			if !w.matches(event) {
+				continue
+			}
+
+			select {
+			case w.events <- event:
+			default:
+				log.Println("score watcher is not keeping up, dropping a score event")
+			}
+		}
+	}
+}
+
+func (w *watcher) matches(event models.ScoreEvent) bool {
+	score := event.After
+	if score == nil {
+		score = event.Before
+	}
+
+	return (w.exam == 0 || w.exam == score.Exam) && (w.student == "" || w.student == score.StudentID)
+}
@@ -0,0 +1,51 @@
+package db
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/kylegk/sse-rest-server/config"
+	"github.com/kylegk/sse-rest-server/models"
+)
+
+// TestWatchScores validates only the events matching a watcher's filters are sent, and that the watcher is removed once its context is done
+func TestWatchScores(t *testing.T) {
+	err := InitDB(config.DBSchema)
+	if err != nil {
+		t.Errorf("Failed to initialize the database")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	events := WatchScores(ctx, 2, "")
+
+	// Only the change to the watched exam is sent
+	err = UpsertRow(config.ScoreTable, models.StudentExam{Exam: 1, StudentID: "test.person1", Score: 0.5})
+	if err != nil {
+		t.Errorf("Failed to record a score")
+	}
+	err = UpsertRow(config.ScoreTable, models.StudentExam{Exam: 2, StudentID: "test.person1", Score: 0.7})
+	if err != nil {
+		t.Errorf("Failed to record a score")
+	}
+
+	select {
+	case event := <-events:
+		if event.Type != models.ScoreUpserted || event.After == nil || event.After.Exam != 2 {
+			t.Errorf("Incorrect event received; have: %+v", event)
+		}
+	case <-time.After(time.Second):
+		t.Errorf("No event received")
+	}
+
+	// Verify the watcher is removed and its channel closed once the context is done
+	cancel()
+	for range events {
+	}
+
+	watchersMu.Lock()
+	defer watchersMu.Unlock()
+	if len(watchers) != 0 {
+		t.Errorf("Watcher was not removed; have: %v", len(watchers))
+	}
+}
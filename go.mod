@@ -4,7 +4,12 @@ go 1.15
 
 require (
 	github.com/davecgh/go-spew v1.1.0
+	github.com/golang-jwt/jwt v3.2.2+incompatible
+	github.com/golang/protobuf v1.4.3
 	github.com/gorilla/mux v1.8.0
+	github.com/graphql-go/graphql v0.8.1
 	github.com/hashicorp/go-memdb v1.3.2
 	github.com/r3labs/sse v0.0.0-20210224172625-26fe804710bc
+	google.golang.org/grpc v1.36.1
+	google.golang.org/protobuf v1.25.0
 )
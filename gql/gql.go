@@ -0,0 +1,241 @@
+package gql
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/gqlerrors"
+	"github.com/graphql-go/graphql/language/ast"
+	"github.com/graphql-go/graphql/language/parser"
+	"github.com/kylegk/sse-rest-server/report"
+)
+
+type contextKey string
+
+// Limits on the operations that are run, checked before an operation is executed so a deeply nested query
+// cannot resolve an unbounded number of fields
+var (
+	// MaxDepth is the deepest that fields can be nested
+	MaxDepth = 6
+	// MaxComplexity is the most fields an operation can select, where the fields under a list count ListComplexity times each
+	MaxComplexity  = 1000
+	ListComplexity = 10
+)
+
+// The context key of the filter of the students an operation can see
+const filterContext contextKey = "filter"
+
+// Request is a GraphQL operation, as sent in the body of a request or its query parameters
+type Request struct {
+	Query         string                 `json:"query"`
+	Variables     map[string]interface{} `json:"variables"`
+	OperationName string                 `json:"operationName"`
+}
+
+func (req Request) params(ctx context.Context) graphql.Params {
+	return graphql.Params{
+		Schema:         Schema,
+		RequestString:  req.Query,
+		VariableValues: req.Variables,
+		OperationName:  req.OperationName,
+		Context:        ctx,
+	}
+}
+
+// Execute runs a query; errors in the query are reported in the result rather than returned
+func Execute(ctx context.Context, req Request) *graphql.Result {
+	_, err := Complexity(req)
+	if err != nil {
+		return errorResult(err)
+	}
+
+	return graphql.Do(req.params(ctx))
+}
+
+// Subscribe runs a subscription, sending a result for every matching score event until the context is done
+// The channel must be read until it is closed
+func Subscribe(ctx context.Context, req Request) chan *graphql.Result {
+	_, err := Complexity(req)
+	if err != nil {
+		results := make(chan *graphql.Result, 1)
+		results <- errorResult(err)
+		close(results)
+		return results
+	}
+
+	return graphql.Subscribe(req.params(ctx))
+}
+
+// A result reporting an error found before the operation was executed
+func errorResult(err error) *graphql.Result {
+	return &graphql.Result{Errors: []gqlerrors.FormattedError{gqlerrors.FormatError(err)}}
+}
+
+// Complexity measures the operation a request selects, returning an error when it is nested deeper than MaxDepth
+// or is more complex than MaxComplexity
+// A query that cannot be parsed has no complexity, so executing it reports the parse error
+func Complexity(req Request) (int, error) {
+	doc, err := parser.Parse(parser.ParseParams{Source: req.Query})
+	if err != nil {
+		return 0, nil
+	}
+
+	fragments := make(map[string]*ast.FragmentDefinition)
+	var op *ast.OperationDefinition
+	for _, def := range doc.Definitions {
+		switch def := def.(type) {
+		case *ast.FragmentDefinition:
+			fragments[def.Name.Value] = def
+		case *ast.OperationDefinition:
+			if op == nil && (req.OperationName == "" || (def.Name != nil && def.Name.Value == req.OperationName)) {
+				op = def
+			}
+		}
+	}
+	if op == nil {
+		return 0, nil
+	}
+
+	var root *graphql.Object
+	switch op.Operation {
+	case ast.OperationTypeQuery:
+		root = Schema.QueryType()
+	case ast.OperationTypeMutation:
+		root = Schema.MutationType()
+	case ast.OperationTypeSubscription:
+		root = Schema.SubscriptionType()
+	}
+
+	m := &measure{fragments: fragments, spread: make(map[string]bool)}
+	complexity := m.selections(op.SelectionSet, root, 1, 1)
+	switch {
+	case m.depth > MaxDepth:
+		return complexity, fmt.Errorf("the operation is nested %d fields deep, more than the limit of %d", m.depth, MaxDepth)
+	case complexity > MaxComplexity:
+		return complexity, fmt.Errorf("the operation has a complexity of %d, more than the limit of %d", complexity, MaxComplexity)
+	}
+
+	return complexity, nil
+}
+
+// measure walks the selections of an operation, following its fragments, to find its depth and complexity
+type measure struct {
+	fragments map[string]*ast.FragmentDefinition
+	// The fragments being walked, so a fragment that spreads itself is not followed forever
+	spread map[string]bool
+	depth  int
+}
+
+// Measure the complexity of a selection set on a type, at a depth and under lists that multiply each field by weight
+// Fields the type does not have are counted once; executing the operation reports them
+func (m *measure) selections(set *ast.SelectionSet, parent *graphql.Object, depth int, weight int) int {
+	if set == nil {
+		return 0
+	}
+
+	complexity := 0
+	for _, selection := range set.Selections {
+		switch selection := selection.(type) {
+		case *ast.Field:
+			complexity += weight
+			if depth > m.depth {
+				m.depth = depth
+			}
+
+			// Introspection fields describe the schema rather than the scores, so they are not limited
+			name := selection.Name.Value
+			if strings.HasPrefix(name, "__") || parent == nil {
+				continue
+			}
+			field, ok := parent.Fields()[name]
+			if !ok {
+				continue
+			}
+
+			child, list := unwrap(field.Type)
+			childWeight := weight
+			if list {
+				childWeight *= ListComplexity
+			}
+			complexity += m.selections(selection.SelectionSet, child, depth+1, childWeight)
+		case *ast.InlineFragment:
+			complexity += m.selections(selection.SelectionSet, fragmentType(selection.TypeCondition, parent), depth, weight)
+		case *ast.FragmentSpread:
+			fragment := m.fragments[selection.Name.Value]
+			if fragment == nil || m.spread[fragment.Name.Value] {
+				continue
+			}
+
+			m.spread[fragment.Name.Value] = true
+			complexity += m.selections(fragment.SelectionSet, fragmentType(fragment.TypeCondition, parent), depth, weight)
+			delete(m.spread, fragment.Name.Value)
+		}
+	}
+
+	return complexity
+}
+
+// The object type of a field's values, and whether the field is a list of them
+func unwrap(t graphql.Type) (*graphql.Object, bool) {
+	list := false
+	for {
+		switch wrapped := t.(type) {
+		case *graphql.NonNull:
+			t = wrapped.OfType
+		case *graphql.List:
+			list = true
+			t = wrapped.OfType
+		case *graphql.Object:
+			return wrapped, list
+		default:
+			return nil, list
+		}
+	}
+}
+
+// The type a fragment applies to, which is the enclosing type unless it names an object type
+func fragmentType(condition *ast.Named, parent *graphql.Object) *graphql.Object {
+	if condition == nil {
+		return parent
+	}
+	if object, ok := Schema.Type(condition.Name.Value).(*graphql.Object); ok {
+		return object
+	}
+
+	return parent
+}
+
+// WithFilter returns a context that limits the operations run with it to the scores of the students the filter includes,
+// such as the students of a teacher's cohorts
+func WithFilter(ctx context.Context, filter report.Filter) context.Context {
+	return context.WithValue(ctx, filterContext, filter)
+}
+
+// The filter of the students an operation can see, which includes every student unless its context limits them
+func visible(ctx context.Context) report.Filter {
+	filter, _ := ctx.Value(filterContext).(report.Filter)
+	return filter
+}
+
+// IsSubscription reports whether the operation a request selects is a subscription
+// A query that cannot be parsed is not a subscription, so executing it reports the parse error
+func IsSubscription(req Request) bool {
+	doc, err := parser.Parse(parser.ParseParams{Source: req.Query})
+	if err != nil {
+		return false
+	}
+
+	for _, def := range doc.Definitions {
+		op, ok := def.(*ast.OperationDefinition)
+		if !ok {
+			continue
+		}
+		if req.OperationName == "" || (op.Name != nil && op.Name.Value == req.OperationName) {
+			return op.Operation == ast.OperationTypeSubscription
+		}
+	}
+
+	return false
+}
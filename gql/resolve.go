@@ -0,0 +1,29 @@
+package gql
+
+import (
+	"github.com/kylegk/sse-rest-server/grading"
+	"github.com/kylegk/sse-rest-server/report"
+)
+
+// Calculate a student's average with a grading policy, as GET /students/{id} does; a student without scores averages zero
+func studentAverage(studentID string, policyName string, curved bool) (float64, error) {
+	policy, err := grading.GetPolicy(policyName)
+	if err != nil {
+		return 0, err
+	}
+
+	scale, err := grading.GetScale("")
+	if err != nil {
+		return 0, err
+	}
+
+	student, err := report.Student(studentID, policy, scale, curved)
+	if err == report.ErrNotFound {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	return student.Average, nil
+}
@@ -0,0 +1,395 @@
+package gql
+
+import (
+	"github.com/graphql-go/graphql"
+	"github.com/kylegk/sse-rest-server/config"
+	"github.com/kylegk/sse-rest-server/db"
+	"github.com/kylegk/sse-rest-server/grading"
+	"github.com/kylegk/sse-rest-server/models"
+	"github.com/kylegk/sse-rest-server/report"
+)
+
+// The sources of the Student and Exam types; their fields are resolved from the indexes when they are selected
+type student struct {
+	id string
+}
+
+type exam struct {
+	id int
+}
+
+// The arguments shared by the fields that report scores or grades
+var (
+	curvedArg = &graphql.ArgumentConfig{Type: graphql.Boolean, DefaultValue: false, Description: "Use the curved scores of any curved exams in place of the raw scores"}
+	policyArg = &graphql.ArgumentConfig{Type: graphql.String, Description: "The grading policy used to calculate the average; the default policy when omitted"}
+	scaleArg  = &graphql.ArgumentConfig{Type: graphql.String, Description: "The grading scale used to grade the scores; the default scale when omitted"}
+)
+
+func newMetadataType() *graphql.Object {
+	return graphql.NewObject(graphql.ObjectConfig{
+		Name:        "ExamMetadata",
+		Description: "The grading metadata of an exam",
+		Fields: graphql.Fields{
+			"exam":     &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+			"title":    &graphql.Field{Type: graphql.String},
+			"category": &graphql.Field{Type: graphql.String},
+			"weight":   &graphql.Field{Type: graphql.NewNonNull(graphql.Float), Description: "A weight of zero is treated as a weight of one"},
+		},
+	})
+}
+
+func newScoreType() *graphql.Object {
+	return graphql.NewObject(graphql.ObjectConfig{
+		Name:        "Score",
+		Description: "A student's score on an exam",
+		Fields: graphql.FieldsThunk(func() graphql.Fields {
+			return graphql.Fields{
+				"exam": &graphql.Field{
+					Type: graphql.NewNonNull(examType),
+					Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+						return exam{id: p.Source.(models.StudentExam).Exam}, nil
+					},
+				},
+				"student": &graphql.Field{
+					Type: graphql.NewNonNull(studentType),
+					Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+						return student{id: p.Source.(models.StudentExam).StudentID}, nil
+					},
+				},
+				"score":  &graphql.Field{Type: graphql.NewNonNull(graphql.Float), Description: "The raw score"},
+				"curved": &graphql.Field{Type: graphql.Float, Description: "The curved score, when the exam has been curved"},
+				"value": &graphql.Field{
+					Type:        graphql.NewNonNull(graphql.Float),
+					Description: "The raw score, or the curved score when requested and the exam has been curved",
+					Args:        graphql.FieldConfigArgument{"curved": curvedArg},
+					Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+						return p.Source.(models.StudentExam).Value(p.Args["curved"].(bool)), nil
+					},
+				},
+				"grade": &graphql.Field{
+					Type: graphql.NewNonNull(graphql.String),
+					Args: graphql.FieldConfigArgument{"scale": scaleArg, "curved": curvedArg},
+					Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+						scale, err := grading.GetScale(stringArg(p, "scale"))
+						if err != nil {
+							return nil, err
+						}
+
+						return scale.Grade(p.Source.(models.StudentExam).Value(p.Args["curved"].(bool))), nil
+					},
+				},
+				"recordedAt": &graphql.Field{
+					Type: graphql.NewNonNull(graphql.DateTime),
+					Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+						return p.Source.(models.StudentExam).RecordedAt, nil
+					},
+				},
+			}
+		}),
+	})
+}
+
+func newStudentType() *graphql.Object {
+	return graphql.NewObject(graphql.ObjectConfig{
+		Name:        "Student",
+		Description: "A student with at least one score",
+		Fields: graphql.FieldsThunk(func() graphql.Fields {
+			return graphql.Fields{
+				"id": &graphql.Field{
+					Type: graphql.NewNonNull(graphql.String),
+					Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+						return p.Source.(student).id, nil
+					},
+				},
+				"scores": &graphql.Field{
+					Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(scoreType))),
+					Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+						return report.Scores(config.StudentIdx, p.Source.(student).id)
+					},
+				},
+				"exams": &graphql.Field{
+					Type:        graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(examType))),
+					Description: "The exams the student has a score on",
+					Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+						res, err := report.Scores(config.StudentIdx, p.Source.(student).id)
+						if err != nil {
+							return nil, err
+						}
+
+						exams := make([]exam, 0, len(res))
+						for _, score := range res {
+							exams = append(exams, exam{id: score.Exam})
+						}
+
+						return exams, nil
+					},
+				},
+				"average": &graphql.Field{
+					Type: graphql.NewNonNull(graphql.Float),
+					Args: graphql.FieldConfigArgument{"policy": policyArg, "curved": curvedArg},
+					Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+						return studentAverage(p.Source.(student).id, stringArg(p, "policy"), p.Args["curved"].(bool))
+					},
+				},
+				"grade": &graphql.Field{
+					Type: graphql.NewNonNull(graphql.String),
+					Args: graphql.FieldConfigArgument{"policy": policyArg, "scale": scaleArg, "curved": curvedArg},
+					Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+						scale, err := grading.GetScale(stringArg(p, "scale"))
+						if err != nil {
+							return nil, err
+						}
+
+						average, err := studentAverage(p.Source.(student).id, stringArg(p, "policy"), p.Args["curved"].(bool))
+						if err != nil {
+							return nil, err
+						}
+
+						return scale.Grade(average), nil
+					},
+				},
+			}
+		}),
+	})
+}
+
+func newExamType() *graphql.Object {
+	return graphql.NewObject(graphql.ObjectConfig{
+		Name:        "Exam",
+		Description: "An exam with at least one score, and the statistics of the class",
+		Fields: graphql.FieldsThunk(func() graphql.Fields {
+			return graphql.Fields{
+				"id": &graphql.Field{
+					Type: graphql.NewNonNull(graphql.Int),
+					Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+						return p.Source.(exam).id, nil
+					},
+				},
+				"scores": &graphql.Field{
+					Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(scoreType))),
+					Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+						res, err := report.Scores(config.ExamIdx, p.Source.(exam).id)
+						if err != nil {
+							return nil, err
+						}
+
+						return visible(p.Context).Apply(res), nil
+					},
+				},
+				"metadata": &graphql.Field{
+					Type: metadataType,
+					Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+						res, err := db.GetRows(config.ExamMetaTable, config.IdFld, p.Source.(exam).id)
+						if err != nil || len(res) == 0 {
+							return nil, err
+						}
+
+						return res[0], nil
+					},
+				},
+				"count":   statField(func(a *models.Aggregate) interface{} { return a.Count }, graphql.Int, "The number of scores"),
+				"average": statField(func(a *models.Aggregate) interface{} { return a.Mean() }, graphql.Float, "The class average"),
+				"stddev":  statField(func(a *models.Aggregate) interface{} { return a.StdDev() }, graphql.Float, "The population standard deviation of the scores"),
+				"min":     statField(func(a *models.Aggregate) interface{} { return a.Min }, graphql.Float, "The lowest score"),
+				"max":     statField(func(a *models.Aggregate) interface{} { return a.Max }, graphql.Float, "The highest score"),
+			}
+		}),
+	})
+}
+
+// A statistic of the exam's scores the operation can see, read from the running aggregate of the raw scores
+// or calculated from the scores
+func statField(stat func(a *models.Aggregate) interface{}, t graphql.Output, description string) *graphql.Field {
+	return &graphql.Field{
+		Type:        graphql.NewNonNull(t),
+		Description: description,
+		Args:        graphql.FieldConfigArgument{"curved": curvedArg},
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			stats, err := report.ExamStats(p.Source.(exam).id, p.Args["curved"].(bool), visible(p.Context))
+			if err != nil {
+				return nil, err
+			}
+
+			return stat(stats), nil
+		},
+	}
+}
+
+func newScoreEventType() *graphql.Object {
+	return graphql.NewObject(graphql.ObjectConfig{
+		Name:        "ScoreEvent",
+		Description: "A change to a score; before is null when the score was created, and after is null when it was deleted",
+		Fields: graphql.Fields{
+			"type": &graphql.Field{Type: graphql.NewNonNull(graphql.String), Description: "score.upserted or score.deleted"},
+			"before": &graphql.Field{
+				Type: scoreType,
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return eventScore(p.Source.(models.ScoreEvent).Before), nil
+				},
+			},
+			"after": &graphql.Field{
+				Type: scoreType,
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return eventScore(p.Source.(models.ScoreEvent).After), nil
+				},
+			},
+			"time": &graphql.Field{Type: graphql.NewNonNull(graphql.DateTime)},
+		},
+	})
+}
+
+// The score resolvers read scores by value, so the pointers of an event are dereferenced
+func eventScore(score *models.StudentExam) interface{} {
+	if score == nil {
+		return nil
+	}
+
+	return *score
+}
+
+func newQueryType() *graphql.Object {
+	return graphql.NewObject(graphql.ObjectConfig{
+		Name: "Query",
+		Fields: graphql.Fields{
+			"students": &graphql.Field{
+				Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(studentType))),
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					res, err := report.Scores(config.UniqueStudentsIdx)
+					if err != nil {
+						return nil, err
+					}
+
+					students := make([]student, 0, len(res))
+					for _, score := range visible(p.Context).Apply(res) {
+						students = append(students, student{id: score.StudentID})
+					}
+
+					return students, nil
+				},
+			},
+			"student": &graphql.Field{
+				Type:        studentType,
+				Description: "A student, or null when the student has no scores or cannot be seen",
+				Args:        graphql.FieldConfigArgument{"id": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)}},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					id := p.Args["id"].(string)
+					if !visible(p.Context).Includes(id) {
+						return nil, nil
+					}
+
+					stats, err := db.GetStudentAggregate(id)
+					if err != nil || stats == nil || stats.Count == 0 {
+						return nil, err
+					}
+
+					return student{id: id}, nil
+				},
+			},
+			"exams": &graphql.Field{
+				Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(examType))),
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					res, err := report.Scores(config.UniqueExamsIdx)
+					if err != nil {
+						return nil, err
+					}
+
+					exams := make([]exam, 0, len(res))
+					for _, score := range res {
+						exams = append(exams, exam{id: score.Exam})
+					}
+
+					return exams, nil
+				},
+			},
+			"exam": &graphql.Field{
+				Type:        examType,
+				Description: "An exam, or null when the exam has no scores that can be seen",
+				Args:        graphql.FieldConfigArgument{"id": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.Int)}},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					id := p.Args["id"].(int)
+					stats, err := report.ExamStats(id, false, visible(p.Context))
+					if err != nil || stats.Count == 0 {
+						return nil, err
+					}
+
+					return exam{id: id}, nil
+				},
+			},
+			"score": &graphql.Field{
+				Type:        scoreType,
+				Description: "A student's score on an exam, or null when there is no score or it cannot be seen",
+				Args: graphql.FieldConfigArgument{
+					"exam":    &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.Int)},
+					"student": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					res, err := report.Scores(config.IdFld, p.Args["exam"].(int), p.Args["student"].(string))
+					res = visible(p.Context).Apply(res)
+					if err != nil || len(res) == 0 {
+						return nil, err
+					}
+
+					return res[0], nil
+				},
+			},
+		},
+	})
+}
+
+func newSubscriptionType() *graphql.Object {
+	return graphql.NewObject(graphql.ObjectConfig{
+		Name: "Subscription",
+		Fields: graphql.Fields{
+			"scoreChanged": &graphql.Field{
+				Type:        graphql.NewNonNull(scoreEventType),
+				Description: "Every change to the scores, optionally limited to an exam or a student",
+				Args: graphql.FieldConfigArgument{
+					"exam":    &graphql.ArgumentConfig{Type: graphql.Int},
+					"student": &graphql.ArgumentConfig{Type: graphql.String},
+				},
+				Subscribe: func(p graphql.ResolveParams) (interface{}, error) {
+					examID, _ := p.Args["exam"].(int)
+					return subscribe(p.Context, examID, stringArg(p, "student")), nil
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return p.Source, nil
+				},
+			},
+		},
+	})
+}
+
+// The object types refer to each other, so they are created in init rather than in their declarations
+var (
+	metadataType   *graphql.Object
+	scoreType      *graphql.Object
+	studentType    *graphql.Object
+	examType       *graphql.Object
+	scoreEventType *graphql.Object
+)
+
+// Schema is the GraphQL schema over the students, exams and scores
+var Schema graphql.Schema
+
+func init() {
+	metadataType = newMetadataType()
+	scoreType = newScoreType()
+	studentType = newStudentType()
+	examType = newExamType()
+	scoreEventType = newScoreEventType()
+
+	var err error
+	Schema, err = graphql.NewSchema(graphql.SchemaConfig{
+		Query:        newQueryType(),
+		Subscription: newSubscriptionType(),
+	})
+	if err != nil {
+		panic(err)
+	}
+}
+
+// Return an optional string argument, or an empty string when it was not passed
+func stringArg(p graphql.ResolveParams, name string) string {
+	value, _ := p.Args[name].(string)
+	return value
+}
@@ -0,0 +1,255 @@
+package gql
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kylegk/sse-rest-server/config"
+	"github.com/kylegk/sse-rest-server/db"
+	"github.com/kylegk/sse-rest-server/models"
+)
+
+var testScores = []models.StudentExam{
+	{Exam: 1, StudentID: "test.person1", Score: 0.5},
+	{Exam: 2, StudentID: "test.person1", Score: 0.9},
+	{Exam: 1, StudentID: "test.person2", Score: 0.7},
+}
+
+func setupTestData() error {
+	err := db.InitDB(config.DBSchema)
+	if err != nil {
+		return err
+	}
+
+	for _, score := range testScores {
+		err = db.UpsertRow(config.ScoreTable, score)
+		if err != nil {
+			return err
+		}
+	}
+
+	return db.UpsertRow(config.ExamMetaTable, models.ExamMetadata{Exam: 1, Title: "Midterm", Weight: 2})
+}
+
+func TestExecute(t *testing.T) {
+	err := setupTestData()
+	if err != nil {
+		t.Errorf("Failed to setup the data")
+	}
+
+	query := `query ($id: String!) {
+		student(id: $id) {
+			id
+			average
+			grade
+			scores { score grade exam { id average count metadata { title weight } } }
+		}
+		missing: student(id: "nobody") { id }
+		exams { id }
+	}`
+	result := Execute(context.Background(), Request{Query: query, Variables: map[string]interface{}{"id": "test.person1"}})
+	if result.HasErrors() {
+		t.Errorf("Query returned errors; %v", result.Errors)
+	}
+
+	type examData struct {
+		ID       int
+		Average  float64
+		Count    int
+		Metadata *struct {
+			Title  string
+			Weight float64
+		}
+	}
+	var data struct {
+		Student struct {
+			ID      string
+			Average float64
+			Grade   string
+			Scores  []struct {
+				Score float64
+				Grade string
+				Exam  examData
+			}
+		}
+		Missing *struct{ ID string }
+		Exams   []struct{ ID int }
+	}
+	b, _ := json.Marshal(result.Data)
+	err = json.Unmarshal(b, &data)
+	if err != nil {
+		t.Errorf("Unable to parse the result; %v", err)
+	}
+
+	if data.Student.ID != "test.person1" || data.Student.Average != 0.7 || len(data.Student.Scores) != 2 {
+		t.Errorf("Incorrect student returned; have: %+v", data.Student)
+	}
+	if data.Missing != nil {
+		t.Errorf("Student without scores was returned; have: %+v", data.Missing)
+	}
+	if len(data.Exams) != 2 {
+		t.Errorf("Incorrect exams returned; have: %+v", data.Exams)
+	}
+
+	// Verify the nested exam carries the class statistics and metadata
+	for _, score := range data.Student.Scores {
+		if score.Exam.ID != 1 {
+			continue
+		}
+		if score.Exam.Count != 2 || score.Exam.Average != 0.6 || score.Exam.Metadata == nil || score.Exam.Metadata.Title != "Midterm" {
+			t.Errorf("Incorrect exam returned; have: %+v", score.Exam)
+		}
+	}
+
+	// Verify an invalid argument is reported as an error
+	result = Execute(context.Background(), Request{Query: `{ student(id: "test.person1") { average(policy: "does_not_exist") } }`})
+	if !result.HasErrors() {
+		t.Errorf("Invalid policy did not return an error")
+	}
+}
+
+func TestIsSubscription(t *testing.T) {
+	tests := map[string]bool{
+		`{ students { id } }`:                                               false,
+		`subscription { scoreChanged { type } }`:                            true,
+		`query A { exams { id } } subscription B { scoreChanged { type } }`: false,
+		`{`: false,
+	}
+
+	for query, want := range tests {
+		have := IsSubscription(Request{Query: query})
+		if have != want {
+			t.Errorf("Incorrect operation type for %v; have: %v, want: %v", query, have, want)
+		}
+	}
+
+	have := IsSubscription(Request{Query: `query A { exams { id } } subscription B { scoreChanged { type } }`, OperationName: "B"})
+	if !have {
+		t.Errorf("Named subscription was not selected")
+	}
+}
+
+func TestComplexity(t *testing.T) {
+	tests := map[string]int{
+		`{ students { id } }`: 11,
+		`{ student(id: "test.person1") { id average exams { id } } }`:                   14,
+		`query { ...F } fragment F on Query { students { id } }`:                        11,
+		`{ students { ...F } } fragment F on Student { id ...F }`:                       11,
+		`{ exams { ... on Exam { id } } }`:                                              11,
+		`{ __schema { types { name fields { name type { name ofType { name } } } } } }`: 1,
+		`{`: 0,
+	}
+
+	for query, want := range tests {
+		have, err := Complexity(Request{Query: query})
+		if err != nil || have != want {
+			t.Errorf("Incorrect complexity for %v; have: %v, %v, want: %v", query, have, err, want)
+		}
+	}
+
+	// Verify an operation over either limit is rejected before it is executed
+	tooComplex := `{ students { scores { exam { scores { value } } } } }`
+	have, err := Complexity(Request{Query: tooComplex})
+	if err == nil || have != 1211 {
+		t.Errorf("Complex query was not rejected; have: %v, %v", have, err)
+	}
+
+	tooDeep := `{ student(id: "test.person1") { scores { exam { scores { student { scores { value } } } } } } }`
+	_, err = Complexity(Request{Query: tooDeep})
+	if err == nil || !strings.Contains(err.Error(), "deep") {
+		t.Errorf("Deep query was not rejected; have: %v", err)
+	}
+
+	result := Execute(context.Background(), Request{Query: tooDeep})
+	if !result.HasErrors() || result.Data != nil {
+		t.Errorf("Deep query was executed; have: %+v", result)
+	}
+}
+
+func TestSubscribe(t *testing.T) {
+	err := setupTestData()
+	if err != nil {
+		t.Errorf("Failed to setup the data")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	results := Subscribe(ctx, Request{Query: `subscription { scoreChanged(exam: 3) { type after { score student { id } } } }`})
+
+	// The subscription is registered once the executor starts, which cannot be observed here, so the scores are recorded until the event arrives
+	done := make(chan bool)
+	defer close(done)
+	go func() {
+		for {
+			select {
+			case <-done:
+				return
+			case <-time.After(10 * time.Millisecond):
+				// Only the change to the subscribed exam is delivered
+				_ = db.UpsertRow(config.ScoreTable, models.StudentExam{Exam: 2, StudentID: "test.person2", Score: 0.8})
+				_ = db.UpsertRow(config.ScoreTable, models.StudentExam{Exam: 3, StudentID: "test.person2", Score: 0.6})
+			}
+		}
+	}()
+
+	select {
+	case result := <-results:
+		b, _ := json.Marshal(result)
+		want := `{"data":{"scoreChanged":{"after":{"score":0.6,"student":{"id":"test.person2"}},"type":"score.upserted"}}}`
+		if string(b) != want {
+			t.Errorf("Incorrect event received; have: %s, want: %s", b, want)
+		}
+	case <-time.After(time.Second):
+		t.Errorf("No event received")
+	}
+
+	// Verify the stream is closed once the context is done
+	cancel()
+	for range results {
+	}
+}
+
+func TestSubscribeFiltered(t *testing.T) {
+	err := setupTestData()
+	if err != nil {
+		t.Errorf("Failed to setup the data")
+	}
+
+	visible := func(student string) bool {
+		return student == "test.person1"
+	}
+	ctx, cancel := context.WithCancel(WithFilter(context.Background(), visible))
+	results := Subscribe(ctx, Request{Query: `subscription { scoreChanged(exam: 3) { after { student { id } } } }`})
+
+	done := make(chan bool)
+	defer close(done)
+	go func() {
+		for {
+			select {
+			case <-done:
+				return
+			case <-time.After(10 * time.Millisecond):
+				// Only the change to the visible student is delivered
+				_ = db.UpsertRow(config.ScoreTable, models.StudentExam{Exam: 3, StudentID: "test.person2", Score: 0.6})
+				_ = db.UpsertRow(config.ScoreTable, models.StudentExam{Exam: 3, StudentID: "test.person1", Score: 0.6})
+			}
+		}
+	}()
+
+	select {
+	case result := <-results:
+		b, _ := json.Marshal(result)
+		want := `{"data":{"scoreChanged":{"after":{"student":{"id":"test.person1"}}}}}`
+		if string(b) != want {
+			t.Errorf("Incorrect event received; have: %s, want: %s", b, want)
+		}
+	case <-time.After(time.Second):
+		t.Errorf("No event received")
+	}
+
+	cancel()
+	for range results {
+	}
+}
@@ -0,0 +1,39 @@
+package gql
+
+import (
+	"context"
+
+	"github.com/kylegk/sse-rest-server/db"
+	"github.com/kylegk/sse-rest-server/models"
+)
+
+// Subscribe to the score events matching the filters until the context is done, when the channel is closed
+// The events are passed on as the untyped values the subscription executor reads, skipping those of the students the context cannot see
+func subscribe(ctx context.Context, exam int, student string) chan interface{} {
+	filter := visible(ctx)
+	events := make(chan interface{})
+	go func() {
+		defer close(events)
+		for event := range db.WatchScores(ctx, exam, student) {
+			if !filter.Includes(eventStudent(event)) {
+				continue
+			}
+
+			select {
+			case events <- event:
+			case <-ctx.Done():
+			}
+		}
+	}()
+
+	return events
+}
+
+// The student whose score changed in an event
+func eventStudent(event models.ScoreEvent) string {
+	if event.After != nil {
+		return event.After.StudentID
+	}
+
+	return event.Before.StudentID
+}
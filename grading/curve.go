@@ -0,0 +1,43 @@
+package grading
+
+import (
+	"fmt"
+
+	"github.com/kylegk/sse-rest-server/models"
+)
+
+// NewCurve validates a curve request and captures the exam statistics the curve method depends on
+func NewCurve(req models.Curve, stats models.Aggregate) (models.Curve, error) {
+	curve := models.Curve{Exam: req.Exam, Method: req.Method}
+
+	switch req.Method {
+	case models.CurveLinear:
+		curve.TargetMax = req.TargetMax
+		if curve.TargetMax == 0 {
+			curve.TargetMax = 1
+		}
+		if curve.TargetMax < 0 || curve.TargetMax > 1 {
+			return curve, fmt.Errorf("target_max must be between 0 and 1")
+		}
+		if stats.Max <= 0 {
+			return curve, fmt.Errorf("cannot scale an exam without a positive score")
+		}
+		curve.Factor = curve.TargetMax / stats.Max
+	case models.CurveSqrt:
+	case models.CurveNormalize:
+		if req.TargetMean <= 0 || req.TargetMean > 1 {
+			return curve, fmt.Errorf("target_mean must be between 0 and 1")
+		}
+		if req.TargetStdDev <= 0 {
+			return curve, fmt.Errorf("target_stddev must be greater than 0")
+		}
+		curve.TargetMean = req.TargetMean
+		curve.TargetStdDev = req.TargetStdDev
+		curve.Mean = stats.Mean()
+		curve.StdDev = stats.StdDev()
+	default:
+		return curve, fmt.Errorf("invalid curve method: %s", req.Method)
+	}
+
+	return curve, nil
+}
@@ -0,0 +1,71 @@
+package grading
+
+import (
+	"testing"
+
+	"github.com/kylegk/sse-rest-server/models"
+)
+
+func curveTestStats(scores ...float64) models.Aggregate {
+	stats := models.Aggregate{}
+	for _, score := range scores {
+		stats.Add(score)
+	}
+
+	return stats
+}
+
+// TestNewCurve validates each of the curve methods against a known set of scores
+func TestNewCurve(t *testing.T) {
+	stats := curveTestStats(0.4, 0.6, 0.8)
+
+	tests := []struct {
+		name  string
+		req   models.Curve
+		score float64
+		want  float64
+	}{
+		{"linear", models.Curve{Method: models.CurveLinear}, 0.4, 0.5},
+		{"linear target", models.Curve{Method: models.CurveLinear, TargetMax: 0.9}, 0.8, 0.9},
+		{"sqrt", models.Curve{Method: models.CurveSqrt}, 0.64, 0.8},
+		{"normalize", models.Curve{Method: models.CurveNormalize, TargetMean: 0.75, TargetStdDev: 0.1}, 0.6, 0.75},
+		{"normalize clamped", models.Curve{Method: models.CurveNormalize, TargetMean: 0.9, TargetStdDev: 0.5}, 0.8, 1},
+	}
+
+	for _, test := range tests {
+		curve, err := NewCurve(test.req, stats)
+		if err != nil {
+			t.Errorf("%s: the curve should have been created: %v", test.name, err)
+			continue
+		}
+
+		have := curve.Apply(test.score)
+		if !almostEqual(have, test.want) {
+			t.Errorf("%s: incorrect curved score; have: %v, want: %v", test.name, have, test.want)
+		}
+	}
+}
+
+// TestNewCurveInvalid validates that invalid curve requests are rejected
+func TestNewCurveInvalid(t *testing.T) {
+	stats := curveTestStats(0.4, 0.6, 0.8)
+
+	invalid := []models.Curve{
+		{Method: "does_not_exist"},
+		{Method: models.CurveLinear, TargetMax: 2},
+		{Method: models.CurveNormalize, TargetMean: 0.7},
+		{Method: models.CurveNormalize, TargetStdDev: 0.1},
+	}
+
+	for _, req := range invalid {
+		_, err := NewCurve(req, stats)
+		if err == nil {
+			t.Errorf("The curve should have been rejected: %+v", req)
+		}
+	}
+
+	_, err := NewCurve(models.Curve{Method: models.CurveLinear}, curveTestStats(0))
+	if err == nil {
+		t.Errorf("Scaling an exam without a positive score should have been rejected")
+	}
+}
@@ -0,0 +1,188 @@
+package grading
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"sync"
+
+	"github.com/kylegk/sse-rest-server/models"
+)
+
+// DefaultCategory is the category used for exams without metadata when weighting by category
+const DefaultCategory = "uncategorized"
+
+// Policy defines how a student's exam scores are combined into a single average
+// The steps are applied in order: drop the lowest scores, keep the best scores, then average using the exam and/or category weights
+type Policy struct {
+	Name            string             `json:"name"`
+	DropLowest      int                `json:"drop_lowest,omitempty"`
+	BestOf          int                `json:"best_of,omitempty"`
+	ExamWeights     bool               `json:"exam_weights,omitempty"`
+	CategoryWeights map[string]float64 `json:"category_weights,omitempty"`
+}
+
+// IsMean reports whether the policy is a plain mean of every score, which can be served from the running aggregates
+func (p Policy) IsMean() bool {
+	return p.DropLowest == 0 && p.BestOf == 0 && !p.ExamWeights && len(p.CategoryWeights) == 0
+}
+
+// Built-in policies, which may be overridden or extended from a policy file
+var builtinPolicies = []Policy{
+	{Name: "mean"},
+	{Name: "weighted", ExamWeights: true},
+	{Name: "drop_lowest", DropLowest: 1},
+	{Name: "best_of_3", BestOf: 3},
+}
+
+var (
+	mu            sync.RWMutex
+	policies      = map[string]Policy{}
+	defaultPolicy = "mean"
+)
+
+func init() {
+	for _, p := range builtinPolicies {
+		policies[p.Name] = p
+	}
+}
+
+// LoadPolicies reads a JSON array of policies from a file and registers each of them
+func LoadPolicies(path string) error {
+	bytes, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	var loaded []Policy
+	err = json.Unmarshal(bytes, &loaded)
+	if err != nil {
+		return fmt.Errorf("unable to parse grading policies: %v", err)
+	}
+
+	for _, p := range loaded {
+		err = RegisterPolicy(p)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// RegisterPolicy adds a policy, replacing any existing policy with the same name
+func RegisterPolicy(p Policy) error {
+	if p.Name == "" {
+		return fmt.Errorf("grading policy is missing a name")
+	}
+	if p.DropLowest < 0 || p.BestOf < 0 {
+		return fmt.Errorf("grading policy %s: drop_lowest and best_of cannot be negative", p.Name)
+	}
+	for category, weight := range p.CategoryWeights {
+		if weight < 0 {
+			return fmt.Errorf("grading policy %s: invalid weight for category %s", p.Name, category)
+		}
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	policies[p.Name] = p
+
+	return nil
+}
+
+// SetDefaultPolicy sets the policy used when a request does not name one
+func SetDefaultPolicy(name string) error {
+	mu.Lock()
+	defer mu.Unlock()
+
+	if _, ok := policies[name]; !ok {
+		return fmt.Errorf("unknown grading policy: %s", name)
+	}
+	defaultPolicy = name
+
+	return nil
+}
+
+// GetPolicy looks up a policy by name, falling back to the default policy when the name is empty
+func GetPolicy(name string) (Policy, error) {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	if name == "" {
+		name = defaultPolicy
+	}
+
+	p, ok := policies[name]
+	if !ok {
+		return Policy{}, fmt.Errorf("unknown grading policy: %s", name)
+	}
+
+	return p, nil
+}
+
+// Average combines a student's scores according to the policy, using the exam metadata for weights and categories
+func Average(p Policy, scores []models.StudentExamScores, meta map[int]models.ExamMetadata) float64 {
+	kept := make([]models.StudentExamScores, len(scores))
+	copy(kept, scores)
+	sort.SliceStable(kept, func(i, j int) bool {
+		return kept[i].Score < kept[j].Score
+	})
+
+	if p.DropLowest > 0 && len(kept) > p.DropLowest {
+		kept = kept[p.DropLowest:]
+	}
+	if p.BestOf > 0 && len(kept) > p.BestOf {
+		kept = kept[len(kept)-p.BestOf:]
+	}
+
+	if len(p.CategoryWeights) == 0 {
+		return weightedMean(p, kept, meta)
+	}
+
+	byCategory := make(map[string][]models.StudentExamScores)
+	for _, score := range kept {
+		category := meta[score.Exam].Category
+		if category == "" {
+			category = DefaultCategory
+		}
+		byCategory[category] = append(byCategory[category], score)
+	}
+
+	// Only the categories the student has scores in contribute, so the category weights are normalized over those
+	var sum, total float64
+	for category, categoryScores := range byCategory {
+		weight := p.CategoryWeights[category]
+		if weight == 0 {
+			continue
+		}
+		sum += weight * weightedMean(p, categoryScores, meta)
+		total += weight
+	}
+
+	if total == 0 {
+		return 0
+	}
+
+	return sum / total
+}
+
+// Average the scores, weighting each one by its exam weight when the policy uses exam weights
+func weightedMean(p Policy, scores []models.StudentExamScores, meta map[int]models.ExamMetadata) float64 {
+	var sum, total float64
+	for _, score := range scores {
+		weight := 1.0
+		if p.ExamWeights && meta[score.Exam].Weight > 0 {
+			weight = meta[score.Exam].Weight
+		}
+		sum += weight * score.Score
+		total += weight
+	}
+
+	if total == 0 {
+		return 0
+	}
+
+	return sum / total
+}
@@ -0,0 +1,84 @@
+package grading
+
+import (
+	"math"
+	"testing"
+
+	"github.com/kylegk/sse-rest-server/models"
+)
+
+var policyTestScores = []models.StudentExamScores{
+	{Exam: 1, Score: 0.4},
+	{Exam: 2, Score: 0.8},
+	{Exam: 3, Score: 0.9},
+	{Exam: 4, Score: 0.6},
+}
+
+var policyTestMeta = map[int]models.ExamMetadata{
+	1: {Exam: 1, Category: "quiz", Weight: 1},
+	2: {Exam: 2, Category: "quiz", Weight: 1},
+	3: {Exam: 3, Category: "final", Weight: 3},
+}
+
+func almostEqual(a float64, b float64) bool {
+	return math.Abs(a-b) < 1e-9
+}
+
+// TestAverage validates each of the policy steps against a known set of scores
+func TestAverage(t *testing.T) {
+	tests := []struct {
+		name   string
+		policy Policy
+		want   float64
+	}{
+		{"mean", Policy{}, 0.675},
+		{"drop lowest", Policy{DropLowest: 1}, (0.6 + 0.8 + 0.9) / 3},
+		{"best of", Policy{BestOf: 2}, (0.8 + 0.9) / 2},
+		{"exam weights", Policy{ExamWeights: true}, (0.4 + 0.8 + 2.7 + 0.6) / 6},
+		{"category weights", Policy{CategoryWeights: map[string]float64{"quiz": 0.4, "final": 0.6}}, 0.4*0.6 + 0.6*0.9},
+		{"uncategorized", Policy{CategoryWeights: map[string]float64{"final": 1, DefaultCategory: 1}}, (0.9 + 0.6) / 2},
+	}
+
+	for _, test := range tests {
+		have := Average(test.policy, policyTestScores, policyTestMeta)
+		if !almostEqual(have, test.want) {
+			t.Errorf("%s: incorrect average; have: %v, want: %v", test.name, have, test.want)
+		}
+	}
+}
+
+// TestGetPolicy validates the default and unknown policy lookups
+func TestGetPolicy(t *testing.T) {
+	p, err := GetPolicy("")
+	if err != nil || !p.IsMean() {
+		t.Errorf("The default policy should be a plain mean; have: %+v", p)
+	}
+
+	_, err = GetPolicy("does_not_exist")
+	if err == nil {
+		t.Errorf("The lookup should have failed")
+	}
+
+	err = SetDefaultPolicy("does_not_exist")
+	if err == nil {
+		t.Errorf("Setting an unknown default policy should have failed")
+	}
+}
+
+// TestRegisterPolicy validates that invalid policies are rejected
+func TestRegisterPolicy(t *testing.T) {
+	err := RegisterPolicy(Policy{Name: "bad", DropLowest: -1})
+	if err == nil {
+		t.Errorf("A negative drop_lowest should have been rejected")
+	}
+
+	err = RegisterPolicy(Policy{Name: "finals", CategoryWeights: map[string]float64{"final": 1}})
+	if err != nil {
+		t.Errorf("The policy should have been registered")
+	}
+
+	p, err := GetPolicy("finals")
+	if err != nil || p.IsMean() {
+		t.Errorf("The registered policy was not returned")
+	}
+}
@@ -0,0 +1,149 @@
+package grading
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"sort"
+)
+
+// Band maps every score greater than or equal to Min (and below the next band) to a grade
+type Band struct {
+	Grade string  `json:"grade"`
+	Min   float64 `json:"min"`
+}
+
+// Scale is a named set of grade bands
+type Scale struct {
+	Name  string `json:"name"`
+	Bands []Band `json:"bands"`
+}
+
+// Built-in scales, which may be overridden or extended from a scale file
+var builtinScales = []Scale{
+	{
+		Name: "letter",
+		Bands: []Band{
+			{Grade: "A", Min: 0.9},
+			{Grade: "B", Min: 0.8},
+			{Grade: "C", Min: 0.7},
+			{Grade: "D", Min: 0.6},
+			{Grade: "F", Min: 0},
+		},
+	},
+	{
+		Name: "pass_fail",
+		Bands: []Band{
+			{Grade: "Pass", Min: 0.6},
+			{Grade: "Fail", Min: 0},
+		},
+	},
+}
+
+var (
+	scales       = map[string]Scale{}
+	defaultScale = "letter"
+)
+
+func init() {
+	for _, s := range builtinScales {
+		scales[s.Name] = s
+	}
+}
+
+// Grade returns the grade of the highest band the score falls in
+// Scores below the lowest band receive the grade of the lowest band
+func (s Scale) Grade(score float64) string {
+	for _, band := range s.Bands {
+		if score >= band.Min {
+			return band.Grade
+		}
+	}
+
+	return s.Bands[len(s.Bands)-1].Grade
+}
+
+// LoadScales reads a JSON array of scales from a file and registers each of them
+func LoadScales(path string) error {
+	bytes, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	var loaded []Scale
+	err = json.Unmarshal(bytes, &loaded)
+	if err != nil {
+		return fmt.Errorf("unable to parse grading scales: %v", err)
+	}
+
+	for _, s := range loaded {
+		err = RegisterScale(s)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// RegisterScale adds a scale, replacing any existing scale with the same name
+// The bands are stored from the highest to the lowest minimum score
+func RegisterScale(s Scale) error {
+	if s.Name == "" {
+		return fmt.Errorf("grading scale is missing a name")
+	}
+	if len(s.Bands) == 0 {
+		return fmt.Errorf("grading scale %s: at least one band is required", s.Name)
+	}
+
+	bands := make([]Band, len(s.Bands))
+	copy(bands, s.Bands)
+	sort.SliceStable(bands, func(i, j int) bool {
+		return bands[i].Min > bands[j].Min
+	})
+
+	seen := make(map[string]bool)
+	for _, band := range bands {
+		if band.Grade == "" || seen[band.Grade] {
+			return fmt.Errorf("grading scale %s: grades must be unique and non-empty", s.Name)
+		}
+		seen[band.Grade] = true
+	}
+	s.Bands = bands
+
+	mu.Lock()
+	defer mu.Unlock()
+	scales[s.Name] = s
+
+	return nil
+}
+
+// SetDefaultScale sets the scale used when a request does not name one
+func SetDefaultScale(name string) error {
+	mu.Lock()
+	defer mu.Unlock()
+
+	if _, ok := scales[name]; !ok {
+		return fmt.Errorf("unknown grading scale: %s", name)
+	}
+	defaultScale = name
+
+	return nil
+}
+
+// GetScale looks up a scale by name, falling back to the default scale when the name is empty
+func GetScale(name string) (Scale, error) {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	if name == "" {
+		name = defaultScale
+	}
+
+	s, ok := scales[name]
+	if !ok {
+		return Scale{}, fmt.Errorf("unknown grading scale: %s", name)
+	}
+
+	return s, nil
+}
@@ -0,0 +1,57 @@
+package grading
+
+import "testing"
+
+// TestGrade validates the built-in letter scale, including the band boundaries
+func TestGrade(t *testing.T) {
+	s, err := GetScale("")
+	if err != nil {
+		t.Fatalf("The default scale should exist")
+	}
+
+	tests := map[float64]string{
+		1:    "A",
+		0.9:  "A",
+		0.89: "B",
+		0.7:  "C",
+		0.65: "D",
+		0.2:  "F",
+		-0.1: "F",
+	}
+
+	for score, want := range tests {
+		have := s.Grade(score)
+		if have != want {
+			t.Errorf("Incorrect grade for %v; have: %v, want: %v", score, have, want)
+		}
+	}
+}
+
+// TestRegisterScale validates that custom bands are sorted and invalid scales are rejected
+func TestRegisterScale(t *testing.T) {
+	err := RegisterScale(Scale{Name: "empty"})
+	if err == nil {
+		t.Errorf("A scale without bands should have been rejected")
+	}
+
+	err = RegisterScale(Scale{Name: "duplicate", Bands: []Band{{Grade: "X", Min: 0.5}, {Grade: "X", Min: 0}}})
+	if err == nil {
+		t.Errorf("A scale with duplicate grades should have been rejected")
+	}
+
+	err = RegisterScale(Scale{Name: "honors", Bands: []Band{{Grade: "Standard", Min: 0}, {Grade: "Honors", Min: 0.95}}})
+	if err != nil {
+		t.Errorf("The scale should have been registered")
+	}
+
+	s, err := GetScale("honors")
+	if err != nil {
+		t.Fatalf("The registered scale was not returned")
+	}
+
+	have := s.Grade(0.97)
+	want := "Honors"
+	if have != want {
+		t.Errorf("Incorrect grade; have: %v, want: %v", have, want)
+	}
+}
@@ -0,0 +1,119 @@
+package handler
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/kylegk/sse-rest-server/alerts"
+	"github.com/kylegk/sse-rest-server/models"
+)
+
+// GetAlerts lists the at-risk student alerts, optionally filtered by the "status" and "student" query parameters
+func GetAlerts(w http.ResponseWriter, r *http.Request) {
+	var err error
+	defer func() {
+		if err != nil {
+			sendError(err, w, r)
+			return
+		}
+	}()
+
+	status := r.URL.Query().Get("status")
+	if status != "" && status != models.AlertOpen && status != models.AlertAcknowledged && status != models.AlertResolved {
+		err = invalidParameter("status", "invalid status: must be open, acknowledged or resolved")
+		return
+	}
+
+	res, err := alerts.List(status, r.URL.Query().Get("student"))
+	if err != nil {
+		log.Println(err)
+		return
+	}
+
+	// Teachers only see the alerts of the students of their cohorts
+	visible := visibleStudents(r)
+	response := &models.AlertListResponse{Alerts: make([]models.Alert, 0, len(res))}
+	for _, alert := range res {
+		if visible.Includes(alert.StudentID) {
+			response.Alerts = append(response.Alerts, alert)
+		}
+	}
+
+	sendResponse(response, http.StatusOK, w)
+}
+
+// GetAlertByID returns a single alert, which is not found when the client cannot see its student
+func GetAlertByID(w http.ResponseWriter, r *http.Request) {
+	var err error
+	defer func() {
+		if err != nil {
+			sendError(err, w, r)
+			return
+		}
+	}()
+
+	alert, err := alerts.Get(mux.Vars(r)["id"])
+	if err == nil && !visibleStudents(r).Includes(alert.StudentID) {
+		err = alerts.ErrNotFound
+	}
+	if err != nil {
+		err = alertError(alert, err)
+		return
+	}
+
+	sendResponse(alert, http.StatusOK, w)
+}
+
+// AcknowledgeAlert marks an open alert as acknowledged
+func AcknowledgeAlert(w http.ResponseWriter, r *http.Request) {
+	var err error
+	defer func() {
+		if err != nil {
+			sendError(err, w, r)
+			return
+		}
+	}()
+
+	id := mux.Vars(r)["id"]
+	alert, err := alerts.Acknowledge(id, resourceAuditor(r, models.AuditAlertAcknowledged, models.AuditResourceAlert)(id))
+	if err != nil {
+		err = alertError(alert, err)
+		return
+	}
+
+	sendResponse(alert, http.StatusOK, w)
+}
+
+// ResolveAlert marks an open or acknowledged alert as resolved
+func ResolveAlert(w http.ResponseWriter, r *http.Request) {
+	var err error
+	defer func() {
+		if err != nil {
+			sendError(err, w, r)
+			return
+		}
+	}()
+
+	id := mux.Vars(r)["id"]
+	alert, err := alerts.Resolve(id, resourceAuditor(r, models.AuditAlertResolved, models.AuditResourceAlert)(id))
+	if err != nil {
+		err = alertError(alert, err)
+		return
+	}
+
+	sendResponse(alert, http.StatusOK, w)
+}
+
+// Map an error from the alert workflow to the matching problem, logging any unexpected error
+func alertError(alert models.Alert, err error) error {
+	switch err {
+	case alerts.ErrNotFound:
+		return notFound("alert not found")
+	case alerts.ErrInvalidTransition:
+		return conflict("cannot move an alert from " + alert.Status + " to the requested status")
+	default:
+		log.Println(err)
+		return err
+	}
+}
@@ -0,0 +1,142 @@
+package handler
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/kylegk/sse-rest-server/alerts"
+	"github.com/kylegk/sse-rest-server/config"
+	"github.com/kylegk/sse-rest-server/db"
+	"github.com/kylegk/sse-rest-server/models"
+)
+
+func addAlertTestRoutes() (*mux.Router, error) {
+	err := db.InitDB(config.DBSchema)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, exam := range studentTestData {
+		err = db.UpsertRow(config.ScoreTable, exam)
+		if err != nil {
+			return nil, err
+		}
+
+		_, err = alerts.Evaluate(exam)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	router := mux.NewRouter()
+	router.HandleFunc("/alerts", GetAlerts).Methods("GET")
+	router.HandleFunc("/alerts/{id}", GetAlertByID).Methods("GET")
+	router.HandleFunc("/alerts/{id}/acknowledge", AcknowledgeAlert).Methods("POST")
+	router.HandleFunc("/alerts/{id}/resolve", ResolveAlert).Methods("POST")
+
+	return router, nil
+}
+
+func TestGetAlerts(t *testing.T) {
+	router, err := addAlertTestRoutes()
+	if err != nil {
+		t.Errorf("Failed to start server")
+	}
+
+	request, _ := http.NewRequest("GET", "/alerts?status=open", nil)
+	response := httptest.NewRecorder()
+	router.ServeHTTP(response, request)
+
+	// Verify status code is 200
+	have := response.Code
+	want := 200
+	if have != want {
+		t.Errorf("HTTP status is not OK; have: %v, want: %v", have, want)
+	}
+
+	resBytes, err := ioutil.ReadAll(response.Body)
+	if err != nil {
+		t.Errorf("Unable to read response body")
+	}
+	body := models.AlertListResponse{}
+	err = json.Unmarshal(resBytes, &body)
+	if err != nil {
+		t.Errorf("Failed to parse response returned from route")
+	}
+
+	// Verify only test.person1 was alerted, after their first score of 0.5 fell below the default threshold
+	have = len(body.Alerts)
+	want = 1
+	if have != want {
+		t.Fatalf("Incorrect number of alerts; have: %v, want: %v", have, want)
+	}
+	if body.Alerts[0].StudentID != "test.person1" {
+		t.Errorf("Incorrect student alerted; have: %v", body.Alerts[0].StudentID)
+	}
+
+	// Test with an invalid status
+	request, _ = http.NewRequest("GET", "/alerts?status=foo", nil)
+	response = httptest.NewRecorder()
+	router.ServeHTTP(response, request)
+
+	// Verify status code is 400
+	have = response.Code
+	want = 400
+	if have != want {
+		t.Errorf("Route returned an incorrect status code; have: %v, want: %v", have, want)
+	}
+}
+
+func TestAlertWorkflow(t *testing.T) {
+	router, err := addAlertTestRoutes()
+	if err != nil {
+		t.Errorf("Failed to start server")
+	}
+
+	score := models.StudentExam{Exam: 3, StudentID: "test.person2", Score: 0.1}
+	err = db.UpsertRow(config.ScoreTable, score)
+	if err != nil {
+		t.Errorf("Failed to insert score")
+	}
+	raised, err := alerts.Evaluate(score)
+	if err != nil || len(raised) != 1 {
+		t.Fatalf("An alert should have been raised")
+	}
+
+	request, _ := http.NewRequest("POST", "/alerts/"+raised[0].ID+"/resolve", nil)
+	response := httptest.NewRecorder()
+	router.ServeHTTP(response, request)
+
+	// Verify status code is 200
+	have := response.Code
+	want := 200
+	if have != want {
+		t.Errorf("HTTP status is not OK; have: %v, want: %v", have, want)
+	}
+
+	// Verify a resolved alert cannot be acknowledged
+	request, _ = http.NewRequest("POST", "/alerts/"+raised[0].ID+"/acknowledge", nil)
+	response = httptest.NewRecorder()
+	router.ServeHTTP(response, request)
+
+	have = response.Code
+	want = 409
+	if have != want {
+		t.Errorf("Route returned an incorrect status code; have: %v, want: %v", have, want)
+	}
+
+	// Verify a missing alert returns 404
+	request, _ = http.NewRequest("GET", "/alerts/does_not_exist", nil)
+	response = httptest.NewRecorder()
+	router.ServeHTTP(response, request)
+
+	have = response.Code
+	want = 404
+	if have != want {
+		t.Errorf("Route returned an incorrect status code; have: %v, want: %v", have, want)
+	}
+}
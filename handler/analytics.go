@@ -0,0 +1,131 @@
+package handler
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/kylegk/sse-rest-server/analytics"
+	"github.com/kylegk/sse-rest-server/config"
+	"github.com/kylegk/sse-rest-server/models"
+	"github.com/kylegk/sse-rest-server/report"
+)
+
+// GetExamCorrelation reports the Pearson and Spearman correlation between every pair of the exams in the "exams" query parameter,
+// over the students who took both exams
+// Passing "scores=curved" correlates the curved scores of any curved exams in place of the raw scores
+func GetExamCorrelation(w http.ResponseWriter, r *http.Request) {
+	var err error
+	defer func() {
+		if err != nil {
+			sendError(err, w, r)
+			return
+		}
+	}()
+
+	exams, parseErr := parseExamList(r.URL.Query().Get("exams"))
+	if parseErr != nil {
+		sendError(invalidParameter("exams", parseErr.Error()), w, r)
+		return
+	}
+
+	curved, curvedErr := useCurvedScores(r)
+	if curvedErr != nil {
+		sendError(invalidParameter("scores", curvedErr.Error()), w, r)
+		return
+	}
+
+	// Index each exam's scores by student so the students who took both exams of a pair can be matched
+	visible := visibleStudents(r)
+	scores := make([]map[string]float64, len(exams))
+	for i, exam := range exams {
+		var res []models.StudentExam
+		res, err = report.Scores(config.ExamIdx, exam)
+		if err != nil {
+			log.Println(err)
+			return
+		}
+
+		res = visible.Apply(res)
+		if len(res) == 0 {
+			SendGenericNotFoundResponse(w, r)
+			return
+		}
+
+		scores[i] = make(map[string]float64, len(res))
+		for _, score := range res {
+			scores[i][score.StudentID] = score.Value(curved)
+		}
+	}
+
+	response := &models.CorrelationResponse{
+		Exams:     exams,
+		ScoreType: report.ScoreType(curved),
+		Students:  make([][]int, len(exams)),
+		Pearson:   make([][]*float64, len(exams)),
+		Spearman:  make([][]*float64, len(exams)),
+	}
+	for i := range exams {
+		response.Students[i] = make([]int, len(exams))
+		response.Pearson[i] = make([]*float64, len(exams))
+		response.Spearman[i] = make([]*float64, len(exams))
+	}
+
+	// The matrices are symmetric, so each pair is only computed once
+	for i := range exams {
+		for j := i; j < len(exams); j++ {
+			xs, ys := make([]float64, 0), make([]float64, 0)
+			for student, x := range scores[i] {
+				if y, ok := scores[j][student]; ok {
+					xs = append(xs, x)
+					ys = append(ys, y)
+				}
+			}
+
+			response.Students[i][j], response.Students[j][i] = len(xs), len(xs)
+			if p, ok := analytics.Pearson(xs, ys); ok {
+				response.Pearson[i][j], response.Pearson[j][i] = &p, &p
+			}
+			if s, ok := analytics.Spearman(xs, ys); ok {
+				response.Spearman[i][j], response.Spearman[j][i] = &s, &s
+			}
+		}
+	}
+
+	sendResponse(response, http.StatusOK, w)
+}
+
+// The most exams that can be correlated at once, as the matrices grow with the square of the exams
+const maxCorrelationExams = 20
+
+// Parse a comma separated list of at least two, and at most maxCorrelationExams, distinct exam ids
+func parseExamList(value string) ([]int, error) {
+	exams := make([]int, 0)
+	seen := make(map[int]bool)
+	for _, field := range strings.Split(value, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+
+		exam, err := strconv.Atoi(field)
+		if err != nil {
+			return nil, fmt.Errorf("invalid exam id: %s", field)
+		}
+		if !seen[exam] {
+			seen[exam] = true
+			exams = append(exams, exam)
+		}
+		if len(exams) > maxCorrelationExams {
+			return nil, fmt.Errorf("invalid exams: at most %d exam ids can be correlated", maxCorrelationExams)
+		}
+	}
+
+	if len(exams) < 2 {
+		return nil, fmt.Errorf("invalid exams: at least two exam ids are required")
+	}
+
+	return exams, nil
+}
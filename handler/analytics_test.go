@@ -0,0 +1,126 @@
+package handler
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/kylegk/sse-rest-server/config"
+	"github.com/kylegk/sse-rest-server/db"
+	"github.com/kylegk/sse-rest-server/models"
+)
+
+var correlationTestData = []models.StudentExam{
+	{Exam: 1, StudentID: "test.person1", Score: 0.50},
+	{Exam: 2, StudentID: "test.person1", Score: 0.55},
+	{Exam: 3, StudentID: "test.person1", Score: 0.90},
+	{Exam: 1, StudentID: "test.person2", Score: 0.70},
+	{Exam: 2, StudentID: "test.person2", Score: 0.75},
+	{Exam: 3, StudentID: "test.person2", Score: 0.60},
+	{Exam: 1, StudentID: "test.person3", Score: 0.90},
+	{Exam: 2, StudentID: "test.person3", Score: 0.95},
+	{Exam: 4, StudentID: "test.person4", Score: 0.80},
+}
+
+func addAnalyticsTestRoutes() (*mux.Router, error) {
+	err := db.InitDB(config.DBSchema)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, exam := range correlationTestData {
+		err = db.UpsertRow(config.ScoreTable, exam)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	router := mux.NewRouter()
+	router.HandleFunc("/analytics/correlation", GetExamCorrelation).Methods("GET")
+
+	return router, nil
+}
+
+func TestGetExamCorrelation(t *testing.T) {
+	router, err := addAnalyticsTestRoutes()
+	if err != nil {
+		t.Errorf("Failed to start server")
+	}
+
+	request, _ := http.NewRequest("GET", "/analytics/correlation?exams=1,2,3,4", nil)
+	response := httptest.NewRecorder()
+	router.ServeHTTP(response, request)
+
+	// Verify status code is 200
+	have := response.Code
+	want := 200
+	if have != want {
+		t.Errorf("HTTP status is not OK; have: %v, want: %v", have, want)
+	}
+
+	resBytes, err := ioutil.ReadAll(response.Body)
+	if err != nil {
+		t.Errorf("Unable to read response body")
+	}
+	body := models.CorrelationResponse{}
+	err = json.Unmarshal(resBytes, &body)
+	if err != nil {
+		t.Fatalf("Failed to parse response returned from route")
+	}
+
+	// Exams 1 and 2 move together for all three students
+	if body.Students[0][1] != 3 || body.Pearson[0][1] == nil || *body.Pearson[0][1] < 0.999 || *body.Spearman[1][0] < 0.999 {
+		t.Errorf("Incorrect correlation of exams 1 and 2; have: %+v", body)
+	}
+
+	// Exam 3 reverses the order of the two students who took it, and two students are perfectly rank correlated
+	if body.Students[0][2] != 2 || body.Pearson[0][2] == nil || *body.Pearson[0][2] > -0.999 {
+		t.Errorf("Incorrect correlation of exams 1 and 3; have: %+v", body)
+	}
+
+	// No student took both exams 1 and 4
+	if body.Students[0][3] != 0 || body.Pearson[0][3] != nil || body.Spearman[0][3] != nil {
+		t.Errorf("The correlation of exams 1 and 4 should be undefined; have: %+v", body)
+	}
+
+	// Verify a single exam is rejected
+	request, _ = http.NewRequest("GET", "/analytics/correlation?exams=1", nil)
+	response = httptest.NewRecorder()
+	router.ServeHTTP(response, request)
+
+	have = response.Code
+	want = 400
+	if have != want {
+		t.Errorf("HTTP status is not Bad Request; have: %v, want: %v", have, want)
+	}
+
+	// Verify more exams than can be correlated are rejected
+	ids := make([]string, 0)
+	for i := 1; i <= maxCorrelationExams+1; i++ {
+		ids = append(ids, strconv.Itoa(i))
+	}
+	request, _ = http.NewRequest("GET", "/analytics/correlation?exams="+strings.Join(ids, ","), nil)
+	response = httptest.NewRecorder()
+	router.ServeHTTP(response, request)
+
+	problem := readProblem(t, response, 400)
+	if problem.Code != CodeInvalidParameter {
+		t.Errorf("Incorrect problem returned for too many exams; have: %+v", problem)
+	}
+
+	// Verify an unknown exam is not found
+	request, _ = http.NewRequest("GET", "/analytics/correlation?exams=1,99", nil)
+	response = httptest.NewRecorder()
+	router.ServeHTTP(response, request)
+
+	have = response.Code
+	want = 404
+	if have != want {
+		t.Errorf("HTTP status is not Not Found; have: %v, want: %v", have, want)
+	}
+}
@@ -0,0 +1,112 @@
+package handler
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"github.com/kylegk/sse-rest-server/anomaly"
+	"github.com/kylegk/sse-rest-server/models"
+)
+
+// The number of anomalies listed on a page when a request does not specify a limit, and the most it can request
+const (
+	defaultAnomalyLimit = 100
+	maxAnomalyLimit     = 1000
+)
+
+// GetAnomalyReport summarizes the anomalies detected in the ingested score stream, optionally filtered by the "reason" query parameter
+// The anomalies are listed in the order they were detected, a page at a time, with the "limit" and "offset" query parameters
+func GetAnomalyReport(w http.ResponseWriter, r *http.Request) {
+	var err error
+	defer func() {
+		if err != nil {
+			sendError(err, w, r)
+			return
+		}
+	}()
+
+	query := r.URL.Query()
+	limit, offset := defaultAnomalyLimit, 0
+	if query.Get("limit") != "" {
+		limit, err = strconv.Atoi(query.Get("limit"))
+		if err != nil || limit < 1 || limit > maxAnomalyLimit {
+			err = invalidParameter("limit", "invalid limit: must be an integer from 1 to "+strconv.Itoa(maxAnomalyLimit))
+			return
+		}
+	}
+	if query.Get("offset") != "" {
+		offset, err = strconv.Atoi(query.Get("offset"))
+		if err != nil || offset < 0 {
+			err = invalidParameter("offset", "invalid offset: must be a non-negative integer")
+			return
+		}
+	}
+
+	res, err := anomaly.List(query.Get("reason"))
+	if err != nil {
+		log.Println(err)
+		return
+	}
+
+	// Teachers only see the anomalies of the students of their cohorts, and those that are not about a student
+	visible := visibleStudents(r)
+	response := &models.AnomalyReportResponse{Counts: make(map[string]int), Limit: limit, Offset: offset, Anomalies: make([]models.Anomaly, 0)}
+	for _, a := range res {
+		if a.StudentID != "" && !visible.Includes(a.StudentID) {
+			continue
+		}
+
+		if response.Total >= offset && len(response.Anomalies) < limit {
+			response.Anomalies = append(response.Anomalies, a)
+		}
+		response.Total++
+		response.Counts[a.Reason]++
+		if a.Quarantined {
+			response.Quarantined++
+		}
+	}
+
+	// The anomalies that were not stored are not broken down by student, so they are only counted for the clients that see every student
+	if visible == nil {
+		response.Dropped = anomaly.Dropped()
+		for reason := range response.Dropped {
+			if query.Get("reason") != "" && reason != query.Get("reason") {
+				delete(response.Dropped, reason)
+			}
+		}
+	}
+
+	sendResponse(response, http.StatusOK, w)
+}
+
+// ReleaseAnomaly records a quarantined event that was flagged incorrectly
+func ReleaseAnomaly(w http.ResponseWriter, r *http.Request) {
+	var err error
+	defer func() {
+		if err != nil {
+			sendError(err, w, r)
+			return
+		}
+	}()
+
+	score, err := anomaly.Release(mux.Vars(r)["id"], requestAuditor(r, models.AuditAnomalyReleased))
+	switch err {
+	case nil:
+	case anomaly.ErrNotFound:
+		err = notFound("anomaly not found")
+		return
+	case anomaly.ErrNotReleasable:
+		err = conflict(err.Error())
+		return
+	case anomaly.ErrOutOfRange:
+		err = validationFailed([]models.FieldError{{Field: "score", Code: FieldInvalid, Message: err.Error()}})
+		return
+	default:
+		log.Println(err)
+		return
+	}
+
+	sendResponse(score, http.StatusOK, w)
+}
@@ -0,0 +1,192 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/kylegk/sse-rest-server/anomaly"
+	"github.com/kylegk/sse-rest-server/config"
+	"github.com/kylegk/sse-rest-server/db"
+	"github.com/kylegk/sse-rest-server/models"
+)
+
+func addAnomalyTestRoutes() (*mux.Router, error) {
+	err := db.InitDB(config.DBSchema)
+	if err != nil {
+		return nil, err
+	}
+	anomaly.Reset()
+
+	// Quarantine an out of range score and a malformed event
+	_, err = anomaly.Inspect(models.StudentExam{Exam: 1, StudentID: "test.person1", Score: 1.5})
+	if err != nil {
+		return nil, err
+	}
+	err = anomaly.InspectMalformed([]byte("{"), errors.New("unexpected end of JSON input"))
+	if err != nil {
+		return nil, err
+	}
+
+	router := mux.NewRouter()
+	router.HandleFunc("/anomalies", GetAnomalyReport).Methods("GET")
+	router.HandleFunc("/anomalies/{id}/release", ReleaseAnomaly).Methods("POST")
+
+	return router, nil
+}
+
+func TestGetAnomalyReport(t *testing.T) {
+	router, err := addAnomalyTestRoutes()
+	if err != nil {
+		t.Errorf("Failed to start server")
+	}
+
+	request, _ := http.NewRequest("GET", "/anomalies", nil)
+	response := httptest.NewRecorder()
+	router.ServeHTTP(response, request)
+
+	// Verify status code is 200
+	have := response.Code
+	want := 200
+	if have != want {
+		t.Errorf("HTTP status is not OK; have: %v, want: %v", have, want)
+	}
+
+	resBytes, err := ioutil.ReadAll(response.Body)
+	if err != nil {
+		t.Errorf("Unable to read response body")
+	}
+	body := models.AnomalyReportResponse{}
+	err = json.Unmarshal(resBytes, &body)
+	if err != nil {
+		t.Errorf("Failed to parse response returned from route")
+	}
+
+	// Verify both events were quarantined and counted by reason
+	have = body.Quarantined
+	want = 2
+	if have != want {
+		t.Errorf("Incorrect number of quarantined events; have: %v, want: %v", have, want)
+	}
+	if body.Counts[models.AnomalyOutOfRange] != 1 || body.Counts[models.AnomalyMalformed] != 1 {
+		t.Errorf("Incorrect counts; have: %v", body.Counts)
+	}
+
+	// Verify the reason filter
+	request, _ = http.NewRequest("GET", "/anomalies?reason="+models.AnomalyMalformed, nil)
+	response = httptest.NewRecorder()
+	router.ServeHTTP(response, request)
+
+	resBytes, _ = ioutil.ReadAll(response.Body)
+	body = models.AnomalyReportResponse{}
+	_ = json.Unmarshal(resBytes, &body)
+	have = len(body.Anomalies)
+	want = 1
+	if have != want {
+		t.Errorf("Incorrect number of anomalies; have: %v, want: %v", have, want)
+	}
+
+	// Verify the anomalies are paged, while the counts cover all of them
+	request, _ = http.NewRequest("GET", "/anomalies?limit=1&offset=1", nil)
+	response = httptest.NewRecorder()
+	router.ServeHTTP(response, request)
+
+	body = models.AnomalyReportResponse{}
+	_ = json.NewDecoder(response.Body).Decode(&body)
+	if len(body.Anomalies) != 1 || body.Anomalies[0].Reason != models.AnomalyMalformed || body.Total != 2 || body.Quarantined != 2 {
+		t.Errorf("Incorrect page of anomalies; have: %+v", body)
+	}
+
+	for _, url := range []string{"/anomalies?limit=0", "/anomalies?limit=1001", "/anomalies?offset=-1"} {
+		request, _ = http.NewRequest("GET", url, nil)
+		response = httptest.NewRecorder()
+		router.ServeHTTP(response, request)
+
+		problem := readProblem(t, response, 400)
+		if problem.Code != CodeInvalidParameter {
+			t.Errorf("Incorrect problem returned for %v; have: %+v", url, problem)
+		}
+	}
+}
+
+func TestReleaseAnomaly(t *testing.T) {
+	router, err := addAnomalyTestRoutes()
+	if err != nil {
+		t.Errorf("Failed to start server")
+	}
+
+	outOfRange, _ := anomaly.List(models.AnomalyOutOfRange)
+	malformed, _ := anomaly.List(models.AnomalyMalformed)
+	if len(outOfRange) != 1 || len(malformed) != 1 {
+		t.Fatalf("Test anomalies were not recorded")
+	}
+
+	// Verify an event outside of the range cannot be recorded
+	request, _ := http.NewRequest("POST", "/anomalies/"+outOfRange[0].ID+"/release", nil)
+	response := httptest.NewRecorder()
+	router.ServeHTTP(response, request)
+
+	problem := readProblem(t, response, 422)
+	if len(problem.Errors) != 1 || problem.Errors[0].Field != "score" {
+		t.Errorf("Incorrect problem returned; have: %+v", problem)
+	}
+
+	// Verify a quarantined event is recorded when it is released
+	flooded := models.StudentExam{Exam: 2, StudentID: "test.person2", Score: 0.7}
+	err = db.UpsertRow(config.AnomalyTable, models.Anomaly{ID: "flooded", Reason: models.AnomalyStudentRate, Exam: 2, StudentID: "test.person2", Event: &flooded, Quarantined: true})
+	if err != nil {
+		t.Fatalf("Failed to setup the anomaly")
+	}
+
+	request, _ = http.NewRequest("POST", "/anomalies/flooded/release", nil)
+	response = httptest.NewRecorder()
+	router.ServeHTTP(response, request)
+
+	have := response.Code
+	want := 200
+	if have != want {
+		t.Errorf("HTTP status is not OK; have: %v, want: %v", have, want)
+	}
+
+	res, err := db.GetRows(config.ScoreTable, config.StudentIdx, "test.person2")
+	if err != nil || len(res) != 1 {
+		t.Errorf("The released score was not recorded")
+	}
+
+	// Verify a released event cannot be released again
+	request, _ = http.NewRequest("POST", "/anomalies/flooded/release", nil)
+	response = httptest.NewRecorder()
+	router.ServeHTTP(response, request)
+
+	have = response.Code
+	want = 409
+	if have != want {
+		t.Errorf("HTTP status is not Conflict; have: %v, want: %v", have, want)
+	}
+
+	// Verify a malformed event cannot be released
+	request, _ = http.NewRequest("POST", "/anomalies/"+malformed[0].ID+"/release", nil)
+	response = httptest.NewRecorder()
+	router.ServeHTTP(response, request)
+
+	have = response.Code
+	want = 409
+	if have != want {
+		t.Errorf("HTTP status is not Conflict; have: %v, want: %v", have, want)
+	}
+
+	// Verify an unknown anomaly is not found
+	request, _ = http.NewRequest("POST", "/anomalies/unknown/release", nil)
+	response = httptest.NewRecorder()
+	router.ServeHTTP(response, request)
+
+	have = response.Code
+	want = 404
+	if have != want {
+		t.Errorf("HTTP status is not Not Found; have: %v, want: %v", have, want)
+	}
+}
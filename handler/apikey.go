@@ -0,0 +1,99 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/kylegk/sse-rest-server/apikey"
+	"github.com/kylegk/sse-rest-server/models"
+)
+
+// GetAPIKeys lists every API key, without the keys themselves
+func GetAPIKeys(w http.ResponseWriter, r *http.Request) {
+	var err error
+	defer func() {
+		if err != nil {
+			sendError(err, w, r)
+			return
+		}
+	}()
+
+	keys, err := apikey.List()
+	if err != nil {
+		log.Println(err)
+		return
+	}
+
+	sendResponse(&models.APIKeyListResponse{Keys: keys}, http.StatusOK, w)
+}
+
+// AddAPIKey generates a new API key with the name and admin flag in the body
+// The key is only returned in this response, as only its hash is stored
+func AddAPIKey(w http.ResponseWriter, r *http.Request) {
+	var err error
+	defer func() {
+		if err != nil {
+			sendError(err, w, r)
+			return
+		}
+	}()
+
+	bytes, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		log.Println(err)
+		return
+	}
+
+	body := models.APIKey{}
+	err = json.Unmarshal(bytes, &body)
+	if err != nil {
+		err = malformedBody("unable to parse request")
+		return
+	}
+	if body.Name == "" {
+		err = validationFailed([]models.FieldError{{Field: "name", Code: FieldRequired, Message: "invalid name: a name is required"}})
+		return
+	}
+
+	key, err := apikey.Create(body.Name, body.Admin, resourceAuditor(r, models.AuditKeyCreated, models.AuditResourceKey))
+	if err != nil {
+		log.Println(err)
+		return
+	}
+
+	// The secret is only returned once, so the response must not be stored, including by the Idempotency middleware
+	w.Header().Set("Cache-Control", "no-store")
+	sendResponse(key, http.StatusCreated, w)
+}
+
+// DeleteAPIKey revokes an API key; the last admin key cannot be revoked
+func DeleteAPIKey(w http.ResponseWriter, r *http.Request) {
+	var err error
+	defer func() {
+		if err != nil {
+			sendError(err, w, r)
+			return
+		}
+	}()
+
+	id := mux.Vars(r)["id"]
+	err = apikey.Revoke(id, resourceAuditor(r, models.AuditKeyRevoked, models.AuditResourceKey)(id))
+	switch err {
+	case nil:
+	case apikey.ErrNotFound:
+		err = notFound("API key not found")
+		return
+	case apikey.ErrLastAdmin:
+		err = conflict(err.Error())
+		return
+	default:
+		log.Println(err)
+		return
+	}
+
+	sendResponse(&models.GenericResponse{Message: fmt.Sprintf("Successfully revoked API key %s", id)}, http.StatusOK, w)
+}
@@ -0,0 +1,73 @@
+package handler
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/kylegk/sse-rest-server/apikey"
+	"github.com/kylegk/sse-rest-server/config"
+	"github.com/kylegk/sse-rest-server/db"
+	"github.com/kylegk/sse-rest-server/models"
+)
+
+func addAPIKeyTestRoutes() (*mux.Router, error) {
+	err := db.InitDB(config.DBSchema)
+	if err != nil {
+		return nil, err
+	}
+
+	router := mux.NewRouter()
+	router.HandleFunc("/admin/keys", GetAPIKeys).Methods("GET")
+	router.HandleFunc("/admin/keys", AddAPIKey).Methods("POST")
+	router.HandleFunc("/admin/keys/{id}", DeleteAPIKey).Methods("DELETE")
+
+	return router, nil
+}
+
+func TestAPIKeys(t *testing.T) {
+	router, err := addAPIKeyTestRoutes()
+	if err != nil {
+		t.Errorf("Failed to start server")
+	}
+
+	request, _ := http.NewRequest("POST", "/admin/keys", strings.NewReader(`{"name": "ops", "admin": true}`))
+	response := httptest.NewRecorder()
+	router.ServeHTTP(response, request)
+
+	have := response.Code
+	want := 201
+	if have != want {
+		t.Errorf("HTTP status is incorrect; have: %v, want: %v", have, want)
+	}
+
+	created := models.APIKey{}
+	resBytes, _ := ioutil.ReadAll(response.Body)
+	_ = json.Unmarshal(resBytes, &created)
+	if created.Name != "ops" || !created.Admin || created.Key == "" {
+		t.Errorf("Incorrect key created; have: %s", resBytes)
+	}
+
+	// Verify the key is only returned when it is created
+	request, _ = http.NewRequest("GET", "/admin/keys", nil)
+	response = httptest.NewRecorder()
+	router.ServeHTTP(response, request)
+
+	if strings.Contains(response.Body.String(), created.Key) || strings.Contains(response.Body.String(), apikey.Hash(created.Key)) {
+		t.Errorf("Listed keys include the key or its hash; have: %s", response.Body.String())
+	}
+
+	// Verify the last admin key cannot be revoked
+	request, _ = http.NewRequest("DELETE", "/admin/keys/"+created.ID, nil)
+	response = httptest.NewRecorder()
+	router.ServeHTTP(response, request)
+
+	problem := readProblem(t, response, 409)
+	if problem.Code != CodeConflict {
+		t.Errorf("Incorrect problem returned; have: %+v", problem)
+	}
+}
@@ -0,0 +1,96 @@
+package handler
+
+import (
+	"log"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/kylegk/sse-rest-server/audit"
+	"github.com/kylegk/sse-rest-server/db"
+	"github.com/kylegk/sse-rest-server/models"
+)
+
+// GetAuditLog lists the changes made to the scores and the other audited resources, in the order they were made, optionally filtered
+// by the "action", "actor", "exam", "student", "resource", "resource_id" and "request_id" query parameters, and by the "since" and "until" times
+func GetAuditLog(w http.ResponseWriter, r *http.Request) {
+	var err error
+	defer func() {
+		if err != nil {
+			sendError(err, w, r)
+			return
+		}
+	}()
+
+	query := r.URL.Query()
+	filter := audit.Filter{
+		Action:     query.Get("action"),
+		Actor:      query.Get("actor"),
+		StudentID:  query.Get("student"),
+		Resource:   query.Get("resource"),
+		ResourceID: query.Get("resource_id"),
+		RequestID:  query.Get("request_id"),
+	}
+
+	if exam := query.Get("exam"); exam != "" {
+		filter.Exam, err = strconv.Atoi(exam)
+		if err != nil || filter.Exam <= 0 {
+			err = invalidParameter("exam", "invalid exam: must be a positive integer")
+			return
+		}
+	}
+
+	for _, param := range []string{"since", "until"} {
+		value := query.Get(param)
+		if value == "" {
+			continue
+		}
+
+		t, parseErr := time.Parse(time.RFC3339, value)
+		if parseErr != nil {
+			err = invalidParameter(param, "invalid "+param+": must be an RFC 3339 time, such as 2024-01-31T09:00:00Z")
+			return
+		}
+		if param == "since" {
+			filter.Since = t
+		} else {
+			filter.Until = t
+		}
+	}
+
+	res, err := audit.List(filter)
+	if err != nil {
+		log.Println(err)
+		return
+	}
+
+	sendResponse(&models.AuditLogResponse{Entries: res}, http.StatusOK, w)
+}
+
+// Record the changes a request makes to the scores in the audit log, in the same transaction as the changes
+func requestAuditor(r *http.Request, action string) db.Auditor {
+	return audit.Auditor(action, auditSource(r))
+}
+
+// Record a change a request makes to a resource other than the scores in the audit log, in the same transaction as the change
+// The id is that of the changed resource, which is only known once it is created for the routes that create one
+func resourceAuditor(r *http.Request, action string, resource string) db.ResourceAuditor {
+	return func(id string) db.Auditor {
+		return audit.ResourceAuditor(action, auditSource(r), resource, id)
+	}
+}
+
+// Identify who made a request and where it came from
+func auditSource(r *http.Request) audit.Source {
+	source := audit.Source{Actor: audit.AnonymousActor, IP: r.RemoteAddr, RequestID: requestID(r)}
+	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		source.IP = host
+	}
+	if principal, ok := requestPrincipal(r); ok {
+		source.Actor = principal.ID
+		source.Role = principal.Role
+	}
+
+	return source
+}
@@ -0,0 +1,299 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/kylegk/sse-rest-server/auth"
+	"github.com/kylegk/sse-rest-server/config"
+	"github.com/kylegk/sse-rest-server/db"
+	"github.com/kylegk/sse-rest-server/models"
+)
+
+func addAuditTestRoutes() (*mux.Router, error) {
+	err := db.InitDB(config.DBSchema)
+	if err != nil {
+		return nil, err
+	}
+
+	router := mux.NewRouter()
+	router.HandleFunc("/exams", AddExam).Methods("POST")
+	router.HandleFunc("/exams/{id}", DeleteExam).Methods("DELETE")
+	router.HandleFunc("/exams/{id}/students/{student}", PutScore).Methods("PUT")
+	router.HandleFunc("/exams/{id}/students/{student}", DeleteScore).Methods("DELETE")
+	router.HandleFunc("/admin/audit", GetAuditLog).Methods("GET")
+	router.Use(RequestID)
+
+	return router, nil
+}
+
+func TestAuditLog(t *testing.T) {
+	router, err := addAuditTestRoutes()
+	if err != nil {
+		t.Errorf("Failed to start server")
+	}
+
+	teacher := auth.Principal{ID: "sub:ms.teacher", Subject: "ms.teacher", Role: auth.RoleTeacher, AllStudents: true}
+	admin := auth.Principal{ID: "key:root", Subject: "root", Role: auth.RoleAdmin, AllStudents: true}
+
+	requests := []struct {
+		principal auth.Principal
+		method    string
+		url       string
+		body      string
+		requestID string
+	}{
+		{teacher, "POST", "/exams", `[{"exam": 7, "studentid": "test.person1", "score": 0.5}, {"exam": 7, "studentid": "test.person2", "score": 0.6}]`, "add-batch"},
+		{teacher, "POST", "/exams", `{"exam": 7, "studentid": "test.person1", "score": 0.8}`, "add-single"},
+		{teacher, "PUT", "/exams/8/students/test.person1", `{"score": 0.4}`, ""},
+		{admin, "DELETE", "/exams/8/students/test.person1", "", ""},
+		{admin, "DELETE", "/exams/7", "", "delete-exam"},
+	}
+	for _, test := range requests {
+		request, _ := http.NewRequest(test.method, test.url, strings.NewReader(test.body))
+		request.RemoteAddr = "192.0.2.10:51234"
+		if test.requestID != "" {
+			request.Header.Set(RequestIDHeader, test.requestID)
+		}
+		request = request.WithContext(context.WithValue(request.Context(), principalContext, test.principal))
+		response := httptest.NewRecorder()
+		router.ServeHTTP(response, request)
+
+		if response.Code != 200 && response.Code != 201 {
+			t.Errorf("HTTP status is incorrect for %v %v; have: %v", test.method, test.url, response.Code)
+		}
+		if test.requestID != "" && response.Header().Get(RequestIDHeader) != test.requestID {
+			t.Errorf("Request id was not returned; have: %v, want: %v", response.Header().Get(RequestIDHeader), test.requestID)
+		}
+	}
+
+	// Find who deleted exam 7, and when
+	entries := getAuditEntries(t, router, "/admin/audit?exam=7&action=exam.deleted")
+	if len(entries) != 2 {
+		t.Fatalf("Incorrect number of entries for the deleted exam; have: %v, want: %v", len(entries), 2)
+	}
+	for _, entry := range entries {
+		if entry.Actor != admin.ID || entry.Role != auth.RoleAdmin || entry.IP != "192.0.2.10" || entry.RequestID != "delete-exam" || entry.Before == nil || entry.After != nil || entry.Time.IsZero() {
+			t.Errorf("Incorrect entry for the deleted exam; have: %+v", entry)
+		}
+	}
+
+	entries = getAuditEntries(t, router, "/admin/audit?request_id=add-single")
+	if len(entries) != 1 || entries[0].Action != models.AuditExamAdded || entries[0].Before == nil || entries[0].Before.Score != 0.5 || entries[0].After.Score != 0.8 {
+		t.Errorf("Incorrect entry for the added score; have: %+v", entries)
+	}
+
+	entries = getAuditEntries(t, router, "/admin/audit?student=test.person1&exam=8")
+	if len(entries) != 2 || entries[0].Action != models.AuditScoreUpdated || entries[1].Action != models.AuditScoreDeleted || entries[0].RequestID == "" {
+		t.Errorf("Incorrect entries for the updated score; have: %+v", entries)
+	}
+
+	entries = getAuditEntries(t, router, "/admin/audit?actor=sub:ms.teacher")
+	if len(entries) != 4 {
+		t.Errorf("Incorrect number of entries for the teacher; have: %v, want: %v", len(entries), 4)
+	}
+
+	for _, url := range []string{"/admin/audit?exam=abc", "/admin/audit?since=yesterday", "/admin/audit?until=2024-01-31"} {
+		request, _ := http.NewRequest("GET", url, nil)
+		response := httptest.NewRecorder()
+		router.ServeHTTP(response, request)
+
+		problem := readProblem(t, response, 400)
+		if problem.Code != CodeInvalidParameter {
+			t.Errorf("Incorrect problem returned for %v; have: %+v", url, problem)
+		}
+	}
+}
+
+func TestAuditLogAllChanges(t *testing.T) {
+	router, err := addAuditTestRoutes()
+	if err != nil {
+		t.Errorf("Failed to start server")
+	}
+	router.HandleFunc("/exams/{id}/restore", RestoreExam).Methods("POST")
+	router.HandleFunc("/exams/{id}/curve", CurveExam).Methods("POST")
+	router.HandleFunc("/students/{id}", DeleteStudent).Methods("DELETE")
+	router.HandleFunc("/anomalies/{id}/release", ReleaseAnomaly).Methods("POST")
+
+	quarantined := models.StudentExam{Exam: 9, StudentID: "test.person3", Score: 0.7}
+	err = db.UpsertRow(config.AnomalyTable, models.Anomaly{ID: "1", Reason: models.AnomalyStudentRate, Exam: 9, StudentID: "test.person3", Event: &quarantined, Quarantined: true})
+	if err != nil {
+		t.Errorf("Failed to setup the anomaly")
+	}
+
+	requests := []struct {
+		method string
+		url    string
+		body   string
+	}{
+		{"POST", "/exams", `[{"exam": 9, "studentid": "test.person1", "score": 0.5}, {"exam": 9, "studentid": "test.person2", "score": 0.6}]`},
+		{"DELETE", "/exams/9", ""},
+		{"POST", "/exams/9/restore", ""},
+		{"POST", "/exams/9/curve", `{"method": "linear", "factor": 1.1}`},
+		{"DELETE", "/students/test.person2", ""},
+		{"POST", "/anomalies/1/release", ""},
+	}
+	for _, test := range requests {
+		request, _ := http.NewRequest(test.method, test.url, strings.NewReader(test.body))
+		response := httptest.NewRecorder()
+		router.ServeHTTP(response, request)
+
+		if response.Code != 200 {
+			t.Errorf("HTTP status is incorrect for %v %v; have: %v", test.method, test.url, response.Code)
+		}
+	}
+
+	// Every change to the scores is recorded, whichever route made it
+	tests := map[string]int{
+		models.AuditExamAdded:       2,
+		models.AuditExamDeleted:     2,
+		models.AuditExamRestored:    2,
+		models.AuditExamCurved:      2,
+		models.AuditStudentDeleted:  1,
+		models.AuditAnomalyReleased: 1,
+	}
+	for action, want := range tests {
+		entries := getAuditEntries(t, router, "/admin/audit?action="+action)
+		if len(entries) != want {
+			t.Errorf("Incorrect number of entries for %v; have: %v, want: %v", action, len(entries), want)
+		}
+	}
+}
+
+func TestAuditAPIKeys(t *testing.T) {
+	router, err := addAuditTestRoutes()
+	if err != nil {
+		t.Errorf("Failed to start server")
+	}
+	router.HandleFunc("/admin/keys", AddAPIKey).Methods("POST")
+	router.HandleFunc("/admin/keys/{id}", DeleteAPIKey).Methods("DELETE")
+
+	response := sendAuditRequest(router, "POST", "/admin/keys", `{"name": "dashboard"}`)
+	key := models.APIKey{}
+	_ = json.NewDecoder(response.Body).Decode(&key)
+	if response.Code != 201 || key.ID == "" {
+		t.Fatalf("Failed to create the key; have: %v", response.Code)
+	}
+	sendAuditRequest(router, "DELETE", "/admin/keys/"+key.ID, "")
+
+	// Verify the key is identified, but never written to the log
+	for _, action := range []string{models.AuditKeyCreated, models.AuditKeyRevoked} {
+		entries := getAuditEntries(t, router, "/admin/audit?action="+action)
+		if len(entries) != 1 || entries[0].Resource != models.AuditResourceKey || entries[0].ResourceID != key.ID || entries[0].Actor != "key:root" {
+			t.Errorf("Incorrect entries for %v; have: %+v", action, entries)
+		}
+	}
+
+	request, _ := http.NewRequest("GET", "/admin/audit", nil)
+	response = httptest.NewRecorder()
+	router.ServeHTTP(response, request)
+	if strings.Contains(response.Body.String(), key.Key) {
+		t.Errorf("The key was written to the audit log")
+	}
+}
+
+func TestAuditWebhooks(t *testing.T) {
+	router, err := addAuditTestRoutes()
+	if err != nil {
+		t.Errorf("Failed to start server")
+	}
+	router.HandleFunc("/webhooks", AddWebhook).Methods("POST")
+	router.HandleFunc("/webhooks/{id}", DeleteWebhook).Methods("DELETE")
+
+	response := sendAuditRequest(router, "POST", "/webhooks", `{"url": "https://example.com/hook", "secret": "test-secret"}`)
+	hook := models.Webhook{}
+	_ = json.NewDecoder(response.Body).Decode(&hook)
+	if response.Code != 201 || hook.ID == "" {
+		t.Fatalf("Failed to create the webhook; have: %v", response.Code)
+	}
+	sendAuditRequest(router, "DELETE", "/webhooks/"+hook.ID, "")
+
+	entries := getAuditEntries(t, router, "/admin/audit?resource=webhook&resource_id="+hook.ID)
+	if len(entries) != 2 || entries[0].Action != models.AuditWebhookCreated || entries[1].Action != models.AuditWebhookDeleted {
+		t.Errorf("Incorrect entries for the webhook; have: %+v", entries)
+	}
+
+	// A failed delete changes nothing, so it is not recorded
+	sendAuditRequest(router, "DELETE", "/webhooks/"+hook.ID, "")
+	entries = getAuditEntries(t, router, "/admin/audit?action="+models.AuditWebhookDeleted)
+	if len(entries) != 1 {
+		t.Errorf("Incorrect number of entries for the deleted webhook; have: %v, want: %v", len(entries), 1)
+	}
+}
+
+func TestAuditExamMetadata(t *testing.T) {
+	router, err := addAuditTestRoutes()
+	if err != nil {
+		t.Errorf("Failed to start server")
+	}
+	router.HandleFunc("/exams/{id}/metadata", PutExamMetadata).Methods("PUT")
+
+	response := sendAuditRequest(router, "PUT", "/exams/3/metadata", `{"title": "Midterm", "weight": 2}`)
+	if response.Code != 200 {
+		t.Fatalf("Failed to put the metadata; have: %v", response.Code)
+	}
+
+	entries := getAuditEntries(t, router, "/admin/audit?action="+models.AuditExamMetadataUpdated)
+	if len(entries) != 1 || entries[0].Resource != models.AuditResourceExamMetadata || entries[0].ResourceID != "3" || entries[0].RequestID == "" {
+		t.Errorf("Incorrect entries for the metadata; have: %+v", entries)
+	}
+}
+
+func TestAuditAlerts(t *testing.T) {
+	router, err := addAuditTestRoutes()
+	if err != nil {
+		t.Errorf("Failed to start server")
+	}
+	router.HandleFunc("/alerts/{id}/acknowledge", AcknowledgeAlert).Methods("POST")
+	router.HandleFunc("/alerts/{id}/resolve", ResolveAlert).Methods("POST")
+
+	err = db.UpsertRow(config.AlertTable, models.Alert{ID: "1", StudentID: "test.person1", Status: models.AlertOpen})
+	if err != nil {
+		t.Errorf("Failed to setup the alert")
+	}
+
+	sendAuditRequest(router, "POST", "/alerts/1/acknowledge", "")
+	sendAuditRequest(router, "POST", "/alerts/1/resolve", "")
+	// An alert that cannot be acknowledged again is not recorded
+	sendAuditRequest(router, "POST", "/alerts/1/acknowledge", "")
+
+	entries := getAuditEntries(t, router, "/admin/audit?resource=alert&resource_id=1")
+	if len(entries) != 2 || entries[0].Action != models.AuditAlertAcknowledged || entries[1].Action != models.AuditAlertResolved {
+		t.Errorf("Incorrect entries for the alert; have: %+v", entries)
+	}
+}
+
+// Send a request as an admin
+func sendAuditRequest(router *mux.Router, method string, url string, body string) *httptest.ResponseRecorder {
+	admin := auth.Principal{ID: "key:root", Subject: "root", Role: auth.RoleAdmin, AllStudents: true}
+
+	request, _ := http.NewRequest(method, url, strings.NewReader(body))
+	request = request.WithContext(context.WithValue(request.Context(), principalContext, admin))
+	response := httptest.NewRecorder()
+	router.ServeHTTP(response, request)
+
+	return response
+}
+
+func getAuditEntries(t *testing.T, router *mux.Router, url string) []models.AuditEntry {
+	request, _ := http.NewRequest("GET", url, nil)
+	response := httptest.NewRecorder()
+	router.ServeHTTP(response, request)
+
+	if response.Code != 200 {
+		t.Errorf("HTTP status is not OK for %v; have: %v", url, response.Code)
+	}
+
+	res := models.AuditLogResponse{}
+	err := json.NewDecoder(response.Body).Decode(&res)
+	if err != nil {
+		t.Errorf("Unable to parse the audit log; %v", err)
+	}
+
+	return res.Entries
+}
@@ -0,0 +1,92 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/kylegk/sse-rest-server/apikey"
+	"github.com/kylegk/sse-rest-server/auth"
+)
+
+// APIKeyHeader is the header a client sends its API key in
+const APIKeyHeader = "X-API-Key"
+
+type contextKey string
+
+// The request context key of the client that authenticated a request
+const principalContext contextKey = "principal"
+
+// Authenticate is a middleware that requires an API key or a bearer token on every request once either has been configured
+// The paths listed as public, such as the description of the API, are served without credentials
+// Every failed attempt spends a token from the rate limit of the address it came from, so credentials cannot be guessed
+// faster than the address could make requests without them
+func Authenticate(public ...string) func(http.Handler) http.Handler {
+	publicPaths := make(map[string]bool, len(public))
+	for _, path := range public {
+		publicPaths[path] = true
+	}
+
+	return func(h http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if publicPaths[r.URL.Path] || !auth.Required() {
+				h.ServeHTTP(w, r)
+				return
+			}
+
+			principal, err := auth.Authenticate(r.Header.Get(APIKeyHeader), r.Header.Get("Authorization"))
+			if err != nil {
+				if !spendTokens(w, r, addressRateLimitKey(r), 1) {
+					return
+				}
+				if auth.TokensEnabled() {
+					w.Header().Set("WWW-Authenticate", `Bearer realm="api"`)
+				}
+				sendError(authenticationError(err), w, r)
+				return
+			}
+
+			h.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), principalContext, principal)))
+		})
+	}
+}
+
+// Map an error authenticating a request to the problem reported to the client
+func authenticationError(err error) error {
+	switch {
+	case err == auth.ErrNoCredentials:
+		return unauthorized("an API key in the X-API-Key header or a bearer token in the Authorization header is required")
+	case err == apikey.ErrInvalidKey:
+		return unauthorized("invalid API key")
+	case errors.Is(err, auth.ErrInvalidToken):
+		return unauthorized(err.Error())
+	default:
+		log.Println(err)
+		return err
+	}
+}
+
+// RequireAdmin only serves requests from an admin
+// Admin routes require credentials even before any have been configured, so they are never open
+func RequireAdmin(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		principal, ok := requestPrincipal(r)
+		if !ok {
+			sendError(unauthorized("an admin API key or bearer token is required"), w, r)
+			return
+		}
+		if principal.Role != auth.RoleAdmin {
+			sendError(forbidden("the admin role is required"), w, r)
+			return
+		}
+
+		h.ServeHTTP(w, r)
+	})
+}
+
+// The client that authenticated a request, if any
+func requestPrincipal(r *http.Request) (auth.Principal, bool) {
+	principal, ok := r.Context().Value(principalContext).(auth.Principal)
+	return principal, ok
+}
@@ -0,0 +1,118 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/kylegk/sse-rest-server/apikey"
+	"github.com/kylegk/sse-rest-server/config"
+	"github.com/kylegk/sse-rest-server/db"
+)
+
+func addAuthTestRoutes() (*mux.Router, error) {
+	err := db.InitDB(config.DBSchema)
+	if err != nil {
+		return nil, err
+	}
+
+	router := mux.NewRouter()
+	router.HandleFunc("/students", GetAllStudents).Methods("GET")
+	router.HandleFunc("/exams", AddExam).Methods("POST")
+	router.HandleFunc("/docs", GetAPIDocs).Methods("GET")
+	router.Handle("/admin/keys", RequireAdmin(http.HandlerFunc(GetAPIKeys))).Methods("GET")
+	router.Use(Authenticate("/docs"))
+	router.Use(Idempotency)
+
+	return router, nil
+}
+
+func TestAuthenticate(t *testing.T) {
+	router, err := addAuthTestRoutes()
+	if err != nil {
+		t.Errorf("Failed to start server")
+	}
+
+	// Verify the API is open until a key is configured, except for the admin routes
+	tests := []struct {
+		path   string
+		key    string
+		status int
+	}{
+		{"/students", "", 200},
+		{"/admin/keys", "", 401},
+	}
+	for _, test := range tests {
+		request, _ := http.NewRequest("GET", test.path, nil)
+		response := httptest.NewRecorder()
+		router.ServeHTTP(response, request)
+		if response.Code != test.status {
+			t.Errorf("HTTP status is incorrect without keys for %v; have: %v, want: %v", test.path, response.Code, test.status)
+		}
+	}
+
+	err = apikey.AddKey("admin", "admin-key", true)
+	if err != nil {
+		t.Errorf("Unable to add the key; %v", err)
+	}
+	client, err := apikey.Create("dashboard", false, nil)
+	if err != nil {
+		t.Errorf("Unable to create the key; %v", err)
+	}
+
+	tests = []struct {
+		path   string
+		key    string
+		status int
+	}{
+		{"/students", "", 401},
+		{"/students", "wrong-key", 401},
+		{"/students", client.Key, 200},
+		{"/docs", "", 200},
+		{"/admin/keys", client.Key, 403},
+		{"/admin/keys", "admin-key", 200},
+	}
+	for _, test := range tests {
+		request, _ := http.NewRequest("GET", test.path, nil)
+		if test.key != "" {
+			request.Header.Set(APIKeyHeader, test.key)
+		}
+		response := httptest.NewRecorder()
+		router.ServeHTTP(response, request)
+
+		if response.Code != test.status {
+			t.Errorf("HTTP status is incorrect for %v with %q; have: %v, want: %v", test.path, test.key, response.Code, test.status)
+		}
+		if response.Code == 401 {
+			problem := readProblem(t, response, 401)
+			if problem.Code != CodeUnauthorized {
+				t.Errorf("Incorrect problem returned; have: %+v", problem)
+			}
+		}
+	}
+}
+
+func TestIdempotencyScopedToKey(t *testing.T) {
+	router, err := addAuthTestRoutes()
+	if err != nil {
+		t.Errorf("Failed to start server")
+	}
+
+	first, _ := apikey.Create("first", false, nil)
+	second, _ := apikey.Create("second", false, nil)
+
+	// The same Idempotency-Key sent with another API key is a different request, so it is handled rather than replayed
+	for _, key := range []string{first.Key, second.Key} {
+		request, _ := http.NewRequest("POST", "/exams", strings.NewReader(`{"exam": 1, "studentid": "test.person1", "score": 0.5}`))
+		request.Header.Set(APIKeyHeader, key)
+		request.Header.Set(IdempotencyKeyHeader, "shared-key")
+		response := httptest.NewRecorder()
+		router.ServeHTTP(response, request)
+
+		if response.Code != 200 || response.Header().Get(IdempotencyReplayedHeader) != "" {
+			t.Errorf("Request was replayed to another API key; have: %v %v", response.Code, response.Header().Get(IdempotencyReplayedHeader))
+		}
+	}
+}
@@ -0,0 +1,107 @@
+package handler
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/kylegk/sse-rest-server/db"
+)
+
+// Conditional wraps a read handler so its responses carry an ETag and Last-Modified header derived from the
+// modification index of the tables it reads, and answers If-None-Match and If-Modified-Since requests with
+// 304 Not Modified when none of those tables have changed
+func Conditional(h http.HandlerFunc, tables ...string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// The version is read before the handler runs, so a change made while it runs produces a new tag on the next request
+		version, modified := db.TableVersion(tables...)
+		etag := responseETag(version, r)
+
+		// Last-Modified only has a resolution of seconds, so it is not sent until the second of the last change has
+		// passed; a client sent it earlier would be told a change made later in that second was not a modification
+		lastModified := modified.Truncate(time.Second)
+		if !lastModified.Before(time.Now().Truncate(time.Second)) {
+			lastModified = time.Time{}
+		}
+
+		if notModified(r, etag, modified, lastModified) {
+			w.Header().Set("ETag", etag)
+			if !lastModified.IsZero() {
+				w.Header().Set("Last-Modified", lastModified.Format(http.TimeFormat))
+			}
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+
+		h(&conditionalWriter{ResponseWriter: w, etag: etag, lastModified: lastModified}, r)
+	})
+}
+
+// conditionalWriter adds the validators to successful responses only, so errors are never revalidated
+type conditionalWriter struct {
+	http.ResponseWriter
+	etag         string
+	lastModified time.Time
+	wroteHeader  bool
+}
+
+func (cw *conditionalWriter) WriteHeader(status int) {
+	if !cw.wroteHeader && status == http.StatusOK {
+		cw.Header().Set("ETag", cw.etag)
+		if !cw.lastModified.IsZero() {
+			cw.Header().Set("Last-Modified", cw.lastModified.Format(http.TimeFormat))
+		}
+		cw.Header().Add("Vary", "Accept")
+	}
+	cw.wroteHeader = true
+	cw.ResponseWriter.WriteHeader(status)
+}
+
+func (cw *conditionalWriter) Write(b []byte) (int, error) {
+	if !cw.wroteHeader {
+		cw.WriteHeader(http.StatusOK)
+	}
+
+	return cw.ResponseWriter.Write(b)
+}
+
+// Flush passes through to the client so streamed exports are not held back
+func (cw *conditionalWriter) Flush() {
+	if f, ok := cw.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Tag a response with the version of its tables and the representation requested, as the query and Accept header
+// select the format, scale and scores returned
+func responseETag(version string, r *http.Request) string {
+	hash := sha256.Sum256([]byte(version + "|" + r.URL.RequestURI() + "|" + r.Header.Get("Accept")))
+
+	return `W/"` + hex.EncodeToString(hash[:8]) + `"`
+}
+
+// Check the request's preconditions; If-Modified-Since is only used when If-None-Match is not sent, and is compared
+// with the exact time of the last change while that change's second has not passed
+func notModified(r *http.Request, etag string, modified time.Time, lastModified time.Time) bool {
+	if match := r.Header.Get("If-None-Match"); match != "" {
+		for _, tag := range strings.Split(match, ",") {
+			tag = strings.TrimSpace(tag)
+			if tag == "*" || strings.TrimPrefix(tag, "W/") == strings.TrimPrefix(etag, "W/") {
+				return true
+			}
+		}
+		return false
+	}
+
+	since, err := http.ParseTime(r.Header.Get("If-Modified-Since"))
+	if err != nil {
+		return false
+	}
+	if lastModified.IsZero() {
+		return !modified.After(since)
+	}
+
+	return !lastModified.After(since)
+}
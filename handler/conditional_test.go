@@ -0,0 +1,168 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/kylegk/sse-rest-server/config"
+	"github.com/kylegk/sse-rest-server/db"
+	"github.com/kylegk/sse-rest-server/models"
+)
+
+func addConditionalTestRoutes() (*mux.Router, error) {
+	_, err := addExamTestRoutes()
+	if err != nil {
+		return nil, err
+	}
+
+	router := mux.NewRouter()
+	router.Handle("/exams/{id}", Conditional(GetExamByID, config.ScoreTable)).Methods("GET")
+
+	return router, nil
+}
+
+func sendConditionalRequest(router *mux.Router, url string, header string, value string) *httptest.ResponseRecorder {
+	request, _ := http.NewRequest("GET", url, nil)
+	if header != "" {
+		request.Header.Set(header, value)
+	}
+	response := httptest.NewRecorder()
+	router.ServeHTTP(response, request)
+
+	return response
+}
+
+func TestConditionalETag(t *testing.T) {
+	router, err := addConditionalTestRoutes()
+	if err != nil {
+		t.Errorf("Failed to start server")
+	}
+
+	response := sendConditionalRequest(router, "/exams/1", "", "")
+	etag := response.Header().Get("ETag")
+	if response.Code != 200 || etag == "" {
+		t.Fatalf("The response should carry validators; have: %v, %v", response.Code, response.Header())
+	}
+
+	// Verify an unchanged exam is not sent again
+	response = sendConditionalRequest(router, "/exams/1", "If-None-Match", etag)
+	have := response.Code
+	want := 304
+	if have != want {
+		t.Errorf("HTTP status is not Not Modified; have: %v, want: %v", have, want)
+	}
+	if response.Body.Len() != 0 {
+		t.Errorf("A 304 response should not have a body")
+	}
+
+	// Verify a change to another table keeps the tag
+	err = db.UpsertRow(config.AlertTable, models.Alert{ID: "1", StudentID: "test.person", Status: models.AlertOpen})
+	if err != nil {
+		t.Errorf("Failed to insert alert")
+	}
+	response = sendConditionalRequest(router, "/exams/1", "If-None-Match", etag)
+	have = response.Code
+	want = 304
+	if have != want {
+		t.Errorf("HTTP status is not Not Modified; have: %v, want: %v", have, want)
+	}
+
+	// Verify a change to the scores produces a new tag
+	err = db.UpsertRow(config.ScoreTable, models.StudentExam{Exam: 1, StudentID: "test.person4", Score: 0.5})
+	if err != nil {
+		t.Errorf("Failed to insert score")
+	}
+	response = sendConditionalRequest(router, "/exams/1", "If-None-Match", etag)
+	have = response.Code
+	want = 200
+	if have != want {
+		t.Errorf("HTTP status is not OK; have: %v, want: %v", have, want)
+	}
+	if response.Header().Get("ETag") == etag {
+		t.Errorf("The tag should have changed")
+	}
+
+	// Verify a different representation has a different tag
+	response = sendConditionalRequest(router, "/exams/1?scale=pass_fail", "If-None-Match", response.Header().Get("ETag"))
+	have = response.Code
+	want = 200
+	if have != want {
+		t.Errorf("HTTP status is not OK; have: %v, want: %v", have, want)
+	}
+
+	// Verify errors are not tagged
+	response = sendConditionalRequest(router, "/exams/99", "", "")
+	if response.Code != 404 || response.Header().Get("ETag") != "" {
+		t.Errorf("The error should not carry a tag; have: %v, %v", response.Code, response.Header().Get("ETag"))
+	}
+}
+
+func TestConditionalModifiedSince(t *testing.T) {
+	router, err := addConditionalTestRoutes()
+	if err != nil {
+		t.Errorf("Failed to start server")
+	}
+
+	response := sendConditionalRequest(router, "/exams/1", "If-Modified-Since", time.Now().Add(time.Hour).UTC().Format(http.TimeFormat))
+	have := response.Code
+	want := 304
+	if have != want {
+		t.Errorf("HTTP status is not Not Modified; have: %v, want: %v", have, want)
+	}
+
+	response = sendConditionalRequest(router, "/exams/1", "If-Modified-Since", time.Now().Add(-time.Hour).UTC().Format(http.TimeFormat))
+	have = response.Code
+	want = 200
+	if have != want {
+		t.Errorf("HTTP status is not OK; have: %v, want: %v", have, want)
+	}
+}
+
+func TestConditionalModifiedSinceSameSecond(t *testing.T) {
+	router, err := addConditionalTestRoutes()
+	if err != nil {
+		t.Errorf("Failed to start server")
+	}
+
+	// Start at the beginning of a second, so the requests below are served in the second of the last change
+	time.Sleep(time.Until(time.Now().Truncate(time.Second).Add(time.Second)))
+	err = db.UpsertRow(config.ScoreTable, models.StudentExam{Exam: 1, StudentID: "test.person4", Score: 0.5})
+	if err != nil {
+		t.Errorf("Failed to insert score")
+	}
+	_, modified := db.TableVersion(config.ScoreTable)
+
+	response := sendConditionalRequest(router, "/exams/1", "", "")
+	if response.Code != 200 || response.Header().Get("Last-Modified") != "" {
+		t.Errorf("The date should not be sent in the second of the last change; have: %v, %v", response.Code, response.Header().Get("Last-Modified"))
+	}
+
+	// Verify a change later in the same second as the date sent is not missed
+	err = db.UpsertRow(config.ScoreTable, models.StudentExam{Exam: 1, StudentID: "test.person5", Score: 0.5})
+	if err != nil {
+		t.Errorf("Failed to insert score")
+	}
+	response = sendConditionalRequest(router, "/exams/1", "If-Modified-Since", modified.UTC().Format(http.TimeFormat))
+	have := response.Code
+	want := 200
+	if have != want {
+		t.Errorf("HTTP status is not OK; have: %v, want: %v", have, want)
+	}
+
+	// Verify the date is sent once the second has passed, and is answered as not modified
+	time.Sleep(time.Until(time.Now().Truncate(time.Second).Add(time.Second)))
+	response = sendConditionalRequest(router, "/exams/1", "", "")
+	lastModified := response.Header().Get("Last-Modified")
+	if lastModified == "" {
+		t.Fatalf("The date should be sent once the second of the last change has passed")
+	}
+	response = sendConditionalRequest(router, "/exams/1", "If-Modified-Since", lastModified)
+	have = response.Code
+	want = 304
+	if have != want {
+		t.Errorf("HTTP status is not Not Modified; have: %v, want: %v", have, want)
+	}
+}
@@ -0,0 +1,67 @@
+package handler
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"log"
+	"net/http"
+
+	"github.com/kylegk/sse-rest-server/db"
+	"github.com/kylegk/sse-rest-server/grading"
+	"github.com/kylegk/sse-rest-server/models"
+)
+
+// CurveExam curves every score of the specified exam, storing the curved scores alongside the raw scores
+// Scores recorded for the exam afterwards are curved using the same parameters
+func CurveExam(w http.ResponseWriter, r *http.Request) {
+	var err error
+	defer func() {
+		if err != nil {
+			sendError(err, w, r)
+			return
+		}
+	}()
+
+	examID, err := examIDParam(r)
+	if err != nil {
+		return
+	}
+
+	req := models.Curve{}
+	bytes, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		log.Println(err)
+		return
+	}
+
+	err = json.Unmarshal(bytes, &req)
+	if err != nil {
+		err = malformedBody("unable to parse request")
+		return
+	}
+	req.Exam = examID
+
+	stats, err := db.GetExamAggregate(examID)
+	if err != nil {
+		log.Println(err)
+		return
+	}
+	if stats == nil {
+		SendGenericNotFoundResponse(w, r)
+		return
+	}
+
+	curve, err := grading.NewCurve(req, *stats)
+	if err != nil {
+		err = invalidRequest(err.Error())
+		return
+	}
+
+	count, err := db.ApplyCurve(curve, requestAuditor(r, models.AuditExamCurved))
+	if err != nil {
+		log.Println(err)
+		return
+	}
+
+	sendResponse(&models.CurveResponse{Curve: curve, Count: count}, http.StatusOK, w)
+}
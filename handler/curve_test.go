@@ -0,0 +1,113 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/kylegk/sse-rest-server/models"
+)
+
+func addCurveTestRoutes() (*mux.Router, error) {
+	router, err := addExamTestRoutes()
+	if err != nil {
+		return nil, err
+	}
+
+	router.HandleFunc("/exams/{id}/curve", CurveExam).Methods("POST")
+
+	return router, nil
+}
+
+func TestCurveExam(t *testing.T) {
+	router, err := addCurveTestRoutes()
+	if err != nil {
+		t.Errorf("Failed to start server")
+	}
+
+	// Test with an invalid method
+	j, _ := json.Marshal(models.Curve{Method: "does_not_exist"})
+	request, _ := http.NewRequest("POST", "/exams/1/curve", bytes.NewBuffer(j))
+	response := httptest.NewRecorder()
+	router.ServeHTTP(response, request)
+
+	// Verify status code is 422
+	have := response.Code
+	want := 422
+	if have != want {
+		t.Errorf("Route returned an incorrect status code; have %v, want %v", have, want)
+	}
+
+	// Test with an exam that does not exist
+	j, _ = json.Marshal(models.Curve{Method: models.CurveLinear})
+	request, _ = http.NewRequest("POST", "/exams/99/curve", bytes.NewBuffer(j))
+	response = httptest.NewRecorder()
+	router.ServeHTTP(response, request)
+
+	// Verify status code is 404
+	have = response.Code
+	want = 404
+	if have != want {
+		t.Errorf("Route returned an incorrect status code; have %v, want %v", have, want)
+	}
+
+	// Test a valid curve
+	request, _ = http.NewRequest("POST", "/exams/1/curve", bytes.NewBuffer(j))
+	response = httptest.NewRecorder()
+	router.ServeHTTP(response, request)
+
+	// Verify status code is 200
+	have = response.Code
+	want = 200
+	if have != want {
+		t.Errorf("HTTP status is not OK; have %v, want %v", have, want)
+	}
+
+	curveBody := models.CurveResponse{}
+	resBytes, _ := ioutil.ReadAll(response.Body)
+	err = json.Unmarshal(resBytes, &curveBody)
+	if err != nil {
+		t.Errorf("Error parsing response body")
+	}
+
+	// Verify every score of the exam was curved
+	have = curveBody.Count
+	want = 3
+	if have != want {
+		t.Errorf("Incorrect number of scores curved; have %v, want %v", have, want)
+	}
+
+	request, _ = http.NewRequest("GET", "/exams/1?scores=curved", nil)
+	response = httptest.NewRecorder()
+	router.ServeHTTP(response, request)
+
+	body := models.ExamByIDResponse{}
+	resBytes, _ = ioutil.ReadAll(response.Body)
+	err = json.Unmarshal(resBytes, &body)
+	if err != nil {
+		t.Errorf("Error parsing response body")
+	}
+
+	// Verify the highest curved score was scaled to 1
+	have64 := body.Max
+	want64 := 1.0
+	if have64 != want64 || body.ScoreType != "curved" {
+		t.Errorf("Incorrect curved maximum; have %v (%s), want %v", have64, body.ScoreType, want64)
+	}
+
+	// Test with an invalid score type
+	request, _ = http.NewRequest("GET", "/exams/1?scores=foo", nil)
+	response = httptest.NewRecorder()
+	router.ServeHTTP(response, request)
+
+	// Verify status code is 400
+	have = response.Code
+	want = 400
+	if have != want {
+		t.Errorf("Route returned an incorrect status code; have %v, want %v", have, want)
+	}
+}
@@ -0,0 +1,26 @@
+package handler
+
+import (
+	"net/http"
+	"time"
+)
+
+// The date after which the deprecated routes may be removed, sent in the Sunset header
+var LegacySunset = time.Date(2027, time.June, 30, 0, 0, 0, 0, time.UTC)
+
+// Deprecated marks every response of a deprecated route, pointing the client to the same path under the prefix of its successor version
+func Deprecated(successor string) func(http.Handler) http.Handler {
+	return func(h http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			link := successor + r.URL.Path
+			if r.URL.RawQuery != "" {
+				link += "?" + r.URL.RawQuery
+			}
+
+			w.Header().Set("Deprecation", "true")
+			w.Header().Set("Sunset", LegacySunset.Format(http.TimeFormat))
+			w.Header().Add("Link", "<"+link+">; rel=\"successor-version\"")
+			h.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,56 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/kylegk/sse-rest-server/config"
+	"github.com/kylegk/sse-rest-server/db"
+)
+
+func TestDeprecated(t *testing.T) {
+	err := db.InitDB(config.DBSchema)
+	if err != nil {
+		t.Errorf("Failed to start server")
+	}
+
+	router := mux.NewRouter()
+	router.HandleFunc("/v1/exams", GetAllUniqueExamIDs).Methods("GET")
+	legacy := router.NewRoute().Subrouter()
+	legacy.Use(Deprecated("/v1"))
+	legacy.HandleFunc("/exams", GetAllUniqueExamIDs).Methods("GET")
+
+	// Verify the versioned route is not marked as deprecated
+	request, _ := http.NewRequest("GET", "/v1/exams", nil)
+	response := httptest.NewRecorder()
+	router.ServeHTTP(response, request)
+
+	if response.Header().Get("Deprecation") != "" || response.Header().Get("Sunset") != "" {
+		t.Errorf("Versioned route is marked as deprecated; have: %v", response.Header())
+	}
+
+	// Verify the alias is marked as deprecated and links to its successor
+	request, _ = http.NewRequest("GET", "/exams?format=csv", nil)
+	response = httptest.NewRecorder()
+	router.ServeHTTP(response, request)
+
+	have := response.Code
+	want := 200
+	if have != want {
+		t.Errorf("HTTP status is not OK; have: %v, want: %v", have, want)
+	}
+
+	headers := map[string]string{
+		"Deprecation": "true",
+		"Sunset":      LegacySunset.Format(http.TimeFormat),
+		"Link":        `</v1/exams?format=csv>; rel="successor-version"`,
+	}
+	for name, want := range headers {
+		have := response.Header().Get(name)
+		if have != want {
+			t.Errorf("Incorrect %v header; have: %v, want: %v", name, have, want)
+		}
+	}
+}
@@ -2,30 +2,43 @@ package handler
 
 import (
 	"encoding/json"
-	"errors"
 	"fmt"
 	"github.com/kylegk/sse-rest-server/config"
 	"io/ioutil"
 	"log"
 	"net/http"
-	"strconv"
+	"strings"
+	"time"
 
-	"github.com/gorilla/mux"
 	"github.com/kylegk/sse-rest-server/db"
+	"github.com/kylegk/sse-rest-server/grading"
 	"github.com/kylegk/sse-rest-server/models"
+	"github.com/kylegk/sse-rest-server/report"
 )
 
-// AddExam adds a single exam (PUT) to the datastore
+// Define the modes of a batch of exams
+const (
+	// Nothing in the batch is recorded if any exam is invalid
+	batchAtomic = "atomic"
+	// The valid exams are recorded and the invalid exams are reported
+	batchBestEffort = "best_effort"
+)
+
+// Define the status of each exam in a batch
+const (
+	batchRecorded    = "recorded"
+	batchInvalid     = "invalid"
+	batchNotRecorded = "not_recorded"
+)
+
+// AddExam adds a single exam, or a JSON array of exams, to the datastore
+// A batch is recorded in a single transaction, and the "mode" query parameter selects whether a batch is
+// recorded all-or-nothing (atomic, the default) or skips the invalid exams (best_effort)
 func AddExam(w http.ResponseWriter, r *http.Request) {
 	var err error
 	defer func() {
 		if err != nil {
-			if err.Error() == "internal_server_error" {
-				SendGenericInternalServerError(w, r)
-			} else {
-				sendResponse(&models.GenericResponse{Code: http.StatusBadRequest, Error: "Bad Request", Message: err.Error()}, http.StatusBadRequest, w)
-			}
-
+			sendError(err, w, r)
 			return
 		}
 	}()
@@ -37,9 +50,14 @@ func AddExam(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if trimmed := strings.TrimSpace(string(bytes)); strings.HasPrefix(trimmed, "[") {
+		err = addExams(w, r, []byte(trimmed))
+		return
+	}
+
 	err = json.Unmarshal(bytes, &exam)
 	if err != nil {
-		err = errors.New("unable to parse request")
+		err = malformedBody("unable to parse request")
 		return
 	}
 
@@ -48,40 +66,150 @@ func AddExam(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err = db.UpsertRow(config.ScoreTable, exam)
+	err = authorizeStudents(r, exam.StudentID)
 	if err != nil {
-		err = errors.New("internal_server_error")
+		return
+	}
+
+	_, err = db.UpsertScores([]models.StudentExam{exam}, requestAuditor(r, models.AuditExamAdded))
+	if err != nil {
+		log.Println(err)
 		return
 	}
 
 	sendResponse(&models.GenericResponse{Message: fmt.Sprintf("Succesfully added exam: %v", exam.Exam)}, http.StatusOK, w)
 }
 
+// Record a batch of exams in a single transaction, sending a result for each exam
+// Errors returned are sent by AddExam
+func addExams(w http.ResponseWriter, r *http.Request, bytes []byte) error {
+	mode := r.URL.Query().Get("mode")
+	if mode == "" {
+		mode = batchAtomic
+	}
+	if mode != batchAtomic && mode != batchBestEffort {
+		return invalidParameter("mode", "invalid mode: must be atomic or best_effort")
+	}
+
+	exams := make([]models.StudentExam, 0)
+	err := json.Unmarshal(bytes, &exams)
+	if err != nil {
+		return malformedBody("unable to parse request")
+	}
+	if len(exams) == 0 {
+		return invalidRequest("no exams to add")
+	}
+
+	students := make([]string, 0, len(exams))
+	for _, exam := range exams {
+		students = append(students, exam.StudentID)
+	}
+	err = authorizeStudents(r, students...)
+	if err != nil {
+		return err
+	}
+
+	response := &models.BatchExamResponse{Mode: mode, Results: make([]models.BatchExamResult, len(exams))}
+	records := make([]models.StudentExam, 0, len(exams))
+	for i, exam := range exams {
+		response.Results[i] = models.BatchExamResult{Index: i, Exam: exam.Exam, StudentID: exam.StudentID, Status: batchRecorded}
+
+		validErr := validateRequestBody(exam)
+		if validErr != nil {
+			response.Results[i].Status = batchInvalid
+			response.Results[i].Error = validErr.Error()
+			response.Results[i].Fields = validErr.(*apiError).fields
+			response.Failed++
+			continue
+		}
+		records = append(records, exam)
+	}
+
+	if mode == batchAtomic && response.Failed > 0 {
+		for i := range response.Results {
+			if response.Results[i].Status == batchRecorded {
+				response.Results[i].Status = batchNotRecorded
+			}
+		}
+		response.Failed = len(exams)
+		sendResponse(response, http.StatusUnprocessableEntity, w)
+		return nil
+	}
+
+	_, err = db.UpsertScores(records, requestAuditor(r, models.AuditExamAdded))
+	if err != nil {
+		log.Println(err)
+		return err
+	}
+	response.Succeeded = len(records)
+
+	sendResponse(response, http.StatusOK, w)
+
+	return nil
+}
+
 // DeleteExam removes all examTestData matching the specified exam id
+// The scores are kept until the delete grace period expires, and can be restored with RestoreExam until then
 func DeleteExam(w http.ResponseWriter, r *http.Request) {
 	var err error
 	defer func() {
 		if err != nil {
-			SendGenericInternalServerError(w, r)
+			sendError(err, w, r)
 			return
 		}
 	}()
 
-	vars := mux.Vars(r)
-	id := vars["id"]
-	examID, err := strconv.Atoi(id)
+	examID, err := examIDParam(r)
+	if err != nil {
+		return
+	}
+
+	res, tombstone, err := db.SoftDeleteExam(examID, requestAuditor(r, models.AuditExamDeleted))
 	if err != nil {
 		log.Println(err)
 		return
 	}
 
-	res, err := db.DeleteRows(config.ScoreTable, config.ExamIdx, examID)
+	message := fmt.Sprintf("Successfully deleted %v exams", res)
+	if tombstone != nil {
+		message += fmt.Sprintf(", which can be restored until %s", tombstone.ExpiresAt.Format(time.RFC3339))
+	}
+
+	sendResponse(&models.GenericResponse{Message: message}, http.StatusOK, w)
+}
+
+// RestoreExam undoes the deletion of an exam whose grace period has not expired
+func RestoreExam(w http.ResponseWriter, r *http.Request) {
+	var err error
+	defer func() {
+		if err != nil {
+			sendError(err, w, r)
+			return
+		}
+	}()
+
+	examID, err := examIDParam(r)
+	if err != nil {
+		return
+	}
+
+	restored, skipped, err := db.RestoreExam(examID, requestAuditor(r, models.AuditExamRestored))
+	if err == db.ErrNoTombstone {
+		err = nil
+		SendGenericNotFoundResponse(w, r)
+		return
+	}
 	if err != nil {
 		log.Println(err)
 		return
 	}
 
-	sendResponse(&models.GenericResponse{Message: fmt.Sprintf("Successfully deleted %v exams", res)}, http.StatusOK, w)
+	message := fmt.Sprintf("Successfully restored %v exams", restored)
+	if skipped > 0 {
+		message += fmt.Sprintf(", skipping %v that were recorded again after the exam was deleted", skipped)
+	}
+
+	sendResponse(&models.GenericResponse{Message: message}, http.StatusOK, w)
 }
 
 // GetAllExams gets a list of all examTestData that have been recorded (every record in data store)
@@ -89,11 +217,37 @@ func GetAllExams(w http.ResponseWriter, r *http.Request) {
 	var err error
 	defer func() {
 		if err != nil {
-			SendGenericInternalServerError(w, r)
+			sendError(err, w, r)
 			return
 		}
 	}()
 
+	format, formatErr := exportFormat(r)
+	if formatErr != nil {
+		sendError(invalidParameter("format", formatErr.Error()), w, r)
+		return
+	}
+
+	visible := visibleStudents(r)
+
+	// An empty export is still a valid (header only) export, so the response is started before any rows are read
+	if format != formatJSON {
+		e := newExporter(w, format, examColumns)
+		exportErr := e.begin()
+		if exportErr == nil {
+			exportErr = db.EachRow(config.ScoreTable, config.IdFld, func(row interface{}) error {
+				exam := row.(models.StudentExam)
+				if !visible.Includes(exam.StudentID) {
+					return nil
+				}
+
+				return e.writeExam(exam)
+			})
+		}
+		e.finish(exportErr, r)
+		return
+	}
+
 	res, err := db.GetRows(config.ScoreTable, config.IdFld)
 	if err != nil {
 		log.Println(err)
@@ -102,7 +256,9 @@ func GetAllExams(w http.ResponseWriter, r *http.Request) {
 
 	response := &models.AllExamsListResponse{}
 	for _, score := range res {
-		response.Exams = append(response.Exams, score.(models.StudentExam))
+		if exam := score.(models.StudentExam); visible.Includes(exam.StudentID) {
+			response.Exams = append(response.Exams, exam)
+		}
 	}
 
 	sendResponse(response, http.StatusOK, w)
@@ -113,7 +269,7 @@ func GetAllUniqueExamIDs(w http.ResponseWriter, r *http.Request) {
 	var err error
 	defer func() {
 		if err != nil {
-			SendGenericInternalServerError(w, r)
+			sendError(err, w, r)
 			return
 		}
 	}()
@@ -133,44 +289,121 @@ func GetAllUniqueExamIDs(w http.ResponseWriter, r *http.Request) {
 }
 
 // GetExamByID lists all the results for the specified exam, and provide the average score across all students
+// Scores are graded using the scale named by the "scale" query parameter, or the default scale
+// Passing "scores=curved" reports the curved scores in place of the raw scores, if the exam has been curved
 func GetExamByID(w http.ResponseWriter, r *http.Request) {
 	var err error
 	defer func() {
 		if err != nil {
-			SendGenericInternalServerError(w, r)
+			sendError(err, w, r)
 			return
 		}
 	}()
 
-	vars := mux.Vars(r)
-	id := vars["id"]
-	examID, err := strconv.Atoi(id)
+	examID, err := examIDParam(r)
+	if err != nil {
+		return
+	}
+
+	scale, scaleErr := grading.GetScale(r.URL.Query().Get("scale"))
+	if scaleErr != nil {
+		sendError(invalidParameter("scale", scaleErr.Error()), w, r)
+		return
+	}
+
+	curved, curvedErr := useCurvedScores(r)
+	if curvedErr != nil {
+		sendError(invalidParameter("scores", curvedErr.Error()), w, r)
+		return
+	}
+
+	format, formatErr := exportFormat(r)
+	if formatErr != nil {
+		sendError(invalidParameter("format", formatErr.Error()), w, r)
+		return
+	}
+
+	visible := visibleStudents(r)
+
+	// Streamed exports contain the graded scores without the summary statistics
+	if format != formatJSON {
+		e := newExporter(w, format, scoreColumns)
+		exportErr := db.EachRow(config.ScoreTable, config.ExamIdx, func(row interface{}) error {
+			exam := row.(models.StudentExam)
+			if !visible.Includes(exam.StudentID) {
+				return nil
+			}
+
+			return e.writeScore(exam, curved, scale)
+		}, examID)
+		e.finish(exportErr, r)
+		return
+	}
+
+	response, err := report.Exam(examID, scale, curved, visible)
+	if err == report.ErrNotFound {
+		err = nil
+		SendGenericNotFoundResponse(w, r)
+		return
+	}
 	if err != nil {
 		log.Println(err)
 		return
 	}
 
-	response := &models.ExamByIDResponse{Exam: examID}
-	res, err := db.GetRows(config.ScoreTable, config.ExamIdx, examID)
+	sendResponse(response, http.StatusOK, w)
+}
+
+// GetExamGradeDistribution counts the number of students that received each grade on the specified exam
+func GetExamGradeDistribution(w http.ResponseWriter, r *http.Request) {
+	var err error
+	defer func() {
+		if err != nil {
+			sendError(err, w, r)
+			return
+		}
+	}()
+
+	examID, err := examIDParam(r)
+	if err != nil {
+		return
+	}
+
+	scale, scaleErr := grading.GetScale(r.URL.Query().Get("scale"))
+	if scaleErr != nil {
+		sendError(invalidParameter("scale", scaleErr.Error()), w, r)
+		return
+	}
+
+	curved, curvedErr := useCurvedScores(r)
+	if curvedErr != nil {
+		sendError(invalidParameter("scores", curvedErr.Error()), w, r)
+		return
+	}
+
+	res, err := report.Scores(config.ExamIdx, examID)
 	if err != nil {
 		log.Println(err)
 		return
 	}
 
-	count := len(res)
-	if count == 0 {
+	res = visibleStudents(r).Apply(res)
+	if len(res) == 0 {
 		SendGenericNotFoundResponse(w, r)
 		return
 	}
 
-	var sum float64
+	counts := make(map[string]int)
 	for _, score := range res {
-		response.Scores = append(response.Scores, models.ExamScorePerStudent{Student: score.(models.StudentExam).StudentID, Score: score.(models.StudentExam).Score})
-		sum += score.(models.StudentExam).Score
+		counts[scale.Grade(score.Value(curved))]++
 	}
 
-	avg := sum / float64(count)
-	response.Average = avg
+	// Every band is included, in scale order, so grades nobody received are reported with a zero count
+	response := &models.GradeDistributionResponse{Exam: examID, Scale: scale.Name, ScoreType: report.ScoreType(curved), Total: len(res)}
+	for _, band := range scale.Bands {
+		count := counts[band.Grade]
+		response.Distribution = append(response.Distribution, models.GradeCount{Grade: band.Grade, Count: count, Percent: float64(count) / float64(len(res)) * 100})
+	}
 
 	sendResponse(response, http.StatusOK, w)
 }
@@ -10,6 +10,7 @@ import (
 	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 )
 
@@ -53,7 +54,9 @@ func addExamTestRoutes() (*mux.Router, error) {
 	router.HandleFunc("/exams", GetAllUniqueExamIDs).Methods("GET")
 	router.HandleFunc("/exams/all", GetAllExams).Methods("GET")
 	router.HandleFunc("/exams/{id}", GetExamByID).Methods("GET")
+	router.HandleFunc("/exams/{id}/grade-distribution", GetExamGradeDistribution).Methods("GET")
 	router.HandleFunc("/exams/{id}", DeleteExam).Methods("DELETE")
+	router.HandleFunc("/exams/{id}/restore", RestoreExam).Methods("POST")
 	router.HandleFunc("/exams", AddExam).Methods("POST")
 
 	return router, nil
@@ -178,6 +181,60 @@ func TestGetExamByID(t *testing.T) {
 	}
 }
 
+func TestGetExamGradeDistribution(t *testing.T) {
+	router, err := addExamTestRoutes()
+	if err != nil {
+		t.Errorf("Failed to start server")
+	}
+
+	request, _ := http.NewRequest("GET", "/exams/1/grade-distribution?scale=pass_fail", nil)
+	response := httptest.NewRecorder()
+	router.ServeHTTP(response, request)
+
+	// Verify status code is 200
+	have := response.Code
+	want := 200
+	if have != want {
+		t.Errorf("HTTP status is not OK; have %v, want %v", response.Code, want)
+	}
+
+	body := models.GradeDistributionResponse{}
+	resBytes, err := ioutil.ReadAll(response.Body)
+	if err != nil {
+		t.Errorf("Error reading response body")
+	}
+	err = json.Unmarshal(resBytes, &body)
+	if err != nil {
+		t.Errorf("Error parsing response body")
+	}
+
+	// Verify every band of the scale is returned
+	have = len(body.Distribution)
+	want = 2
+	if have != want {
+		t.Fatalf("Incorrect number of grades returned; have %v, want %v", have, want)
+	}
+
+	// Verify all three scores passed
+	have = body.Distribution[0].Count
+	want = 3
+	if have != want || body.Distribution[0].Grade != "Pass" {
+		t.Errorf("Incorrect distribution; have %+v", body.Distribution)
+	}
+
+	// Test with an unknown scale
+	request, _ = http.NewRequest("GET", "/exams/1/grade-distribution?scale=does_not_exist", nil)
+	response = httptest.NewRecorder()
+	router.ServeHTTP(response, request)
+
+	// Verify status code is 400
+	have = response.Code
+	want = 400
+	if have != want {
+		t.Errorf("Route returned an incorrect status code; have %v, want %v", have, want)
+	}
+}
+
 func TestAddExam(t *testing.T) {
 	router, err := addExamTestRoutes()
 	if err != nil {
@@ -187,7 +244,7 @@ func TestAddExam(t *testing.T) {
 	exam := models.StudentExam{
 		Exam:      1,
 		StudentID: "test.person5",
-		Score:     100,
+		Score:     1,
 	}
 
 	j, err := json.Marshal(exam)
@@ -209,6 +266,24 @@ func TestAddExam(t *testing.T) {
 	}
 }
 
+func TestAddExamOutOfRange(t *testing.T) {
+	router, err := addExamTestRoutes()
+	if err != nil {
+		t.Errorf("Failed to start server")
+	}
+
+	for _, body := range []string{`{"exam": 1, "studentid": "test.person5", "score": 100}`, `{"exam": 1, "studentid": "test.person5", "score": -0.5}`} {
+		request, _ := http.NewRequest("POST", "/exams", strings.NewReader(body))
+		response := httptest.NewRecorder()
+		router.ServeHTTP(response, request)
+
+		problem := readProblem(t, response, 422)
+		if problem.Code != CodeValidationFailed || len(problem.Errors) != 1 || problem.Errors[0].Field != "score" || problem.Errors[0].Code != FieldInvalid {
+			t.Errorf("Incorrect problem returned for %v; have: %+v", body, problem)
+		}
+	}
+}
+
 func TestDeleteExam(t *testing.T) {
 	router, err := addExamTestRoutes()
 	if err != nil {
@@ -226,3 +301,121 @@ func TestDeleteExam(t *testing.T) {
 		t.Errorf("HTTP status is not OK; have %v, want %v", response.Code, want)
 	}
 }
+
+func TestAddExamBatch(t *testing.T) {
+	router, err := addExamTestRoutes()
+	if err != nil {
+		t.Errorf("Failed to start server")
+	}
+
+	exams := []models.StudentExam{
+		{Exam: 3, StudentID: "test.person5", Score: 0.8},
+		{Exam: 3, StudentID: "", Score: 0.7},
+		{Exam: 3, StudentID: "test.person6", Score: 0.9},
+	}
+	j, err := json.Marshal(exams)
+	if err != nil {
+		t.Errorf("Cannot marshal request struct")
+		return
+	}
+
+	// Verify an atomic batch with an invalid exam records nothing
+	request, _ := http.NewRequest("POST", "/exams", bytes.NewBuffer(j))
+	response := httptest.NewRecorder()
+	router.ServeHTTP(response, request)
+
+	have := response.Code
+	want := 422
+	if have != want {
+		t.Errorf("HTTP status is not Unprocessable Entity; have %v, want %v", response.Code, want)
+	}
+
+	body := models.BatchExamResponse{}
+	resBytes, _ := ioutil.ReadAll(response.Body)
+	err = json.Unmarshal(resBytes, &body)
+	if err != nil || len(body.Results) != 3 {
+		t.Fatalf("Error parsing response body")
+	}
+	if body.Results[0].Status != "not_recorded" || body.Results[1].Status != "invalid" || body.Succeeded != 0 {
+		t.Errorf("Incorrect results; have: %+v", body)
+	}
+
+	res, _ := db.GetRows(config.ScoreTable, config.ExamIdx, 3)
+	if len(res) != 0 {
+		t.Errorf("No exams should have been recorded; have: %v", len(res))
+	}
+
+	// Verify a best effort batch records the valid exams
+	request, _ = http.NewRequest("POST", "/exams?mode=best_effort", bytes.NewBuffer(j))
+	response = httptest.NewRecorder()
+	router.ServeHTTP(response, request)
+
+	have = response.Code
+	want = 200
+	if have != want {
+		t.Errorf("HTTP status is not OK; have %v, want %v", response.Code, want)
+	}
+
+	body = models.BatchExamResponse{}
+	resBytes, _ = ioutil.ReadAll(response.Body)
+	err = json.Unmarshal(resBytes, &body)
+	if err != nil || body.Succeeded != 2 || body.Failed != 1 || body.Results[2].Status != "recorded" {
+		t.Errorf("Incorrect results; have: %+v", body)
+	}
+
+	res, _ = db.GetRows(config.ScoreTable, config.ExamIdx, 3)
+	if len(res) != 2 {
+		t.Errorf("Incorrect number of exams recorded; have: %v, want: 2", len(res))
+	}
+
+	// Verify an invalid mode is rejected
+	request, _ = http.NewRequest("POST", "/exams?mode=foo", bytes.NewBuffer(j))
+	response = httptest.NewRecorder()
+	router.ServeHTTP(response, request)
+
+	have = response.Code
+	want = 400
+	if have != want {
+		t.Errorf("HTTP status is not Bad Request; have %v, want %v", response.Code, want)
+	}
+}
+
+func TestRestoreExam(t *testing.T) {
+	router, err := addExamTestRoutes()
+	if err != nil {
+		t.Errorf("Failed to start server")
+	}
+
+	// Verify an exam that was not deleted cannot be restored
+	request, _ := http.NewRequest("POST", "/exams/1/restore", nil)
+	response := httptest.NewRecorder()
+	router.ServeHTTP(response, request)
+
+	have := response.Code
+	want := 404
+	if have != want {
+		t.Errorf("HTTP status is not Not Found; have %v, want %v", response.Code, want)
+	}
+
+	request, _ = http.NewRequest("DELETE", "/exams/1", nil)
+	response = httptest.NewRecorder()
+	router.ServeHTTP(response, request)
+
+	// Verify the deleted exam is restored
+	request, _ = http.NewRequest("POST", "/exams/1/restore", nil)
+	response = httptest.NewRecorder()
+	router.ServeHTTP(response, request)
+
+	have = response.Code
+	want = 200
+	if have != want {
+		t.Errorf("HTTP status is not OK; have %v, want %v", response.Code, want)
+	}
+
+	res, _ := db.GetRows(config.ScoreTable, config.ExamIdx, 1)
+	have = len(res)
+	want = 3
+	if have != want {
+		t.Errorf("Incorrect number of scores restored; have: %v, want: %v", have, want)
+	}
+}
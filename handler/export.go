@@ -0,0 +1,212 @@
+package handler
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"log"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kylegk/sse-rest-server/grading"
+	"github.com/kylegk/sse-rest-server/models"
+)
+
+// Define the supported response formats
+const (
+	formatJSON   = "json"
+	formatCSV    = "csv"
+	formatNDJSON = "ndjson"
+)
+
+// The content type of each streamed format
+var exportContentTypes = map[string]string{
+	formatCSV:    "text/csv; charset=UTF-8",
+	formatNDJSON: "application/x-ndjson",
+}
+
+// The format of each media type an Accept header can ask for
+var acceptFormats = map[string]string{
+	"text/csv":             formatCSV,
+	"application/x-ndjson": formatNDJSON,
+	"application/json":     formatJSON,
+	"*/*":                  formatJSON,
+}
+
+// The CSV columns of each exported row type
+var (
+	examColumns  = []string{"exam", "studentid", "score", "curved", "recorded_at"}
+	scoreColumns = []string{"exam", "student", "score", "grade"}
+)
+
+// Streamed rows are flushed to the client in batches of this size
+const exportFlushRows = 100
+
+// Determine the response format from the "format" query parameter, falling back to the Accept header
+// Requests that do not ask for CSV or NDJSON, or only with a quality of 0, receive the default JSON response
+func exportFormat(r *http.Request) (string, error) {
+	switch format := r.URL.Query().Get("format"); format {
+	case "":
+	case formatJSON, formatCSV, formatNDJSON:
+		return format, nil
+	default:
+		return "", fmt.Errorf("invalid format: must be json, csv or ndjson")
+	}
+
+	// The supported media type with the highest quality is used, or the first of them when their qualities are equal
+	format, quality := formatJSON, 0.0
+	for _, accepted := range strings.Split(r.Header.Get("Accept"), ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(accepted))
+		if err != nil {
+			continue
+		}
+
+		accept, ok := acceptFormats[mediaType]
+		if !ok {
+			continue
+		}
+
+		q := 1.0
+		if params["q"] != "" {
+			q, err = strconv.ParseFloat(params["q"], 64)
+			if err != nil {
+				continue
+			}
+		}
+		if q > quality {
+			format, quality = accept, q
+		}
+	}
+
+	return format, nil
+}
+
+// exporter streams rows to the client as CSV or NDJSON
+// The response is only started when the first row is written (or begin is called), so a handler can still send
+// an error response if there turn out to be no rows
+type exporter struct {
+	w       http.ResponseWriter
+	format  string
+	columns []string
+	csv     *csv.Writer
+	json    *json.Encoder
+	started bool
+	rows    int
+}
+
+func newExporter(w http.ResponseWriter, format string, columns []string) *exporter {
+	return &exporter{w: w, format: format, columns: columns}
+}
+
+// Send the headers, and the header row of a CSV export
+func (e *exporter) begin() error {
+	if e.started {
+		return nil
+	}
+	e.started = true
+
+	e.w.Header().Set("Content-Type", exportContentTypes[e.format])
+	e.w.WriteHeader(http.StatusOK)
+
+	if e.format == formatCSV {
+		e.csv = csv.NewWriter(e.w)
+		return e.csv.Write(e.columns)
+	}
+	e.json = json.NewEncoder(e.w)
+
+	return nil
+}
+
+// Write a single row; record is encoded for NDJSON and values, in the order of the columns, are written for CSV
+func (e *exporter) write(record interface{}, values []string) error {
+	err := e.begin()
+	if err != nil {
+		return err
+	}
+
+	if e.format == formatCSV {
+		err = e.csv.Write(values)
+	} else {
+		err = e.json.Encode(record)
+	}
+	if err != nil {
+		return err
+	}
+
+	e.rows++
+	if e.rows%exportFlushRows == 0 {
+		return e.flush()
+	}
+
+	return nil
+}
+
+// Send any buffered rows to the client
+func (e *exporter) flush() error {
+	if e.csv != nil {
+		e.csv.Flush()
+		if err := e.csv.Error(); err != nil {
+			return err
+		}
+	}
+	if f, ok := e.w.(http.Flusher); ok {
+		f.Flush()
+	}
+
+	return nil
+}
+
+// Write a stored score as it is kept in the database
+func (e *exporter) writeExam(exam models.StudentExam) error {
+	curved := ""
+	if exam.Curved != nil {
+		curved = formatFloat(*exam.Curved)
+	}
+
+	return e.write(exam, []string{strconv.Itoa(exam.Exam), csvText(exam.StudentID), formatFloat(exam.Score), curved, exam.RecordedAt.Format(time.RFC3339Nano)})
+}
+
+// Write a raw or curved score, graded with the scale
+func (e *exporter) writeScore(exam models.StudentExam, curved bool, scale grading.Scale) error {
+	value := exam.Value(curved)
+	row := models.ScoreRow{Exam: exam.Exam, Student: exam.StudentID, Score: value, Grade: scale.Grade(value)}
+
+	return e.write(row, []string{strconv.Itoa(row.Exam), csvText(row.Student), formatFloat(row.Score), csvText(row.Grade)})
+}
+
+// Complete an export, sending an error response instead if the export failed or found nothing before the response was started
+func (e *exporter) finish(err error, r *http.Request) {
+	if err != nil {
+		log.Println(err)
+		if !e.started {
+			SendGenericInternalServerError(e.w, r)
+		}
+		return
+	}
+
+	if !e.started {
+		SendGenericNotFoundResponse(e.w, r)
+		return
+	}
+
+	err = e.flush()
+	if err != nil {
+		log.Println(err)
+	}
+}
+
+// Escape a text cell that a spreadsheet would run as a formula, by prefixing it with a quote
+func csvText(value string) string {
+	if value != "" && strings.ContainsRune("=+-@\t\r", rune(value[0])) {
+		return "'" + value
+	}
+
+	return value
+}
+
+func formatFloat(value float64) string {
+	return strconv.FormatFloat(value, 'f', -1, 64)
+}
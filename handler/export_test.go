@@ -0,0 +1,205 @@
+package handler
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/kylegk/sse-rest-server/models"
+)
+
+// TestExportFormat validates the format parameter takes precedence over the Accept header
+func TestExportFormat(t *testing.T) {
+	tests := []struct {
+		url    string
+		accept string
+		want   string
+	}{
+		{"/exams/all", "", formatJSON},
+		{"/exams/all", "text/csv", formatCSV},
+		{"/exams/all", "application/x-ndjson; charset=utf-8", formatNDJSON},
+		{"/exams/all", "application/json, text/csv", formatJSON},
+		{"/exams/all", "text/html", formatJSON},
+		{"/exams/all?format=ndjson", "text/csv", formatNDJSON},
+		{"/exams/all", "application/json;q=0.5, text/csv", formatCSV},
+		{"/exams/all", "text/csv;q=0.2, application/x-ndjson;q=0.8", formatNDJSON},
+		{"/exams/all", "text/csv;q=0, */*", formatJSON},
+		{"/exams/all", "text/csv;q=0", formatJSON},
+	}
+
+	for _, test := range tests {
+		request, _ := http.NewRequest("GET", test.url, nil)
+		request.Header.Set("Accept", test.accept)
+		have, err := exportFormat(request)
+		if err != nil || have != test.want {
+			t.Errorf("Incorrect format for %s (%s); have: %v, want: %v", test.url, test.accept, have, test.want)
+		}
+	}
+
+	request, _ := http.NewRequest("GET", "/exams/all?format=xml", nil)
+	_, err := exportFormat(request)
+	if err == nil {
+		t.Errorf("The format should have been rejected")
+	}
+}
+
+// TestExportCSVFormulas validates that text cells a spreadsheet would run as a formula are escaped
+func TestExportCSVFormulas(t *testing.T) {
+	response := httptest.NewRecorder()
+	e := newExporter(response, formatCSV, examColumns)
+	for _, student := range []string{"=HYPERLINK(\"http://example.com\")", "+1", "-1", "@SUM(A1)", "test.person1"} {
+		err := e.writeExam(models.StudentExam{Exam: 1, StudentID: student, Score: 0.5})
+		if err != nil {
+			t.Fatalf("Failed to write the row: %v", err)
+		}
+	}
+	e.finish(nil, nil)
+
+	records, err := csv.NewReader(response.Body).ReadAll()
+	if err != nil || len(records) != 6 {
+		t.Fatalf("Failed to parse the CSV response; have: %v, %v", records, err)
+	}
+
+	want := []string{"'=HYPERLINK(\"http://example.com\")", "'+1", "'-1", "'@SUM(A1)", "test.person1"}
+	for i, student := range want {
+		if records[i+1][1] != student {
+			t.Errorf("Incorrect cell; have: %v, want: %v", records[i+1][1], student)
+		}
+	}
+}
+
+func TestExportAllExamsCSV(t *testing.T) {
+	router, err := addExamTestRoutes()
+	if err != nil {
+		t.Errorf("Failed to start server")
+	}
+
+	request, _ := http.NewRequest("GET", "/exams/all", nil)
+	request.Header.Set("Accept", "text/csv")
+	response := httptest.NewRecorder()
+	router.ServeHTTP(response, request)
+
+	// Verify status code is 200
+	have := response.Code
+	want := 200
+	if have != want {
+		t.Errorf("HTTP status is not OK; have: %v, want: %v", have, want)
+	}
+	if response.Header().Get("Content-Type") != "text/csv; charset=UTF-8" {
+		t.Errorf("Incorrect content type; have: %v", response.Header().Get("Content-Type"))
+	}
+
+	records, err := csv.NewReader(response.Body).ReadAll()
+	if err != nil {
+		t.Fatalf("Failed to parse the CSV response")
+	}
+
+	// Verify the header row and a row for every score
+	have = len(records)
+	want = len(examTestData) + 1
+	if have != want {
+		t.Fatalf("Incorrect number of rows; have: %v, want: %v", have, want)
+	}
+	if records[0][0] != "exam" || records[1][0] != "1" || records[1][2] != "0.67" {
+		t.Errorf("Incorrect rows; have: %v", records)
+	}
+}
+
+func TestExportExamNDJSON(t *testing.T) {
+	router, err := addExamTestRoutes()
+	if err != nil {
+		t.Errorf("Failed to start server")
+	}
+
+	request, _ := http.NewRequest("GET", "/exams/1?format=ndjson", nil)
+	response := httptest.NewRecorder()
+	router.ServeHTTP(response, request)
+
+	// Verify status code is 200
+	have := response.Code
+	want := 200
+	if have != want {
+		t.Errorf("HTTP status is not OK; have: %v, want: %v", have, want)
+	}
+
+	rows := make([]models.ScoreRow, 0)
+	scanner := bufio.NewScanner(response.Body)
+	for scanner.Scan() {
+		row := models.ScoreRow{}
+		err = json.Unmarshal(scanner.Bytes(), &row)
+		if err != nil {
+			t.Fatalf("Failed to parse row: %s", scanner.Text())
+		}
+		rows = append(rows, row)
+	}
+
+	// Verify each score of exam 1 was written and graded
+	have = len(rows)
+	want = 3
+	if have != want {
+		t.Fatalf("Incorrect number of rows; have: %v, want: %v", have, want)
+	}
+	if rows[0].Exam != 1 || rows[0].Grade == "" {
+		t.Errorf("Incorrect row; have: %+v", rows[0])
+	}
+
+	// Verify an unknown exam is not found
+	request, _ = http.NewRequest("GET", "/exams/99?format=ndjson", nil)
+	response = httptest.NewRecorder()
+	router.ServeHTTP(response, request)
+
+	have = response.Code
+	want = 404
+	if have != want {
+		t.Errorf("HTTP status is not Not Found; have: %v, want: %v", have, want)
+	}
+}
+
+func TestExportStudentCSV(t *testing.T) {
+	router, err := addStudentTestRoutes()
+	if err != nil {
+		t.Errorf("Failed to start server")
+	}
+
+	request, _ := http.NewRequest("GET", "/students/test.person1?format=csv&scale=pass_fail", nil)
+	response := httptest.NewRecorder()
+	router.ServeHTTP(response, request)
+
+	// Verify status code is 200
+	have := response.Code
+	want := 200
+	if have != want {
+		t.Errorf("HTTP status is not OK; have: %v, want: %v", have, want)
+	}
+
+	records, err := csv.NewReader(response.Body).ReadAll()
+	if err != nil {
+		t.Fatalf("Failed to parse the CSV response")
+	}
+
+	// Verify the header row and both of the student's scores, graded with the requested scale
+	have = len(records)
+	want = 3
+	if have != want {
+		t.Fatalf("Incorrect number of rows; have: %v, want: %v", have, want)
+	}
+	for _, record := range records[1:] {
+		if record[1] != "test.person1" || (record[3] != "Pass" && record[3] != "Fail") {
+			t.Errorf("Incorrect row; have: %v", record)
+		}
+	}
+
+	// Verify an invalid format is rejected
+	request, _ = http.NewRequest("GET", "/students/test.person1?format=xml", nil)
+	response = httptest.NewRecorder()
+	router.ServeHTTP(response, request)
+
+	have = response.Code
+	want = 400
+	if have != want {
+		t.Errorf("HTTP status is not Bad Request; have: %v, want: %v", have, want)
+	}
+}
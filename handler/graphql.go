@@ -0,0 +1,94 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"mime"
+	"net/http"
+
+	"github.com/kylegk/sse-rest-server/gql"
+)
+
+// GraphQL runs a GraphQL operation sent in the "query", "variables" and "operationName" query parameters of a GET request,
+// or in the JSON body of a POST request
+// Subscriptions are streamed as server-sent events, with a "next" event for every result and a "complete" event when the stream ends
+func GraphQL(w http.ResponseWriter, r *http.Request) {
+	req, err := graphQLRequest(r)
+	if err != nil {
+		sendError(err, w, r)
+		return
+	}
+
+	// Teachers only see the students of their cohorts, wherever the operation reaches their scores
+	r = r.WithContext(gql.WithFilter(r.Context(), visibleStudents(r)))
+	if gql.IsSubscription(req) {
+		streamGraphQL(w, r, req)
+		return
+	}
+
+	// Errors in the query are reported in the result, as GraphQL clients expect
+	sendResponse(gql.Execute(r.Context(), req), http.StatusOK, w)
+}
+
+// Read the operation from the query parameters or the body of a request
+func graphQLRequest(r *http.Request) (gql.Request, error) {
+	req := gql.Request{}
+	if r.Method == http.MethodGet {
+		query := r.URL.Query()
+		req.Query = query.Get("query")
+		req.OperationName = query.Get("operationName")
+		if query.Get("variables") != "" && json.Unmarshal([]byte(query.Get("variables")), &req.Variables) != nil {
+			return req, invalidParameter("variables", "invalid variables: must be a JSON object")
+		}
+	} else {
+		bytes, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			log.Println(err)
+			return req, err
+		}
+
+		mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+		if mediaType == "application/graphql" {
+			req.Query = string(bytes)
+		} else if json.Unmarshal(bytes, &req) != nil {
+			return req, malformedBody("unable to parse request")
+		}
+	}
+
+	if req.Query == "" {
+		return req, invalidParameter("query", "invalid query: a query is required")
+	}
+
+	return req, nil
+}
+
+// Stream the results of a subscription until the client disconnects
+func streamGraphQL(w http.ResponseWriter, r *http.Request, req gql.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		sendError(invalidRequest("subscriptions require a connection that can be streamed"), w, r)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	// The results are read until the channel is closed, even once writing fails, so the subscription can finish
+	for result := range gql.Subscribe(r.Context(), req) {
+		data, err := json.Marshal(result)
+		if err != nil {
+			log.Println(err)
+			continue
+		}
+
+		fmt.Fprintf(w, "event: next\ndata: %s\n\n", data)
+		flusher.Flush()
+	}
+
+	fmt.Fprint(w, "event: complete\ndata:\n\n")
+	flusher.Flush()
+}
@@ -0,0 +1,175 @@
+package handler
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/kylegk/sse-rest-server/config"
+	"github.com/kylegk/sse-rest-server/db"
+	"github.com/kylegk/sse-rest-server/models"
+)
+
+func addGraphQLTestRoutes() (*mux.Router, error) {
+	err := db.InitDB(config.DBSchema)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, exam := range studentTestData {
+		err = db.UpsertRow(config.ScoreTable, exam)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	router := mux.NewRouter()
+	router.HandleFunc("/graphql", GraphQL).Methods("GET", "POST")
+
+	return router, nil
+}
+
+type graphQLTestResult struct {
+	Data struct {
+		Student *struct {
+			ID      string
+			Average float64
+			Exams   []struct{ ID int }
+		}
+	}
+	Errors []struct{ Message string }
+}
+
+func TestGraphQL(t *testing.T) {
+	router, err := addGraphQLTestRoutes()
+	if err != nil {
+		t.Errorf("Failed to start server")
+	}
+
+	query := `query ($id: String!) { student(id: $id) { id average exams { id } } }`
+	variables := `{"id":"test.person1"}`
+	body, _ := json.Marshal(map[string]interface{}{"query": query, "variables": json.RawMessage(variables)})
+
+	requests := map[string]*http.Request{}
+	requests["POST"], _ = http.NewRequest("POST", "/graphql", bytes.NewReader(body))
+	requests["POST"].Header.Set("Content-Type", "application/json")
+	requests["GET"], _ = http.NewRequest("GET", "/graphql?query="+url.QueryEscape(query)+"&variables="+url.QueryEscape(variables), nil)
+
+	for method, request := range requests {
+		response := httptest.NewRecorder()
+		router.ServeHTTP(response, request)
+
+		if response.Code != 200 {
+			t.Errorf("HTTP status is incorrect for %v; have: %v, want: %v", method, response.Code, 200)
+		}
+
+		result := graphQLTestResult{}
+		resBytes, _ := ioutil.ReadAll(response.Body)
+		err = json.Unmarshal(resBytes, &result)
+		if err != nil {
+			t.Errorf("Unable to parse the result; %v", err)
+		}
+
+		if result.Data.Student == nil || result.Data.Student.ID != "test.person1" || result.Data.Student.Average != 0.7 || len(result.Data.Student.Exams) != 2 {
+			t.Errorf("Incorrect student returned for %v; have: %s", method, resBytes)
+		}
+	}
+
+	// Verify a query sent as application/graphql is accepted, and that errors in it are reported in the result
+	request, _ := http.NewRequest("POST", "/graphql", strings.NewReader(`{ student(id: "test.person1") { name } }`))
+	request.Header.Set("Content-Type", "application/graphql")
+	response := httptest.NewRecorder()
+	router.ServeHTTP(response, request)
+
+	result := graphQLTestResult{}
+	resBytes, _ := ioutil.ReadAll(response.Body)
+	_ = json.Unmarshal(resBytes, &result)
+	if response.Code != 200 || len(result.Errors) == 0 {
+		t.Errorf("Invalid field was not reported; have: %v %s", response.Code, resBytes)
+	}
+
+	// Verify a request without a query is rejected
+	request, _ = http.NewRequest("POST", "/graphql", strings.NewReader(`{"variables":{}}`))
+	response = httptest.NewRecorder()
+	router.ServeHTTP(response, request)
+
+	problem := readProblem(t, response, 400)
+	if problem.Code != CodeInvalidParameter {
+		t.Errorf("Incorrect problem returned; have: %+v", problem)
+	}
+}
+
+func TestGraphQLSubscription(t *testing.T) {
+	router, err := addGraphQLTestRoutes()
+	if err != nil {
+		t.Errorf("Failed to start server")
+	}
+
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	query := `subscription { scoreChanged(student: "test.person5") { type after { score } } }`
+	request, _ := http.NewRequest("GET", server.URL+"/graphql?query="+url.QueryEscape(query), nil)
+	request = request.WithContext(ctx)
+	response, err := http.DefaultClient.Do(request)
+	if err != nil {
+		t.Fatalf("Failed to subscribe; %v", err)
+	}
+	defer response.Body.Close()
+
+	contentType := response.Header.Get("Content-Type")
+	if contentType != "text/event-stream" {
+		t.Errorf("Content type is incorrect; have: %v, want: %v", contentType, "text/event-stream")
+	}
+
+	// The subscription is registered once the stream has started, which cannot be observed here, so the score is recorded until it arrives
+	done := make(chan bool)
+	defer close(done)
+	go func() {
+		for {
+			select {
+			case <-done:
+				return
+			case <-time.After(10 * time.Millisecond):
+				_ = db.UpsertRow(config.ScoreTable, models.StudentExam{Exam: 3, StudentID: "test.person5", Score: 0.4})
+			}
+		}
+	}()
+
+	lines := make(chan string)
+	go func() {
+		scanner := bufio.NewScanner(response.Body)
+		for scanner.Scan() {
+			lines <- scanner.Text()
+		}
+		close(lines)
+	}()
+
+	want := []string{"event: next", `data: {"data":{"scoreChanged":{"after":{"score":0.4},"type":"score.upserted"}}}`}
+	for _, line := range want {
+		select {
+		case have := <-lines:
+			if have != line {
+				t.Errorf("Incorrect event received; have: %v, want: %v", have, line)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("No event received")
+		}
+	}
+
+	cancel()
+	for range lines {
+	}
+}
@@ -0,0 +1,245 @@
+package handler
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kylegk/sse-rest-server/config"
+	"github.com/kylegk/sse-rest-server/db"
+	"github.com/kylegk/sse-rest-server/models"
+)
+
+// Define the headers used by idempotent requests
+const (
+	IdempotencyKeyHeader      = "Idempotency-Key"
+	IdempotencyReplayedHeader = "Idempotent-Replayed"
+)
+
+// The longest Idempotency-Key accepted
+const maxIdempotencyKeyLength = 255
+
+// IdempotencyWindow is how long the response to an idempotent request is replayed to retries of the request
+var IdempotencyWindow = 24 * time.Hour
+
+var (
+	idempotencyMu sync.Mutex
+	// The keys of the requests that are still being handled
+	inFlight = make(map[string]bool)
+	// Expired responses are removed at most once a minute
+	lastIdempotencyPurge time.Time
+)
+
+// Idempotency is a middleware that makes write requests sent with an Idempotency-Key header safe to retry
+// The first response to a key is cached for the IdempotencyWindow and replayed to any retry, without the request being handled again
+// Server errors are not cached, so a request that failed can be retried, and neither are streamed responses, such as GraphQL subscriptions,
+// or responses marked Cache-Control: no-store, such as those holding a newly created secret
+func Idempotency(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get(IdempotencyKeyHeader)
+		if key == "" || r.Method == http.MethodGet || r.Method == http.MethodHead || r.Method == http.MethodOptions {
+			h.ServeHTTP(w, r)
+			return
+		}
+
+		if len(key) > maxIdempotencyKeyLength {
+			sendError(invalidParameter("Idempotency-Key", "invalid Idempotency-Key: must be at most 255 characters"), w, r)
+			return
+		}
+
+		body, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			log.Println(err)
+			SendGenericInternalServerError(w, r)
+			return
+		}
+		r.Body = ioutil.NopCloser(bytes.NewReader(body))
+		fingerprint := requestFingerprint(r, body)
+
+		// Keys are scoped to the client of the request, so one client cannot replay another's response
+		if principal, ok := requestPrincipal(r); ok {
+			key = principal.ID + "/" + key
+		}
+
+		cached, claimed, err := claimIdempotencyKey(key)
+		if err != nil {
+			log.Println(err)
+			SendGenericInternalServerError(w, r)
+			return
+		}
+
+		switch {
+		case cached == nil && !claimed:
+			sendError(conflict("a request with this Idempotency-Key is still being processed"), w, r)
+			return
+		case cached != nil && cached.Fingerprint != fingerprint:
+			sendError(&apiError{status: http.StatusUnprocessableEntity, code: CodeKeyReused, detail: "the Idempotency-Key has already been used for a different request"}, w, r)
+			return
+		case cached != nil:
+			for name, values := range cached.Header {
+				w.Header()[name] = values
+			}
+			w.Header().Set(IdempotencyReplayedHeader, "true")
+			w.WriteHeader(cached.Status)
+			w.Write(cached.Body)
+			return
+		}
+		defer releaseIdempotencyKey(key)
+
+		// The headers set before the handler runs, such as the rate limit headers, describe this request rather than the response
+		before := w.Header().Clone()
+		recorder := &responseRecorder{ResponseWriter: w, status: http.StatusOK}
+		h.ServeHTTP(recorder, r)
+
+		if recorder.status >= 500 || recorder.streamed || noStore(recorder.Header()) {
+			return
+		}
+
+		now := time.Now().UTC()
+		err = db.UpsertRow(config.IdempotencyTable, models.IdempotentResponse{
+			Key:         key,
+			Fingerprint: fingerprint,
+			Status:      recorder.status,
+			Header:      handlerHeaders(before, recorder.Header()),
+			Body:        recorder.body.Bytes(),
+			CreatedAt:   now,
+			ExpiresAt:   now.Add(IdempotencyWindow),
+		})
+		if err != nil {
+			log.Println(err)
+		}
+	})
+}
+
+// responseRecorder copies a response as it is written to the client
+// Once a response is flushed it is streamed, so it is no longer copied
+type responseRecorder struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+	streamed    bool
+	body        bytes.Buffer
+}
+
+func (rec *responseRecorder) WriteHeader(status int) {
+	if !rec.wroteHeader {
+		rec.status = status
+		rec.wroteHeader = true
+	}
+	rec.ResponseWriter.WriteHeader(status)
+}
+
+func (rec *responseRecorder) Write(b []byte) (int, error) {
+	rec.wroteHeader = true
+	if !rec.streamed {
+		rec.body.Write(b)
+	}
+
+	return rec.ResponseWriter.Write(b)
+}
+
+// Flush streams the response written so far, if the client's connection can be streamed
+func (rec *responseRecorder) Flush() {
+	flusher, ok := rec.ResponseWriter.(http.Flusher)
+	if !ok {
+		return
+	}
+
+	rec.streamed = true
+	rec.body.Reset()
+	flusher.Flush()
+}
+
+// Check whether a response must not be stored, as it holds a secret that is only returned once
+func noStore(header http.Header) bool {
+	for _, directive := range strings.Split(header.Get("Cache-Control"), ",") {
+		if strings.EqualFold(strings.TrimSpace(directive), "no-store") {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Collect the headers the handler added or changed, so they can be replayed with the response
+func handlerHeaders(before, after http.Header) map[string][]string {
+	headers := make(map[string][]string)
+	for name, values := range after {
+		if strings.Join(before[name], ",") != strings.Join(values, ",") {
+			headers[name] = append([]string(nil), values...)
+		}
+	}
+
+	return headers
+}
+
+// Identify a request by its method, path, query and body
+func requestFingerprint(r *http.Request, body []byte) string {
+	hash := sha256.New()
+	hash.Write([]byte(r.Method + " " + r.URL.RequestURI() + "\n"))
+	hash.Write(body)
+
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// Look up the cached response for a key, or claim the key if there is none and it is not held by a request in flight
+func claimIdempotencyKey(key string) (*models.IdempotentResponse, bool, error) {
+	idempotencyMu.Lock()
+	defer idempotencyMu.Unlock()
+
+	now := time.Now()
+	if now.Sub(lastIdempotencyPurge) >= time.Minute {
+		lastIdempotencyPurge = now
+		purgeIdempotentResponses(now)
+	}
+
+	res, err := db.GetRows(config.IdempotencyTable, config.IdFld, key)
+	if err != nil {
+		return nil, false, err
+	}
+	if len(res) > 0 {
+		cached := res[0].(models.IdempotentResponse)
+		if cached.ExpiresAt.After(now) {
+			return &cached, false, nil
+		}
+	}
+
+	if inFlight[key] {
+		return nil, false, nil
+	}
+	inFlight[key] = true
+
+	return nil, true, nil
+}
+
+// Release a claimed key once its response has been cached
+func releaseIdempotencyKey(key string) {
+	idempotencyMu.Lock()
+	defer idempotencyMu.Unlock()
+
+	delete(inFlight, key)
+}
+
+// Remove the cached responses whose window has expired
+func purgeIdempotentResponses(now time.Time) {
+	res, err := db.GetRows(config.IdempotencyTable, config.IdFld)
+	if err != nil {
+		log.Println(err)
+		return
+	}
+
+	for _, row := range res {
+		if !row.(models.IdempotentResponse).ExpiresAt.After(now) {
+			err = db.DeleteRow(config.IdempotencyTable, row)
+			if err != nil {
+				log.Println(err)
+			}
+		}
+	}
+}
@@ -0,0 +1,196 @@
+package handler
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/kylegk/sse-rest-server/config"
+	"github.com/kylegk/sse-rest-server/db"
+)
+
+func addIdempotencyTestRoutes() (*mux.Router, error) {
+	router, err := addExamTestRoutes()
+	if err != nil {
+		return nil, err
+	}
+	router.Use(Idempotency)
+
+	return router, nil
+}
+
+func sendIdempotentRequest(router *mux.Router, method string, url string, key string, body string) *httptest.ResponseRecorder {
+	request, _ := http.NewRequest(method, url, strings.NewReader(body))
+	request.Header.Set(IdempotencyKeyHeader, key)
+	response := httptest.NewRecorder()
+	router.ServeHTTP(response, request)
+
+	return response
+}
+
+func TestIdempotencyReplay(t *testing.T) {
+	router, err := addIdempotencyTestRoutes()
+	if err != nil {
+		t.Errorf("Failed to start server")
+	}
+
+	first := sendIdempotentRequest(router, "DELETE", "/exams/1", "delete-1", "")
+	firstBody, _ := ioutil.ReadAll(first.Body)
+
+	// Verify the retry replays the original response instead of deleting again
+	retry := sendIdempotentRequest(router, "DELETE", "/exams/1", "delete-1", "")
+	retryBody, _ := ioutil.ReadAll(retry.Body)
+
+	have := retry.Code
+	want := first.Code
+	if have != want {
+		t.Errorf("The status was not replayed; have: %v, want: %v", have, want)
+	}
+	if string(retryBody) != string(firstBody) || !strings.Contains(string(retryBody), "deleted 3 exams") {
+		t.Errorf("The body was not replayed; have: %s, want: %s", retryBody, firstBody)
+	}
+	if retry.Header().Get(IdempotencyReplayedHeader) != "true" {
+		t.Errorf("The replayed response was not marked as replayed")
+	}
+
+	// Verify a new key handles the request again
+	other := sendIdempotentRequest(router, "DELETE", "/exams/1", "delete-2", "")
+	otherBody, _ := ioutil.ReadAll(other.Body)
+	if !strings.Contains(string(otherBody), "deleted 0 exams") {
+		t.Errorf("The request was not handled again; have: %s", otherBody)
+	}
+}
+
+func TestIdempotencyKeyReuse(t *testing.T) {
+	router, err := addIdempotencyTestRoutes()
+	if err != nil {
+		t.Errorf("Failed to start server")
+	}
+
+	response := sendIdempotentRequest(router, "POST", "/exams", "add-1", `{"exam": 5, "studentid": "test.person", "score": 0.5}`)
+	have := response.Code
+	want := 200
+	if have != want {
+		t.Errorf("HTTP status is not OK; have: %v, want: %v", have, want)
+	}
+
+	// Verify the key cannot be reused for a different request
+	response = sendIdempotentRequest(router, "POST", "/exams", "add-1", `{"exam": 5, "studentid": "test.person", "score": 0.6}`)
+	have = response.Code
+	want = 422
+	if have != want {
+		t.Errorf("HTTP status is not Unprocessable Entity; have: %v, want: %v", have, want)
+	}
+}
+
+func TestIdempotencyInFlight(t *testing.T) {
+	_, err := addIdempotencyTestRoutes()
+	if err != nil {
+		t.Errorf("Failed to start server")
+	}
+
+	_, claimed, err := claimIdempotencyKey("in-flight")
+	if err != nil || !claimed {
+		t.Fatalf("The key should have been claimed")
+	}
+
+	// Verify a second request cannot claim the key until the first releases it
+	_, claimed, _ = claimIdempotencyKey("in-flight")
+	if claimed {
+		t.Errorf("The key should still be held by the first request")
+	}
+
+	releaseIdempotencyKey("in-flight")
+	_, claimed, _ = claimIdempotencyKey("in-flight")
+	if !claimed {
+		t.Errorf("The released key should have been claimed")
+	}
+	releaseIdempotencyKey("in-flight")
+}
+
+func TestIdempotencySubscription(t *testing.T) {
+	router, err := addGraphQLTestRoutes()
+	if err != nil {
+		t.Errorf("Failed to start server")
+	}
+	router.Use(Idempotency)
+
+	// The subscription streams until its request is cancelled
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	request, _ := http.NewRequest("POST", "/graphql", strings.NewReader(`{"query": "subscription { scoreChanged { type } }"}`))
+	request.Header.Set(IdempotencyKeyHeader, "subscribe-1")
+	request = request.WithContext(ctx)
+	response := httptest.NewRecorder()
+	router.ServeHTTP(response, request)
+
+	// Verify the subscription is streamed rather than rejected, and the stream is not cached
+	have := response.Code
+	want := 200
+	if have != want {
+		t.Errorf("HTTP status is not OK; have: %v, want: %v", have, want)
+	}
+	if response.Header().Get("Content-Type") != "text/event-stream" || !strings.Contains(response.Body.String(), "event: complete") {
+		t.Errorf("The subscription was not streamed; have: %s", response.Body.String())
+	}
+
+	res, _ := db.GetRows(config.IdempotencyTable, config.IdFld, "subscribe-1")
+	if len(res) != 0 {
+		t.Errorf("The streamed response was cached")
+	}
+}
+
+func TestIdempotencyHeaders(t *testing.T) {
+	err := db.InitDB(config.DBSchema)
+	if err != nil {
+		t.Errorf("Failed to start server")
+	}
+
+	calls := 0
+	router := mux.NewRouter()
+	router.HandleFunc("/things", func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.Header().Set("Location", "/things/1")
+		w.Header().Set("ETag", `"1"`)
+		sendResponse(&struct{}{}, http.StatusCreated, w)
+	}).Methods("POST")
+	router.HandleFunc("/secrets", func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.Header().Set("Cache-Control", "no-store")
+		sendResponse(&struct{}{}, http.StatusCreated, w)
+	}).Methods("POST")
+	router.Use(func(h http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("X-RateLimit-Remaining", strconv.Itoa(10-calls))
+			h.ServeHTTP(w, r)
+		})
+	})
+	router.Use(Idempotency)
+
+	// Verify every header set by the handler is replayed, while the headers set for the request are not
+	sendIdempotentRequest(router, "POST", "/things", "things-1", "")
+	retry := sendIdempotentRequest(router, "POST", "/things", "things-1", "")
+	if calls != 1 {
+		t.Errorf("The request was handled again; have: %v, want: %v", calls, 1)
+	}
+	if retry.Header().Get("Location") != "/things/1" || retry.Header().Get("ETag") != `"1"` || retry.Header().Get("Content-Type") != "application/json; charset=UTF-8" {
+		t.Errorf("The headers were not replayed; have: %v", retry.Header())
+	}
+	if have := retry.Header().Get("X-RateLimit-Remaining"); have != "9" {
+		t.Errorf("The request's headers were replayed; have: %v, want: %v", have, "9")
+	}
+
+	// Verify a response that must not be stored is not replayed
+	sendIdempotentRequest(router, "POST", "/secrets", "secrets-1", "")
+	retry = sendIdempotentRequest(router, "POST", "/secrets", "secrets-1", "")
+	if calls != 3 || retry.Header().Get(IdempotencyReplayedHeader) != "" {
+		t.Errorf("The response was stored; have: %v calls, want: %v", calls, 3)
+	}
+}
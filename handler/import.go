@@ -0,0 +1,348 @@
+package handler
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/kylegk/sse-rest-server/db"
+	"github.com/kylegk/sse-rest-server/models"
+)
+
+// Define the supported import formats and modes
+const (
+	importCSV   = "csv"
+	importJSONL = "jsonl"
+
+	// Every line is written in a single transaction, and nothing is written if any line is invalid
+	importAtomic = "atomic"
+	// Valid lines are written in transactions of chunk_size lines, and invalid lines are skipped
+	importChunked = "chunked"
+)
+
+// Import limits
+const (
+	maxImportSize          = 32 << 20
+	maxImportLineSize      = 1 << 20
+	defaultImportChunkSize = 500
+)
+
+// A score read from an imported file, and the line it was read from
+type importLine struct {
+	line  int
+	score models.StudentExam
+}
+
+// ImportScores records the scores in an uploaded CSV or JSONL file, reporting the lines that could not be imported
+// The file is either the request body or the "file" field of a multipart form
+func ImportScores(w http.ResponseWriter, r *http.Request) {
+	var err error
+	defer func() {
+		if err != nil {
+			sendError(err, w, r)
+			return
+		}
+	}()
+
+	query := r.URL.Query()
+
+	mode := query.Get("mode")
+	if mode == "" {
+		mode = importAtomic
+	}
+	if mode != importAtomic && mode != importChunked {
+		err = invalidParameter("mode", "invalid mode: must be atomic or chunked")
+		return
+	}
+
+	chunkSize := defaultImportChunkSize
+	if query.Get("chunk_size") != "" {
+		parsed, parseErr := strconv.Atoi(query.Get("chunk_size"))
+		if parseErr != nil || parsed < 1 {
+			err = invalidParameter("chunk_size", "invalid chunk_size: must be a positive integer")
+			return
+		}
+		chunkSize = parsed
+	}
+
+	dryRun := false
+	if query.Get("dry_run") != "" {
+		parsed, parseErr := strconv.ParseBool(query.Get("dry_run"))
+		if parseErr != nil {
+			err = invalidParameter("dry_run", "invalid dry_run: must be true or false")
+			return
+		}
+		dryRun = parsed
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxImportSize)
+	file, format, err := importSource(r)
+	if err != nil {
+		return
+	}
+	defer file.Close()
+
+	var lines []importLine
+	var lineErrors []models.ImportError
+	if format == importCSV {
+		lines, lineErrors, err = parseImportCSV(file)
+	} else {
+		lines, lineErrors, err = parseImportJSONL(file)
+	}
+	if err != nil {
+		err = malformedBody(err.Error())
+		return
+	}
+
+	students := make([]string, 0, len(lines))
+	for _, line := range lines {
+		students = append(students, line.score.StudentID)
+	}
+	err = authorizeStudents(r, students...)
+	if err != nil {
+		return
+	}
+
+	response := &models.ImportResponse{
+		Format: format,
+		Mode:   mode,
+		DryRun: dryRun,
+		Total:  len(lines) + len(lineErrors),
+		Errors: lineErrors,
+	}
+
+	if mode == importAtomic && len(lineErrors) > 0 {
+		response.Failed = response.Total
+		sendResponse(response, http.StatusUnprocessableEntity, w)
+		return
+	}
+
+	if dryRun {
+		response.Imported = len(lines)
+		response.Failed = len(lineErrors)
+		sendResponse(response, http.StatusOK, w)
+		return
+	}
+
+	// An atomic import is a single chunk
+	if mode == importAtomic {
+		chunkSize = len(lines)
+	}
+
+	imported := 0
+	for start := 0; start < len(lines); start += chunkSize {
+		end := start + chunkSize
+		if end > len(lines) {
+			end = len(lines)
+		}
+
+		records := make([]models.StudentExam, 0, end-start)
+		for _, line := range lines[start:end] {
+			records = append(records, line.score)
+		}
+
+		// A chunked import reports a chunk that failed and goes on, so the error is only returned for an atomic import
+		_, writeErr := db.UpsertScores(records, requestAuditor(r, models.AuditScoreImported))
+		if writeErr != nil {
+			log.Println(writeErr)
+			if mode == importAtomic {
+				err = writeErr
+				return
+			}
+
+			for _, line := range lines[start:end] {
+				response.Errors = append(response.Errors, models.ImportError{Line: line.line, Error: "unable to record score"})
+			}
+			continue
+		}
+
+		imported += len(records)
+	}
+
+	response.Imported = imported
+	response.Failed = len(response.Errors)
+	sendResponse(response, http.StatusOK, w)
+}
+
+// Locate the uploaded file and determine its format from the "format" query parameter, the content type or the file name
+func importSource(r *http.Request) (io.ReadCloser, string, error) {
+	format := r.URL.Query().Get("format")
+	if format != "" && format != importCSV && format != importJSONL {
+		return nil, "", invalidParameter("format", "invalid format: must be csv or jsonl")
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType != "multipart/form-data" {
+		if format == "" {
+			format = importFormat(mediaType, "")
+		}
+		if format == "" {
+			return nil, "", malformedBody("unable to determine the file format: set the format query parameter or the Content-Type header")
+		}
+
+		return r.Body, format, nil
+	}
+
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		return nil, "", malformedBody(fmt.Sprintf("unable to read the uploaded file: %v", err))
+	}
+
+	if format == "" {
+		partType, _, _ := mime.ParseMediaType(header.Header.Get("Content-Type"))
+		format = importFormat(partType, header.Filename)
+	}
+	if format == "" {
+		file.Close()
+		return nil, "", malformedBody("unable to determine the file format: set the format query parameter or upload a .csv or .jsonl file")
+	}
+
+	return file, format, nil
+}
+
+// Map a content type or file name to an import format, returning an empty string if neither is recognized
+func importFormat(mediaType string, filename string) string {
+	switch mediaType {
+	case "text/csv":
+		return importCSV
+	case "application/x-ndjson", "application/jsonl", "application/x-jsonlines":
+		return importJSONL
+	}
+
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".csv":
+		return importCSV
+	case ".jsonl", ".ndjson":
+		return importJSONL
+	}
+
+	return ""
+}
+
+// Parse a CSV file with a header row naming the exam, studentid (or student) and score columns
+// Lines are numbered by record, counting the header as line 1
+func parseImportCSV(file io.Reader) ([]importLine, []models.ImportError, error) {
+	reader := csv.NewReader(file)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, nil, fmt.Errorf("unable to read the csv header: %v", err)
+	}
+
+	columns := map[string]int{"exam": -1, "studentid": -1, "score": -1}
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "student" {
+			name = "studentid"
+		}
+		if _, ok := columns[name]; ok {
+			columns[name] = i
+		}
+	}
+	for name, i := range columns {
+		if i < 0 {
+			return nil, nil, fmt.Errorf("the csv header is missing the %s column", name)
+		}
+	}
+
+	lines := make([]importLine, 0)
+	lineErrors := make([]models.ImportError, 0)
+	for line := 2; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			if _, ok := err.(*csv.ParseError); !ok {
+				return nil, nil, fmt.Errorf("unable to read the csv file: %v", err)
+			}
+			lineErrors = append(lineErrors, models.ImportError{Line: line, Error: err.Error()})
+			continue
+		}
+
+		score, err := parseImportRecord(record, columns)
+		if err == nil {
+			err = validateRequestBody(score)
+		}
+		if err != nil {
+			lineErrors = append(lineErrors, models.ImportError{Line: line, Error: err.Error()})
+			continue
+		}
+
+		lines = append(lines, importLine{line: line, score: score})
+	}
+
+	return lines, lineErrors, nil
+}
+
+// Convert a CSV record to a score
+func parseImportRecord(record []string, columns map[string]int) (models.StudentExam, error) {
+	score := models.StudentExam{}
+	for _, i := range columns {
+		if i >= len(record) {
+			return score, fmt.Errorf("expected at least %d fields, found %d", i+1, len(record))
+		}
+	}
+
+	exam, err := strconv.Atoi(strings.TrimSpace(record[columns["exam"]]))
+	if err != nil {
+		return score, fmt.Errorf("invalid exam id")
+	}
+
+	value, err := strconv.ParseFloat(strings.TrimSpace(record[columns["score"]]), 64)
+	if err != nil {
+		return score, fmt.Errorf("invalid score")
+	}
+
+	score.Exam = exam
+	score.StudentID = strings.TrimSpace(record[columns["studentid"]])
+	score.Score = value
+
+	return score, nil
+}
+
+// Parse a file with one JSON score per line, in the format accepted by POST /exams; blank lines are ignored
+func parseImportJSONL(file io.Reader) ([]importLine, []models.ImportError, error) {
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxImportLineSize)
+
+	lines := make([]importLine, 0)
+	lineErrors := make([]models.ImportError, 0)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+
+		score := models.StudentExam{}
+		err := json.Unmarshal([]byte(text), &score)
+		if err != nil {
+			lineErrors = append(lineErrors, models.ImportError{Line: line, Error: "unable to parse line"})
+			continue
+		}
+
+		err = validateRequestBody(score)
+		if err != nil {
+			lineErrors = append(lineErrors, models.ImportError{Line: line, Error: err.Error()})
+			continue
+		}
+
+		lines = append(lines, importLine{line: line, score: score})
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, nil, fmt.Errorf("unable to read the jsonl file: %v", err)
+	}
+
+	return lines, lineErrors, nil
+}
@@ -0,0 +1,167 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/kylegk/sse-rest-server/config"
+	"github.com/kylegk/sse-rest-server/db"
+	"github.com/kylegk/sse-rest-server/models"
+)
+
+const importTestCSV = `exam,student,score
+1,test.person1,0.5
+1,test.person2,abc
+2,test.person1,0.9
+`
+
+const importTestJSONL = `{"exam": 1, "studentid": "test.person1", "score": 0.5}
+
+{"exam": 2, "studentid": "test.person1", "score": 0.9}
+{"exam": 3, "score": 0.7}
+`
+
+func addImportTestRoutes() (*mux.Router, error) {
+	err := db.InitDB(config.DBSchema)
+	if err != nil {
+		return nil, err
+	}
+
+	router := mux.NewRouter()
+	router.HandleFunc("/import", ImportScores).Methods("POST")
+
+	return router, nil
+}
+
+func sendImportRequest(router *mux.Router, url string, contentType string, body string) (int, models.ImportResponse) {
+	request, _ := http.NewRequest("POST", url, strings.NewReader(body))
+	request.Header.Set("Content-Type", contentType)
+	response := httptest.NewRecorder()
+	router.ServeHTTP(response, request)
+
+	resBytes, _ := ioutil.ReadAll(response.Body)
+	report := models.ImportResponse{}
+	_ = json.Unmarshal(resBytes, &report)
+
+	return response.Code, report
+}
+
+func countImportedScores(t *testing.T) int {
+	res, err := db.GetRows(config.ScoreTable, config.IdFld)
+	if err != nil {
+		t.Errorf("Unable to read scores")
+	}
+
+	return len(res)
+}
+
+func TestImportAtomic(t *testing.T) {
+	router, err := addImportTestRoutes()
+	if err != nil {
+		t.Errorf("Failed to start server")
+	}
+
+	// Verify a file with an invalid line is rejected without writing anything
+	have, report := sendImportRequest(router, "/import", "text/csv", importTestCSV)
+	want := 422
+	if have != want {
+		t.Errorf("HTTP status is not Unprocessable Entity; have: %v, want: %v", have, want)
+	}
+	if len(report.Errors) != 1 || report.Errors[0].Line != 3 {
+		t.Errorf("Incorrect errors reported; have: %+v", report.Errors)
+	}
+
+	have = countImportedScores(t)
+	want = 0
+	if have != want {
+		t.Errorf("No scores should have been imported; have: %v, want: %v", have, want)
+	}
+
+	// Verify a valid file is imported
+	valid := strings.Replace(importTestCSV, "1,test.person2,abc\n", "", 1)
+	have, report = sendImportRequest(router, "/import", "text/csv", valid)
+	want = 200
+	if have != want {
+		t.Errorf("HTTP status is not OK; have: %v, want: %v", have, want)
+	}
+	if report.Imported != 2 || report.Failed != 0 {
+		t.Errorf("Incorrect report; have: %+v", report)
+	}
+
+	have = countImportedScores(t)
+	want = 2
+	if have != want {
+		t.Errorf("Incorrect number of scores imported; have: %v, want: %v", have, want)
+	}
+}
+
+func TestImportChunked(t *testing.T) {
+	router, err := addImportTestRoutes()
+	if err != nil {
+		t.Errorf("Failed to start server")
+	}
+
+	have, report := sendImportRequest(router, "/import?mode=chunked&chunk_size=1", "application/x-ndjson", importTestJSONL)
+	want := 200
+	if have != want {
+		t.Errorf("HTTP status is not OK; have: %v, want: %v", have, want)
+	}
+
+	// Verify the valid lines were imported and the line missing a student was reported
+	if report.Format != "jsonl" || report.Total != 3 || report.Imported != 2 || report.Failed != 1 {
+		t.Errorf("Incorrect report; have: %+v", report)
+	}
+	if len(report.Errors) != 1 || report.Errors[0].Line != 4 || report.Errors[0].Error != "invalid studentid" {
+		t.Errorf("Incorrect errors reported; have: %+v", report.Errors)
+	}
+
+	have = countImportedScores(t)
+	want = 2
+	if have != want {
+		t.Errorf("Incorrect number of scores imported; have: %v, want: %v", have, want)
+	}
+}
+
+func TestImportDryRun(t *testing.T) {
+	router, err := addImportTestRoutes()
+	if err != nil {
+		t.Errorf("Failed to start server")
+	}
+
+	// Upload the file as a multipart form, with the format taken from the file name
+	body := &bytes.Buffer{}
+	form := multipart.NewWriter(body)
+	part, _ := form.CreateFormFile("file", "scores.csv")
+	part.Write([]byte(importTestCSV))
+	form.Close()
+
+	have, report := sendImportRequest(router, "/import?mode=chunked&dry_run=true", form.FormDataContentType(), body.String())
+	want := 200
+	if have != want {
+		t.Errorf("HTTP status is not OK; have: %v, want: %v", have, want)
+	}
+	if !report.DryRun || report.Format != "csv" || report.Imported != 2 || report.Failed != 1 {
+		t.Errorf("Incorrect report; have: %+v", report)
+	}
+
+	// Verify nothing was written
+	have = countImportedScores(t)
+	want = 0
+	if have != want {
+		t.Errorf("No scores should have been imported; have: %v, want: %v", have, want)
+	}
+
+	// Verify a file in an unknown format is rejected
+	have, _ = sendImportRequest(router, "/import", "text/plain", importTestCSV)
+	want = 400
+	if have != want {
+		t.Errorf("HTTP status is not Bad Request; have: %v, want: %v", have, want)
+	}
+}
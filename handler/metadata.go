@@ -0,0 +1,88 @@
+package handler
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/kylegk/sse-rest-server/config"
+	"github.com/kylegk/sse-rest-server/db"
+	"github.com/kylegk/sse-rest-server/models"
+)
+
+// GetExamMetadata returns the grading metadata (title, category, weight) for the specified exam
+func GetExamMetadata(w http.ResponseWriter, r *http.Request) {
+	var err error
+	defer func() {
+		if err != nil {
+			sendError(err, w, r)
+			return
+		}
+	}()
+
+	examID, err := examIDParam(r)
+	if err != nil {
+		return
+	}
+
+	res, err := db.GetRows(config.ExamMetaTable, config.IdFld, examID)
+	if err != nil {
+		log.Println(err)
+		return
+	}
+
+	if len(res) == 0 {
+		SendGenericNotFoundResponse(w, r)
+		return
+	}
+
+	sendResponse(res[0].(models.ExamMetadata), http.StatusOK, w)
+}
+
+// PutExamMetadata creates or replaces the grading metadata for the specified exam
+func PutExamMetadata(w http.ResponseWriter, r *http.Request) {
+	var err error
+	defer func() {
+		if err != nil {
+			sendError(err, w, r)
+			return
+		}
+	}()
+
+	examID, err := examIDParam(r)
+	if err != nil {
+		return
+	}
+
+	meta := models.ExamMetadata{}
+	bytes, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		log.Println(err)
+		return
+	}
+
+	err = json.Unmarshal(bytes, &meta)
+	if err != nil {
+		err = malformedBody("unable to parse request")
+		return
+	}
+
+	meta.Exam = examID
+	if meta.Weight < 0 {
+		err = validationFailed([]models.FieldError{{Field: "weight", Code: FieldInvalid, Message: "invalid weight"}})
+		return
+	}
+
+	auditor := resourceAuditor(r, models.AuditExamMetadataUpdated, models.AuditResourceExamMetadata)(strconv.Itoa(examID))
+	_, err = db.Update(auditor, func(txn *db.Txn) error {
+		return txn.UpsertRow(config.ExamMetaTable, meta)
+	})
+	if err != nil {
+		log.Println(err)
+		return
+	}
+
+	sendResponse(meta, http.StatusOK, w)
+}
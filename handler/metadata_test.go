@@ -0,0 +1,133 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/kylegk/sse-rest-server/config"
+	"github.com/kylegk/sse-rest-server/db"
+	"github.com/kylegk/sse-rest-server/models"
+)
+
+func addMetadataTestRoutes() (*mux.Router, error) {
+	err := db.InitDB(config.DBSchema)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, exam := range studentTestData {
+		err = db.UpsertRow(config.ScoreTable, exam)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	router := mux.NewRouter()
+	router.HandleFunc("/students/{id}", GetStudentByID).Methods("GET")
+	router.HandleFunc("/exams/{id}/metadata", GetExamMetadata).Methods("GET")
+	router.HandleFunc("/exams/{id}/metadata", PutExamMetadata).Methods("PUT")
+
+	return router, nil
+}
+
+func TestPutExamMetadata(t *testing.T) {
+	router, err := addMetadataTestRoutes()
+	if err != nil {
+		t.Errorf("Failed to start server")
+	}
+
+	// Test with an invalid weight
+	j, _ := json.Marshal(models.ExamMetadata{Category: "final", Weight: -1})
+	request, _ := http.NewRequest("PUT", "/exams/2/metadata", bytes.NewBuffer(j))
+	response := httptest.NewRecorder()
+	router.ServeHTTP(response, request)
+
+	// Verify status code is 422
+	have := response.Code
+	want := 422
+	if have != want {
+		t.Errorf("Route returned an incorrect status code; have: %v, want: %v", have, want)
+	}
+
+	// Test with valid metadata
+	j, _ = json.Marshal(models.ExamMetadata{Category: "final", Weight: 3})
+	request, _ = http.NewRequest("PUT", "/exams/2/metadata", bytes.NewBuffer(j))
+	response = httptest.NewRecorder()
+	router.ServeHTTP(response, request)
+
+	// Verify status code is 200
+	have = response.Code
+	want = 200
+	if have != want {
+		t.Errorf("Route returned an incorrect status code; have: %v, want: %v", have, want)
+	}
+
+	request, _ = http.NewRequest("GET", "/exams/2/metadata", nil)
+	response = httptest.NewRecorder()
+	router.ServeHTTP(response, request)
+
+	resBytes, err := ioutil.ReadAll(response.Body)
+	if err != nil {
+		t.Errorf("Unable to read response body")
+	}
+	body := models.ExamMetadata{}
+	err = json.Unmarshal(resBytes, &body)
+	if err != nil {
+		t.Errorf("Failed to parse response returned from route")
+	}
+
+	// Verify the stored metadata is returned
+	if body.Exam != 2 || body.Category != "final" || body.Weight != 3 {
+		t.Errorf("Incorrect metadata returned; have: %+v", body)
+	}
+}
+
+func TestGetStudentByIDWithPolicy(t *testing.T) {
+	router, err := addMetadataTestRoutes()
+	if err != nil {
+		t.Errorf("Failed to start server")
+	}
+
+	j, _ := json.Marshal(models.ExamMetadata{Weight: 3})
+	request, _ := http.NewRequest("PUT", "/exams/2/metadata", bytes.NewBuffer(j))
+	router.ServeHTTP(httptest.NewRecorder(), request)
+
+	// Test with an unknown policy
+	request, _ = http.NewRequest("GET", "/students/test.person1?policy=does_not_exist", nil)
+	response := httptest.NewRecorder()
+	router.ServeHTTP(response, request)
+
+	// Verify status code is 400
+	have := response.Code
+	want := 400
+	if have != want {
+		t.Errorf("Route returned an incorrect status code; have: %v, want: %v", have, want)
+	}
+
+	// Test with the weighted policy
+	request, _ = http.NewRequest("GET", "/students/test.person1?policy=weighted", nil)
+	response = httptest.NewRecorder()
+	router.ServeHTTP(response, request)
+
+	resBytes, err := ioutil.ReadAll(response.Body)
+	if err != nil {
+		t.Errorf("Unable to read response body")
+	}
+	body := models.StudentByIDResponse{}
+	err = json.Unmarshal(resBytes, &body)
+	if err != nil {
+		t.Errorf("Failed to parse response returned from route")
+	}
+
+	// Verify the weighted average is used
+	have64 := body.Average
+	want64 := (0.50 + 3*0.90) / 4
+	if have64 != want64 || body.Policy != "weighted" {
+		t.Errorf("Incorrect average; have: %v (%s), want: %v", have64, body.Policy, want64)
+	}
+}
@@ -0,0 +1,23 @@
+package handler
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/kylegk/sse-rest-server/openapi"
+)
+
+// GetOpenAPISpec returns the OpenAPI document describing the API
+func GetOpenAPISpec(w http.ResponseWriter, r *http.Request) {
+	sendResponse(openapi.Spec(), http.StatusOK, w)
+}
+
+// GetAPIDocs returns a page that renders the OpenAPI document, and can be used to try out the API
+func GetAPIDocs(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=UTF-8")
+	w.WriteHeader(http.StatusOK)
+	_, err := w.Write([]byte(openapi.Viewer))
+	if err != nil {
+		log.Println(err)
+	}
+}
@@ -0,0 +1,257 @@
+package handler
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/kylegk/sse-rest-server/alerts"
+	"github.com/kylegk/sse-rest-server/anomaly"
+	"github.com/kylegk/sse-rest-server/apikey"
+	"github.com/kylegk/sse-rest-server/config"
+	"github.com/kylegk/sse-rest-server/db"
+	"github.com/kylegk/sse-rest-server/models"
+	"github.com/kylegk/sse-rest-server/openapi"
+	"github.com/kylegk/sse-rest-server/webhook"
+)
+
+// The handler of every documented route, keyed by the method and path template
+var openAPITestHandlers = map[string]http.HandlerFunc{
+	"GET /students":                         GetAllStudents,
+	"GET /students/compare":                 CompareStudents,
+	"GET /students/{id}":                    GetStudentByID,
+	"DELETE /students/{id}":                 DeleteStudent,
+	"GET /students/{id}/trend":              GetStudentTrend,
+	"GET /exams":                            GetAllUniqueExamIDs,
+	"POST /exams":                           AddExam,
+	"GET /exams/all":                        GetAllExams,
+	"GET /exams/{id}":                       GetExamByID,
+	"DELETE /exams/{id}":                    DeleteExam,
+	"POST /exams/{id}/restore":              RestoreExam,
+	"POST /exams/{id}/curve":                CurveExam,
+	"GET /exams/{id}/grade-distribution":    GetExamGradeDistribution,
+	"GET /exams/{id}/metadata":              GetExamMetadata,
+	"PUT /exams/{id}/metadata":              PutExamMetadata,
+	"PUT /exams/{id}/students/{student}":    PutScore,
+	"PATCH /exams/{id}/students/{student}":  PatchScore,
+	"DELETE /exams/{id}/students/{student}": DeleteScore,
+	"POST /import":                          ImportScores,
+	"GET /analytics/correlation":            GetExamCorrelation,
+	"GET /alerts":                           GetAlerts,
+	"GET /alerts/{id}":                      GetAlertByID,
+	"POST /alerts/{id}/acknowledge":         AcknowledgeAlert,
+	"POST /alerts/{id}/resolve":             ResolveAlert,
+	"GET /anomalies":                        GetAnomalyReport,
+	"POST /anomalies/{id}/release":          ReleaseAnomaly,
+	"GET /webhooks":                         GetWebhooks,
+	"POST /webhooks":                        AddWebhook,
+	"GET /webhooks/{id}":                    GetWebhookByID,
+	"DELETE /webhooks/{id}":                 DeleteWebhook,
+	"GET /webhooks/{id}/deliveries":         GetWebhookDeliveries,
+	"GET /admin/keys":                       GetAPIKeys,
+	"POST /admin/keys":                      AddAPIKey,
+	"DELETE /admin/keys/{id}":               DeleteAPIKey,
+	"GET /admin/audit":                      GetAuditLog,
+}
+
+func addOpenAPITestRoutes() (*mux.Router, error) {
+	err := db.InitDB(config.DBSchema)
+	if err != nil {
+		return nil, err
+	}
+	anomaly.Reset()
+
+	for _, exam := range studentTestData {
+		err = db.UpsertRow(config.ScoreTable, exam)
+		if err != nil {
+			return nil, err
+		}
+
+		_, err = alerts.Evaluate(exam)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Quarantine an event for a student that sent too many, which can be released
+	for i := 0; i <= anomaly.StudentRateLimit; i++ {
+		_, err = anomaly.Inspect(models.StudentExam{Exam: 10 + i, StudentID: "test.person1", Score: 0.5})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	router := mux.NewRouter()
+	router.NotFoundHandler = http.HandlerFunc(SendGenericNotFoundResponse)
+	// Register the routes by path, so fixed paths such as /exams/all come before the templates that would match them
+	routes := make([]string, 0, len(openAPITestHandlers))
+	for route := range openAPITestHandlers {
+		routes = append(routes, route)
+	}
+	sort.Slice(routes, func(i, j int) bool {
+		return strings.SplitN(routes[i], " ", 2)[1] < strings.SplitN(routes[j], " ", 2)[1]
+	})
+	for _, route := range routes {
+		parts := strings.SplitN(route, " ", 2)
+		router.HandleFunc(parts[1], openAPITestHandlers[route]).Methods(parts[0])
+	}
+
+	return router, nil
+}
+
+// The id of the first alert, anomaly, webhook or API key, used to fill the placeholders of the test urls
+func openAPITestID(kind string) string {
+	switch kind {
+	case "{alert}":
+		res, _ := alerts.List("", "")
+		if len(res) > 0 {
+			return res[0].ID
+		}
+	case "{anomaly}":
+		res, _ := anomaly.List("")
+		if len(res) > 0 {
+			return res[0].ID
+		}
+	case "{webhook}":
+		res, _ := webhook.List()
+		if len(res) > 0 {
+			return res[0].ID
+		}
+	case "{apikey}":
+		res, _ := apikey.List()
+		if len(res) > 0 {
+			return res[0].ID
+		}
+	}
+
+	return "missing"
+}
+
+func TestResponsesMatchOpenAPI(t *testing.T) {
+	router, err := addOpenAPITestRoutes()
+	if err != nil {
+		t.Errorf("Failed to start server")
+	}
+
+	// Every documented route is described by the handlers of this test
+	for _, route := range openapi.Spec().Routes() {
+		if openAPITestHandlers[route] == nil {
+			t.Errorf("Documented route has no handler in the test; %v", route)
+		}
+	}
+
+	tests := []struct {
+		method      string
+		path        string
+		url         string
+		body        string
+		contentType string
+		status      int
+	}{
+		{"GET", "/students", "/students", "", "", 200},
+		{"GET", "/students/compare", "/students/compare?ids=test.person1,test.person2", "", "", 200},
+		{"GET", "/students/compare", "/students/compare?ids=test.person1", "", "", 400},
+		{"GET", "/students/{id}", "/students/test.person1", "", "", 200},
+		{"GET", "/students/{id}", "/students/test.person1?format=csv", "", "", 200},
+		{"GET", "/students/{id}", "/students/nobody", "", "", 404},
+		{"GET", "/students/{id}/trend", "/students/test.person1/trend", "", "", 200},
+		{"GET", "/exams", "/exams", "", "", 200},
+		{"POST", "/exams", "/exams", `{"exam": 2, "studentid": "test.person2", "score": 0.8}`, "application/json", 200},
+		{"POST", "/exams", "/exams?mode=best_effort", `[{"exam": 2, "studentid": "test.person3", "score": 0.7}, {"exam": 2}]`, "application/json", 200},
+		{"POST", "/exams", "/exams", `[{"exam": 2}]`, "application/json", 422},
+		{"POST", "/exams", "/exams", `{}`, "application/json", 422},
+		{"GET", "/exams/all", "/exams/all", "", "", 200},
+		{"GET", "/exams/all", "/exams/all?format=ndjson", "", "", 200},
+		{"GET", "/exams/{id}", "/exams/1", "", "", 200},
+		{"GET", "/exams/{id}", "/exams/abc", "", "", 400},
+		{"POST", "/exams/{id}/curve", "/exams/1/curve", `{"method": "sqrt"}`, "application/json", 200},
+		{"GET", "/exams/{id}/grade-distribution", "/exams/1/grade-distribution?scores=curved", "", "", 200},
+		{"PUT", "/exams/{id}/metadata", "/exams/1/metadata", `{"title": "Midterm", "category": "midterm", "weight": 2}`, "application/json", 200},
+		{"GET", "/exams/{id}/metadata", "/exams/1/metadata", "", "", 200},
+		{"PUT", "/exams/{id}/students/{student}", "/exams/3/students/test.person1", `{"score": 0.55}`, "application/json", 201},
+		{"PATCH", "/exams/{id}/students/{student}", "/exams/3/students/test.person1", `{"score": 0.65}`, "application/json", 200},
+		{"DELETE", "/exams/{id}/students/{student}", "/exams/3/students/test.person1", "", "", 200},
+		{"POST", "/import", "/import?mode=chunked", "exam,studentid,score\n4,test.person1,0.7\n4,,0.6\n", "text/csv", 200},
+		{"GET", "/analytics/correlation", "/analytics/correlation?exams=1,2", "", "", 200},
+		{"GET", "/alerts", "/alerts", "", "", 200},
+		{"GET", "/alerts/{id}", "/alerts/{alert}", "", "", 200},
+		{"POST", "/alerts/{id}/acknowledge", "/alerts/{alert}/acknowledge", "", "", 200},
+		{"POST", "/alerts/{id}/resolve", "/alerts/{alert}/resolve", "", "", 200},
+		{"POST", "/alerts/{id}/resolve", "/alerts/{alert}/resolve", "", "", 409},
+		{"GET", "/anomalies", "/anomalies", "", "", 200},
+		{"POST", "/anomalies/{id}/release", "/anomalies/{anomaly}/release", "", "", 200},
+		{"POST", "/webhooks", "/webhooks", `{"url": "http://localhost:9/hook", "exams": [1]}`, "application/json", 201},
+		{"GET", "/webhooks", "/webhooks", "", "", 200},
+		{"GET", "/webhooks/{id}", "/webhooks/{webhook}", "", "", 200},
+		{"GET", "/webhooks/{id}/deliveries", "/webhooks/{webhook}/deliveries", "", "", 200},
+		{"DELETE", "/webhooks/{id}", "/webhooks/{webhook}", "", "", 200},
+		{"DELETE", "/exams/{id}", "/exams/2", "", "", 200},
+		{"POST", "/exams/{id}/restore", "/exams/2/restore", "", "", 200},
+		{"DELETE", "/students/{id}", "/students/test.person3", "", "", 200},
+		{"POST", "/admin/keys", "/admin/keys", `{"name": "dashboard"}`, "application/json", 201},
+		{"POST", "/admin/keys", "/admin/keys", `{}`, "application/json", 422},
+		{"GET", "/admin/keys", "/admin/keys", "", "", 200},
+		{"DELETE", "/admin/keys/{id}", "/admin/keys/{apikey}", "", "", 200},
+		{"DELETE", "/admin/keys/{id}", "/admin/keys/missing", "", "", 404},
+		{"GET", "/admin/audit", "/admin/audit", "", "", 200},
+		{"GET", "/admin/audit", "/admin/audit?action=exam.deleted&exam=2", "", "", 200},
+		{"GET", "/admin/audit", "/admin/audit?since=yesterday", "", "", 400},
+	}
+
+	for _, test := range tests {
+		url := test.url
+		for _, kind := range []string{"{alert}", "{anomaly}", "{webhook}", "{apikey}"} {
+			if strings.Contains(url, kind) {
+				url = strings.Replace(url, kind, openAPITestID(kind), 1)
+			}
+		}
+
+		request, _ := http.NewRequest(test.method, url, strings.NewReader(test.body))
+		if test.contentType != "" {
+			request.Header.Set("Content-Type", test.contentType)
+		}
+		response := httptest.NewRecorder()
+		router.ServeHTTP(response, request)
+
+		if response.Code != test.status {
+			t.Errorf("HTTP status is incorrect for %v %v; have: %v, want: %v", test.method, url, response.Code, test.status)
+		}
+
+		resBytes, _ := ioutil.ReadAll(response.Body)
+		err := openapi.Spec().ValidateResponse(test.method, test.path, response.Code, response.Header().Get("Content-Type"), resBytes)
+		if err != nil {
+			t.Errorf("Response to %v %v does not match the OpenAPI document; %v", test.method, url, err)
+		}
+	}
+}
+
+func TestGetOpenAPISpec(t *testing.T) {
+	router := mux.NewRouter()
+	router.HandleFunc("/openapi.json", GetOpenAPISpec).Methods("GET")
+	router.HandleFunc("/docs", GetAPIDocs).Methods("GET")
+
+	request, _ := http.NewRequest("GET", "/openapi.json", nil)
+	response := httptest.NewRecorder()
+	router.ServeHTTP(response, request)
+
+	have := response.Code
+	want := 200
+	if have != want {
+		t.Errorf("HTTP status is not OK; have: %v, want: %v", have, want)
+	}
+	if !strings.Contains(response.Body.String(), `"openapi":"3.0.3"`) {
+		t.Errorf("Response is not an OpenAPI document")
+	}
+
+	request, _ = http.NewRequest("GET", "/docs", nil)
+	response = httptest.NewRecorder()
+	router.ServeHTTP(response, request)
+
+	if response.Code != 200 || !strings.HasPrefix(response.Header().Get("Content-Type"), "text/html") {
+		t.Errorf("Incorrect docs response; have: %v %v", response.Code, response.Header().Get("Content-Type"))
+	}
+}
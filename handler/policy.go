@@ -0,0 +1,110 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"github.com/kylegk/sse-rest-server/auth"
+	"github.com/kylegk/sse-rest-server/report"
+)
+
+// Policy decides whether a client can make a request to a route
+type Policy func(p auth.Principal, r *http.Request) bool
+
+// Authorize only serves the requests the policy allows, rejecting the others with 403 Forbidden
+// Requests are not checked when authentication has not been configured, as they carry no client
+func Authorize(policy Policy, h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		principal, ok := requestPrincipal(r)
+		if !ok {
+			if auth.Required() {
+				sendError(unauthorized("an API key or bearer token is required"), w, r)
+				return
+			}
+
+			h.ServeHTTP(w, r)
+			return
+		}
+
+		if !policy(principal, r) {
+			sendError(forbidden(fmt.Sprintf("the %s role cannot %s %s", principal.Role, r.Method, r.URL.Path)), w, r)
+			return
+		}
+
+		h.ServeHTTP(w, r)
+	})
+}
+
+// AdminOnly allows admins
+func AdminOnly(p auth.Principal, r *http.Request) bool {
+	return p.Role == auth.RoleAdmin
+}
+
+// StaffOnly allows teachers and admins
+func StaffOnly(p auth.Principal, r *http.Request) bool {
+	return p.IsStaff()
+}
+
+// StudentInPath allows the clients that can access the student named by a path parameter,
+// including a student reading their own scores
+func StudentInPath(param string) Policy {
+	return func(p auth.Principal, r *http.Request) bool {
+		return p.CanAccessStudent(mux.Vars(r)[param])
+	}
+}
+
+// StaffStudentInPath allows the teachers and admins that can access the student named by a path parameter
+func StaffStudentInPath(param string) Policy {
+	return func(p auth.Principal, r *http.Request) bool {
+		return p.IsStaff() && p.CanAccessStudent(mux.Vars(r)[param])
+	}
+}
+
+// StaffStudentsInQuery allows the teachers and admins that can access every student in a comma separated query parameter
+func StaffStudentsInQuery(param string) Policy {
+	return func(p auth.Principal, r *http.Request) bool {
+		if !p.IsStaff() {
+			return false
+		}
+
+		for _, id := range strings.Split(r.URL.Query().Get(param), ",") {
+			id = strings.TrimSpace(id)
+			if id != "" && !p.CanAccessStudent(id) {
+				return false
+			}
+		}
+
+		return true
+	}
+}
+
+// Verify the client of a request can access every student whose scores are in its body
+// Routes that take the students from the body check them here, as the route policy cannot see the body
+func authorizeStudents(r *http.Request, students ...string) error {
+	principal, ok := requestPrincipal(r)
+	if !ok {
+		return nil
+	}
+
+	// Scores without a student are left for the validation to report
+	for _, student := range students {
+		if student != "" && !principal.CanAccessStudent(student) {
+			return forbidden(fmt.Sprintf("the %s role cannot change the scores of student %s", principal.Role, student))
+		}
+	}
+
+	return nil
+}
+
+// The filter of the students whose scores the client of a request can see, for the routes that list the scores of many students
+// Teachers only see the students of their cohorts, and every student is visible when authentication has not been configured
+func visibleStudents(r *http.Request) report.Filter {
+	principal, ok := requestPrincipal(r)
+	if !ok {
+		return nil
+	}
+
+	return principal.Visible()
+}
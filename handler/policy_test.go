@@ -0,0 +1,180 @@
+package handler
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/kylegk/sse-rest-server/auth"
+	"github.com/kylegk/sse-rest-server/config"
+	"github.com/kylegk/sse-rest-server/db"
+	"github.com/kylegk/sse-rest-server/models"
+)
+
+func addPolicyTestRoutes() (*mux.Router, error) {
+	err := db.InitDB(config.DBSchema)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, exam := range studentTestData {
+		err = db.UpsertRow(config.ScoreTable, exam)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	dir, err := ioutil.TempDir("", "cohorts")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "cohorts.json")
+	err = ioutil.WriteFile(path, []byte(`{"math-101": ["test.person1"]}`), 0600)
+	if err != nil {
+		return nil, err
+	}
+	err = auth.LoadCohorts(path)
+	if err != nil {
+		return nil, err
+	}
+
+	router := mux.NewRouter()
+	router.Handle("/students", Authorize(StaffOnly, http.HandlerFunc(GetAllStudents))).Methods("GET")
+	router.Handle("/students/compare", Authorize(StaffStudentsInQuery("ids"), http.HandlerFunc(CompareStudents))).Methods("GET")
+	router.Handle("/students/{id}", Authorize(StudentInPath("id"), http.HandlerFunc(GetStudentByID))).Methods("GET")
+	router.Handle("/students/{id}", Authorize(AdminOnly, http.HandlerFunc(DeleteStudent))).Methods("DELETE")
+	router.Handle("/exams", Authorize(StaffOnly, http.HandlerFunc(AddExam))).Methods("POST")
+	router.Handle("/exams/{id}/students/{student}", Authorize(StaffStudentInPath("student"), http.HandlerFunc(PatchScore))).Methods("PATCH")
+	router.Handle("/exams/all", Authorize(StaffOnly, http.HandlerFunc(GetAllExams))).Methods("GET")
+	router.Handle("/exams/{id}", Authorize(StaffOnly, http.HandlerFunc(GetExamByID))).Methods("GET")
+	router.Handle("/exams/{id}/grade-distribution", Authorize(StaffOnly, http.HandlerFunc(GetExamGradeDistribution))).Methods("GET")
+	router.Handle("/analytics/correlation", Authorize(StaffOnly, http.HandlerFunc(GetExamCorrelation))).Methods("GET")
+	router.Handle("/alerts", Authorize(StaffOnly, http.HandlerFunc(GetAlerts))).Methods("GET")
+	router.Handle("/alerts/{id}", Authorize(StaffOnly, http.HandlerFunc(GetAlertByID))).Methods("GET")
+	router.Handle("/graphql", Authorize(StaffOnly, http.HandlerFunc(GraphQL))).Methods("GET")
+
+	return router, nil
+}
+
+func TestAuthorize(t *testing.T) {
+	router, err := addPolicyTestRoutes()
+	if err != nil {
+		t.Errorf("Failed to start server")
+	}
+
+	student := auth.Principal{ID: "sub:test.person1", Subject: "test.person1", Role: auth.RoleStudent}
+	teacher := auth.Principal{ID: "sub:ms.teacher", Subject: "ms.teacher", Role: auth.RoleTeacher, Cohorts: []string{"math-101"}}
+	admin := auth.Principal{ID: "sub:root", Subject: "root", Role: auth.RoleAdmin, AllStudents: true}
+
+	tests := []struct {
+		principal auth.Principal
+		method    string
+		url       string
+		body      string
+		status    int
+	}{
+		{student, "GET", "/students/test.person1", "", 200},
+		{student, "GET", "/students/test.person2", "", 403},
+		{student, "GET", "/students", "", 403},
+		{student, "POST", "/exams", `{"exam": 5, "studentid": "test.person1", "score": 1}`, 403},
+		{teacher, "GET", "/students", "", 200},
+		{teacher, "GET", "/students/test.person1", "", 200},
+		{teacher, "GET", "/students/test.person2", "", 403},
+		{teacher, "GET", "/students/compare?ids=test.person1,test.person2", "", 403},
+		{teacher, "PATCH", "/exams/1/students/test.person1", `{"score": 0.6}`, 200},
+		{teacher, "PATCH", "/exams/1/students/test.person2", `{"score": 0.6}`, 403},
+		{teacher, "POST", "/exams", `{"exam": 5, "studentid": "test.person1", "score": 0.8}`, 200},
+		// The students in the body are checked as well as the route
+		{teacher, "POST", "/exams", `[{"exam": 5, "studentid": "test.person1", "score": 0.8}, {"exam": 5, "studentid": "test.person2", "score": 0.8}]`, 403},
+		{teacher, "DELETE", "/students/test.person1", "", 403},
+		{admin, "GET", "/students/compare?ids=test.person1,test.person2", "", 200},
+		{admin, "DELETE", "/students/test.person2", "", 200},
+	}
+	for _, test := range tests {
+		request, _ := http.NewRequest(test.method, test.url, strings.NewReader(test.body))
+		request = request.WithContext(context.WithValue(request.Context(), principalContext, test.principal))
+		response := httptest.NewRecorder()
+		router.ServeHTTP(response, request)
+
+		if response.Code != test.status {
+			t.Errorf("HTTP status is incorrect for the %v role on %v %v; have: %v, want: %v", test.principal.Role, test.method, test.url, response.Code, test.status)
+		}
+		if response.Code == 403 {
+			problem := readProblem(t, response, 403)
+			if problem.Code != CodeForbidden {
+				t.Errorf("Incorrect problem returned; have: %+v", problem)
+			}
+		}
+	}
+
+	// Requests are not checked while authentication has not been configured
+	request, _ := http.NewRequest("DELETE", "/students/test.person1", nil)
+	response := httptest.NewRecorder()
+	router.ServeHTTP(response, request)
+	if response.Code != 200 {
+		t.Errorf("Request without authentication configured was rejected; have: %v", response.Code)
+	}
+}
+
+func TestVisibleStudents(t *testing.T) {
+	router, err := addPolicyTestRoutes()
+	if err != nil {
+		t.Errorf("Failed to start server")
+	}
+
+	for i, student := range []string{"test.person1", "test.person2"} {
+		err = db.UpsertRow(config.AlertTable, models.Alert{ID: strconv.Itoa(i + 1), StudentID: student, Status: models.AlertOpen})
+		if err != nil {
+			t.Errorf("Failed to setup the alerts")
+		}
+	}
+
+	// The teacher's cohort only has test.person1, so no other student may appear in any response
+	teacher := auth.Principal{ID: "sub:ms.teacher", Subject: "ms.teacher", Role: auth.RoleTeacher, Cohorts: []string{"math-101"}}
+	tests := []struct {
+		url    string
+		status int
+		want   string
+	}{
+		{"/students", 200, `"test.person1"`},
+		{"/exams/all", 200, `"test.person1"`},
+		{"/exams/all?format=csv", 200, "test.person1"},
+		{"/exams/1", 200, `"average":0.5`},
+		{"/exams/1?format=csv", 200, "test.person1"},
+		{"/exams/1/grade-distribution", 200, `"total":1`},
+		{"/analytics/correlation?exams=1,2", 200, `"students":[[1,1],[1,1]]`},
+		{"/alerts", 200, `"test.person1"`},
+		{"/alerts/1", 200, `"test.person1"`},
+		{"/alerts/2", 404, ""},
+		{"/graphql?query=" + url.QueryEscape(`{ students { id } exam(id: 1) { count scores { student { id } } } }`), 200, `"count":1`},
+		{"/graphql?query=" + url.QueryEscape(`{ student(id: "test.person2") { id } score(exam: 1, student: "test.person2") { score } }`), 200, `"student":null`},
+	}
+	for _, test := range tests {
+		request, _ := http.NewRequest("GET", test.url, nil)
+		request = request.WithContext(context.WithValue(request.Context(), principalContext, teacher))
+		response := httptest.NewRecorder()
+		router.ServeHTTP(response, request)
+
+		body := response.Body.String()
+		if response.Code != test.status {
+			t.Errorf("HTTP status is incorrect for %v; have: %v, want: %v", test.url, response.Code, test.status)
+		}
+		if !strings.Contains(body, test.want) {
+			t.Errorf("Response is missing the visible scores for %v; have: %v, want: %v", test.url, body, test.want)
+		}
+		for _, student := range []string{"test.person2", "test.person3", "test.person4"} {
+			if strings.Contains(body, student) {
+				t.Errorf("Response includes a student outside the teacher's cohorts for %v; have: %v", test.url, body)
+			}
+		}
+	}
+}
@@ -0,0 +1,135 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/kylegk/sse-rest-server/models"
+)
+
+// The content type of an error response
+const problemContentType = "application/problem+json"
+
+// Define the stable codes identifying each kind of problem
+const (
+	CodeInvalidParameter = "invalid_parameter"
+	CodeMalformedBody    = "malformed_body"
+	CodeValidationFailed = "validation_failed"
+	CodeUnauthorized     = "unauthorized"
+	CodeForbidden        = "forbidden"
+	CodeNotFound         = "not_found"
+	CodeMethodNotAllowed = "method_not_allowed"
+	CodeConflict         = "conflict"
+	CodeKeyReused        = "idempotency_key_reused"
+	CodeRateLimited      = "rate_limited"
+	CodeInternalError    = "internal_error"
+)
+
+// Define the codes of the individual field violations
+const (
+	FieldRequired = models.FieldRequired
+	FieldInvalid  = models.FieldInvalid
+)
+
+// apiError is an error that is reported to the client as a problem with the matching status
+type apiError struct {
+	status int
+	code   string
+	detail string
+	fields []models.FieldError
+}
+
+func (e *apiError) Error() string {
+	if e.detail != "" {
+		return e.detail
+	}
+
+	messages := make([]string, 0, len(e.fields))
+	for _, field := range e.fields {
+		messages = append(messages, field.Message)
+	}
+
+	return strings.Join(messages, "; ")
+}
+
+// A query or path parameter has an invalid value
+func invalidParameter(name string, message string) error {
+	return &apiError{
+		status: http.StatusBadRequest,
+		code:   CodeInvalidParameter,
+		detail: message,
+		fields: []models.FieldError{{Field: name, Code: FieldInvalid, Message: message}},
+	}
+}
+
+// The request body could not be parsed
+func malformedBody(message string) error {
+	return &apiError{status: http.StatusBadRequest, code: CodeMalformedBody, detail: message}
+}
+
+// The request body was parsed, but one or more of its fields are invalid
+func validationFailed(fields []models.FieldError) error {
+	return &apiError{status: http.StatusUnprocessableEntity, code: CodeValidationFailed, fields: fields}
+}
+
+// The request was parsed, but cannot be carried out as it was sent
+func invalidRequest(message string) error {
+	return &apiError{status: http.StatusUnprocessableEntity, code: CodeValidationFailed, detail: message}
+}
+
+// The request was not sent with valid credentials
+func unauthorized(message string) error {
+	return &apiError{status: http.StatusUnauthorized, code: CodeUnauthorized, detail: message}
+}
+
+// The credentials of the request do not allow it
+func forbidden(message string) error {
+	return &apiError{status: http.StatusForbidden, code: CodeForbidden, detail: message}
+}
+
+// The requested resource does not exist
+func notFound(message string) error {
+	return &apiError{status: http.StatusNotFound, code: CodeNotFound, detail: message}
+}
+
+// The request conflicts with the current state of the resource
+func conflict(message string) error {
+	return &apiError{status: http.StatusConflict, code: CodeConflict, detail: message}
+}
+
+// The client has made too many requests, and must wait before making another
+func tooManyRequests(message string) error {
+	return &apiError{status: http.StatusTooManyRequests, code: CodeRateLimited, detail: message}
+}
+
+// sendError reports an error to the client as a problem; errors that are not an apiError are reported as internal errors,
+// and are expected to have been logged by the caller
+func sendError(err error, w http.ResponseWriter, r *http.Request) {
+	var apiErr *apiError
+	if !errors.As(err, &apiErr) {
+		apiErr = &apiError{status: http.StatusInternalServerError, code: CodeInternalError, detail: "An error has occurred"}
+	}
+
+	problem := &models.Problem{
+		Type:     "about:blank",
+		Title:    http.StatusText(apiErr.status),
+		Status:   apiErr.status,
+		Code:     apiErr.code,
+		Detail:   apiErr.Error(),
+		Instance: r.URL.Path,
+		Errors:   apiErr.fields,
+	}
+	if len(apiErr.fields) > 0 && apiErr.detail == "" {
+		problem.Detail = "The request has invalid fields"
+	}
+
+	w.Header().Set("Content-Type", problemContentType)
+	w.WriteHeader(apiErr.status)
+	err = json.NewEncoder(w).Encode(problem)
+	if err != nil {
+		log.Println(err)
+	}
+}
@@ -0,0 +1,125 @@
+package handler
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/kylegk/sse-rest-server/models"
+)
+
+// Decode a problem response, verifying its status and content type
+func readProblem(t *testing.T, response *httptest.ResponseRecorder, status int) models.Problem {
+	have := response.Code
+	want := status
+	if have != want {
+		t.Errorf("HTTP status is incorrect; have: %v, want: %v", have, want)
+	}
+
+	contentType := response.Header().Get("Content-Type")
+	if contentType != problemContentType {
+		t.Errorf("Content type is not a problem; have: %v, want: %v", contentType, problemContentType)
+	}
+
+	problem := models.Problem{}
+	resBytes, _ := ioutil.ReadAll(response.Body)
+	err := json.Unmarshal(resBytes, &problem)
+	if err != nil {
+		t.Errorf("Unable to parse the problem; %v", err)
+	}
+	if problem.Status != status {
+		t.Errorf("Problem status is incorrect; have: %v, want: %v", problem.Status, status)
+	}
+
+	return problem
+}
+
+func TestValidationProblem(t *testing.T) {
+	router, err := addExamTestRoutes()
+	if err != nil {
+		t.Errorf("Failed to start server")
+	}
+
+	// Verify every missing field is reported, rather than only the first
+	request, _ := http.NewRequest("POST", "/exams", strings.NewReader(`{}`))
+	response := httptest.NewRecorder()
+	router.ServeHTTP(response, request)
+
+	problem := readProblem(t, response, 422)
+	if problem.Code != CodeValidationFailed || problem.Instance != "/exams" {
+		t.Errorf("Incorrect problem returned; have: %+v", problem)
+	}
+
+	have := make([]string, 0)
+	for _, field := range problem.Errors {
+		if field.Code != FieldRequired {
+			t.Errorf("Incorrect field code; have: %v, want: %v", field.Code, FieldRequired)
+		}
+		have = append(have, field.Field)
+	}
+	want := "exam,score,studentid"
+	if strings.Join(have, ",") != want {
+		t.Errorf("Incorrect fields reported; have: %v, want: %v", have, want)
+	}
+
+	// Verify a body that cannot be parsed is a malformed body
+	request, _ = http.NewRequest("POST", "/exams", strings.NewReader(`{"exam":`))
+	response = httptest.NewRecorder()
+	router.ServeHTTP(response, request)
+
+	problem = readProblem(t, response, 400)
+	if problem.Code != CodeMalformedBody {
+		t.Errorf("Incorrect problem code; have: %v, want: %v", problem.Code, CodeMalformedBody)
+	}
+}
+
+func TestInvalidPathProblem(t *testing.T) {
+	router, err := addExamTestRoutes()
+	if err != nil {
+		t.Errorf("Failed to start server")
+	}
+
+	// Verify an exam id that is not a number is a bad request rather than a server error
+	for _, path := range []string{"/exams/abc", "/exams/abc/grade-distribution"} {
+		request, _ := http.NewRequest("GET", path, nil)
+		response := httptest.NewRecorder()
+		router.ServeHTTP(response, request)
+
+		problem := readProblem(t, response, 400)
+		if problem.Code != CodeInvalidParameter || len(problem.Errors) != 1 || problem.Errors[0].Field != "id" {
+			t.Errorf("Incorrect problem returned for %v; have: %+v", path, problem)
+		}
+	}
+
+	// Verify a missing exam is reported as a problem
+	request, _ := http.NewRequest("GET", "/exams/99", nil)
+	response := httptest.NewRecorder()
+	router.ServeHTTP(response, request)
+
+	problem := readProblem(t, response, 404)
+	if problem.Code != CodeNotFound || problem.Title != "Not Found" {
+		t.Errorf("Incorrect problem returned; have: %+v", problem)
+	}
+}
+
+func TestPanicProblem(t *testing.T) {
+	router := mux.NewRouter()
+	router.HandleFunc("/panic", func(w http.ResponseWriter, r *http.Request) {
+		panic("test panic")
+	})
+	router.Use(PanicRecovery)
+
+	// Verify a panic is reported as an internal error problem
+	request, _ := http.NewRequest("GET", "/panic", nil)
+	response := httptest.NewRecorder()
+	router.ServeHTTP(response, request)
+
+	problem := readProblem(t, response, 500)
+	if problem.Code != CodeInternalError || problem.Detail != "An error has occurred" {
+		t.Errorf("Incorrect problem returned; have: %+v", problem)
+	}
+}
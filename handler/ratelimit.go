@@ -0,0 +1,95 @@
+package handler
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/kylegk/sse-rest-server/ratelimit"
+)
+
+// Define the headers reporting the state of a client's rate limit
+const (
+	RateLimitLimitHeader     = "RateLimit-Limit"
+	RateLimitRemainingHeader = "RateLimit-Remaining"
+	RateLimitResetHeader     = "RateLimit-Reset"
+)
+
+// RateLimit is a middleware that spends tokens from the rate limit of the client of every request, rejecting the request
+// with 429 Too Many Requests once the client has none left
+// Authenticated clients are limited by their API key or token subject, and the others by their IP address
+// The costs are keyed by the method and path template of a route, with the prefix removed so the versioned and
+// unversioned paths cost the same; routes that are not listed cost one token
+func RateLimit(prefix string, costs map[string]int) func(http.Handler) http.Handler {
+	return func(h http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if spendTokens(w, r, rateLimitKey(r), routeCost(r, prefix, costs)) {
+				h.ServeHTTP(w, r)
+			}
+		})
+	}
+}
+
+// Spend tokens from a rate limit, reporting its state in the response headers
+// Once the limit has no tokens left the request is rejected with 429 Too Many Requests, and false is returned
+func spendTokens(w http.ResponseWriter, r *http.Request, key string, cost int) bool {
+	if !ratelimit.Enabled() {
+		return true
+	}
+
+	res := ratelimit.Take(key, cost)
+	w.Header().Set(RateLimitLimitHeader, strconv.Itoa(res.Limit))
+	w.Header().Set(RateLimitRemainingHeader, strconv.Itoa(res.Remaining))
+	w.Header().Set(RateLimitResetHeader, strconv.Itoa(int(res.Reset/time.Second)))
+
+	if !res.Allowed {
+		retryAfter := int(res.RetryAfter / time.Second)
+		w.Header().Set("Retry-After", strconv.Itoa(retryAfter))
+		sendError(tooManyRequests(fmt.Sprintf("rate limit exceeded: retry in %d seconds", retryAfter)), w, r)
+		return false
+	}
+
+	return true
+}
+
+// Identify the client a request is limited as
+func rateLimitKey(r *http.Request) string {
+	if principal, ok := requestPrincipal(r); ok {
+		return principal.ID
+	}
+
+	return addressRateLimitKey(r)
+}
+
+// Identify the address a request came from, which limits the requests without a client
+func addressRateLimitKey(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+
+	return "ip:" + host
+}
+
+// The number of tokens the route matching a request costs
+func routeCost(r *http.Request, prefix string, costs map[string]int) int {
+	route := mux.CurrentRoute(r)
+	if route == nil {
+		return 1
+	}
+
+	path, err := route.GetPathTemplate()
+	if err != nil {
+		return 1
+	}
+
+	if cost, ok := costs[r.Method+" "+strings.TrimPrefix(path, prefix)]; ok {
+		return cost
+	}
+
+	return 1
+}
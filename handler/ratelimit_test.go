@@ -0,0 +1,130 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/kylegk/sse-rest-server/apikey"
+	"github.com/kylegk/sse-rest-server/auth"
+	"github.com/kylegk/sse-rest-server/config"
+	"github.com/kylegk/sse-rest-server/db"
+	"github.com/kylegk/sse-rest-server/ratelimit"
+)
+
+func addRateLimitTestRoutes() (*mux.Router, error) {
+	err := db.InitDB(config.DBSchema)
+	if err != nil {
+		return nil, err
+	}
+
+	router := mux.NewRouter()
+	v1 := router.PathPrefix("/v1").Subrouter()
+	v1.HandleFunc("/exams", GetAllUniqueExamIDs).Methods("GET")
+	v1.HandleFunc("/exams/all", GetAllExams).Methods("GET")
+	router.Use(RateLimit("/v1", map[string]int{"GET /exams/all": 4}))
+
+	return router, nil
+}
+
+func TestRateLimit(t *testing.T) {
+	router, err := addRateLimitTestRoutes()
+	if err != nil {
+		t.Errorf("Failed to start server")
+	}
+	defer ratelimit.Setup(0, 0)
+	ratelimit.Setup(1, 10)
+
+	dashboard := auth.Principal{ID: "key:dashboard", Role: auth.RoleTeacher, AllStudents: true}
+
+	tests := []struct {
+		url       string
+		principal *auth.Principal
+		status    int
+		remaining string
+	}{
+		{"/v1/exams/all", nil, 200, "6"},
+		{"/v1/exams/all", nil, 200, "2"},
+		{"/v1/exams/all", nil, 429, "2"},
+		{"/v1/exams", nil, 200, "1"},
+		// Authenticated clients are limited by their key rather than their address
+		{"/v1/exams/all", &dashboard, 200, "6"},
+	}
+	for _, test := range tests {
+		request, _ := http.NewRequest("GET", test.url, nil)
+		request.RemoteAddr = "192.0.2.10:51234"
+		if test.principal != nil {
+			request = request.WithContext(context.WithValue(request.Context(), principalContext, *test.principal))
+		}
+		response := httptest.NewRecorder()
+		router.ServeHTTP(response, request)
+
+		if response.Code != test.status {
+			t.Errorf("HTTP status is incorrect for %v; have: %v, want: %v", test.url, response.Code, test.status)
+		}
+		if response.Header().Get(RateLimitLimitHeader) != "10" || response.Header().Get(RateLimitRemainingHeader) != test.remaining || response.Header().Get(RateLimitResetHeader) == "" {
+			t.Errorf("Incorrect rate limit headers for %v; have: %v", test.url, response.Header())
+		}
+		if response.Code == 429 {
+			problem := readProblem(t, response, 429)
+			if problem.Code != CodeRateLimited || response.Header().Get("Retry-After") == "" {
+				t.Errorf("Incorrect rate limited response; have: %+v, Retry-After: %q", problem, response.Header().Get("Retry-After"))
+			}
+		}
+	}
+
+	// Requests are not limited while the limits are off
+	ratelimit.Setup(0, 0)
+	request, _ := http.NewRequest("GET", "/v1/exams/all", nil)
+	request.RemoteAddr = "192.0.2.10:51234"
+	response := httptest.NewRecorder()
+	router.ServeHTTP(response, request)
+	if response.Code != 200 || response.Header().Get(RateLimitLimitHeader) != "" {
+		t.Errorf("Request was limited while the limits are off; have: %v %v", response.Code, response.Header())
+	}
+}
+
+func TestRateLimitFailedAuthentication(t *testing.T) {
+	err := db.InitDB(config.DBSchema)
+	if err != nil {
+		t.Errorf("Failed to start server")
+	}
+	defer ratelimit.Setup(0, 0)
+	ratelimit.Setup(1, 3)
+
+	// The requests are authenticated before they are limited, as they are by the server
+	router := mux.NewRouter()
+	router.HandleFunc("/v1/exams", GetAllUniqueExamIDs).Methods("GET")
+	router.Use(Authenticate())
+	router.Use(RateLimit("/v1", nil))
+
+	err = apikey.AddKey("dashboard", "dashboard-key", false)
+	if err != nil {
+		t.Errorf("Unable to add the key; %v", err)
+	}
+
+	// Every bad key spends a token from the address, so guessing is rejected once the address has none left
+	for i, want := range []int{401, 401, 401, 429, 429} {
+		request, _ := http.NewRequest("GET", "/v1/exams", nil)
+		request.RemoteAddr = "192.0.2.10:51234"
+		request.Header.Set(APIKeyHeader, "wrong-key")
+		response := httptest.NewRecorder()
+		router.ServeHTTP(response, request)
+
+		if response.Code != want {
+			t.Errorf("HTTP status is incorrect for attempt %v; have: %v, want: %v", i, response.Code, want)
+		}
+	}
+
+	// A valid key is limited by the key rather than the address
+	request, _ := http.NewRequest("GET", "/v1/exams", nil)
+	request.RemoteAddr = "192.0.2.10:51234"
+	request.Header.Set(APIKeyHeader, "dashboard-key")
+	response := httptest.NewRecorder()
+	router.ServeHTTP(response, request)
+	if response.Code != 200 {
+		t.Errorf("HTTP status is incorrect for a valid key; have: %v, want: %v", response.Code, 200)
+	}
+}
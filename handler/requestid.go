@@ -0,0 +1,52 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/kylegk/sse-rest-server/db"
+)
+
+// RequestIDHeader is the header identifying a request, sent by the client or generated by the server, and returned in the response
+const RequestIDHeader = "X-Request-ID"
+
+// The longest request id accepted from a client; longer ids are replaced, so a client cannot fill the audit log with them
+const maxRequestIDLength = 128
+
+// The request context key of the id of a request
+const requestIDContext contextKey = "request_id"
+
+// RequestID is a middleware that identifies every request, so the changes it made can be found in the audit log
+// The id sent by the client is kept, so a request can be traced across services, and one is generated otherwise
+func RequestID(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(RequestIDHeader)
+		if !validRequestID(id) {
+			id = db.NewID()
+		}
+
+		w.Header().Set(RequestIDHeader, id)
+		h.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), requestIDContext, id)))
+	})
+}
+
+// A request id must be printable ASCII, as it is returned in a header and written to the audit log
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+
+	for _, c := range id {
+		if c < '!' || c > '~' {
+			return false
+		}
+	}
+
+	return true
+}
+
+// The id of a request, if it has one
+func requestID(r *http.Request) string {
+	id, _ := r.Context().Value(requestIDContext).(string)
+	return id
+}
@@ -0,0 +1,41 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRequestID(t *testing.T) {
+	var seen string
+	h := RequestID(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = requestID(r)
+	}))
+
+	tests := []struct {
+		sent string
+		kept bool
+	}{
+		{"", false},
+		{"trace-1234", true},
+		{"has spaces", false},
+		{strings.Repeat("a", maxRequestIDLength+1), false},
+	}
+	for _, test := range tests {
+		request, _ := http.NewRequest("GET", "/students", nil)
+		if test.sent != "" {
+			request.Header.Set(RequestIDHeader, test.sent)
+		}
+		response := httptest.NewRecorder()
+		h.ServeHTTP(response, request)
+
+		have := response.Header().Get(RequestIDHeader)
+		if have == "" || have != seen {
+			t.Errorf("Request id was not set; have: %q, seen by the handler: %q", have, seen)
+		}
+		if (have == test.sent) != test.kept {
+			t.Errorf("Incorrect request id for %q; have: %q, kept: %v", test.sent, have, test.kept)
+		}
+	}
+}
@@ -4,29 +4,27 @@ import (
 	"encoding/json"
 	"log"
 	"net/http"
-
-	"github.com/kylegk/sse-rest-server/models"
 )
 
 // SendGenericNotFoundResponse returns a generic 404 error
 func SendGenericNotFoundResponse(w http.ResponseWriter, r *http.Request) {
-	sendResponse(&models.GenericResponse{Error: "Not Found", Code: http.StatusNotFound, Message: "Resource not found"}, http.StatusNotFound, w)
+	sendError(notFound("Resource not found"), w, r)
 }
 
 // SendGenericNotAllowedResponse returns a generic 405 error
 func SendGenericNotAllowedResponse(w http.ResponseWriter, r *http.Request) {
-	sendResponse(&models.GenericResponse{Error: "Not Allowed", Code: http.StatusMethodNotAllowed, Message: "You are not authorized to access this resource"}, http.StatusMethodNotAllowed, w)
+	sendError(&apiError{status: http.StatusMethodNotAllowed, code: CodeMethodNotAllowed, detail: "The method is not allowed for this resource"}, w, r)
 }
 
 // SendGenericInternalServerError returns a generic 500 error
 func SendGenericInternalServerError(w http.ResponseWriter, r *http.Request) {
-	sendResponse(&models.GenericResponse{Error: "Internal Server Error", Code: http.StatusInternalServerError, Message: "An error has occurred"}, http.StatusInternalServerError, w)
+	sendError(&apiError{status: http.StatusInternalServerError, code: CodeInternalError, detail: "An error has occurred"}, w, r)
 }
 
 // sendResponse is a generic method to send a custom response to the client
 func sendResponse(payload interface{}, status int, w http.ResponseWriter) {
-	w.WriteHeader(status)
 	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	w.WriteHeader(status)
 	enc := json.NewEncoder(w)
 	err := enc.Encode(payload)
 	if err != nil {
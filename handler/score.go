@@ -0,0 +1,141 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/kylegk/sse-rest-server/config"
+	"github.com/kylegk/sse-rest-server/db"
+	"github.com/kylegk/sse-rest-server/models"
+)
+
+// The request body of a score update; the exam and student are taken from the url, and only need to be sent if they match it
+type scoreUpdate struct {
+	Exam      int      `json:"exam"`
+	StudentID string   `json:"studentid"`
+	Score     *float64 `json:"score"`
+}
+
+// PutScore records a student's score for an exam, replacing the existing score if there is one
+func PutScore(w http.ResponseWriter, r *http.Request) {
+	updateScore(w, r, true)
+}
+
+// PatchScore corrects a student's existing score for an exam
+func PatchScore(w http.ResponseWriter, r *http.Request) {
+	updateScore(w, r, false)
+}
+
+// DeleteScore removes a single student's score for an exam
+func DeleteScore(w http.ResponseWriter, r *http.Request) {
+	var err error
+	defer func() {
+		if err != nil {
+			sendError(err, w, r)
+			return
+		}
+	}()
+
+	examID, studentID, parseErr := scoreKey(r)
+	if parseErr != nil {
+		sendError(parseErr, w, r)
+		return
+	}
+
+	// The score is looked up and removed in one transaction, so a score deleted concurrently is not found
+	events, err := db.DeleteScores(requestAuditor(r, models.AuditScoreDeleted), config.IdFld, examID, studentID)
+	if err != nil {
+		log.Println(err)
+		return
+	}
+
+	if len(events) == 0 {
+		SendGenericNotFoundResponse(w, r)
+		return
+	}
+
+	sendResponse(&models.GenericResponse{Message: fmt.Sprintf("Successfully deleted exam %v for student %v", examID, studentID)}, http.StatusOK, w)
+}
+
+// Replace or correct a student's score; a new score is only created when create is set
+func updateScore(w http.ResponseWriter, r *http.Request, create bool) {
+	var err error
+	defer func() {
+		if err != nil {
+			sendError(err, w, r)
+			return
+		}
+	}()
+
+	examID, studentID, parseErr := scoreKey(r)
+	if parseErr != nil {
+		sendError(parseErr, w, r)
+		return
+	}
+
+	bytes, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		log.Println(err)
+		return
+	}
+
+	update := scoreUpdate{}
+	if json.Unmarshal(bytes, &update) != nil {
+		sendError(malformedBody("unable to parse request"), w, r)
+		return
+	}
+	if (update.Exam != 0 && update.Exam != examID) || (update.StudentID != "" && update.StudentID != studentID) {
+		sendError(invalidRequest("the exam and studentid cannot be changed"), w, r)
+		return
+	}
+	if update.Score == nil {
+		sendError(validationFailed([]models.FieldError{{Field: "score", Code: FieldRequired, Message: "invalid score"}}), w, r)
+		return
+	}
+
+	score := models.StudentExam{Exam: examID, StudentID: studentID, Score: *update.Score}
+	validErr := validateRequestBody(score)
+	if validErr != nil {
+		sendError(validErr, w, r)
+		return
+	}
+
+	// A correction looks up and writes the score in one transaction, so it never creates a score that was deleted concurrently
+	var events []models.ScoreEvent
+	if create {
+		events, err = db.UpsertScores([]models.StudentExam{score}, requestAuditor(r, models.AuditScoreUpdated))
+	} else {
+		events, err = db.UpdateScore(score, requestAuditor(r, models.AuditScoreUpdated))
+	}
+	if err == db.ErrNoScore {
+		err = nil
+		SendGenericNotFoundResponse(w, r)
+		return
+	}
+	if err != nil {
+		log.Println(err)
+		return
+	}
+
+	// Return the score as it was stored, with its curve applied and the time it was recorded
+	status := http.StatusOK
+	if events[0].Before == nil {
+		status = http.StatusCreated
+	}
+
+	sendResponse(events[0].After, status, w)
+}
+
+// Parse the exam and student ids of a single score from the url
+func scoreKey(r *http.Request) (int, string, error) {
+	examID, err := examIDParam(r)
+	if err != nil {
+		return 0, "", err
+	}
+
+	return examID, mux.Vars(r)["student"], nil
+}
@@ -0,0 +1,143 @@
+package handler
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/kylegk/sse-rest-server/config"
+	"github.com/kylegk/sse-rest-server/db"
+	"github.com/kylegk/sse-rest-server/models"
+)
+
+func addScoreTestRoutes() (*mux.Router, error) {
+	err := db.InitDB(config.DBSchema)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, exam := range examTestData {
+		err = db.UpsertRow(config.ScoreTable, exam)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	router := mux.NewRouter()
+	router.HandleFunc("/exams/{id}/students/{student}", PutScore).Methods("PUT")
+	router.HandleFunc("/exams/{id}/students/{student}", PatchScore).Methods("PATCH")
+	router.HandleFunc("/exams/{id}/students/{student}", DeleteScore).Methods("DELETE")
+
+	return router, nil
+}
+
+func TestPutScore(t *testing.T) {
+	router, err := addScoreTestRoutes()
+	if err != nil {
+		t.Errorf("Failed to start server")
+	}
+
+	// Verify a new score is created
+	request, _ := http.NewRequest("PUT", "/exams/2/students/test.person2", strings.NewReader(`{"score": 0.55}`))
+	response := httptest.NewRecorder()
+	router.ServeHTTP(response, request)
+
+	have := response.Code
+	want := 201
+	if have != want {
+		t.Errorf("HTTP status is not Created; have: %v, want: %v", have, want)
+	}
+
+	body := models.StudentExam{}
+	resBytes, _ := ioutil.ReadAll(response.Body)
+	err = json.Unmarshal(resBytes, &body)
+	if err != nil || body.Exam != 2 || body.StudentID != "test.person2" || body.Score != 0.55 {
+		t.Errorf("Incorrect score returned; have: %+v", body)
+	}
+
+	// Verify the exam cannot be changed through the body
+	request, _ = http.NewRequest("PUT", "/exams/2/students/test.person2", strings.NewReader(`{"exam": 3, "score": 0.55}`))
+	response = httptest.NewRecorder()
+	router.ServeHTTP(response, request)
+
+	have = response.Code
+	want = 422
+	if have != want {
+		t.Errorf("HTTP status is not Unprocessable Entity; have: %v, want: %v", have, want)
+	}
+}
+
+func TestPatchScore(t *testing.T) {
+	router, err := addScoreTestRoutes()
+	if err != nil {
+		t.Errorf("Failed to start server")
+	}
+
+	// Verify an existing score is corrected, and the exam aggregate follows it
+	request, _ := http.NewRequest("PATCH", "/exams/1/students/test.person", strings.NewReader(`{"score": 0.77}`))
+	response := httptest.NewRecorder()
+	router.ServeHTTP(response, request)
+
+	have := response.Code
+	want := 200
+	if have != want {
+		t.Errorf("HTTP status is not OK; have: %v, want: %v", have, want)
+	}
+
+	stats, err := db.GetExamAggregate(1)
+	if err != nil || stats == nil || stats.Count != 3 || stats.Min != 0.75 {
+		t.Errorf("Incorrect exam aggregate; have: %+v", stats)
+	}
+
+	// Verify a missing score is not created
+	request, _ = http.NewRequest("PATCH", "/exams/2/students/test.person2", strings.NewReader(`{"score": 0.77}`))
+	response = httptest.NewRecorder()
+	router.ServeHTTP(response, request)
+
+	have = response.Code
+	want = 404
+	if have != want {
+		t.Errorf("HTTP status is not Not Found; have: %v, want: %v", have, want)
+	}
+}
+
+func TestDeleteScore(t *testing.T) {
+	router, err := addScoreTestRoutes()
+	if err != nil {
+		t.Errorf("Failed to start server")
+	}
+
+	request, _ := http.NewRequest("DELETE", "/exams/1/students/test.person", nil)
+	response := httptest.NewRecorder()
+	router.ServeHTTP(response, request)
+
+	// Verify status code is 200
+	have := response.Code
+	want := 200
+	if have != want {
+		t.Errorf("HTTP status is not OK; have: %v, want: %v", have, want)
+	}
+
+	// Verify only the one score was removed
+	res, _ := db.GetRows(config.ScoreTable, config.ExamIdx, 1)
+	have = len(res)
+	want = 2
+	if have != want {
+		t.Errorf("Incorrect number of scores remaining; have: %v, want: %v", have, want)
+	}
+
+	// Verify the score cannot be deleted twice
+	request, _ = http.NewRequest("DELETE", "/exams/1/students/test.person", nil)
+	response = httptest.NewRecorder()
+	router.ServeHTTP(response, request)
+
+	have = response.Code
+	want = 404
+	if have != want {
+		t.Errorf("HTTP status is not Not Found; have: %v, want: %v", have, want)
+	}
+}
@@ -1,21 +1,31 @@
 package handler
 
 import (
+	"fmt"
 	"github.com/kylegk/sse-rest-server/config"
 	"log"
 	"net/http"
+	"sort"
+	"strconv"
+	"strings"
 
 	"github.com/gorilla/mux"
+	"github.com/kylegk/sse-rest-server/analytics"
 	"github.com/kylegk/sse-rest-server/db"
+	"github.com/kylegk/sse-rest-server/grading"
 	"github.com/kylegk/sse-rest-server/models"
+	"github.com/kylegk/sse-rest-server/report"
 )
 
+// The number of exams averaged by the moving average when a trend request does not specify a window
+const defaultTrendWindow = 3
+
 // GetAllStudents lists all students that have received at least one test score
 func GetAllStudents(w http.ResponseWriter, r *http.Request) {
 	var err error
 	defer func() {
 		if err != nil {
-			SendGenericInternalServerError(w, r)
+			sendError(err, w, r)
 			return
 		}
 	}()
@@ -26,48 +36,303 @@ func GetAllStudents(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	visible := visibleStudents(r)
 	response := &models.AllStudentListResponse{}
 	for _, score := range res {
-		response.Students = append(response.Students, score.(models.StudentExam).StudentID)
+		if student := score.(models.StudentExam).StudentID; visible.Includes(student) {
+			response.Students = append(response.Students, student)
+		}
 	}
 
 	sendResponse(response, http.StatusOK, w)
 }
 
 // GetStudentByID lists the exam results for the specified student, and provides the student's average score across all examTestData
+// The average is calculated with the grading policy named by the "policy" query parameter, or the default policy,
+// and scores are graded using the scale named by the "scale" query parameter, or the default scale
+// Passing "scores=curved" reports the curved scores of any curved exams in place of the raw scores
 func GetStudentByID(w http.ResponseWriter, r *http.Request) {
 	var err error
 	defer func() {
 		if err != nil {
-			SendGenericInternalServerError(w, r)
+			sendError(err, w, r)
+			return
+		}
+	}()
+
+	vars := mux.Vars(r)
+	studentID := vars["id"]
+
+	policy, policyErr := grading.GetPolicy(r.URL.Query().Get("policy"))
+	if policyErr != nil {
+		sendError(invalidParameter("policy", policyErr.Error()), w, r)
+		return
+	}
+
+	scale, scaleErr := grading.GetScale(r.URL.Query().Get("scale"))
+	if scaleErr != nil {
+		sendError(invalidParameter("scale", scaleErr.Error()), w, r)
+		return
+	}
+
+	curved, curvedErr := useCurvedScores(r)
+	if curvedErr != nil {
+		sendError(invalidParameter("scores", curvedErr.Error()), w, r)
+		return
+	}
+
+	format, formatErr := exportFormat(r)
+	if formatErr != nil {
+		sendError(invalidParameter("format", formatErr.Error()), w, r)
+		return
+	}
+
+	// Streamed exports contain the graded scores without the average
+	if format != formatJSON {
+		e := newExporter(w, format, scoreColumns)
+		exportErr := db.EachRow(config.ScoreTable, config.StudentIdx, func(row interface{}) error {
+			return e.writeScore(row.(models.StudentExam), curved, scale)
+		}, studentID)
+		e.finish(exportErr, r)
+		return
+	}
+
+	response, err := report.Student(studentID, policy, scale, curved)
+	if err == report.ErrNotFound {
+		err = nil
+		SendGenericNotFoundResponse(w, r)
+		return
+	}
+	if err != nil {
+		log.Println(err)
+		return
+	}
+
+	sendResponse(response, http.StatusOK, w)
+}
+
+// DeleteStudent removes all of the specified student's exams
+func DeleteStudent(w http.ResponseWriter, r *http.Request) {
+	var err error
+	defer func() {
+		if err != nil {
+			sendError(err, w, r)
 			return
 		}
 	}()
 
 	vars := mux.Vars(r)
 	studentID := vars["id"]
-	response := &models.StudentByIDResponse{Student: studentID}
 
-	res, err := db.GetRows(config.ScoreTable, config.StudentIdx, studentID)
+	events, err := db.DeleteScores(requestAuditor(r, models.AuditStudentDeleted), config.StudentIdx, studentID)
+	if err != nil {
+		log.Println(err)
+		return
+	}
+
+	sendResponse(&models.GenericResponse{Message: fmt.Sprintf("Successfully deleted %v exams", len(events))}, http.StatusOK, w)
+}
+
+// GetStudentTrend orders a student's exams by the time they were recorded (or by exam id), fits a linear trend to the scores
+// and reports the moving average of the scores and whether the student is significantly improving or declining
+func GetStudentTrend(w http.ResponseWriter, r *http.Request) {
+	var err error
+	defer func() {
+		if err != nil {
+			sendError(err, w, r)
+			return
+		}
+	}()
+
+	vars := mux.Vars(r)
+	studentID := vars["id"]
+	query := r.URL.Query()
+
+	order := query.Get("order")
+	if order == "" {
+		order = "time"
+	}
+	if order != "time" && order != "exam" {
+		sendError(invalidParameter("order", "invalid order: must be time or exam"), w, r)
+		return
+	}
+
+	window := defaultTrendWindow
+	if query.Get("window") != "" {
+		parsed, parseErr := strconv.Atoi(query.Get("window"))
+		if parseErr != nil || parsed < 1 {
+			sendError(invalidParameter("window", "invalid window: must be a positive integer"), w, r)
+			return
+		}
+		window = parsed
+	}
+
+	curved, curvedErr := useCurvedScores(r)
+	if curvedErr != nil {
+		sendError(invalidParameter("scores", curvedErr.Error()), w, r)
+		return
+	}
+
+	res, err := report.Scores(config.StudentIdx, studentID)
 	if err != nil {
 		log.Println(err)
 		return
 	}
 
-	count := len(res)
-	if count == 0 {
+	if len(res) == 0 {
 		SendGenericNotFoundResponse(w, r)
 		return
 	}
 
-	var sum float64
-	for _, score := range res {
-		response.Exams = append(response.Exams, models.StudentExamScores{Exam: score.(models.StudentExam).Exam, Score: score.(models.StudentExam).Score})
-		sum += score.(models.StudentExam).Score
+	sort.SliceStable(res, func(i, j int) bool {
+		if order == "exam" || res[i].RecordedAt.Equal(res[j].RecordedAt) {
+			return res[i].Exam < res[j].Exam
+		}
+		return res[i].RecordedAt.Before(res[j].RecordedAt)
+	})
+
+	// Exams are evenly spaced on the x axis, so the slope is the change in score per exam
+	xs := make([]float64, len(res))
+	ys := make([]float64, len(res))
+	for i, exam := range res {
+		xs[i] = float64(i)
+		ys[i] = exam.Value(curved)
+	}
+
+	fit := analytics.LinearFit(xs, ys)
+	averages := analytics.MovingAverage(ys, window)
+
+	response := &models.StudentTrendResponse{
+		Student:     studentID,
+		Order:       order,
+		Window:      window,
+		ScoreType:   report.ScoreType(curved),
+		Slope:       fit.Slope,
+		Intercept:   fit.Intercept,
+		RSquared:    fit.RSquared,
+		Significant: fit.Significant,
+		Trend:       trendDirection(len(res), fit),
+	}
+	for i, exam := range res {
+		response.Points = append(response.Points, models.TrendPoint{Exam: exam.Exam, Score: ys[i], RecordedAt: exam.RecordedAt, MovingAverage: averages[i]})
+	}
+
+	sendResponse(response, http.StatusOK, w)
+}
+
+// CompareStudents compares the students in the "ids" query parameter head-to-head on the exams every one of them took,
+// reporting each student's score and the leader of every shared exam, and each student's average and number of wins
+// Passing "scores=curved" compares the curved scores of any curved exams in place of the raw scores
+func CompareStudents(w http.ResponseWriter, r *http.Request) {
+	var err error
+	defer func() {
+		if err != nil {
+			sendError(err, w, r)
+			return
+		}
+	}()
+
+	students := make([]string, 0)
+	seen := make(map[string]bool)
+	for _, id := range strings.Split(r.URL.Query().Get("ids"), ",") {
+		id = strings.TrimSpace(id)
+		if id != "" && !seen[id] {
+			seen[id] = true
+			students = append(students, id)
+		}
+	}
+	if len(students) < 2 {
+		sendError(invalidParameter("ids", "invalid ids: at least two student ids are required"), w, r)
+		return
+	}
+
+	curved, curvedErr := useCurvedScores(r)
+	if curvedErr != nil {
+		sendError(invalidParameter("scores", curvedErr.Error()), w, r)
+		return
 	}
 
-	avg := sum / float64(count)
-	response.Average = avg
+	// Index each student's scores by exam, keeping only the exams every student took
+	scores := make(map[string]map[int]float64, len(students))
+	shared := make(map[int]int)
+	for _, studentID := range students {
+		var res []models.StudentExam
+		res, err = report.Scores(config.StudentIdx, studentID)
+		if err != nil {
+			log.Println(err)
+			return
+		}
+
+		if len(res) == 0 {
+			SendGenericNotFoundResponse(w, r)
+			return
+		}
+
+		scores[studentID] = make(map[int]float64, len(res))
+		for _, exam := range res {
+			scores[studentID][exam.Exam] = exam.Value(curved)
+			shared[exam.Exam]++
+		}
+	}
+
+	exams := make([]int, 0)
+	for exam, count := range shared {
+		if count == len(students) {
+			exams = append(exams, exam)
+		}
+	}
+	sort.Ints(exams)
+
+	response := &models.StudentCompareResponse{Students: students, ScoreType: report.ScoreType(curved), Exams: make([]models.ComparedExam, 0, len(exams))}
+	wins := make(map[string]int)
+	for _, exam := range exams {
+		compared := models.ComparedExam{Exam: exam, Scores: make(map[string]float64, len(students))}
+		best, tied := -1.0, false
+		for _, studentID := range students {
+			score := scores[studentID][exam]
+			compared.Scores[studentID] = score
+
+			switch {
+			case score > best:
+				best, tied = score, false
+				compared.Leader = studentID
+			case score == best:
+				tied = true
+			}
+		}
+
+		if tied {
+			compared.Leader = ""
+		} else {
+			wins[compared.Leader]++
+		}
+		response.Exams = append(response.Exams, compared)
+	}
+
+	for _, studentID := range students {
+		summary := models.ComparedStudent{Student: studentID, Wins: wins[studentID]}
+		for _, exam := range exams {
+			summary.Average += scores[studentID][exam]
+		}
+		if len(exams) > 0 {
+			summary.Average /= float64(len(exams))
+		}
+		response.Summary = append(response.Summary, summary)
+	}
 
 	sendResponse(response, http.StatusOK, w)
 }
+
+// Describe the direction of a trend, which is only reported as changing when the slope is significant
+func trendDirection(count int, fit analytics.Fit) string {
+	switch {
+	case count < 3:
+		return "insufficient_data"
+	case fit.Significant && fit.Slope > 0:
+		return "improving"
+	case fit.Significant && fit.Slope < 0:
+		return "declining"
+	default:
+		return "stable"
+	}
+}
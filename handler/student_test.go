@@ -9,7 +9,9 @@ import (
 	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
+	"reflect"
 	"testing"
+	"time"
 )
 
 var studentTestData = []models.StudentExam{
@@ -55,7 +57,10 @@ func addStudentTestRoutes() (*mux.Router, error) {
 
 	router := mux.NewRouter()
 	router.HandleFunc("/students", GetAllStudents).Methods("GET")
+	router.HandleFunc("/students/compare", CompareStudents).Methods("GET")
 	router.HandleFunc("/students/{id}", GetStudentByID).Methods("GET")
+	router.HandleFunc("/students/{id}", DeleteStudent).Methods("DELETE")
+	router.HandleFunc("/students/{id}/trend", GetStudentTrend).Methods("GET")
 
 	return router, nil
 }
@@ -146,3 +151,199 @@ func TestGetStudentByID(t *testing.T) {
 		t.Errorf("Incorrect average; have: %v, want: %v", have, want)
 	}
 }
+
+func TestGetStudentTrend(t *testing.T) {
+	router, err := addStudentTestRoutes()
+	if err != nil {
+		t.Errorf("Failed to start server")
+	}
+
+	// Test with an invalid window
+	request, _ := http.NewRequest("GET", "/students/test.person1/trend?window=0", nil)
+	response := httptest.NewRecorder()
+	router.ServeHTTP(response, request)
+
+	// Verify status code is 400
+	have := response.Code
+	want := 400
+	if have != want {
+		t.Errorf("Route returned an incorrect status code; have: %v, want: %v", have, want)
+	}
+
+	// Test a valid user ordered by exam
+	request, _ = http.NewRequest("GET", "/students/test.person1/trend?order=exam&window=2", nil)
+	response = httptest.NewRecorder()
+	router.ServeHTTP(response, request)
+
+	// Verify status code is 200
+	have = response.Code
+	want = 200
+	if have != want {
+		t.Errorf("Route returned an incorrect status code; have: %v, want: %v", have, want)
+	}
+
+	resBytes, err := ioutil.ReadAll(response.Body)
+	if err != nil {
+		t.Errorf("Unable to read response body")
+	}
+	body := models.StudentTrendResponse{}
+	err = json.Unmarshal(resBytes, &body)
+	if err != nil {
+		t.Errorf("Failed to parse response returned from route")
+	}
+
+	// Verify the exams are ordered and the trend is reported
+	have = len(body.Points)
+	want = 2
+	if have != want {
+		t.Fatalf("Incorrect number of points returned; have: %v, want: %v", have, want)
+	}
+	if body.Points[0].Exam != 1 || body.Points[1].Exam != 2 {
+		t.Errorf("Exams were returned out of order; have: %+v", body.Points)
+	}
+	if body.Trend != "insufficient_data" || body.Slope <= 0 {
+		t.Errorf("Incorrect trend; have: %v, slope: %v", body.Trend, body.Slope)
+	}
+
+	// Verify the moving average of the second exam covers both exams
+	have64 := body.Points[1].MovingAverage
+	want64 := 0.7
+	if have64 != want64 {
+		t.Errorf("Incorrect moving average; have: %v, want: %v", have64, want64)
+	}
+}
+
+func TestGetStudentTrendCorrection(t *testing.T) {
+	router, err := addStudentTestRoutes()
+	if err != nil {
+		t.Errorf("Failed to start server")
+	}
+
+	for exam := 10; exam <= 12; exam++ {
+		_, err = db.UpsertScores([]models.StudentExam{{Exam: exam, StudentID: "test.person9", Score: 0.5}}, nil)
+		if err != nil {
+			t.Fatalf("Failed to insert score")
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	// Correct the earliest exam, which should keep its place in the student's history
+	_, err = db.UpsertScores([]models.StudentExam{{Exam: 10, StudentID: "test.person9", Score: 0.9}}, nil)
+	if err != nil {
+		t.Fatalf("Failed to correct score")
+	}
+
+	request, _ := http.NewRequest("GET", "/students/test.person9/trend", nil)
+	response := httptest.NewRecorder()
+	router.ServeHTTP(response, request)
+
+	body := models.StudentTrendResponse{}
+	err = json.NewDecoder(response.Body).Decode(&body)
+	if err != nil || len(body.Points) != 3 {
+		t.Fatalf("Incorrect trend returned; have: %+v, %v", body, err)
+	}
+
+	have := []int{body.Points[0].Exam, body.Points[1].Exam, body.Points[2].Exam}
+	want := []int{10, 11, 12}
+	if !reflect.DeepEqual(have, want) {
+		t.Errorf("Exams were returned out of order; have: %v, want: %v", have, want)
+	}
+	if body.Points[0].Score != 0.9 {
+		t.Errorf("The corrected score was not returned; have: %v, want: %v", body.Points[0].Score, 0.9)
+	}
+}
+
+func TestCompareStudents(t *testing.T) {
+	router, err := addStudentTestRoutes()
+	if err != nil {
+		t.Errorf("Failed to start server")
+	}
+
+	// Test with a single student
+	request, _ := http.NewRequest("GET", "/students/compare?ids=test.person1", nil)
+	response := httptest.NewRecorder()
+	router.ServeHTTP(response, request)
+
+	// Verify status code is 400
+	have := response.Code
+	want := 400
+	if have != want {
+		t.Errorf("Route returned an incorrect status code; have: %v, want: %v", have, want)
+	}
+
+	// Test two students who share exam 1
+	request, _ = http.NewRequest("GET", "/students/compare?ids=test.person1,test.person2", nil)
+	response = httptest.NewRecorder()
+	router.ServeHTTP(response, request)
+
+	// Verify status code is 200
+	have = response.Code
+	want = 200
+	if have != want {
+		t.Errorf("Route returned an incorrect status code; have: %v, want: %v", have, want)
+	}
+
+	resBytes, err := ioutil.ReadAll(response.Body)
+	if err != nil {
+		t.Errorf("Unable to read response body")
+	}
+	body := models.StudentCompareResponse{}
+	err = json.Unmarshal(resBytes, &body)
+	if err != nil {
+		t.Fatalf("Failed to parse response returned from route")
+	}
+
+	// Verify only the shared exam is compared, and test.person2 leads it
+	if len(body.Exams) != 1 || body.Exams[0].Exam != 1 || body.Exams[0].Leader != "test.person2" {
+		t.Fatalf("Incorrect exams compared; have: %+v", body.Exams)
+	}
+	if len(body.Summary) != 2 || body.Summary[0].Average != 0.5 || body.Summary[1].Wins != 1 {
+		t.Errorf("Incorrect summary; have: %+v", body.Summary)
+	}
+
+	// Test an unknown student
+	request, _ = http.NewRequest("GET", "/students/compare?ids=test.person1,unknown", nil)
+	response = httptest.NewRecorder()
+	router.ServeHTTP(response, request)
+
+	// Verify status code is 404
+	have = response.Code
+	want = 404
+	if have != want {
+		t.Errorf("Route returned an incorrect status code; have: %v, want: %v", have, want)
+	}
+}
+
+func TestDeleteStudent(t *testing.T) {
+	router, err := addStudentTestRoutes()
+	if err != nil {
+		t.Errorf("Failed to start server")
+	}
+
+	request, _ := http.NewRequest("DELETE", "/students/test.person1", nil)
+	response := httptest.NewRecorder()
+	router.ServeHTTP(response, request)
+
+	// Verify status code is 200
+	have := response.Code
+	want := 200
+	if have != want {
+		t.Errorf("Route returned an incorrect status code; have: %v, want: %v", have, want)
+	}
+
+	// Verify the student's scores and aggregate were removed, leaving the other students
+	res, _ := db.GetRows(config.ScoreTable, config.StudentIdx, "test.person1")
+	if len(res) != 0 {
+		t.Errorf("The student's scores were not deleted; have: %v", len(res))
+	}
+	stats, _ := db.GetStudentAggregate("test.person1")
+	if stats != nil {
+		t.Errorf("The student's aggregate was not deleted; have: %+v", stats)
+	}
+	res, _ = db.GetRows(config.ScoreTable, config.IdFld)
+	have = len(res)
+	want = len(studentTestData) - 2
+	if have != want {
+		t.Errorf("Incorrect number of scores remaining; have: %v, want: %v", have, want)
+	}
+}
@@ -5,11 +5,15 @@ import (
 	"log"
 	"net/http"
 	"runtime"
+	"strconv"
 
+	"github.com/gorilla/mux"
+	"github.com/kylegk/sse-rest-server/anomaly"
 	"github.com/kylegk/sse-rest-server/models"
 )
 
 // PanicRecovery is a middleware function used to inform the client an error has occurred and gracefully recover from a panic
+// The client receives the same internal error problem as any other failed request
 func PanicRecovery(h http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		defer func() {
@@ -18,9 +22,8 @@ func PanicRecovery(h http.Handler) http.Handler {
 				n := runtime.Stack(buf, false)
 				buf = buf[:n]
 
-				fmt.Printf("recovering from error: %v\n %s", err, buf)
-				w.WriteHeader(http.StatusInternalServerError)
-				w.Write([]byte(`{"error":"Internal Server Error"}`))
+				log.Printf("recovering from error: %v\n %s", err, buf)
+				SendGenericInternalServerError(w, r)
 			}
 		}()
 
@@ -36,17 +39,34 @@ func LogRequest(handler http.Handler) http.Handler {
 	})
 }
 
-// Verify that the request body (StudentExam struct) is valid
+// Verify that the request body (StudentExam struct) is valid, reporting every invalid field
 func validateRequestBody(exam models.StudentExam) error {
-	if exam.Exam == 0 {
-		return fmt.Errorf("invalid exam id")
+	fields := anomaly.Validate(exam)
+	if len(fields) > 0 {
+		return validationFailed(fields)
 	}
-	if exam.Score == 0 {
-		return fmt.Errorf("invalid score")
+
+	return nil
+}
+
+// Determine whether the raw or curved scores were requested with the "scores" query parameter
+func useCurvedScores(r *http.Request) (bool, error) {
+	switch r.URL.Query().Get("scores") {
+	case "", "raw":
+		return false, nil
+	case "curved":
+		return true, nil
+	default:
+		return false, fmt.Errorf("invalid scores: must be raw or curved")
 	}
-	if exam.StudentID == "" {
-		return fmt.Errorf("invalid studentid")
+}
+
+// Parse the exam id from the url
+func examIDParam(r *http.Request) (int, error) {
+	examID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		return 0, invalidParameter("id", "invalid exam id")
 	}
 
-	return nil
+	return examID, nil
 }
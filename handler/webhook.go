@@ -0,0 +1,149 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/kylegk/sse-rest-server/models"
+	"github.com/kylegk/sse-rest-server/webhook"
+)
+
+// AddWebhook subscribes a url to the score events
+// The secret used to sign the payloads is only returned when the webhook is created
+func AddWebhook(w http.ResponseWriter, r *http.Request) {
+	var err error
+	defer func() {
+		if err != nil {
+			sendError(err, w, r)
+			return
+		}
+	}()
+
+	hook := models.Webhook{}
+	bytes, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		log.Println(err)
+		return
+	}
+
+	err = json.Unmarshal(bytes, &hook)
+	if err != nil {
+		err = malformedBody("unable to parse request")
+		return
+	}
+
+	hook, err = webhook.Create(hook, resourceAuditor(r, models.AuditWebhookCreated, models.AuditResourceWebhook))
+	switch {
+	case err == nil:
+	case errors.Is(err, webhook.ErrInvalidURL):
+		err = validationFailed([]models.FieldError{{Field: "url", Code: FieldInvalid, Message: err.Error()}})
+		return
+	case errors.Is(err, webhook.ErrInvalidEvent):
+		err = validationFailed([]models.FieldError{{Field: "events", Code: FieldInvalid, Message: err.Error()}})
+		return
+	default:
+		log.Println(err)
+		return
+	}
+
+	// The secret is only returned once, so the response must not be stored, including by the Idempotency middleware
+	w.Header().Set("Cache-Control", "no-store")
+	sendResponse(hook, http.StatusCreated, w)
+}
+
+// GetWebhooks lists every webhook subscription
+func GetWebhooks(w http.ResponseWriter, r *http.Request) {
+	var err error
+	defer func() {
+		if err != nil {
+			sendError(err, w, r)
+			return
+		}
+	}()
+
+	hooks, err := webhook.List()
+	if err != nil {
+		log.Println(err)
+		return
+	}
+
+	for i := range hooks {
+		hooks[i].Secret = ""
+	}
+
+	sendResponse(&models.WebhookListResponse{Webhooks: hooks}, http.StatusOK, w)
+}
+
+// GetWebhookByID returns a single webhook subscription
+func GetWebhookByID(w http.ResponseWriter, r *http.Request) {
+	var err error
+	defer func() {
+		if err != nil {
+			sendError(err, w, r)
+			return
+		}
+	}()
+
+	hook, err := webhook.Get(mux.Vars(r)["id"])
+	if err != nil {
+		err = webhookError(err)
+		return
+	}
+
+	hook.Secret = ""
+	sendResponse(hook, http.StatusOK, w)
+}
+
+// DeleteWebhook removes a webhook subscription
+func DeleteWebhook(w http.ResponseWriter, r *http.Request) {
+	var err error
+	defer func() {
+		if err != nil {
+			sendError(err, w, r)
+			return
+		}
+	}()
+
+	id := mux.Vars(r)["id"]
+	err = webhook.Delete(id, resourceAuditor(r, models.AuditWebhookDeleted, models.AuditResourceWebhook)(id))
+	if err != nil {
+		err = webhookError(err)
+		return
+	}
+
+	sendResponse(&models.GenericResponse{Message: fmt.Sprintf("Successfully deleted webhook %s", id)}, http.StatusOK, w)
+}
+
+// GetWebhookDeliveries returns the delivery log of a webhook
+func GetWebhookDeliveries(w http.ResponseWriter, r *http.Request) {
+	var err error
+	defer func() {
+		if err != nil {
+			sendError(err, w, r)
+			return
+		}
+	}()
+
+	deliveries, err := webhook.Deliveries(mux.Vars(r)["id"])
+	if err != nil {
+		err = webhookError(err)
+		return
+	}
+
+	sendResponse(&models.WebhookDeliveryListResponse{Deliveries: deliveries}, http.StatusOK, w)
+}
+
+// Map an error from the webhook package to the matching problem, logging any unexpected error
+func webhookError(err error) error {
+	if err == webhook.ErrNotFound {
+		return notFound("webhook not found")
+	}
+
+	log.Println(err)
+	return err
+}
@@ -0,0 +1,166 @@
+package handler
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/kylegk/sse-rest-server/config"
+	"github.com/kylegk/sse-rest-server/db"
+	"github.com/kylegk/sse-rest-server/models"
+	"github.com/kylegk/sse-rest-server/webhook"
+)
+
+func addWebhookTestRoutes() (*mux.Router, error) {
+	err := db.InitDB(config.DBSchema)
+	if err != nil {
+		return nil, err
+	}
+
+	router := mux.NewRouter()
+	router.HandleFunc("/webhooks", GetWebhooks).Methods("GET")
+	router.HandleFunc("/webhooks", AddWebhook).Methods("POST")
+	router.HandleFunc("/webhooks/{id}", GetWebhookByID).Methods("GET")
+	router.HandleFunc("/webhooks/{id}", DeleteWebhook).Methods("DELETE")
+
+	return router, nil
+}
+
+func TestWebhooks(t *testing.T) {
+	router, err := addWebhookTestRoutes()
+	if err != nil {
+		t.Errorf("Failed to start server")
+	}
+
+	// Verify the secret is only returned when the webhook is created
+	request, _ := http.NewRequest("POST", "/webhooks", strings.NewReader(`{"url": "https://example.com/hook", "exams": [1]}`))
+	response := httptest.NewRecorder()
+	router.ServeHTTP(response, request)
+
+	have := response.Code
+	want := 201
+	if have != want {
+		t.Errorf("HTTP status is not Created; have: %v, want: %v", have, want)
+	}
+
+	hook := models.Webhook{}
+	err = json.NewDecoder(response.Body).Decode(&hook)
+	if err != nil || hook.ID == "" || hook.Secret == "" {
+		t.Fatalf("Incorrect webhook returned; have: %+v", hook)
+	}
+
+	request, _ = http.NewRequest("GET", "/webhooks", nil)
+	response = httptest.NewRecorder()
+	router.ServeHTTP(response, request)
+
+	list := models.WebhookListResponse{}
+	_ = json.NewDecoder(response.Body).Decode(&list)
+	if len(list.Webhooks) != 1 || list.Webhooks[0].ID != hook.ID || list.Webhooks[0].Secret != "" {
+		t.Errorf("Incorrect webhooks listed; have: %+v", list.Webhooks)
+	}
+
+	// Verify a deleted webhook is no longer found
+	request, _ = http.NewRequest("DELETE", "/webhooks/"+hook.ID, nil)
+	response = httptest.NewRecorder()
+	router.ServeHTTP(response, request)
+
+	have = response.Code
+	want = 200
+	if have != want {
+		t.Errorf("HTTP status is not OK; have: %v, want: %v", have, want)
+	}
+
+	for _, method := range []string{"GET", "DELETE"} {
+		request, _ = http.NewRequest(method, "/webhooks/"+hook.ID, nil)
+		response = httptest.NewRecorder()
+		router.ServeHTTP(response, request)
+
+		problem := readProblem(t, response, 404)
+		if problem.Code != CodeNotFound {
+			t.Errorf("Incorrect problem returned for %v; have: %+v", method, problem)
+		}
+	}
+}
+
+func TestAddWebhookInvalid(t *testing.T) {
+	router, err := addWebhookTestRoutes()
+	if err != nil {
+		t.Errorf("Failed to start server")
+	}
+
+	tests := map[string]string{
+		`{"url": "not a url"}`:                                         "url",
+		`{"url": "ftp://example.com/hook"}`:                            "url",
+		`{"url": "https://example.com/hook", "events": ["score.foo"]}`: "events",
+	}
+
+	for body, field := range tests {
+		request, _ := http.NewRequest("POST", "/webhooks", strings.NewReader(body))
+		response := httptest.NewRecorder()
+		router.ServeHTTP(response, request)
+
+		problem := readProblem(t, response, 422)
+		if problem.Code != CodeValidationFailed || len(problem.Errors) != 1 || problem.Errors[0].Field != field {
+			t.Errorf("Incorrect problem returned for %v; have: %+v", body, problem)
+		}
+	}
+
+	request, _ := http.NewRequest("POST", "/webhooks", strings.NewReader(`{"url": `))
+	response := httptest.NewRecorder()
+	router.ServeHTTP(response, request)
+
+	problem := readProblem(t, response, 400)
+	if problem.Code != CodeMalformedBody {
+		t.Errorf("Incorrect problem returned; have: %+v", problem)
+	}
+}
+
+func TestWebhookSignature(t *testing.T) {
+	router, err := addWebhookTestRoutes()
+	if err != nil {
+		t.Errorf("Failed to start server")
+	}
+
+	type delivery struct {
+		signature string
+		body      []byte
+	}
+	deliveries := make(chan delivery, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		select {
+		case deliveries <- delivery{signature: r.Header.Get(webhook.SignatureHeader), body: body}:
+		default:
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	request, _ := http.NewRequest("POST", "/webhooks", strings.NewReader(`{"url": "`+server.URL+`", "secret": "test-secret", "exams": [5]}`))
+	response := httptest.NewRecorder()
+	router.ServeHTTP(response, request)
+	if response.Code != 201 {
+		t.Fatalf("Failed to create the webhook; have: %v", response.Code)
+	}
+
+	webhook.Start(1)
+	_, err = db.UpsertScores([]models.StudentExam{{Exam: 5, StudentID: "test.person1", Score: 0.5}}, nil)
+	if err != nil {
+		t.Fatalf("Failed to insert score")
+	}
+
+	// Verify the payload is signed with the webhook's secret
+	select {
+	case d := <-deliveries:
+		if d.signature != webhook.Sign("test-secret", d.body) {
+			t.Errorf("Incorrect signature; have: %v, want: %v", d.signature, webhook.Sign("test-secret", d.body))
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("The payload was not delivered")
+	}
+}
@@ -30,5 +30,45 @@ func setupEnv() config.Config {
 	clientURL := os.Getenv(config.EnvURL)
 	port := os.Getenv(config.EnvPort)
 
-	return config.Config{MemDBSchema: config.DBSchema, SSEServerUrl: clientURL, PORT: port}
+	// Optional settings
+	policyFile := os.Getenv(config.EnvGradingPolicyFile)
+	defaultPolicy := os.Getenv(config.EnvDefaultGradingPolicy)
+	scaleFile := os.Getenv(config.EnvGradingScaleFile)
+	defaultScale := os.Getenv(config.EnvDefaultGradingScale)
+	alertRuleFile := os.Getenv(config.EnvAlertRuleFile)
+	deleteGracePeriod := os.Getenv(config.EnvDeleteGracePeriod)
+	idempotencyWindow := os.Getenv(config.EnvIdempotencyWindow)
+	legacySunset := os.Getenv(config.EnvLegacySunset)
+	grpcPort := os.Getenv(config.EnvGRPCPort)
+	apiKeyFile := os.Getenv(config.EnvAPIKeyFile)
+	adminAPIKey := os.Getenv(config.EnvAdminAPIKey)
+	jwksFile := os.Getenv(config.EnvJWKSFile)
+	jwtIssuer := os.Getenv(config.EnvJWTIssuer)
+	jwtAudience := os.Getenv(config.EnvJWTAudience)
+	cohortFile := os.Getenv(config.EnvCohortFile)
+	rateLimit := os.Getenv(config.EnvRateLimit)
+	rateLimitBurst := os.Getenv(config.EnvRateLimitBurst)
+
+	return config.Config{
+		MemDBSchema:          config.DBSchema,
+		SSEServerUrl:         clientURL,
+		PORT:                 port,
+		GradingPolicyFile:    policyFile,
+		DefaultGradingPolicy: defaultPolicy,
+		GradingScaleFile:     scaleFile,
+		DefaultGradingScale:  defaultScale,
+		AlertRuleFile:        alertRuleFile,
+		DeleteGracePeriod:    deleteGracePeriod,
+		IdempotencyWindow:    idempotencyWindow,
+		LegacySunset:         legacySunset,
+		GRPCPort:             grpcPort,
+		APIKeyFile:           apiKeyFile,
+		AdminAPIKey:          adminAPIKey,
+		JWKSFile:             jwksFile,
+		JWTIssuer:            jwtIssuer,
+		JWTAudience:          jwtAudience,
+		CohortFile:           cohortFile,
+		RateLimit:            rateLimit,
+		RateLimitBurst:       rateLimitBurst,
+	}
 }
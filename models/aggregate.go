@@ -0,0 +1,58 @@
+package models
+
+import "math"
+
+// Aggregate holds the running statistics for every score recorded against a single student or exam
+type Aggregate struct {
+	Kind  string
+	Key   string
+	Count int
+	Sum   float64
+	SumSq float64
+	Min   float64
+	Max   float64
+}
+
+// Add includes a single score in the aggregate
+func (a *Aggregate) Add(score float64) {
+	if a.Count == 0 || score < a.Min {
+		a.Min = score
+	}
+	if a.Count == 0 || score > a.Max {
+		a.Max = score
+	}
+	a.Count++
+	a.Sum += score
+	a.SumSq += score * score
+}
+
+// Mean returns the average of the aggregated scores
+func (a Aggregate) Mean() float64 {
+	if a.Count == 0 {
+		return 0
+	}
+
+	return a.Sum / float64(a.Count)
+}
+
+// Variance returns the population variance of the aggregated scores
+func (a Aggregate) Variance() float64 {
+	if a.Count == 0 {
+		return 0
+	}
+
+	mean := a.Mean()
+	variance := a.SumSq/float64(a.Count) - mean*mean
+
+	// Guard against a slightly negative result caused by floating point error
+	if variance < 0 {
+		return 0
+	}
+
+	return variance
+}
+
+// StdDev returns the population standard deviation of the aggregated scores
+func (a Aggregate) StdDev() float64 {
+	return math.Sqrt(a.Variance())
+}
@@ -0,0 +1,25 @@
+package models
+
+import "time"
+
+// Define the states of the alert workflow
+const (
+	AlertOpen         = "open"
+	AlertAcknowledged = "acknowledged"
+	AlertResolved     = "resolved"
+)
+
+// Alert is raised when a score recorded for a student triggers one of the at-risk rules
+type Alert struct {
+	ID             string     `json:"id"`
+	StudentID      string     `json:"student"`
+	Exam           int        `json:"exam"`
+	Rule           string     `json:"rule"`
+	Message        string     `json:"message"`
+	Score          float64    `json:"score"`
+	Average        float64    `json:"average"`
+	Status         string     `json:"status"`
+	CreatedAt      time.Time  `json:"created_at"`
+	AcknowledgedAt *time.Time `json:"acknowledged_at,omitempty"`
+	ResolvedAt     *time.Time `json:"resolved_at,omitempty"`
+}
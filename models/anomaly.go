@@ -0,0 +1,28 @@
+package models
+
+import "time"
+
+// Define the reasons an ingested event is considered anomalous
+const (
+	AnomalyMalformed      = "malformed"
+	AnomalyOutOfRange     = "out_of_range"
+	AnomalyDuplicateFlood = "duplicate_flood"
+	AnomalyStudentRate    = "student_rate"
+	AnomalyExamRate       = "exam_rate"
+	AnomalyShift          = "distribution_shift"
+)
+
+// Anomaly is a suspicious event (or pattern of events) detected in the ingested score stream
+// Quarantined events were not recorded; Raw holds the original event data when it could not be parsed
+type Anomaly struct {
+	ID          string       `json:"id"`
+	Reason      string       `json:"reason"`
+	Detail      string       `json:"detail"`
+	Exam        int          `json:"exam,omitempty"`
+	StudentID   string       `json:"student,omitempty"`
+	Event       *StudentExam `json:"event,omitempty"`
+	Raw         string       `json:"raw,omitempty"`
+	Quarantined bool         `json:"quarantined"`
+	DetectedAt  time.Time    `json:"detected_at"`
+	ReleasedAt  *time.Time   `json:"released_at,omitempty"`
+}
@@ -0,0 +1,14 @@
+package models
+
+import "time"
+
+// APIKey authenticates a client of the API; admin keys can also manage the other keys
+// Only a hash of the key is stored, so the key itself is returned once, when it is created
+type APIKey struct {
+	ID        string    `json:"id"`
+	Name      string    `json:"name"`
+	Admin     bool      `json:"admin"`
+	Key       string    `json:"key,omitempty"`
+	Hash      string    `json:"-"`
+	CreatedAt time.Time `json:"created_at"`
+}
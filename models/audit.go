@@ -0,0 +1,52 @@
+package models
+
+import "time"
+
+// Define the actions recorded in the audit log
+const (
+	AuditExamAdded       = "exam.added"
+	AuditExamDeleted     = "exam.deleted"
+	AuditExamRestored    = "exam.restored"
+	AuditExamCurved      = "exam.curved"
+	AuditStudentDeleted  = "student.deleted"
+	AuditScoreUpdated    = "score.updated"
+	AuditScoreDeleted    = "score.deleted"
+	AuditScoreImported   = "score.imported"
+	AuditScoreIngested   = "score.ingested"
+	AuditAnomalyReleased = "anomaly.released"
+
+	AuditKeyCreated          = "api_key.created"
+	AuditKeyRevoked          = "api_key.revoked"
+	AuditWebhookCreated      = "webhook.created"
+	AuditWebhookDeleted      = "webhook.deleted"
+	AuditExamMetadataUpdated = "exam.metadata_updated"
+	AuditAlertAcknowledged   = "alert.acknowledged"
+	AuditAlertResolved       = "alert.resolved"
+)
+
+// Define the resources, other than the scores, whose changes are recorded in the audit log
+const (
+	AuditResourceKey          = "api_key"
+	AuditResourceWebhook      = "webhook"
+	AuditResourceExamMetadata = "exam_metadata"
+	AuditResourceAlert        = "alert"
+)
+
+// AuditEntry records a single change, who made it and where the request came from
+// A change to a score holds the score Before and After the change: Before is nil when the score was created, and After is nil
+// when the score was deleted. A change to another resource, such as an API key or webhook, holds the Resource and its id instead
+type AuditEntry struct {
+	ID         string       `json:"id"`
+	Action     string       `json:"action"`
+	Actor      string       `json:"actor"`
+	Role       string       `json:"role,omitempty"`
+	IP         string       `json:"ip,omitempty"`
+	RequestID  string       `json:"request_id,omitempty"`
+	Exam       int          `json:"exam"`
+	StudentID  string       `json:"student"`
+	Resource   string       `json:"resource,omitempty"`
+	ResourceID string       `json:"resource_id,omitempty"`
+	Before     *StudentExam `json:"before,omitempty"`
+	After      *StudentExam `json:"after,omitempty"`
+	Time       time.Time    `json:"time"`
+}
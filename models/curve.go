@@ -0,0 +1,43 @@
+package models
+
+import "math"
+
+// Define the supported curve methods
+const (
+	CurveLinear    = "linear"
+	CurveSqrt      = "sqrt"
+	CurveNormalize = "normalize"
+)
+
+// Curve defines how the raw scores of an exam are adjusted
+// The exam statistics are captured when the curve is created, so scores recorded afterwards are curved consistently
+type Curve struct {
+	Exam         int     `json:"exam"`
+	Method       string  `json:"method"`
+	TargetMax    float64 `json:"target_max,omitempty"`
+	TargetMean   float64 `json:"target_mean,omitempty"`
+	TargetStdDev float64 `json:"target_stddev,omitempty"`
+	Factor       float64 `json:"factor,omitempty"`
+	Mean         float64 `json:"mean,omitempty"`
+	StdDev       float64 `json:"stddev,omitempty"`
+}
+
+// Apply returns the curved value of a raw score, limited to the range of 0 to 1
+func (c Curve) Apply(score float64) float64 {
+	var curved float64
+	switch c.Method {
+	case CurveLinear:
+		curved = score * c.Factor
+	case CurveSqrt:
+		curved = math.Sqrt(math.Max(score, 0))
+	case CurveNormalize:
+		curved = c.TargetMean
+		if c.StdDev > 0 {
+			curved = (score-c.Mean)/c.StdDev*c.TargetStdDev + c.TargetMean
+		}
+	default:
+		curved = score
+	}
+
+	return math.Min(math.Max(curved, 0), 1)
+}
@@ -0,0 +1,18 @@
+package models
+
+import "time"
+
+// Define the types of change made to a score
+const (
+	ScoreUpserted = "score.upserted"
+	ScoreDeleted  = "score.deleted"
+)
+
+// ScoreEvent describes a committed change to a single score
+// Before is nil when the score was created, and After is nil when the score was deleted
+type ScoreEvent struct {
+	Type   string       `json:"type"`
+	Before *StudentExam `json:"before,omitempty"`
+	After  *StudentExam `json:"after,omitempty"`
+	Time   time.Time    `json:"time"`
+}
@@ -1,8 +1,29 @@
 package models
 
+import "time"
+
 // StudentExam defines event messages returned from the sse client
 type StudentExam struct {
-	Exam      int     `json:"exam"`
-	StudentID string  `json:"studentid"`
-	Score     float64 `json:"score"`
+	Exam       int       `json:"exam"`
+	StudentID  string    `json:"studentid"`
+	Score      float64   `json:"score"`
+	Curved     *float64  `json:"curved,omitempty"`
+	RecordedAt time.Time `json:"recorded_at"`
+}
+
+// Value returns the curved score when requested and the exam has been curved, otherwise the raw score
+func (s StudentExam) Value(curved bool) float64 {
+	if curved && s.Curved != nil {
+		return *s.Curved
+	}
+
+	return s.Score
+}
+
+// ExamMetadata describes an exam for the purpose of grading; a zero weight is treated as a weight of one
+type ExamMetadata struct {
+	Exam     int     `json:"exam"`
+	Title    string  `json:"title,omitempty"`
+	Category string  `json:"category,omitempty"`
+	Weight   float64 `json:"weight,omitempty"`
 }
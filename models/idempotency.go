@@ -0,0 +1,16 @@
+package models
+
+import "time"
+
+// IdempotentResponse is the response to a write request sent with an Idempotency-Key header, which is replayed to retries of the request
+// The request is fingerprinted so that a key reused for a different request can be rejected
+// Header holds the headers set by the handler, such as Content-Type, Location and ETag, so they are replayed with the body
+type IdempotentResponse struct {
+	Key         string              `json:"key"`
+	Fingerprint string              `json:"fingerprint"`
+	Status      int                 `json:"status"`
+	Header      map[string][]string `json:"header"`
+	Body        []byte              `json:"body"`
+	CreatedAt   time.Time           `json:"created_at"`
+	ExpiresAt   time.Time           `json:"expires_at"`
+}
@@ -0,0 +1,26 @@
+package models
+
+// Problem is an RFC 7807 problem details response, sent with the application/problem+json content type
+// Code is a stable, machine readable identifier of the problem, and Errors lists each invalid field of the request
+type Problem struct {
+	Type     string       `json:"type"`
+	Title    string       `json:"title"`
+	Status   int          `json:"status"`
+	Code     string       `json:"code"`
+	Detail   string       `json:"detail,omitempty"`
+	Instance string       `json:"instance,omitempty"`
+	Errors   []FieldError `json:"errors,omitempty"`
+}
+
+// Define the codes of the individual field violations
+const (
+	FieldRequired = "required"
+	FieldInvalid  = "invalid"
+)
+
+// FieldError describes why a single field of a request is invalid
+type FieldError struct {
+	Field   string `json:"field"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
@@ -1,5 +1,7 @@
 package models
 
+import "time"
+
 // GenericResponse is the generic model for providing a response to the client
 type GenericResponse struct {
 	Status  string `json:"status,omitempty"`
@@ -15,15 +17,23 @@ type AllStudentListResponse struct {
 
 // StudentByIDResponse defines the response returned when retrieving a specific student record
 type StudentByIDResponse struct {
-	Student string              `json:"student"`
-	Exams   []StudentExamScores `json:"exams"`
-	Average float64             `json:"average"`
+	Student   string              `json:"student"`
+	Exams     []StudentExamScores `json:"exams"`
+	Policy    string              `json:"policy"`
+	Scale     string              `json:"scale"`
+	ScoreType string              `json:"score_type"`
+	Average   float64             `json:"average"`
+	Grade     string              `json:"grade"`
+	StdDev    float64             `json:"stddev"`
+	Min       float64             `json:"min"`
+	Max       float64             `json:"max"`
 }
 
 // StudentExamScores is a simple struct that contains an exam id and score
 type StudentExamScores struct {
 	Exam  int     `json:"exam"`
 	Score float64 `json:"score"`
+	Grade string  `json:"grade,omitempty"`
 }
 
 // AllUniqueExamsListResponse is the response returned when retrieving a list of all unique exams
@@ -38,13 +48,177 @@ type AllExamsListResponse struct {
 
 // ExamByIDResponse is the response returned when retrieving a specific exam record
 type ExamByIDResponse struct {
-	Exam    int                   `json:"exam"`
-	Scores  []ExamScorePerStudent `json:"scores"`
-	Average float64               `json:"average"`
+	Exam      int                   `json:"exam"`
+	Scores    []ExamScorePerStudent `json:"scores"`
+	Scale     string                `json:"scale"`
+	ScoreType string                `json:"score_type"`
+	Average   float64               `json:"average"`
+	Grade     string                `json:"grade"`
+	StdDev    float64               `json:"stddev"`
+	Min       float64               `json:"min"`
+	Max       float64               `json:"max"`
 }
 
 // ExamScorePerStudent is a simple struct that contains a student id and score
 type ExamScorePerStudent struct {
 	Student string  `json:"student"`
 	Score   float64 `json:"score"`
+	Grade   string  `json:"grade,omitempty"`
+}
+
+// GradeDistributionResponse is the response returned when retrieving the grade distribution for an exam
+type GradeDistributionResponse struct {
+	Exam         int          `json:"exam"`
+	Scale        string       `json:"scale"`
+	ScoreType    string       `json:"score_type"`
+	Total        int          `json:"total"`
+	Distribution []GradeCount `json:"distribution"`
+}
+
+// GradeCount is the number and percentage of scores that received a grade
+type GradeCount struct {
+	Grade   string  `json:"grade"`
+	Count   int     `json:"count"`
+	Percent float64 `json:"percent"`
+}
+
+// CurveResponse is the response returned after curving an exam
+type CurveResponse struct {
+	Curve Curve `json:"curve"`
+	Count int   `json:"count"`
+}
+
+// AlertListResponse is the response returned when retrieving a list of alerts
+type AlertListResponse struct {
+	Alerts []Alert `json:"alerts"`
+}
+
+// AuditLogResponse is the response returned when querying the audit log
+type AuditLogResponse struct {
+	Entries []AuditEntry `json:"entries"`
+}
+
+// APIKeyListResponse is the response returned when retrieving the list of API keys
+type APIKeyListResponse struct {
+	Keys []APIKey `json:"keys"`
+}
+
+// WebhookListResponse is the response returned when retrieving a list of webhooks
+type WebhookListResponse struct {
+	Webhooks []Webhook `json:"webhooks"`
+}
+
+// WebhookDeliveryListResponse is the response returned when retrieving the delivery log of a webhook
+type WebhookDeliveryListResponse struct {
+	Deliveries []WebhookDelivery `json:"deliveries"`
+}
+
+// AnomalyReportResponse is the response returned when retrieving the anomalies detected in the ingested events
+// Counts and Quarantined cover every stored anomaly, while Anomalies holds one page of them; Dropped counts the anomalies that were not stored
+type AnomalyReportResponse struct {
+	Counts      map[string]int `json:"counts"`
+	Quarantined int            `json:"quarantined"`
+	Dropped     map[string]int `json:"dropped,omitempty"`
+	Total       int            `json:"total"`
+	Limit       int            `json:"limit"`
+	Offset      int            `json:"offset"`
+	Anomalies   []Anomaly      `json:"anomalies"`
+}
+
+// StudentTrendResponse is the response returned when retrieving the performance trend of a student
+type StudentTrendResponse struct {
+	Student     string       `json:"student"`
+	Order       string       `json:"order"`
+	Window      int          `json:"window"`
+	ScoreType   string       `json:"score_type"`
+	Points      []TrendPoint `json:"points"`
+	Slope       float64      `json:"slope"`
+	Intercept   float64      `json:"intercept"`
+	RSquared    float64      `json:"r_squared"`
+	Significant bool         `json:"significant"`
+	Trend       string       `json:"trend"`
+}
+
+// TrendPoint is a single exam in a student's trend, with the moving average of the scores up to and including it
+type TrendPoint struct {
+	Exam          int       `json:"exam"`
+	Score         float64   `json:"score"`
+	RecordedAt    time.Time `json:"recorded_at"`
+	MovingAverage float64   `json:"moving_average"`
+}
+
+// CorrelationResponse is the response returned when correlating the scores of exams
+// Each matrix is indexed in the order of Exams, and each cell only covers the students who took both exams;
+// a coefficient is null when fewer than two students took both exams or their scores did not vary
+type CorrelationResponse struct {
+	Exams     []int        `json:"exams"`
+	ScoreType string       `json:"score_type"`
+	Students  [][]int      `json:"students"`
+	Pearson   [][]*float64 `json:"pearson"`
+	Spearman  [][]*float64 `json:"spearman"`
+}
+
+// StudentCompareResponse is the response returned when comparing students on the exams they all took
+type StudentCompareResponse struct {
+	Students  []string          `json:"students"`
+	ScoreType string            `json:"score_type"`
+	Summary   []ComparedStudent `json:"summary"`
+	Exams     []ComparedExam    `json:"exams"`
+}
+
+// ComparedStudent summarizes a student's results on the shared exams
+type ComparedStudent struct {
+	Student string  `json:"student"`
+	Average float64 `json:"average"`
+	Wins    int     `json:"wins"`
+}
+
+// ComparedExam is the score of each student on a shared exam, and the student with the highest score (empty when tied)
+type ComparedExam struct {
+	Exam   int                `json:"exam"`
+	Scores map[string]float64 `json:"scores"`
+	Leader string             `json:"leader,omitempty"`
+}
+
+// ScoreRow is a single score written by the CSV and NDJSON exports of an exam or student
+type ScoreRow struct {
+	Exam    int     `json:"exam"`
+	Student string  `json:"student"`
+	Score   float64 `json:"score"`
+	Grade   string  `json:"grade,omitempty"`
+}
+
+// ImportResponse is the report returned after importing a file of scores
+type ImportResponse struct {
+	Format   string        `json:"format"`
+	Mode     string        `json:"mode"`
+	DryRun   bool          `json:"dry_run"`
+	Total    int           `json:"total"`
+	Imported int           `json:"imported"`
+	Failed   int           `json:"failed"`
+	Errors   []ImportError `json:"errors"`
+}
+
+// ImportError describes why a line of an imported file was rejected
+type ImportError struct {
+	Line  int    `json:"line"`
+	Error string `json:"error"`
+}
+
+// BatchExamResponse is the response returned after adding a batch of exams
+type BatchExamResponse struct {
+	Mode      string            `json:"mode"`
+	Succeeded int               `json:"succeeded"`
+	Failed    int               `json:"failed"`
+	Results   []BatchExamResult `json:"results"`
+}
+
+// BatchExamResult is the outcome of a single exam in a batch, identified by its position in the request
+type BatchExamResult struct {
+	Index     int          `json:"index"`
+	Exam      int          `json:"exam"`
+	StudentID string       `json:"studentid"`
+	Status    string       `json:"status"`
+	Error     string       `json:"error,omitempty"`
+	Fields    []FieldError `json:"fields,omitempty"`
 }
@@ -0,0 +1,11 @@
+package models
+
+import "time"
+
+// Tombstone holds the scores of a deleted exam until its grace period expires, so the deletion can be undone
+type Tombstone struct {
+	Exam      int           `json:"exam"`
+	Scores    []StudentExam `json:"scores"`
+	DeletedAt time.Time     `json:"deleted_at"`
+	ExpiresAt time.Time     `json:"expires_at"`
+}
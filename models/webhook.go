@@ -0,0 +1,35 @@
+package models
+
+import "time"
+
+// Webhook is a subscription to the score events, delivered to a URL as signed JSON
+// Empty filters match every exam, student or event type
+type Webhook struct {
+	ID        string    `json:"id"`
+	URL       string    `json:"url"`
+	Secret    string    `json:"secret,omitempty"`
+	Exams     []int     `json:"exams,omitempty"`
+	Students  []string  `json:"students,omitempty"`
+	Events    []string  `json:"events,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// WebhookPayload is the body posted to a webhook for a single score event
+type WebhookPayload struct {
+	ID    string     `json:"id"`
+	Event string     `json:"event"`
+	Data  ScoreEvent `json:"data"`
+}
+
+// WebhookDelivery records a single attempt to deliver a payload to a webhook
+type WebhookDelivery struct {
+	ID         string    `json:"id"`
+	WebhookID  string    `json:"webhook"`
+	PayloadID  string    `json:"payload"`
+	Event      string    `json:"event"`
+	Attempt    int       `json:"attempt"`
+	StatusCode int       `json:"status_code,omitempty"`
+	Error      string    `json:"error,omitempty"`
+	Success    bool      `json:"success"`
+	Timestamp  time.Time `json:"timestamp"`
+}
@@ -0,0 +1,250 @@
+package openapi
+
+import (
+	"net/http"
+
+	"github.com/kylegk/sse-rest-server/models"
+)
+
+// route describes a single route registered in app.addV1Routes
+// The body and responses hold either a value of the model type or a *Schema
+type route struct {
+	method    string
+	path      string
+	id        string
+	summary   string
+	tag       string
+	params    []Parameter
+	body      map[string]interface{}
+	responses []response
+	exports   bool
+}
+
+type response struct {
+	status      int
+	description string
+	model       interface{}
+}
+
+var tags = []Tag{
+	{Name: "students", Description: "Students and their scores"},
+	{Name: "exams", Description: "Exams, their scores, curves and metadata"},
+	{Name: "scores", Description: "A single student's score on an exam"},
+	{Name: "import", Description: "Bulk import of score files"},
+	{Name: "analytics", Description: "Statistics across exams"},
+	{Name: "alerts", Description: "At-risk student alerts"},
+	{Name: "anomalies", Description: "Anomalies detected in the ingested events"},
+	{Name: "webhooks", Description: "Outbound webhook subscriptions"},
+	{Name: "admin", Description: "API key management and the audit log, which require an admin"},
+}
+
+// The parameters taken from the path, keyed by their name in the path template
+var pathParameters = map[string]Parameter{
+	"id":      {Name: "id", In: "path", Required: true, Description: "The id of the resource", Schema: &Schema{Type: "string"}},
+	"student": {Name: "student", In: "path", Required: true, Description: "The student id", Schema: &Schema{Type: "string"}},
+}
+
+var examID = Parameter{Name: "id", In: "path", Required: true, Description: "The exam id", Schema: &Schema{Type: "integer"}}
+
+var idempotencyKey = Parameter{Name: "Idempotency-Key", In: "header", Description: "A unique value identifying the request, so a retry is answered with the original response", Schema: &Schema{Type: "string"}}
+
+// Define the query parameters shared by several routes
+var (
+	scoresParam = query("scores", "Whether to report the raw or curved scores", "raw", "curved")
+	scaleParam  = query("scale", "The grading scale used to grade the scores")
+	policyParam = query("policy", "The grading policy used to calculate the average")
+	formatParam = query("format", "Export the scores as CSV or newline delimited JSON instead of JSON", "json", "csv", "ndjson")
+)
+
+func query(name string, description string, enum ...string) Parameter {
+	return Parameter{Name: name, In: "query", Description: description, Schema: &Schema{Type: "string", Enum: enum}}
+}
+
+func integerQuery(name string, description string) Parameter {
+	return Parameter{Name: name, In: "query", Description: description, Schema: &Schema{Type: "integer"}}
+}
+
+func timeQuery(name string, description string) Parameter {
+	return Parameter{Name: name, In: "query", Description: description, Schema: &Schema{Type: "string", Format: "date-time"}}
+}
+
+func required(p Parameter) Parameter {
+	p.Required = true
+	return p
+}
+
+func ok(model interface{}) []response {
+	return []response{{status: http.StatusOK, description: "OK", model: model}}
+}
+
+func jsonBody(model interface{}) map[string]interface{} {
+	return map[string]interface{}{JSONContentType: model}
+}
+
+// The body of a score update; the exam and student are taken from the path
+var scoreUpdate = &Schema{
+	Type: "object",
+	Properties: map[string]*Schema{
+		"exam":      {Type: "integer", Description: "Must match the exam in the path when sent"},
+		"studentid": {Type: "string", Description: "Must match the student in the path when sent"},
+		"score":     {Type: "number"},
+	},
+	Required: []string{"score"},
+}
+
+// The body of a new API key; the key itself is generated by the server
+var apiKeyRequest = &Schema{
+	Type: "object",
+	Properties: map[string]*Schema{
+		"name":  {Type: "string", Description: "A name identifying the client the key is for"},
+		"admin": {Type: "boolean", Description: "Whether the key can manage the other keys"},
+	},
+	Required: []string{"name"},
+}
+
+// Every route of version 1 of the API
+var routes = []route{
+	// Students
+	{method: "GET", path: "/students", id: "listStudents", summary: "List every student with a score", tag: "students",
+		responses: ok(models.AllStudentListResponse{})},
+	{method: "GET", path: "/students/compare", id: "compareStudents", summary: "Compare students on the exams they all took", tag: "students",
+		params:    []Parameter{required(query("ids", "A comma separated list of two or more student ids")), scoresParam},
+		responses: ok(models.StudentCompareResponse{})},
+	{method: "GET", path: "/students/{id}", id: "getStudent", summary: "Get a student's graded scores and statistics", tag: "students",
+		params:    []Parameter{policyParam, scaleParam, scoresParam, formatParam},
+		responses: ok(models.StudentByIDResponse{}), exports: true},
+	{method: "DELETE", path: "/students/{id}", id: "deleteStudent", summary: "Delete every score of a student", tag: "students",
+		responses: ok(models.GenericResponse{})},
+	{method: "GET", path: "/students/{id}/trend", id: "getStudentTrend", summary: "Get the performance trend of a student", tag: "students",
+		params:    []Parameter{query("order", "Order the exams by the time they were recorded or by exam id", "time", "exam"), integerQuery("window", "The number of exams in the moving average"), scoresParam},
+		responses: ok(models.StudentTrendResponse{})},
+
+	// Exams
+	{method: "GET", path: "/exams", id: "listExams", summary: "List the id of every exam", tag: "exams",
+		responses: ok(models.AllUniqueExamsListResponse{})},
+	{method: "POST", path: "/exams", id: "addExams", summary: "Record a score, or a batch of scores", tag: "exams",
+		params: []Parameter{query("mode", "Whether a batch is rejected or partially recorded when an exam is invalid", "atomic", "best_effort")},
+		body:   jsonBody(&Schema{OneOf: []*Schema{ref("StudentExam"), {Type: "array", Items: ref("StudentExam")}}}),
+		responses: []response{
+			{status: http.StatusOK, description: "The score or batch was recorded", model: &Schema{OneOf: []*Schema{ref("GenericResponse"), ref("BatchExamResponse")}}},
+			{status: http.StatusUnprocessableEntity, description: "An atomic batch had invalid exams, so nothing was recorded", model: models.BatchExamResponse{}},
+		}},
+	{method: "GET", path: "/exams/all", id: "listAllScores", summary: "List every recorded score", tag: "exams",
+		params:    []Parameter{formatParam},
+		responses: ok(models.AllExamsListResponse{}), exports: true},
+	{method: "GET", path: "/exams/{id}", id: "getExam", summary: "Get an exam's graded scores and statistics", tag: "exams",
+		params:    []Parameter{scaleParam, scoresParam, formatParam},
+		responses: ok(models.ExamByIDResponse{}), exports: true},
+	{method: "DELETE", path: "/exams/{id}", id: "deleteExam", summary: "Delete an exam, which can be restored during the grace period", tag: "exams",
+		responses: ok(models.GenericResponse{})},
+	{method: "POST", path: "/exams/{id}/restore", id: "restoreExam", summary: "Restore a deleted exam", tag: "exams",
+		responses: ok(models.GenericResponse{})},
+	{method: "POST", path: "/exams/{id}/curve", id: "curveExam", summary: "Curve the scores of an exam", tag: "exams",
+		body:      jsonBody(models.Curve{}),
+		responses: ok(models.CurveResponse{})},
+	{method: "GET", path: "/exams/{id}/grade-distribution", id: "getGradeDistribution", summary: "Get the number of students that received each grade", tag: "exams",
+		params:    []Parameter{scaleParam, scoresParam},
+		responses: ok(models.GradeDistributionResponse{})},
+	{method: "GET", path: "/exams/{id}/metadata", id: "getExamMetadata", summary: "Get the grading metadata of an exam", tag: "exams",
+		responses: ok(models.ExamMetadata{})},
+	{method: "PUT", path: "/exams/{id}/metadata", id: "putExamMetadata", summary: "Create or replace the grading metadata of an exam", tag: "exams",
+		body:      jsonBody(models.ExamMetadata{}),
+		responses: ok(models.ExamMetadata{})},
+
+	// Scores
+	{method: "PUT", path: "/exams/{id}/students/{student}", id: "putScore", summary: "Record or replace a student's score", tag: "scores",
+		body: jsonBody(scoreUpdate),
+		responses: []response{
+			{status: http.StatusOK, description: "The score was replaced", model: models.StudentExam{}},
+			{status: http.StatusCreated, description: "The score was created", model: models.StudentExam{}},
+		}},
+	{method: "PATCH", path: "/exams/{id}/students/{student}", id: "patchScore", summary: "Correct a student's existing score", tag: "scores",
+		body:      jsonBody(scoreUpdate),
+		responses: ok(models.StudentExam{})},
+	{method: "DELETE", path: "/exams/{id}/students/{student}", id: "deleteScore", summary: "Delete a student's score", tag: "scores",
+		responses: ok(models.GenericResponse{})},
+
+	// Import
+	{method: "POST", path: "/import", id: "importScores", summary: "Import a CSV or JSON lines file of scores", tag: "import",
+		params: []Parameter{
+			query("mode", "Whether the file is rejected or imported in chunks when a line is invalid", "atomic", "chunked"),
+			integerQuery("chunk_size", "The number of lines written in each transaction of a chunked import"),
+			query("dry_run", "Validate the file without importing it", "true", "false"),
+			query("format", "The format of the file, when it cannot be determined from the content type or file name", "csv", "jsonl"),
+		},
+		body: map[string]interface{}{
+			CSVContentType:        &Schema{Type: "string"},
+			NDJSONContentType:     &Schema{Type: "string"},
+			"multipart/form-data": &Schema{Type: "object", Properties: map[string]*Schema{"file": {Type: "string", Format: "binary"}}, Required: []string{"file"}},
+		},
+		responses: []response{
+			{status: http.StatusOK, description: "The report of the import", model: models.ImportResponse{}},
+			{status: http.StatusUnprocessableEntity, description: "An atomic import had invalid lines, so nothing was imported", model: models.ImportResponse{}},
+		}},
+
+	// Analytics
+	{method: "GET", path: "/analytics/correlation", id: "getExamCorrelation", summary: "Correlate the scores of exams", tag: "analytics",
+		params:    []Parameter{required(query("exams", "A comma separated list of 2 to 20 exam ids")), scoresParam},
+		responses: ok(models.CorrelationResponse{})},
+
+	// Alerts
+	{method: "GET", path: "/alerts", id: "listAlerts", summary: "List the at-risk student alerts", tag: "alerts",
+		params:    []Parameter{query("status", "Only list the alerts with this status", models.AlertOpen, models.AlertAcknowledged, models.AlertResolved), query("student", "Only list the alerts of this student")},
+		responses: ok(models.AlertListResponse{})},
+	{method: "GET", path: "/alerts/{id}", id: "getAlert", summary: "Get an alert", tag: "alerts",
+		responses: ok(models.Alert{})},
+	{method: "POST", path: "/alerts/{id}/acknowledge", id: "acknowledgeAlert", summary: "Acknowledge an open alert", tag: "alerts",
+		responses: ok(models.Alert{})},
+	{method: "POST", path: "/alerts/{id}/resolve", id: "resolveAlert", summary: "Resolve an open or acknowledged alert", tag: "alerts",
+		responses: ok(models.Alert{})},
+
+	// Anomalies
+	{method: "GET", path: "/anomalies", id: "listAnomalies", summary: "Summarize the anomalies detected in the ingested events", tag: "anomalies",
+		params: []Parameter{
+			query("reason", "Only list the anomalies with this reason", models.AnomalyMalformed, models.AnomalyOutOfRange, models.AnomalyDuplicateFlood, models.AnomalyStudentRate, models.AnomalyExamRate, models.AnomalyShift),
+			integerQuery("limit", "The number of anomalies to list, from 1 to 1000 (100 by default)"),
+			integerQuery("offset", "The number of anomalies to skip before the first one listed"),
+		},
+		responses: ok(models.AnomalyReportResponse{})},
+	{method: "POST", path: "/anomalies/{id}/release", id: "releaseAnomaly", summary: "Record a quarantined event that was flagged incorrectly", tag: "anomalies",
+		responses: ok(models.StudentExam{})},
+
+	// Webhooks
+	{method: "GET", path: "/webhooks", id: "listWebhooks", summary: "List the webhook subscriptions", tag: "webhooks",
+		responses: ok(models.WebhookListResponse{})},
+	{method: "POST", path: "/webhooks", id: "addWebhook", summary: "Subscribe a url to the score events", tag: "webhooks",
+		body:      jsonBody(models.Webhook{}),
+		responses: []response{{status: http.StatusCreated, description: "The webhook, with the secret used to sign its payloads", model: models.Webhook{}}}},
+	{method: "GET", path: "/webhooks/{id}", id: "getWebhook", summary: "Get a webhook subscription", tag: "webhooks",
+		responses: ok(models.Webhook{})},
+	{method: "DELETE", path: "/webhooks/{id}", id: "deleteWebhook", summary: "Delete a webhook subscription and its delivery log", tag: "webhooks",
+		responses: ok(models.GenericResponse{})},
+	{method: "GET", path: "/webhooks/{id}/deliveries", id: "listWebhookDeliveries", summary: "List the delivery log of a webhook", tag: "webhooks",
+		responses: ok(models.WebhookDeliveryListResponse{})},
+
+	// Admin
+	{method: "GET", path: "/admin/keys", id: "listAPIKeys", summary: "List the API keys, without the keys themselves", tag: "admin",
+		responses: ok(models.APIKeyListResponse{})},
+	{method: "POST", path: "/admin/keys", id: "addAPIKey", summary: "Generate a new API key", tag: "admin",
+		body:      jsonBody(apiKeyRequest),
+		responses: []response{{status: http.StatusCreated, description: "The API key, which is only returned in this response", model: models.APIKey{}}}},
+	{method: "DELETE", path: "/admin/keys/{id}", id: "deleteAPIKey", summary: "Revoke an API key", tag: "admin",
+		responses: []response{
+			{status: http.StatusOK, description: "The key was revoked", model: models.GenericResponse{}},
+			{status: http.StatusConflict, description: "The key is the last admin key, so it cannot be revoked", model: models.Problem{}},
+		}},
+	{method: "GET", path: "/admin/audit", id: "listAuditEntries", summary: "List the changes made to the scores and other resources, with who made them, in the order they were made", tag: "admin",
+		params: []Parameter{
+			query("action", "Only list the changes made by this action", models.AuditExamAdded, models.AuditExamDeleted, models.AuditExamRestored, models.AuditExamCurved, models.AuditStudentDeleted, models.AuditScoreUpdated, models.AuditScoreDeleted, models.AuditScoreImported, models.AuditScoreIngested, models.AuditAnomalyReleased,
+				models.AuditKeyCreated, models.AuditKeyRevoked, models.AuditWebhookCreated, models.AuditWebhookDeleted, models.AuditExamMetadataUpdated, models.AuditAlertAcknowledged, models.AuditAlertResolved),
+			query("actor", "Only list the changes made by this client, such as key:{id}, sub:{subject}, anonymous or ingestion"),
+			integerQuery("exam", "Only list the changes to the scores of this exam"),
+			query("student", "Only list the changes to the scores of this student"),
+			query("resource", "Only list the changes to this kind of resource", models.AuditResourceKey, models.AuditResourceWebhook, models.AuditResourceExamMetadata, models.AuditResourceAlert),
+			query("resource_id", "Only list the changes to the resource with this id"),
+			query("request_id", "Only list the changes made by the request with this X-Request-ID"),
+			timeQuery("since", "Only list the changes made at or after this time"),
+			timeQuery("until", "Only list the changes made before this time"),
+		},
+		responses: ok(models.AuditLogResponse{})},
+}
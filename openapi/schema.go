@@ -0,0 +1,133 @@
+package openapi
+
+import (
+	"reflect"
+	"strings"
+	"time"
+)
+
+// Schema is the subset of an OpenAPI 3.0 schema object used to describe the models
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Nullable             bool               `json:"nullable,omitempty"`
+	ReadOnly             bool               `json:"readOnly,omitempty"`
+	Enum                 []string           `json:"enum,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	AdditionalProperties interface{}        `json:"additionalProperties,omitempty"`
+	AllOf                []*Schema          `json:"allOf,omitempty"`
+	OneOf                []*Schema          `json:"oneOf,omitempty"`
+}
+
+// The properties of the models that are set by the server, keyed by the model and the json name of the field
+// They are ignored in request bodies, so they are only required in responses
+var readOnly = map[string]bool{
+	"StudentExam.curved":      true,
+	"StudentExam.recorded_at": true,
+	"ExamMetadata.exam":       true,
+	"Curve.exam":              true,
+	"Curve.mean":              true,
+	"Curve.stddev":            true,
+	"Webhook.id":              true,
+	"Webhook.created_at":      true,
+	"APIKey.id":               true,
+	"APIKey.key":              true,
+	"APIKey.created_at":       true,
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// Generates the schemas of the models, adding each named struct to the components so it is only described once
+type generator struct {
+	schemas map[string]*Schema
+}
+
+// Return the schema of a model, or a reference to it for named structs
+func (g *generator) schema(t reflect.Type) *Schema {
+	switch t.Kind() {
+	case reflect.Ptr:
+		s := g.schema(t.Elem())
+		if s.Ref != "" {
+			return &Schema{Nullable: true, AllOf: []*Schema{s}}
+		}
+		s.Nullable = true
+		return s
+	case reflect.Slice, reflect.Array:
+		return &Schema{Type: "array", Items: g.schema(t.Elem()), Nullable: t.Kind() == reflect.Slice}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: g.schema(t.Elem()), Nullable: true}
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &Schema{Type: "integer"}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number"}
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Struct:
+		if t == timeType {
+			return &Schema{Type: "string", Format: "date-time"}
+		}
+		if t.Name() == "" {
+			return g.object(t)
+		}
+		if _, ok := g.schemas[t.Name()]; !ok {
+			// Reserve the name first, so a model that refers to itself does not recurse forever
+			g.schemas[t.Name()] = &Schema{}
+			*g.schemas[t.Name()] = *g.object(t)
+		}
+		return ref(t.Name())
+	}
+
+	// Anything else, such as an interface, can hold any value
+	return &Schema{}
+}
+
+// Describe the exported fields of a struct by their json names; fields that are not omitted when empty are always present
+func (g *generator) object(t reflect.Type) *Schema {
+	s := &Schema{Type: "object", Properties: make(map[string]*Schema), AdditionalProperties: false}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.PkgPath != "" {
+			continue
+		}
+
+		tag := strings.Split(field.Tag.Get("json"), ",")
+		name := tag[0]
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+
+		prop := g.schema(field.Type)
+		if readOnly[t.Name()+"."+name] {
+			if prop.Ref != "" {
+				prop = &Schema{AllOf: []*Schema{prop}}
+			}
+			prop.ReadOnly = true
+		}
+		s.Properties[name] = prop
+
+		omitEmpty := false
+		for _, option := range tag[1:] {
+			omitEmpty = omitEmpty || option == "omitempty"
+		}
+		if !omitEmpty {
+			s.Required = append(s.Required, name)
+		}
+	}
+
+	return s
+}
+
+// Refer to a schema in the components of the document
+func ref(name string) *Schema {
+	return &Schema{Ref: "#/components/schemas/" + name}
+}
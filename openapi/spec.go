@@ -0,0 +1,255 @@
+package openapi
+
+import (
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/kylegk/sse-rest-server/models"
+)
+
+// Define the content types of the responses
+const (
+	JSONContentType    = "application/json"
+	ProblemContentType = "application/problem+json"
+	CSVContentType     = "text/csv"
+	NDJSONContentType  = "application/x-ndjson"
+)
+
+// Document is the subset of an OpenAPI 3.0 document used to describe the API
+type Document struct {
+	OpenAPI    string                `json:"openapi"`
+	Info       Info                  `json:"info"`
+	Servers    []Server              `json:"servers"`
+	Tags       []Tag                 `json:"tags"`
+	Paths      map[string]PathItem   `json:"paths"`
+	Components Components            `json:"components"`
+	Security   []map[string][]string `json:"security"`
+}
+
+// Info describes the API
+type Info struct {
+	Title       string `json:"title"`
+	Description string `json:"description"`
+	Version     string `json:"version"`
+}
+
+// Server is the base url of the paths
+type Server struct {
+	URL         string `json:"url"`
+	Description string `json:"description"`
+}
+
+// Tag groups the operations on a resource
+type Tag struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+// PathItem holds the operations of a path, keyed by their lower case method
+type PathItem map[string]*Operation
+
+// Operation describes a single method of a path
+type Operation struct {
+	OperationID string               `json:"operationId"`
+	Summary     string               `json:"summary"`
+	Tags        []string             `json:"tags"`
+	Parameters  []Parameter          `json:"parameters,omitempty"`
+	RequestBody *RequestBody         `json:"requestBody,omitempty"`
+	Responses   map[string]*Response `json:"responses"`
+}
+
+// Parameter describes a path, query or header parameter
+type Parameter struct {
+	Name        string  `json:"name"`
+	In          string  `json:"in"`
+	Description string  `json:"description"`
+	Required    bool    `json:"required,omitempty"`
+	Schema      *Schema `json:"schema"`
+}
+
+// RequestBody describes the body of a request in each of its content types
+type RequestBody struct {
+	Required bool                 `json:"required"`
+	Content  map[string]MediaType `json:"content"`
+}
+
+// Response describes the body of a response in each of its content types, and its headers
+type Response struct {
+	Description string               `json:"description"`
+	Headers     map[string]Header    `json:"headers,omitempty"`
+	Content     map[string]MediaType `json:"content,omitempty"`
+}
+
+// Header describes a header of a response
+type Header struct {
+	Description string  `json:"description"`
+	Schema      *Schema `json:"schema"`
+}
+
+// MediaType holds the schema of a body
+type MediaType struct {
+	Schema *Schema `json:"schema"`
+}
+
+// Components holds the schemas of the models, keyed by the model name, and the ways a client can authenticate
+type Components struct {
+	Schemas         map[string]*Schema        `json:"schemas"`
+	SecuritySchemes map[string]SecurityScheme `json:"securitySchemes"`
+}
+
+// SecurityScheme describes a way a client can authenticate
+type SecurityScheme struct {
+	Type         string `json:"type"`
+	In           string `json:"in,omitempty"`
+	Name         string `json:"name,omitempty"`
+	Scheme       string `json:"scheme,omitempty"`
+	BearerFormat string `json:"bearerFormat,omitempty"`
+	Description  string `json:"description"`
+}
+
+var (
+	spec     *Document
+	specOnce sync.Once
+)
+
+// Spec returns the OpenAPI document describing version 1 of the API
+func Spec() *Document {
+	specOnce.Do(func() {
+		spec = build()
+	})
+
+	return spec
+}
+
+// Build the document from the route table, generating the schemas of the models the routes refer to
+func build() *Document {
+	g := &generator{schemas: make(map[string]*Schema)}
+	doc := &Document{
+		OpenAPI: "3.0.3",
+		Info: Info{
+			Title:       "SSE REST Server",
+			Description: "Exam scores collected from a Server-Sent Events stream. The unversioned paths are deprecated aliases of /v1.",
+			Version:     "1",
+		},
+		Servers: []Server{{URL: "/v1", Description: "Version 1 of the API"}},
+		Tags:    tags,
+		Paths:   make(map[string]PathItem),
+		// Every route requires an API key or a bearer token once either has been configured
+		Security: []map[string][]string{{"apiKey": {}}, {"bearer": {}}},
+	}
+
+	problem := g.schema(reflect.TypeOf(models.Problem{}))
+	for _, rt := range routes {
+		op := &Operation{
+			OperationID: rt.id,
+			Summary:     rt.summary,
+			Tags:        []string{rt.tag},
+			Responses: map[string]*Response{
+				"default": {Description: "An error, described as a problem", Content: map[string]MediaType{ProblemContentType: {Schema: problem}}},
+			},
+		}
+
+		for _, name := range pathParams(rt.path) {
+			param := pathParameters[name]
+			if name == "id" && strings.HasPrefix(rt.path, "/exams/") {
+				param = examID
+			}
+			op.Parameters = append(op.Parameters, param)
+		}
+		op.Parameters = append(op.Parameters, rt.params...)
+		op.Responses["429"] = &Response{
+			Description: "The client has made too many requests, and must wait before making another",
+			Headers:     rateLimitHeaders,
+			Content:     map[string]MediaType{ProblemContentType: {Schema: problem}},
+		}
+		if rt.method == http.MethodGet {
+			op.Responses["304"] = &Response{Description: "Not modified since the ETag or date of a conditional request"}
+		} else {
+			op.Parameters = append(op.Parameters, idempotencyKey)
+		}
+
+		if rt.body != nil {
+			op.RequestBody = &RequestBody{Required: true, Content: make(map[string]MediaType)}
+			for contentType, model := range rt.body {
+				op.RequestBody.Content[contentType] = MediaType{Schema: schemaOf(g, model)}
+			}
+		}
+
+		for _, res := range rt.responses {
+			response := &Response{Description: res.description, Content: map[string]MediaType{JSONContentType: {Schema: schemaOf(g, res.model)}}}
+			if rt.exports {
+				response.Content[CSVContentType] = MediaType{Schema: &Schema{Type: "string"}}
+				response.Content[NDJSONContentType] = MediaType{Schema: &Schema{Type: "string"}}
+			}
+			// Errors with a documented status can still be reported as a problem, such as a body that cannot be parsed
+			if res.status >= 400 {
+				response.Content[ProblemContentType] = MediaType{Schema: problem}
+			}
+			op.Responses[strconv.Itoa(res.status)] = response
+		}
+
+		if doc.Paths[rt.path] == nil {
+			doc.Paths[rt.path] = make(PathItem)
+		}
+		doc.Paths[rt.path][strings.ToLower(rt.method)] = op
+	}
+
+	doc.Components.Schemas = g.schemas
+	doc.Components.SecuritySchemes = map[string]SecurityScheme{
+		"apiKey": {Type: "apiKey", In: "header", Name: "X-API-Key", Description: "An API key; the admin routes require an admin key"},
+		"bearer": {Type: "http", Scheme: "bearer", BearerFormat: "JWT", Description: "A signed token whose role and cohorts claims decide the students and routes the client can use"},
+	}
+
+	return doc
+}
+
+// The headers reporting the state of a client's rate limit, sent when a request is rejected for exceeding it
+var rateLimitHeaders = map[string]Header{
+	"Retry-After":         {Description: "The number of seconds until the request can be made", Schema: &Schema{Type: "integer"}},
+	"RateLimit-Limit":     {Description: "The most tokens the client can spend at once", Schema: &Schema{Type: "integer"}},
+	"RateLimit-Remaining": {Description: "The number of tokens the client has left", Schema: &Schema{Type: "integer"}},
+	"RateLimit-Reset":     {Description: "The number of seconds until the client has every token again", Schema: &Schema{Type: "integer"}},
+}
+
+// A model is either a schema describing it, or a value of the model type
+func schemaOf(g *generator, model interface{}) *Schema {
+	if s, ok := model.(*Schema); ok {
+		return s
+	}
+
+	return g.schema(reflect.TypeOf(model))
+}
+
+// Return the names of the parameters in a path template
+func pathParams(path string) []string {
+	var names []string
+	for _, part := range strings.Split(path, "/") {
+		if strings.HasPrefix(part, "{") && strings.HasSuffix(part, "}") {
+			names = append(names, part[1:len(part)-1])
+		}
+	}
+
+	return names
+}
+
+// Operation finds the operation of a method and path template, returning nil if the route is not described
+func (d *Document) Operation(method string, path string) *Operation {
+	return d.Paths[path][strings.ToLower(method)]
+}
+
+// Routes lists the method and path template of every described operation, in a stable order
+func (d *Document) Routes() []string {
+	var list []string
+	for path, item := range d.Paths {
+		for method := range item {
+			list = append(list, strings.ToUpper(method)+" "+path)
+		}
+	}
+	sort.Strings(list)
+
+	return list
+}
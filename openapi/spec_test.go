@@ -0,0 +1,146 @@
+package openapi
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+// Collect the references made by a schema and the schemas nested in it
+func refs(s *Schema, found map[string]bool) {
+	if s == nil {
+		return
+	}
+	if s.Ref != "" {
+		found[strings.TrimPrefix(s.Ref, "#/components/schemas/")] = true
+	}
+	refs(s.Items, found)
+	for _, prop := range s.Properties {
+		refs(prop, found)
+	}
+	if additional, ok := s.AdditionalProperties.(*Schema); ok {
+		refs(additional, found)
+	}
+	for _, sub := range append(s.AllOf, s.OneOf...) {
+		refs(sub, found)
+	}
+}
+
+func TestSpec(t *testing.T) {
+	doc := Spec()
+
+	// Verify the document can be served
+	_, err := json.Marshal(doc)
+	if err != nil {
+		t.Errorf("Unable to encode the document; %v", err)
+	}
+
+	// Verify every reference resolves and every operation id is unique
+	found := make(map[string]bool)
+	ids := make(map[string]bool)
+	for path, item := range doc.Paths {
+		for method, op := range item {
+			if ids[op.OperationID] {
+				t.Errorf("Duplicate operation id %v", op.OperationID)
+			}
+			ids[op.OperationID] = true
+
+			for _, name := range pathParams(path) {
+				documented := false
+				for _, param := range op.Parameters {
+					documented = documented || (param.In == "path" && param.Name == name)
+				}
+				if !documented {
+					t.Errorf("%v %v does not document the path parameter %v", method, path, name)
+				}
+			}
+
+			if op.RequestBody != nil {
+				for _, content := range op.RequestBody.Content {
+					refs(content.Schema, found)
+				}
+			}
+			for _, res := range op.Responses {
+				for _, content := range res.Content {
+					refs(content.Schema, found)
+				}
+			}
+		}
+	}
+	for _, s := range doc.Components.Schemas {
+		refs(s, found)
+	}
+	for name := range found {
+		if _, ok := doc.Components.Schemas[name]; !ok {
+			t.Errorf("Reference to an undefined schema %v", name)
+		}
+	}
+
+	// Verify the fields that are not omitted when empty are required
+	exam := doc.Components.Schemas["StudentExam"]
+	have := strings.Join(exam.Required, ",")
+	want := "exam,studentid,score,recorded_at"
+	if have != want {
+		t.Errorf("Incorrect required properties; have: %v, want: %v", have, want)
+	}
+	if !exam.Properties["recorded_at"].ReadOnly || exam.Properties["score"].ReadOnly {
+		t.Errorf("Incorrect read only properties; have: %+v", exam.Properties)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	doc := Spec()
+	exam := ref("StudentExam")
+
+	tests := []struct {
+		body  string
+		valid bool
+	}{
+		{`{"exam": 1, "studentid": "a", "score": 0.5, "recorded_at": "2021-03-01T17:02:11.482913Z"}`, true},
+		{`{"exam": 1, "studentid": "a", "score": 0.5, "curved": 0.6, "recorded_at": "2021-03-01T17:02:11Z"}`, true},
+		{`{"exam": 1, "studentid": "a", "score": 0.5}`, false},
+		{`{"exam": 1.5, "studentid": "a", "score": 0.5, "recorded_at": "2021-03-01T17:02:11Z"}`, false},
+		{`{"exam": 1, "studentid": 2, "score": 0.5, "recorded_at": "2021-03-01T17:02:11Z"}`, false},
+		{`{"exam": 1, "studentid": "a", "score": 0.5, "recorded_at": "yesterday"}`, false},
+		{`{"exam": 1, "studentid": "a", "score": 0.5, "recorded_at": "2021-03-01T17:02:11Z", "extra": true}`, false},
+		{`null`, false},
+	}
+
+	for _, test := range tests {
+		var value interface{}
+		json.Unmarshal([]byte(test.body), &value)
+		err := doc.Validate(exam, value)
+		if (err == nil) != test.valid {
+			t.Errorf("Incorrect validation of %v; have: %v, want valid: %v", test.body, err, test.valid)
+		}
+	}
+
+	// Verify a slice may be null, and the items of a nullable slice are checked
+	list := ref("AllExamsListResponse")
+	for body, valid := range map[string]bool{`{"exams": null}`: true, `{"exams": []}`: true, `{"exams": [{}]}`: false, `{}`: false} {
+		var value interface{}
+		json.Unmarshal([]byte(body), &value)
+		err := doc.Validate(list, value)
+		if (err == nil) != valid {
+			t.Errorf("Incorrect validation of %v; have: %v, want valid: %v", body, err, valid)
+		}
+	}
+
+	// Verify a response is matched to the schema of its status and content type
+	err := doc.ValidateResponse("GET", "/exams/{id}", 404, "application/problem+json", []byte(`{"type": "about:blank", "title": "Not Found", "status": 404, "code": "not_found"}`))
+	if err != nil {
+		t.Errorf("Problem response is invalid; %v", err)
+	}
+	err = doc.ValidateResponse("GET", "/exams/{id}", 200, "text/csv", []byte("exam,student,score,grade\n"))
+	if err != nil {
+		t.Errorf("CSV response is invalid; %v", err)
+	}
+	err = doc.ValidateResponse("GET", "/exams/{id}/metadata", 200, "text/csv", nil)
+	if err == nil {
+		t.Errorf("Undocumented content type was accepted")
+	}
+	err = doc.ValidateResponse("GET", "/does/not/exist", 200, "application/json", nil)
+	if err == nil {
+		t.Errorf("Undocumented route was accepted")
+	}
+}
@@ -0,0 +1,190 @@
+package openapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"mime"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ValidateResponse checks a response of a route against the schema documented for its status and content type
+// Only JSON bodies are checked; the path is the template the route was registered with, such as /exams/{id}
+func (d *Document) ValidateResponse(method string, path string, status int, contentType string, body []byte) error {
+	op := d.Operation(method, path)
+	if op == nil {
+		return fmt.Errorf("%s %s is not documented", method, path)
+	}
+
+	response, ok := op.Responses[strconv.Itoa(status)]
+	if !ok {
+		response, ok = op.Responses["default"]
+	}
+	if !ok {
+		return fmt.Errorf("%s %s does not document the status %d", method, path, status)
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	content, ok := response.Content[mediaType]
+	if !ok {
+		return fmt.Errorf("%s %s does not document a %s response with the status %d", method, path, mediaType, status)
+	}
+	if mediaType != JSONContentType && mediaType != ProblemContentType {
+		return nil
+	}
+
+	var value interface{}
+	err := json.Unmarshal(body, &value)
+	if err != nil {
+		return err
+	}
+
+	return d.Validate(content.Schema, value)
+}
+
+// Validate checks a decoded JSON value against a schema, resolving references to the components of the document
+func (d *Document) Validate(schema *Schema, value interface{}) error {
+	return d.validate(schema, value, "$")
+}
+
+func (d *Document) validate(schema *Schema, value interface{}, at string) error {
+	if schema.Ref != "" {
+		name := strings.TrimPrefix(schema.Ref, "#/components/schemas/")
+		resolved, ok := d.Components.Schemas[name]
+		if !ok {
+			return fmt.Errorf("%s: unknown schema %s", at, schema.Ref)
+		}
+		return d.validate(resolved, value, at)
+	}
+
+	if value == nil {
+		if schema.Nullable || schema.Type == "" && len(schema.AllOf) == 0 && len(schema.OneOf) == 0 {
+			return nil
+		}
+		return fmt.Errorf("%s: must not be null", at)
+	}
+
+	for _, s := range schema.AllOf {
+		err := d.validate(s, value, at)
+		if err != nil {
+			return err
+		}
+	}
+
+	if len(schema.OneOf) > 0 {
+		matched := 0
+		for _, s := range schema.OneOf {
+			if d.validate(s, value, at) == nil {
+				matched++
+			}
+		}
+		if matched != 1 {
+			return fmt.Errorf("%s: must match exactly one schema, matched %d", at, matched)
+		}
+	}
+
+	switch schema.Type {
+	case "":
+		return nil
+	case "object":
+		return d.validateObject(schema, value, at)
+	case "array":
+		items, ok := value.([]interface{})
+		if !ok {
+			return fmt.Errorf("%s: must be an array", at)
+		}
+		for i, item := range items {
+			err := d.validate(schema.Items, item, fmt.Sprintf("%s[%d]", at, i))
+			if err != nil {
+				return err
+			}
+		}
+	case "string":
+		s, ok := value.(string)
+		if !ok {
+			return fmt.Errorf("%s: must be a string", at)
+		}
+		if schema.Format == "date-time" {
+			_, err := time.Parse(time.RFC3339Nano, s)
+			if err != nil {
+				return fmt.Errorf("%s: must be a date-time", at)
+			}
+		}
+		if len(schema.Enum) > 0 && !contains(schema.Enum, s) {
+			return fmt.Errorf("%s: must be one of %s", at, strings.Join(schema.Enum, ", "))
+		}
+	case "number", "integer":
+		n, ok := value.(float64)
+		if !ok {
+			return fmt.Errorf("%s: must be a %s", at, schema.Type)
+		}
+		if schema.Type == "integer" && n != math.Trunc(n) {
+			return fmt.Errorf("%s: must be an integer", at)
+		}
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			return fmt.Errorf("%s: must be a boolean", at)
+		}
+	default:
+		return fmt.Errorf("%s: unknown type %s", at, schema.Type)
+	}
+
+	return nil
+}
+
+func (d *Document) validateObject(schema *Schema, value interface{}, at string) error {
+	object, ok := value.(map[string]interface{})
+	if !ok {
+		return fmt.Errorf("%s: must be an object", at)
+	}
+
+	for _, name := range schema.Required {
+		if _, ok := object[name]; !ok {
+			return fmt.Errorf("%s: missing the required property %s", at, name)
+		}
+	}
+
+	// Check the properties in a stable order, so the same error is reported every time
+	names := make([]string, 0, len(object))
+	for name := range object {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		prop, ok := schema.Properties[name]
+		if !ok {
+			switch additional := schema.AdditionalProperties.(type) {
+			case bool:
+				if !additional {
+					return fmt.Errorf("%s: unexpected property %s", at, name)
+				}
+				continue
+			case *Schema:
+				prop = additional
+			default:
+				continue
+			}
+		}
+
+		err := d.validate(prop, object[name], at+"."+name)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func contains(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+
+	return false
+}
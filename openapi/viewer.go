@@ -0,0 +1,183 @@
+package openapi
+
+// Viewer is a self-contained HTML page that renders the document served at /openapi.json
+// It lists the operations of each tag, and can send requests to try them out
+const Viewer = `<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>SSE REST Server API</title>
+<style>
+body { font-family: sans-serif; margin: 0 auto; max-width: 1100px; padding: 1em; color: #3b4151; }
+h1 small { font-size: 0.5em; color: #888; }
+h2 { border-bottom: 1px solid #ddd; padding-bottom: 0.3em; text-transform: capitalize; }
+details { border: 1px solid; border-radius: 4px; margin: 0.5em 0; }
+summary { cursor: pointer; padding: 0.5em; }
+.method { display: inline-block; width: 5em; padding: 0.2em 0; border-radius: 3px; color: #fff; font-weight: bold; text-align: center; text-transform: uppercase; }
+.path { font-family: monospace; font-size: 1.1em; margin: 0 1em; }
+.body { padding: 0 1em 1em; }
+.get { border-color: #61affe; background: #ebf3fb; } .get .method { background: #61affe; }
+.post { border-color: #49cc90; background: #e8f6f0; } .post .method { background: #49cc90; }
+.put { border-color: #fca130; background: #fbf1e6; } .put .method { background: #fca130; }
+.patch { border-color: #50e3c2; background: #e9fbf7; } .patch .method { background: #50e3c2; }
+.delete { border-color: #f93e3e; background: #fbe7e7; } .delete .method { background: #f93e3e; }
+table { border-collapse: collapse; width: 100%; background: #fff; }
+th, td { border: 1px solid #ddd; padding: 0.3em 0.5em; text-align: left; vertical-align: top; }
+pre { background: #333; color: #eee; padding: 0.5em; overflow: auto; max-height: 30em; }
+input, textarea { font-family: monospace; width: 100%; box-sizing: border-box; }
+button { margin: 0.5em 0; padding: 0.4em 1.2em; cursor: pointer; }
+</style>
+</head>
+<body>
+<h1 id="title">API <small id="version"></small></h1>
+<p id="description"></p>
+<div id="operations">Loading...</div>
+<script>
+"use strict";
+
+function element(tag, attrs, children) {
+  var el = document.createElement(tag);
+  Object.keys(attrs || {}).forEach(function (key) { el.setAttribute(key, attrs[key]); });
+  (children || []).forEach(function (child) {
+    el.appendChild(typeof child === "string" ? document.createTextNode(child) : child);
+  });
+  return el;
+}
+
+// Expand the references of a schema into an example value
+function example(spec, schema, depth) {
+  if (!schema || depth > 6) { return null; }
+  if (schema.$ref) { return example(spec, spec.components.schemas[schema.$ref.split("/").pop()], depth + 1); }
+  if (schema.allOf) { return example(spec, schema.allOf[0], depth + 1); }
+  if (schema.oneOf) { return example(spec, schema.oneOf[0], depth + 1); }
+  if (schema.enum) { return schema.enum[0]; }
+  switch (schema.type) {
+  case "object":
+    var value = {};
+    Object.keys(schema.properties || {}).forEach(function (name) {
+      value[name] = example(spec, schema.properties[name], depth + 1);
+    });
+    return value;
+  case "array": return [example(spec, schema.items, depth + 1)];
+  case "integer": return 0;
+  case "number": return 0.0;
+  case "boolean": return false;
+  case "string": return schema.format === "date-time" ? new Date().toISOString() : "string";
+  }
+  return null;
+}
+
+function schemaBlock(spec, content) {
+  var block = element("div");
+  Object.keys(content || {}).forEach(function (type) {
+    block.appendChild(element("div", {}, [element("code", {}, [type])]));
+    block.appendChild(element("pre", {}, [JSON.stringify(example(spec, content[type].schema, 0), null, 2)]));
+  });
+  return block;
+}
+
+function operation(spec, server, path, method, op) {
+  var inputs = {};
+  var body = element("div", { "class": "body" }, [element("p", {}, [op.summary])]);
+
+  if (op.parameters && op.parameters.length) {
+    var rows = [element("tr", {}, [element("th", {}, ["Name"]), element("th", {}, ["In"]), element("th", {}, ["Description"]), element("th", {}, ["Value"])])];
+    op.parameters.forEach(function (p) {
+      var input = element("input", { placeholder: (p.schema.enum || []).join(" | ") });
+      inputs[p.in + ":" + p.name] = input;
+      rows.push(element("tr", {}, [
+        element("td", {}, [element("code", {}, [p.name + (p.required ? " *" : "")])]),
+        element("td", {}, [p.in]),
+        element("td", {}, [p.description]),
+        element("td", {}, [input])
+      ]));
+    });
+    body.appendChild(element("h4", {}, ["Parameters"]));
+    body.appendChild(element("table", {}, rows));
+  }
+
+  var requestBody = null;
+  if (op.requestBody) {
+    var types = Object.keys(op.requestBody.content);
+    var first = op.requestBody.content[types[0]];
+    requestBody = element("textarea", { rows: "8" });
+    requestBody.value = types[0] === "application/json" ? JSON.stringify(example(spec, first.schema, 0), null, 2) : "";
+    requestBody.dataset.type = types[0];
+    body.appendChild(element("h4", {}, ["Request body (" + types.join(", ") + ")"]));
+    body.appendChild(requestBody);
+  }
+
+  body.appendChild(element("h4", {}, ["Responses"]));
+  Object.keys(op.responses).sort().forEach(function (status) {
+    var res = op.responses[status];
+    body.appendChild(element("div", {}, [element("strong", {}, [status]), " " + res.description]));
+    body.appendChild(schemaBlock(spec, res.content));
+  });
+
+  var output = element("pre");
+  var send = element("button", {}, ["Try it out"]);
+  send.addEventListener("click", function () {
+    var url = server + path.replace(/\{(\w+)\}/g, function (_, name) {
+      return encodeURIComponent(inputs["path:" + name].value);
+    });
+    var query = [];
+    var headers = {};
+    Object.keys(inputs).forEach(function (key) {
+      var parts = key.split(":");
+      var value = inputs[key].value;
+      if (value === "") { return; }
+      if (parts[0] === "query") { query.push(encodeURIComponent(parts[1]) + "=" + encodeURIComponent(value)); }
+      if (parts[0] === "header") { headers[parts[1]] = value; }
+    });
+    if (query.length) { url += "?" + query.join("&"); }
+    var init = { method: method.toUpperCase(), headers: headers };
+    if (requestBody) {
+      headers["Content-Type"] = requestBody.dataset.type;
+      init.body = requestBody.value;
+    }
+    output.textContent = "Sending...";
+    fetch(url, init).then(function (res) {
+      return res.text().then(function (text) {
+        output.textContent = res.status + " " + res.statusText + "\n\n" + text;
+      });
+    }).catch(function (err) {
+      output.textContent = String(err);
+    });
+  });
+  body.appendChild(send);
+  body.appendChild(output);
+
+  return element("details", { "class": method }, [
+    element("summary", {}, [element("span", { "class": "method" }, [method]), element("span", { "class": "path" }, [path]), op.summary]),
+    body
+  ]);
+}
+
+fetch("/openapi.json").then(function (res) { return res.json(); }).then(function (spec) {
+  document.title = spec.info.title;
+  document.getElementById("title").firstChild.textContent = spec.info.title + " ";
+  document.getElementById("version").textContent = "version " + spec.info.version;
+  document.getElementById("description").textContent = spec.info.description;
+
+  var server = spec.servers && spec.servers.length ? spec.servers[0].url : "";
+  var container = document.getElementById("operations");
+  container.textContent = "";
+  spec.tags.forEach(function (tag) {
+    container.appendChild(element("h2", {}, [tag.name]));
+    container.appendChild(element("p", {}, [tag.description]));
+    Object.keys(spec.paths).sort().forEach(function (path) {
+      ["get", "post", "put", "patch", "delete"].forEach(function (method) {
+        var op = spec.paths[path][method];
+        if (op && op.tags.indexOf(tag.name) >= 0) {
+          container.appendChild(operation(spec, server, path, method, op));
+        }
+      });
+    });
+  });
+}).catch(function (err) {
+  document.getElementById("operations").textContent = "Unable to load the API description: " + err;
+});
+</script>
+</body>
+</html>
+`
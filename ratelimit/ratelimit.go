@@ -0,0 +1,124 @@
+package ratelimit
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// Define the default limits, which let a client make a steady 10 requests a second with bursts of up to 120
+const (
+	DefaultRate  = 600
+	DefaultBurst = 120
+)
+
+// How long a client's bucket is kept after it has refilled, before it is removed to bound the memory used by idle clients
+const idleTimeout = 10 * time.Minute
+
+// Result is the outcome of taking tokens from a client's bucket
+type Result struct {
+	Allowed bool
+	// Limit is the size of the bucket, the most tokens a client can spend at once
+	Limit int
+	// Remaining is the number of whole tokens left in the bucket
+	Remaining int
+	// Reset is how long until the bucket is full again
+	Reset time.Duration
+	// RetryAfter is how long until the bucket holds enough tokens for a rejected request
+	RetryAfter time.Duration
+}
+
+// A client's bucket, holding the tokens left when it was last used
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+var (
+	mu sync.Mutex
+	// The number of tokens added to each bucket every second, and the size of the buckets; the limits are off while rate is 0
+	rate    float64
+	burst   int
+	buckets = make(map[string]*bucket)
+	swept   time.Time
+)
+
+// Setup limits every client to perMinute tokens a minute, spent in bursts of up to burst tokens, clearing the existing buckets
+// A perMinute of 0 turns the limits off
+func Setup(perMinute int, size int) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	rate = float64(perMinute) / 60
+	burst = size
+	buckets = make(map[string]*bucket)
+}
+
+// Enabled reports whether requests are rate limited
+func Enabled() bool {
+	mu.Lock()
+	defer mu.Unlock()
+
+	return rate > 0 && burst > 0
+}
+
+// Take spends cost tokens from the bucket of a client, rejecting the request when the bucket does not hold enough
+// A cost larger than the bucket is capped at its size, so every request can succeed once the bucket is full
+func Take(key string, cost int) Result {
+	return take(key, cost, time.Now())
+}
+
+func take(key string, cost int, now time.Time) Result {
+	mu.Lock()
+	defer mu.Unlock()
+
+	if rate <= 0 || burst <= 0 {
+		return Result{Allowed: true}
+	}
+	if cost > burst {
+		cost = burst
+	}
+	sweep(now)
+
+	b, ok := buckets[key]
+	if !ok {
+		b = &bucket{tokens: float64(burst), last: now}
+		buckets[key] = b
+	}
+
+	// Refill the bucket for the time since it was last used
+	b.tokens = math.Min(float64(burst), b.tokens+now.Sub(b.last).Seconds()*rate)
+	b.last = now
+
+	res := Result{Limit: burst}
+	if b.tokens >= float64(cost) {
+		b.tokens -= float64(cost)
+		res.Allowed = true
+	} else {
+		res.RetryAfter = seconds((float64(cost) - b.tokens) / rate)
+	}
+	res.Remaining = int(b.tokens)
+	res.Reset = seconds((float64(burst) - b.tokens) / rate)
+
+	return res
+}
+
+// Remove the buckets of the clients that have been idle long enough for their bucket to refill, at most once every idle timeout
+func sweep(now time.Time) {
+	if now.Sub(swept) < idleTimeout {
+		return
+	}
+	swept = now
+
+	refill := time.Duration(float64(burst) / rate * float64(time.Second))
+	for key, b := range buckets {
+		if now.Sub(b.last) > refill+idleTimeout {
+			delete(buckets, key)
+		}
+	}
+}
+
+// Round a number of seconds up to a whole second, as the rate limit headers are sent in seconds
+func seconds(s float64) time.Duration {
+	return time.Duration(math.Ceil(s)) * time.Second
+}
@@ -0,0 +1,74 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTake(t *testing.T) {
+	defer Setup(0, 0)
+
+	now := time.Date(2021, 3, 1, 9, 0, 0, 0, time.UTC)
+	res := take("client", 100, now)
+	if !res.Allowed || Enabled() {
+		t.Errorf("Request was limited while the limits are off; have: %+v", res)
+	}
+
+	// 60 tokens a minute is one token a second, in bursts of up to 10
+	Setup(60, 10)
+	if !Enabled() {
+		t.Errorf("Limits are not enabled")
+	}
+
+	tests := []struct {
+		key        string
+		cost       int
+		after      time.Duration
+		allowed    bool
+		remaining  int
+		retryAfter time.Duration
+	}{
+		{"client", 4, 0, true, 6, 0},
+		{"client", 6, 0, true, 0, 0},
+		{"client", 1, 0, false, 0, time.Second},
+		// Other clients have their own bucket
+		{"other", 1, 0, true, 9, 0},
+		{"client", 3, 1500 * time.Millisecond, false, 1, 2 * time.Second},
+		{"client", 3, 3 * time.Second, true, 1, 0},
+		// A cost larger than the bucket is capped at its size
+		{"client", 50, 20 * time.Second, true, 0, 0},
+	}
+	for _, test := range tests {
+		now = now.Add(test.after)
+		res = take(test.key, test.cost, now)
+
+		if res.Allowed != test.allowed || res.Remaining != test.remaining || res.RetryAfter != test.retryAfter || res.Limit != 10 {
+			t.Errorf("Incorrect result for %v spending %v; have: %+v, want: allowed %v, remaining %v, retry after %v", test.key, test.cost, res, test.allowed, test.remaining, test.retryAfter)
+		}
+	}
+
+	have := res.Reset
+	want := 10 * time.Second
+	if have != want {
+		t.Errorf("Incorrect time until the bucket is full; have: %v, want: %v", have, want)
+	}
+}
+
+func TestSweep(t *testing.T) {
+	defer Setup(0, 0)
+	Setup(60, 10)
+
+	now := time.Now()
+	take("idle", 1, now)
+	take("active", 1, now.Add(idleTimeout))
+	take("active", 1, now.Add(2*idleTimeout))
+
+	mu.Lock()
+	defer mu.Unlock()
+	if _, ok := buckets["idle"]; ok {
+		t.Errorf("Idle bucket was not removed")
+	}
+	if _, ok := buckets["active"]; !ok {
+		t.Errorf("Active bucket was removed")
+	}
+}
@@ -0,0 +1,246 @@
+package report
+
+import (
+	"errors"
+	"strconv"
+
+	"github.com/kylegk/sse-rest-server/config"
+	"github.com/kylegk/sse-rest-server/db"
+	"github.com/kylegk/sse-rest-server/grading"
+	"github.com/kylegk/sse-rest-server/models"
+)
+
+// ErrNotFound is returned when a student or exam has no scores
+var ErrNotFound = errors.New("no scores found")
+
+// Filter reports whether a student's scores can be included in a report, such as whether the client can see them;
+// a nil filter includes every student
+type Filter func(student string) bool
+
+// Includes reports whether the filter includes a student
+func (f Filter) Includes(student string) bool {
+	return f == nil || f(student)
+}
+
+// Apply keeps the scores of the students the filter includes
+func (f Filter) Apply(scores []models.StudentExam) []models.StudentExam {
+	if f == nil {
+		return scores
+	}
+
+	kept := make([]models.StudentExam, 0, len(scores))
+	for _, score := range scores {
+		if f(score.StudentID) {
+			kept = append(kept, score)
+		}
+	}
+
+	return kept
+}
+
+// Scores retrieves the scores matching an index of the score table
+func Scores(idx string, args ...interface{}) ([]models.StudentExam, error) {
+	var list []models.StudentExam
+	err := db.View(func(txn *db.Txn) error {
+		var err error
+		list, err = scores(txn, idx, args...)
+		return err
+	})
+
+	return list, err
+}
+
+// Retrieve the scores matching an index of the score table from the transaction's snapshot
+func scores(txn *db.Txn, idx string, args ...interface{}) ([]models.StudentExam, error) {
+	res, err := txn.GetRows(config.ScoreTable, idx, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	list := make([]models.StudentExam, 0, len(res))
+	for _, row := range res {
+		list = append(list, row.(models.StudentExam))
+	}
+
+	return list, nil
+}
+
+// ExamMetadata retrieves the metadata of every exam, keyed by exam id
+func ExamMetadata() (map[int]models.ExamMetadata, error) {
+	var meta map[int]models.ExamMetadata
+	err := db.View(func(txn *db.Txn) error {
+		var err error
+		meta, err = examMetadata(txn)
+		return err
+	})
+
+	return meta, err
+}
+
+// Retrieve the metadata of every exam from the transaction's snapshot
+func examMetadata(txn *db.Txn) (map[int]models.ExamMetadata, error) {
+	res, err := txn.GetRows(config.ExamMetaTable, config.IdFld)
+	if err != nil {
+		return nil, err
+	}
+
+	meta := make(map[int]models.ExamMetadata, len(res))
+	for _, row := range res {
+		m := row.(models.ExamMetadata)
+		meta[m.Exam] = m
+	}
+
+	return meta, nil
+}
+
+// ScoreType is the name of the type of scores reported in a response
+func ScoreType(curved bool) string {
+	if curved {
+		return "curved"
+	}
+
+	return "raw"
+}
+
+// Student lists a student's scores with their grades, and the student's average calculated with the grading policy
+// Passing curved reports the curved scores of any curved exams in place of the raw scores
+// The scores and statistics are read from one snapshot, so a score written while the report is built is counted in both or neither
+func Student(studentID string, policy grading.Policy, scale grading.Scale, curved bool) (*models.StudentByIDResponse, error) {
+	var report *models.StudentByIDResponse
+	err := db.View(func(txn *db.Txn) error {
+		var err error
+		report, err = student(txn, studentID, policy, scale, curved)
+		return err
+	})
+
+	return report, err
+}
+
+func student(txn *db.Txn, studentID string, policy grading.Policy, scale grading.Scale, curved bool) (*models.StudentByIDResponse, error) {
+	res, err := scores(txn, config.StudentIdx, studentID)
+	if err != nil {
+		return nil, err
+	}
+	if len(res) == 0 {
+		return nil, ErrNotFound
+	}
+
+	report := &models.StudentByIDResponse{Student: studentID, Policy: policy.Name, Scale: scale.Name, ScoreType: ScoreType(curved)}
+	stats := &models.Aggregate{}
+	for _, exam := range res {
+		value := exam.Value(curved)
+		report.Exams = append(report.Exams, models.StudentExamScores{Exam: exam.Exam, Score: value, Grade: scale.Grade(value)})
+		stats.Add(value)
+	}
+
+	// The running aggregates only cover the raw scores
+	if !curved {
+		stats, err = txn.GetAggregate(config.StudentAggregate, studentID)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if stats != nil {
+		report.Average = stats.Mean()
+		report.StdDev = stats.StdDev()
+		report.Min = stats.Min
+		report.Max = stats.Max
+	}
+
+	// Anything other than a plain mean has to be computed from the individual scores
+	if !policy.IsMean() {
+		meta, err := examMetadata(txn)
+		if err != nil {
+			return nil, err
+		}
+		report.Average = grading.Average(policy, report.Exams, meta)
+	}
+	report.Grade = scale.Grade(report.Average)
+
+	return report, nil
+}
+
+// Exam lists an exam's scores with their grades, and the class statistics
+// Passing curved reports the curved scores in place of the raw scores, if the exam has been curved
+// Only the scores of the students the filter includes are listed and counted in the statistics
+// The scores and statistics are read from one snapshot, so a score written while the report is built is counted in both or neither
+func Exam(examID int, scale grading.Scale, curved bool, filter Filter) (*models.ExamByIDResponse, error) {
+	var report *models.ExamByIDResponse
+	err := db.View(func(txn *db.Txn) error {
+		var err error
+		report, err = exam(txn, examID, scale, curved, filter)
+		return err
+	})
+
+	return report, err
+}
+
+func exam(txn *db.Txn, examID int, scale grading.Scale, curved bool, filter Filter) (*models.ExamByIDResponse, error) {
+	res, err := scores(txn, config.ExamIdx, examID)
+	if err != nil {
+		return nil, err
+	}
+	res = filter.Apply(res)
+	if len(res) == 0 {
+		return nil, ErrNotFound
+	}
+
+	report := &models.ExamByIDResponse{Exam: examID, Scale: scale.Name, ScoreType: ScoreType(curved)}
+	for _, score := range res {
+		value := score.Value(curved)
+		report.Scores = append(report.Scores, models.ExamScorePerStudent{Student: score.StudentID, Score: value, Grade: scale.Grade(value)})
+	}
+
+	stats, err := examStats(txn, examID, res, curved, filter)
+	if err != nil {
+		return nil, err
+	}
+	report.Average = stats.Mean()
+	report.StdDev = stats.StdDev()
+	report.Min = stats.Min
+	report.Max = stats.Max
+	report.Grade = scale.Grade(report.Average)
+
+	return report, nil
+}
+
+// ExamStats calculates the class statistics of the scores of an exam the filter includes,
+// which are empty for an exam without scores
+func ExamStats(examID int, curved bool, filter Filter) (*models.Aggregate, error) {
+	var stats *models.Aggregate
+	err := db.View(func(txn *db.Txn) error {
+		var res []models.StudentExam
+		var err error
+		if curved || filter != nil {
+			res, err = scores(txn, config.ExamIdx, examID)
+			if err != nil {
+				return err
+			}
+		}
+
+		stats, err = examStats(txn, examID, filter.Apply(res), curved, filter)
+		return err
+	})
+
+	return stats, err
+}
+
+// Calculate the statistics of an exam from its filtered scores; the running aggregate only covers the raw scores of every student,
+// so the curved or filtered statistics are calculated from the scores
+func examStats(txn *db.Txn, examID int, res []models.StudentExam, curved bool, filter Filter) (*models.Aggregate, error) {
+	if !curved && filter == nil {
+		stats, err := txn.GetAggregate(config.ExamAggregate, strconv.Itoa(examID))
+		if err != nil || stats != nil {
+			return stats, err
+		}
+
+		return &models.Aggregate{}, nil
+	}
+
+	stats := &models.Aggregate{}
+	for _, score := range res {
+		stats.Add(score.Value(curved))
+	}
+
+	return stats, nil
+}
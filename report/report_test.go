@@ -0,0 +1,180 @@
+package report
+
+import (
+	"fmt"
+	"math"
+	"testing"
+
+	"github.com/kylegk/sse-rest-server/config"
+	"github.com/kylegk/sse-rest-server/db"
+	"github.com/kylegk/sse-rest-server/grading"
+	"github.com/kylegk/sse-rest-server/models"
+)
+
+func setupTestData(t *testing.T) {
+	err := db.InitDB(config.DBSchema)
+	if err != nil {
+		t.Fatalf("Failed to setup the data; %v", err)
+	}
+
+	_, err = db.UpsertScores([]models.StudentExam{
+		{Exam: 1, StudentID: "test.person1", Score: 0.5},
+		{Exam: 2, StudentID: "test.person1", Score: 0.9},
+		{Exam: 1, StudentID: "test.person2", Score: 0.7},
+	}, nil)
+	if err != nil {
+		t.Fatalf("Failed to setup the data; %v", err)
+	}
+
+	// The running aggregates do not cover the curved scores
+	_, err = db.ApplyCurve(models.Curve{Exam: 1, Method: models.CurveLinear, Factor: 1.2}, nil)
+	if err != nil {
+		t.Fatalf("Failed to setup the data; %v", err)
+	}
+}
+
+func TestStudent(t *testing.T) {
+	setupTestData(t)
+
+	policy, _ := grading.GetPolicy("")
+	scale, _ := grading.GetScale("")
+
+	student, err := Student("test.person1", policy, scale, false)
+	if err != nil {
+		t.Fatalf("Student returned an error; %v", err)
+	}
+	if have, want := len(student.Exams), 2; have != want {
+		t.Errorf("Incorrect number of exams; have: %d, want: %d", have, want)
+	}
+	if have, want := student.Average, 0.7; math.Abs(have-want) > 1e-9 {
+		t.Errorf("Incorrect raw average; have: %v, want: %v", have, want)
+	}
+	if have, want := student.ScoreType, "raw"; have != want {
+		t.Errorf("Incorrect score type; have: %s, want: %s", have, want)
+	}
+
+	student, err = Student("test.person1", policy, scale, true)
+	if err != nil {
+		t.Fatalf("Student returned an error; %v", err)
+	}
+	if have, want := student.Average, 0.75; math.Abs(have-want) > 1e-9 {
+		t.Errorf("Incorrect curved average; have: %v, want: %v", have, want)
+	}
+
+	policy, _ = grading.GetPolicy("drop_lowest")
+	student, err = Student("test.person1", policy, scale, false)
+	if err != nil {
+		t.Fatalf("Student returned an error; %v", err)
+	}
+	if have, want := student.Average, 0.9; math.Abs(have-want) > 1e-9 {
+		t.Errorf("Incorrect average with the lowest score dropped; have: %v, want: %v", have, want)
+	}
+
+	_, err = Student("nobody", policy, scale, false)
+	if err != ErrNotFound {
+		t.Errorf("Incorrect error for a student without scores; have: %v, want: %v", err, ErrNotFound)
+	}
+}
+
+func TestExam(t *testing.T) {
+	setupTestData(t)
+
+	scale, _ := grading.GetScale("")
+
+	exam, err := Exam(1, scale, false, nil)
+	if err != nil {
+		t.Fatalf("Exam returned an error; %v", err)
+	}
+	if have, want := len(exam.Scores), 2; have != want {
+		t.Errorf("Incorrect number of scores; have: %d, want: %d", have, want)
+	}
+	if have, want := exam.Average, 0.6; math.Abs(have-want) > 1e-9 {
+		t.Errorf("Incorrect raw average; have: %v, want: %v", have, want)
+	}
+
+	exam, err = Exam(1, scale, true, nil)
+	if err != nil {
+		t.Fatalf("Exam returned an error; %v", err)
+	}
+	if have, want := exam.Average, 0.72; math.Abs(have-want) > 1e-9 {
+		t.Errorf("Incorrect curved average; have: %v, want: %v", have, want)
+	}
+
+	_, err = Exam(3, scale, false, nil)
+	if err != ErrNotFound {
+		t.Errorf("Incorrect error for an exam without scores; have: %v, want: %v", err, ErrNotFound)
+	}
+
+	stats, err := ExamStats(3, false, nil)
+	if err != nil {
+		t.Fatalf("ExamStats returned an error; %v", err)
+	}
+	if have, want := stats.Count, 0; have != want {
+		t.Errorf("Incorrect count for an exam without scores; have: %d, want: %d", have, want)
+	}
+}
+
+func TestExamSnapshot(t *testing.T) {
+	setupTestData(t)
+
+	scale, _ := grading.GetScale("")
+	_, err := db.UpsertScores([]models.StudentExam{{Exam: 3, StudentID: "test.person0", Score: 0}}, nil)
+	if err != nil {
+		t.Fatalf("Failed to setup the data; %v", err)
+	}
+
+	// Each score written while the reports are built moves the average, so the listed scores and the
+	// statistics only agree when they are read from the same snapshot
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 1; i <= 1000; i++ {
+			_, err := db.UpsertScores([]models.StudentExam{{Exam: 3, StudentID: fmt.Sprintf("test.writer%d", i), Score: 1}}, nil)
+			if err != nil {
+				t.Errorf("Failed to write a score; %v", err)
+				return
+			}
+		}
+	}()
+
+	for running := true; running; {
+		select {
+		case <-done:
+			running = false
+		default:
+		}
+
+		exam, err := Exam(3, scale, false, nil)
+		if err != nil {
+			t.Errorf("Exam returned an error; %v", err)
+			break
+		}
+
+		var sum float64
+		for _, score := range exam.Scores {
+			sum += score.Score
+		}
+		if have, want := exam.Average, sum/float64(len(exam.Scores)); math.Abs(have-want) > 1e-9 {
+			t.Errorf("The average does not match the listed scores; have: %v, want: %v", have, want)
+			break
+		}
+	}
+	<-done
+}
+
+func TestExamMetadata(t *testing.T) {
+	setupTestData(t)
+
+	err := db.UpsertRow(config.ExamMetaTable, models.ExamMetadata{Exam: 1, Title: "Midterm", Weight: 2})
+	if err != nil {
+		t.Fatalf("Failed to setup the data; %v", err)
+	}
+
+	meta, err := ExamMetadata()
+	if err != nil {
+		t.Fatalf("ExamMetadata returned an error; %v", err)
+	}
+	if have, want := meta[1].Title, "Midterm"; have != want {
+		t.Errorf("Incorrect metadata; have: %s, want: %s", have, want)
+	}
+}
@@ -0,0 +1,270 @@
+package rpc
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net"
+	"runtime"
+	"strconv"
+	"time"
+
+	"github.com/kylegk/sse-rest-server/apikey"
+	"github.com/kylegk/sse-rest-server/audit"
+	"github.com/kylegk/sse-rest-server/auth"
+	"github.com/kylegk/sse-rest-server/db"
+	"github.com/kylegk/sse-rest-server/ratelimit"
+	"github.com/kylegk/sse-rest-server/report"
+	"github.com/kylegk/sse-rest-server/rpc/pb"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// The metadata keys a client sends its credentials in, matching the X-API-Key and Authorization headers of the REST API
+const (
+	apiKeyMetadata        = "x-api-key"
+	authorizationMetadata = "authorization"
+	// The id of the call, matching the X-Request-ID header of the REST API
+	requestIDMetadata = "x-request-id"
+)
+
+type contextKey string
+
+// The call context key of the client that authenticated a call
+const principalContext contextKey = "principal"
+
+// Log basic information about every unary call, as handler.LogRequest does for HTTP, authenticate and authorize it
+// and recover from any panic in the call
+func unaryInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp interface{}, err error) {
+	logCall(ctx, info.FullMethod)
+	defer recoverCall(&err)
+
+	ctx, err = authenticate(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	err = limit(ctx, info.FullMethod)
+	if err != nil {
+		return nil, err
+	}
+
+	err = authorize(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	return handler(ctx, req)
+}
+
+// Log basic information about every streaming call, authenticate it and recover from any panic in the call
+// Streaming calls are authorized by the server method, once it has received the request
+func streamInterceptor(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) (err error) {
+	logCall(stream.Context(), info.FullMethod)
+	defer recoverCall(&err)
+
+	ctx, err := authenticate(stream.Context())
+	if err != nil {
+		return err
+	}
+
+	err = limit(ctx, info.FullMethod)
+	if err != nil {
+		return err
+	}
+
+	return handler(srv, &authenticatedStream{ServerStream: stream, ctx: ctx})
+}
+
+// A server stream whose context carries the client that authenticated the call
+type authenticatedStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *authenticatedStream) Context() context.Context {
+	return s.ctx
+}
+
+// Require an API key or a bearer token once either has been configured, as handler.Authenticate does for HTTP,
+// returning a context carrying the client
+// Every failed attempt spends a token from the rate limit of the address it came from, as it does for HTTP
+func authenticate(ctx context.Context) (context.Context, error) {
+	if !auth.Required() {
+		return ctx, nil
+	}
+
+	md, _ := metadata.FromIncomingContext(ctx)
+	principal, err := auth.Authenticate(firstValue(md, apiKeyMetadata), firstValue(md, authorizationMetadata))
+	if err != nil {
+		limitErr := spendTokens(ctx, "ip:"+callIP(ctx), 1)
+		if limitErr != nil {
+			return nil, limitErr
+		}
+	}
+
+	switch {
+	case err == auth.ErrNoCredentials:
+		return nil, status.Error(codes.Unauthenticated, "an API key in the x-api-key metadata or a bearer token in the authorization metadata is required")
+	case err == apikey.ErrInvalidKey, errors.Is(err, auth.ErrInvalidToken):
+		return nil, status.Error(codes.Unauthenticated, err.Error())
+	case err != nil:
+		return nil, internalError(err)
+	}
+
+	return context.WithValue(ctx, principalContext, principal), nil
+}
+
+func firstValue(md metadata.MD, key string) string {
+	values := md.Get(key)
+	if len(values) == 0 {
+		return ""
+	}
+
+	return values[0]
+}
+
+// Apply the policy of the REST route matching each call: students can only read their own scores,
+// teachers can read and add the scores of their cohorts, and only admins can delete exams
+func authorize(ctx context.Context, req interface{}) error {
+	principal, ok := callPrincipal(ctx)
+	if !ok {
+		return nil
+	}
+
+	allowed := principal.IsStaff()
+	switch req := req.(type) {
+	case *pb.GetStudentRequest:
+		allowed = principal.CanAccessStudent(req.Id)
+	case *pb.AddScoreRequest:
+		allowed = principal.IsStaff() && (req.Student == "" || principal.CanAccessStudent(req.Student))
+	case *pb.DeleteExamRequest:
+		allowed = principal.Role == auth.RoleAdmin
+	case *pb.WatchScoresRequest:
+		// Watching every student is limited to the clients that can see every student
+		allowed = principal.AllStudents
+		if req.Student != "" {
+			allowed = principal.CanAccessStudent(req.Student)
+		}
+	}
+
+	if !allowed {
+		return status.Errorf(codes.PermissionDenied, "the %s role cannot make this call", principal.Role)
+	}
+
+	return nil
+}
+
+// The client that authenticated a call, if any
+func callPrincipal(ctx context.Context) (auth.Principal, bool) {
+	principal, ok := ctx.Value(principalContext).(auth.Principal)
+	return principal, ok
+}
+
+// The filter of the students whose scores the client of a call can see, as handler.visibleStudents does for HTTP
+func visibleStudents(ctx context.Context) report.Filter {
+	principal, ok := callPrincipal(ctx)
+	if !ok {
+		return nil
+	}
+
+	return principal.Visible()
+}
+
+// The number of tokens of the client's rate limit each method costs, matching the cost of the REST route it mirrors;
+// the methods that are not listed cost one token
+var methodCosts = map[string]int{
+	"/scores.v1.Scores/ListStudents": 5,
+	"/scores.v1.Scores/ListExams":    5,
+}
+
+// Spend tokens from the rate limit of the client of a call, as handler.RateLimit does for HTTP, so a client shares its
+// limit across both APIs
+func limit(ctx context.Context, method string) error {
+	cost, ok := methodCosts[method]
+	if !ok {
+		cost = 1
+	}
+
+	return spendTokens(ctx, callRateLimitKey(ctx), cost)
+}
+
+// Spend tokens from a rate limit, rejecting the call once the limit has no tokens left
+func spendTokens(ctx context.Context, key string, cost int) error {
+	if !ratelimit.Enabled() {
+		return nil
+	}
+
+	res := ratelimit.Take(key, cost)
+	if !res.Allowed {
+		retryAfter := int(res.RetryAfter / time.Second)
+		_ = grpc.SetHeader(ctx, metadata.Pairs("retry-after", strconv.Itoa(retryAfter)))
+		return status.Errorf(codes.ResourceExhausted, "rate limit exceeded: retry in %d seconds", retryAfter)
+	}
+
+	return nil
+}
+
+// Identify the client a call is limited as, which is the same client its requests to the REST API are limited as
+func callRateLimitKey(ctx context.Context) string {
+	if principal, ok := callPrincipal(ctx); ok {
+		return principal.ID
+	}
+
+	return "ip:" + callIP(ctx)
+}
+
+// The address of the client of a call, without its port
+func callIP(ctx context.Context) string {
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return ""
+	}
+
+	addr := p.Addr.String()
+	if host, _, err := net.SplitHostPort(addr); err == nil {
+		return host
+	}
+
+	return addr
+}
+
+// Identify who made a call and where it came from, for the audit log
+// The request id sent by the client is kept, as the REST API does, and one is generated otherwise
+func callSource(ctx context.Context) audit.Source {
+	md, _ := metadata.FromIncomingContext(ctx)
+	source := audit.Source{Actor: audit.AnonymousActor, IP: callIP(ctx), RequestID: firstValue(md, requestIDMetadata)}
+	if source.RequestID == "" || len(source.RequestID) > 128 {
+		source.RequestID = db.NewID()
+	}
+	if principal, ok := callPrincipal(ctx); ok {
+		source.Actor = principal.ID
+		source.Role = principal.Role
+	}
+
+	return source
+}
+
+func logCall(ctx context.Context, method string) {
+	addr := "unknown"
+	if p, ok := peer.FromContext(ctx); ok {
+		addr = p.Addr.String()
+	}
+
+	log.Printf("%s gRPC %s\n", addr, method)
+}
+
+// Turn a panic into an internal error, as handler.PanicRecovery does for HTTP
+func recoverCall(err *error) {
+	if r := recover(); r != nil {
+		buf := make([]byte, 2048)
+		n := runtime.Stack(buf, false)
+		buf = buf[:n]
+
+		log.Printf("recovering from error: %v\n %s", r, buf)
+		*err = status.Error(codes.Internal, "internal error")
+	}
+}
@@ -0,0 +1,147 @@
+package rpc
+
+import (
+	"context"
+	"testing"
+
+	"github.com/kylegk/sse-rest-server/apikey"
+	"github.com/kylegk/sse-rest-server/auth"
+	"github.com/kylegk/sse-rest-server/ratelimit"
+	"github.com/kylegk/sse-rest-server/rpc/pb"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+func TestUnaryInterceptorRecovers(t *testing.T) {
+	panics := func(ctx context.Context, req interface{}) (interface{}, error) {
+		panic("test panic")
+	}
+
+	_, err := unaryInterceptor(context.Background(), nil, &grpc.UnaryServerInfo{FullMethod: "/scores.v1.Scores/Test"}, panics)
+	have := status.Code(err)
+	want := codes.Internal
+	if have != want {
+		t.Errorf("Panic was not recovered; have: %v, want: %v", have, want)
+	}
+}
+
+func TestAuthenticate(t *testing.T) {
+	client, stop := startTestServer(t)
+	defer stop()
+
+	err := apikey.AddKey("dashboard", "dashboard-key", false)
+	if err != nil {
+		t.Errorf("Unable to add the key; %v", err)
+	}
+
+	tests := map[string]codes.Code{
+		"":              codes.Unauthenticated,
+		"wrong-key":     codes.Unauthenticated,
+		"dashboard-key": codes.OK,
+	}
+	for key, want := range tests {
+		ctx := context.Background()
+		if key != "" {
+			ctx = metadata.AppendToOutgoingContext(ctx, apiKeyMetadata, key)
+		}
+
+		_, err = client.ListStudents(ctx, &pb.ListStudentsRequest{})
+		have := status.Code(err)
+		if have != want {
+			t.Errorf("Incorrect status for the key %q; have: %v, want: %v", key, have, want)
+		}
+	}
+}
+
+func TestAuthorize(t *testing.T) {
+	student := auth.Principal{Subject: "test.person1", Role: auth.RoleStudent}
+	teacher := auth.Principal{Subject: "dashboard", Role: auth.RoleTeacher, AllStudents: true}
+	admin := auth.Principal{Subject: "root", Role: auth.RoleAdmin, AllStudents: true}
+
+	tests := []struct {
+		principal auth.Principal
+		req       interface{}
+		want      codes.Code
+	}{
+		{student, &pb.GetStudentRequest{Id: "test.person1"}, codes.OK},
+		{student, &pb.GetStudentRequest{Id: "test.person2"}, codes.PermissionDenied},
+		{student, &pb.ListStudentsRequest{}, codes.PermissionDenied},
+		{student, &pb.AddScoreRequest{Exam: 1, Student: "test.person1", Score: 1}, codes.PermissionDenied},
+		{student, &pb.WatchScoresRequest{Student: "test.person1"}, codes.OK},
+		{student, &pb.WatchScoresRequest{}, codes.PermissionDenied},
+		{teacher, &pb.ListStudentsRequest{}, codes.OK},
+		{teacher, &pb.AddScoreRequest{Exam: 1, Student: "test.person2", Score: 0.5}, codes.OK},
+		{teacher, &pb.DeleteExamRequest{Id: 1}, codes.PermissionDenied},
+		{admin, &pb.DeleteExamRequest{Id: 1}, codes.OK},
+	}
+	for _, test := range tests {
+		ctx := context.WithValue(context.Background(), principalContext, test.principal)
+		have := status.Code(authorize(ctx, test.req))
+		if have != test.want {
+			t.Errorf("Incorrect status for the %v role calling with %T; have: %v, want: %v", test.principal.Role, test.req, have, test.want)
+		}
+	}
+
+	// Calls are not checked while authentication has not been configured
+	err := authorize(context.Background(), &pb.DeleteExamRequest{Id: 1})
+	if err != nil {
+		t.Errorf("Call without authentication configured was rejected; %v", err)
+	}
+}
+
+func TestRateLimit(t *testing.T) {
+	client, stop := startTestServer(t)
+	defer stop()
+	defer ratelimit.Setup(0, 0)
+	ratelimit.Setup(1, 6)
+
+	listStudents := func() error {
+		_, err := client.ListStudents(context.Background(), &pb.ListStudentsRequest{})
+		return err
+	}
+	getExam := func() error {
+		_, err := client.GetExam(context.Background(), &pb.GetExamRequest{Id: 1})
+		return err
+	}
+
+	// Listing the students costs 5 of the 6 tokens, so the second list is rejected while a cheaper call is not
+	tests := []struct {
+		call func() error
+		want codes.Code
+	}{
+		{listStudents, codes.OK},
+		{listStudents, codes.ResourceExhausted},
+		{getExam, codes.OK},
+		{getExam, codes.ResourceExhausted},
+	}
+	for i, test := range tests {
+		have := status.Code(test.call())
+		if have != test.want {
+			t.Errorf("Incorrect status for call %v; have: %v, want: %v", i, have, test.want)
+		}
+	}
+}
+
+func TestRateLimitFailedAuthentication(t *testing.T) {
+	client, stop := startTestServer(t)
+	defer stop()
+	defer ratelimit.Setup(0, 0)
+	ratelimit.Setup(1, 3)
+
+	err := apikey.AddKey("dashboard", "dashboard-key", false)
+	if err != nil {
+		t.Errorf("Unable to add the key; %v", err)
+	}
+
+	// Every bad key spends a token from the address, so guessing is rejected once the address has none left
+	ctx := metadata.AppendToOutgoingContext(context.Background(), apiKeyMetadata, "wrong-key")
+	for i, want := range []codes.Code{codes.Unauthenticated, codes.Unauthenticated, codes.Unauthenticated, codes.ResourceExhausted} {
+		_, err = client.GetExam(ctx, &pb.GetExamRequest{Id: 1})
+		have := status.Code(err)
+		if have != want {
+			t.Errorf("Incorrect status for attempt %v; have: %v, want: %v", i, have, want)
+		}
+	}
+}
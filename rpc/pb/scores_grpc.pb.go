@@ -0,0 +1,359 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+
+package pb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.32.0 or later.
+const _ = grpc.SupportPackageIsVersion7
+
+// ScoresClient is the client API for Scores service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type ScoresClient interface {
+	// ListStudents lists all students that have received at least one score
+	ListStudents(ctx context.Context, in *ListStudentsRequest, opts ...grpc.CallOption) (*ListStudentsResponse, error)
+	// GetStudent returns a student's scores and average, as GET /v1/students/{id} does
+	GetStudent(ctx context.Context, in *GetStudentRequest, opts ...grpc.CallOption) (*Student, error)
+	// ListExams lists the ids of all exams with at least one score
+	ListExams(ctx context.Context, in *ListExamsRequest, opts ...grpc.CallOption) (*ListExamsResponse, error)
+	// GetExam returns an exam's scores and the class statistics, as GET /v1/exams/{id} does
+	GetExam(ctx context.Context, in *GetExamRequest, opts ...grpc.CallOption) (*Exam, error)
+	// AddScore records a single score, as POST /v1/exams does
+	AddScore(ctx context.Context, in *AddScoreRequest, opts ...grpc.CallOption) (*Score, error)
+	// DeleteExam removes an exam's scores, which can be restored until the delete grace period expires
+	DeleteExam(ctx context.Context, in *DeleteExamRequest, opts ...grpc.CallOption) (*DeleteExamResponse, error)
+	// WatchScores streams every change to the scores matching the filters, from the same events as the webhooks
+	WatchScores(ctx context.Context, in *WatchScoresRequest, opts ...grpc.CallOption) (Scores_WatchScoresClient, error)
+}
+
+type scoresClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewScoresClient(cc grpc.ClientConnInterface) ScoresClient {
+	return &scoresClient{cc}
+}
+
+func (c *scoresClient) ListStudents(ctx context.Context, in *ListStudentsRequest, opts ...grpc.CallOption) (*ListStudentsResponse, error) {
+	out := new(ListStudentsResponse)
+	err := c.cc.Invoke(ctx, "/scores.v1.Scores/ListStudents", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *scoresClient) GetStudent(ctx context.Context, in *GetStudentRequest, opts ...grpc.CallOption) (*Student, error) {
+	out := new(Student)
+	err := c.cc.Invoke(ctx, "/scores.v1.Scores/GetStudent", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *scoresClient) ListExams(ctx context.Context, in *ListExamsRequest, opts ...grpc.CallOption) (*ListExamsResponse, error) {
+	out := new(ListExamsResponse)
+	err := c.cc.Invoke(ctx, "/scores.v1.Scores/ListExams", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *scoresClient) GetExam(ctx context.Context, in *GetExamRequest, opts ...grpc.CallOption) (*Exam, error) {
+	out := new(Exam)
+	err := c.cc.Invoke(ctx, "/scores.v1.Scores/GetExam", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *scoresClient) AddScore(ctx context.Context, in *AddScoreRequest, opts ...grpc.CallOption) (*Score, error) {
+	out := new(Score)
+	err := c.cc.Invoke(ctx, "/scores.v1.Scores/AddScore", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *scoresClient) DeleteExam(ctx context.Context, in *DeleteExamRequest, opts ...grpc.CallOption) (*DeleteExamResponse, error) {
+	out := new(DeleteExamResponse)
+	err := c.cc.Invoke(ctx, "/scores.v1.Scores/DeleteExam", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *scoresClient) WatchScores(ctx context.Context, in *WatchScoresRequest, opts ...grpc.CallOption) (Scores_WatchScoresClient, error) {
+	stream, err := c.cc.NewStream(ctx, &Scores_ServiceDesc.Streams[0], "/scores.v1.Scores/WatchScores", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &scoresWatchScoresClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type Scores_WatchScoresClient interface {
+	Recv() (*ScoreEvent, error)
+	grpc.ClientStream
+}
+
+type scoresWatchScoresClient struct {
+	grpc.ClientStream
+}
+
+func (x *scoresWatchScoresClient) Recv() (*ScoreEvent, error) {
+	m := new(ScoreEvent)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// ScoresServer is the server API for Scores service.
+// All implementations must embed UnimplementedScoresServer
+// for forward compatibility
+type ScoresServer interface {
+	// ListStudents lists all students that have received at least one score
+	ListStudents(context.Context, *ListStudentsRequest) (*ListStudentsResponse, error)
+	// GetStudent returns a student's scores and average, as GET /v1/students/{id} does
+	GetStudent(context.Context, *GetStudentRequest) (*Student, error)
+	// ListExams lists the ids of all exams with at least one score
+	ListExams(context.Context, *ListExamsRequest) (*ListExamsResponse, error)
+	// GetExam returns an exam's scores and the class statistics, as GET /v1/exams/{id} does
+	GetExam(context.Context, *GetExamRequest) (*Exam, error)
+	// AddScore records a single score, as POST /v1/exams does
+	AddScore(context.Context, *AddScoreRequest) (*Score, error)
+	// DeleteExam removes an exam's scores, which can be restored until the delete grace period expires
+	DeleteExam(context.Context, *DeleteExamRequest) (*DeleteExamResponse, error)
+	// WatchScores streams every change to the scores matching the filters, from the same events as the webhooks
+	WatchScores(*WatchScoresRequest, Scores_WatchScoresServer) error
+	mustEmbedUnimplementedScoresServer()
+}
+
+// UnimplementedScoresServer must be embedded to have forward compatible implementations.
+type UnimplementedScoresServer struct {
+}
+
+func (UnimplementedScoresServer) ListStudents(context.Context, *ListStudentsRequest) (*ListStudentsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListStudents not implemented")
+}
+func (UnimplementedScoresServer) GetStudent(context.Context, *GetStudentRequest) (*Student, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetStudent not implemented")
+}
+func (UnimplementedScoresServer) ListExams(context.Context, *ListExamsRequest) (*ListExamsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListExams not implemented")
+}
+func (UnimplementedScoresServer) GetExam(context.Context, *GetExamRequest) (*Exam, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetExam not implemented")
+}
+func (UnimplementedScoresServer) AddScore(context.Context, *AddScoreRequest) (*Score, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method AddScore not implemented")
+}
+func (UnimplementedScoresServer) DeleteExam(context.Context, *DeleteExamRequest) (*DeleteExamResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DeleteExam not implemented")
+}
+func (UnimplementedScoresServer) WatchScores(*WatchScoresRequest, Scores_WatchScoresServer) error {
+	return status.Errorf(codes.Unimplemented, "method WatchScores not implemented")
+}
+func (UnimplementedScoresServer) mustEmbedUnimplementedScoresServer() {}
+
+// UnsafeScoresServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to ScoresServer will
+// result in compilation errors.
+type UnsafeScoresServer interface {
+	mustEmbedUnimplementedScoresServer()
+}
+
+func RegisterScoresServer(s grpc.ServiceRegistrar, srv ScoresServer) {
+	s.RegisterService(&Scores_ServiceDesc, srv)
+}
+
+func _Scores_ListStudents_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListStudentsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ScoresServer).ListStudents(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/scores.v1.Scores/ListStudents",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ScoresServer).ListStudents(ctx, req.(*ListStudentsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Scores_GetStudent_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetStudentRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ScoresServer).GetStudent(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/scores.v1.Scores/GetStudent",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ScoresServer).GetStudent(ctx, req.(*GetStudentRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Scores_ListExams_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListExamsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ScoresServer).ListExams(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/scores.v1.Scores/ListExams",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ScoresServer).ListExams(ctx, req.(*ListExamsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Scores_GetExam_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetExamRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ScoresServer).GetExam(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/scores.v1.Scores/GetExam",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ScoresServer).GetExam(ctx, req.(*GetExamRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Scores_AddScore_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AddScoreRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ScoresServer).AddScore(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/scores.v1.Scores/AddScore",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ScoresServer).AddScore(ctx, req.(*AddScoreRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Scores_DeleteExam_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeleteExamRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ScoresServer).DeleteExam(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/scores.v1.Scores/DeleteExam",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ScoresServer).DeleteExam(ctx, req.(*DeleteExamRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Scores_WatchScores_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(WatchScoresRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(ScoresServer).WatchScores(m, &scoresWatchScoresServer{stream})
+}
+
+type Scores_WatchScoresServer interface {
+	Send(*ScoreEvent) error
+	grpc.ServerStream
+}
+
+type scoresWatchScoresServer struct {
+	grpc.ServerStream
+}
+
+func (x *scoresWatchScoresServer) Send(m *ScoreEvent) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+// Scores_ServiceDesc is the grpc.ServiceDesc for Scores service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var Scores_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "scores.v1.Scores",
+	HandlerType: (*ScoresServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "ListStudents",
+			Handler:    _Scores_ListStudents_Handler,
+		},
+		{
+			MethodName: "GetStudent",
+			Handler:    _Scores_GetStudent_Handler,
+		},
+		{
+			MethodName: "ListExams",
+			Handler:    _Scores_ListExams_Handler,
+		},
+		{
+			MethodName: "GetExam",
+			Handler:    _Scores_GetExam_Handler,
+		},
+		{
+			MethodName: "AddScore",
+			Handler:    _Scores_AddScore_Handler,
+		},
+		{
+			MethodName: "DeleteExam",
+			Handler:    _Scores_DeleteExam_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "WatchScores",
+			Handler:       _Scores_WatchScores_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "scores.proto",
+}
@@ -0,0 +1,228 @@
+package rpc
+
+import (
+	"context"
+	"log"
+	"net"
+	"strings"
+
+	"github.com/kylegk/sse-rest-server/anomaly"
+	"github.com/kylegk/sse-rest-server/audit"
+	"github.com/kylegk/sse-rest-server/config"
+	"github.com/kylegk/sse-rest-server/db"
+	"github.com/kylegk/sse-rest-server/grading"
+	"github.com/kylegk/sse-rest-server/models"
+	"github.com/kylegk/sse-rest-server/report"
+	"github.com/kylegk/sse-rest-server/rpc/pb"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+// Server implements the Scores service over the same datastore as the REST API
+type Server struct {
+	pb.UnimplementedScoresServer
+}
+
+// NewServer creates a gRPC server with the Scores service registered, logging every call and recovering from panics
+func NewServer() *grpc.Server {
+	s := grpc.NewServer(grpc.UnaryInterceptor(unaryInterceptor), grpc.StreamInterceptor(streamInterceptor))
+	pb.RegisterScoresServer(s, &Server{})
+
+	return s
+}
+
+// Serve listens for gRPC calls on the port, which must include the ":" as the REST port does
+func Serve(port string) error {
+	listener, err := net.Listen("tcp", port)
+	if err != nil {
+		return err
+	}
+
+	return NewServer().Serve(listener)
+}
+
+// ListStudents lists all students that have received at least one score, limited to the students the client can see
+func (s *Server) ListStudents(ctx context.Context, req *pb.ListStudentsRequest) (*pb.ListStudentsResponse, error) {
+	res, err := db.GetRows(config.ScoreTable, config.UniqueStudentsIdx)
+	if err != nil {
+		return nil, internalError(err)
+	}
+
+	visible := visibleStudents(ctx)
+	response := &pb.ListStudentsResponse{}
+	for _, row := range res {
+		if student := row.(models.StudentExam).StudentID; visible.Includes(student) {
+			response.Students = append(response.Students, student)
+		}
+	}
+
+	return response, nil
+}
+
+// GetStudent returns a student's scores and average, calculated with the requested grading policy as GET /v1/students/{id} does
+func (s *Server) GetStudent(ctx context.Context, req *pb.GetStudentRequest) (*pb.Student, error) {
+	policy, err := grading.GetPolicy(req.Policy)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	scale, err := grading.GetScale(req.Scale)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	student, err := report.Student(req.Id, policy, scale, req.Curved)
+	if err == report.ErrNotFound {
+		return nil, status.Errorf(codes.NotFound, "student %s not found", req.Id)
+	}
+	if err != nil {
+		return nil, internalError(err)
+	}
+
+	response := &pb.Student{
+		Id:        student.Student,
+		Policy:    student.Policy,
+		Scale:     student.Scale,
+		ScoreType: student.ScoreType,
+		Average:   student.Average,
+		Stddev:    student.StdDev,
+		Min:       student.Min,
+		Max:       student.Max,
+		Grade:     student.Grade,
+	}
+	for _, exam := range student.Exams {
+		response.Exams = append(response.Exams, &pb.ExamScore{Exam: int64(exam.Exam), Score: exam.Score, Grade: exam.Grade})
+	}
+
+	return response, nil
+}
+
+// ListExams lists the ids of all exams with at least one score
+func (s *Server) ListExams(ctx context.Context, req *pb.ListExamsRequest) (*pb.ListExamsResponse, error) {
+	res, err := db.GetRows(config.ScoreTable, config.UniqueExamsIdx)
+	if err != nil {
+		return nil, internalError(err)
+	}
+
+	response := &pb.ListExamsResponse{}
+	for _, row := range res {
+		response.Exams = append(response.Exams, int64(row.(models.StudentExam).Exam))
+	}
+
+	return response, nil
+}
+
+// GetExam returns an exam's scores and the class statistics, as GET /v1/exams/{id} does,
+// limited to the scores of the students the client can see
+func (s *Server) GetExam(ctx context.Context, req *pb.GetExamRequest) (*pb.Exam, error) {
+	scale, err := grading.GetScale(req.Scale)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	exam, err := report.Exam(int(req.Id), scale, req.Curved, visibleStudents(ctx))
+	if err == report.ErrNotFound {
+		return nil, status.Errorf(codes.NotFound, "exam %d not found", req.Id)
+	}
+	if err != nil {
+		return nil, internalError(err)
+	}
+
+	response := &pb.Exam{
+		Id:        req.Id,
+		Scale:     exam.Scale,
+		ScoreType: exam.ScoreType,
+		Average:   exam.Average,
+		Stddev:    exam.StdDev,
+		Min:       exam.Min,
+		Max:       exam.Max,
+		Grade:     exam.Grade,
+	}
+	for _, score := range exam.Scores {
+		response.Scores = append(response.Scores, &pb.StudentScore{Student: score.Student, Score: score.Score, Grade: score.Grade})
+	}
+
+	return response, nil
+}
+
+// AddScore records a single score and evaluates the alert rules against it, as POST /v1/exams does
+func (s *Server) AddScore(ctx context.Context, req *pb.AddScoreRequest) (*pb.Score, error) {
+	exam := models.StudentExam{Exam: int(req.Exam), StudentID: req.Student, Score: req.Score}
+
+	// The score is validated like one posted to the REST API
+	if fields := anomaly.Validate(exam); len(fields) > 0 {
+		messages := make([]string, 0, len(fields))
+		for _, field := range fields {
+			messages = append(messages, field.Message)
+		}
+		return nil, status.Errorf(codes.InvalidArgument, "invalid score: %s", strings.Join(messages, ", "))
+	}
+
+	events, err := db.UpsertScores([]models.StudentExam{exam}, audit.Auditor(models.AuditExamAdded, callSource(ctx)))
+	if err != nil {
+		return nil, internalError(err)
+	}
+
+	// The score as it was stored, with the fields set when it was recorded
+	return toScore(events[0].After), nil
+}
+
+// DeleteExam removes an exam's scores, which can be restored until the delete grace period expires
+func (s *Server) DeleteExam(ctx context.Context, req *pb.DeleteExamRequest) (*pb.DeleteExamResponse, error) {
+	deleted, tombstone, err := db.SoftDeleteExam(int(req.Id), audit.Auditor(models.AuditExamDeleted, callSource(ctx)))
+	if err != nil {
+		return nil, internalError(err)
+	}
+
+	response := &pb.DeleteExamResponse{Deleted: int64(deleted)}
+	if tombstone != nil {
+		response.RestorableUntil = timestamppb.New(tombstone.ExpiresAt)
+	}
+
+	return response, nil
+}
+
+// WatchScores streams every change to the scores matching the filters until the client cancels the call
+func (s *Server) WatchScores(req *pb.WatchScoresRequest, stream pb.Scores_WatchScoresServer) error {
+	err := authorize(stream.Context(), req)
+	if err != nil {
+		return err
+	}
+
+	for event := range db.WatchScores(stream.Context(), int(req.Exam), req.Student) {
+		err := stream.Send(&pb.ScoreEvent{
+			Type:   event.Type,
+			Before: toScore(event.Before),
+			After:  toScore(event.After),
+			Time:   timestamppb.New(event.Time),
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return stream.Context().Err()
+}
+
+// Convert a score to its message, which is unset for a nil score
+func toScore(score *models.StudentExam) *pb.Score {
+	if score == nil {
+		return nil
+	}
+
+	message := &pb.Score{Exam: int64(score.Exam), Student: score.StudentID, Score: score.Score, RecordedAt: timestamppb.New(score.RecordedAt)}
+	if score.Curved != nil {
+		message.Curved = wrapperspb.Double(*score.Curved)
+	}
+
+	return message
+}
+
+// Log an unexpected error, and report it to the client without its detail
+func internalError(err error) error {
+	log.Println(err)
+	return status.Error(codes.Internal, "internal error")
+}
@@ -0,0 +1,238 @@
+package rpc
+
+import (
+	"context"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/kylegk/sse-rest-server/audit"
+	"github.com/kylegk/sse-rest-server/auth"
+	"github.com/kylegk/sse-rest-server/config"
+	"github.com/kylegk/sse-rest-server/db"
+	"github.com/kylegk/sse-rest-server/models"
+	"github.com/kylegk/sse-rest-server/rpc/pb"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+var rpcTestData = []models.StudentExam{
+	{Exam: 1, StudentID: "test.person1", Score: 0.5},
+	{Exam: 2, StudentID: "test.person1", Score: 0.9},
+	{Exam: 1, StudentID: "test.person2", Score: 0.7},
+}
+
+// Start a server over an in-memory connection, returning a client of it and a function to stop it
+func startTestServer(t *testing.T) (pb.ScoresClient, func()) {
+	err := db.InitDB(config.DBSchema)
+	if err != nil {
+		t.Fatalf("Failed to initialize the database")
+	}
+
+	for _, score := range rpcTestData {
+		err = db.UpsertRow(config.ScoreTable, score)
+		if err != nil {
+			t.Fatalf("Failed to setup the data")
+		}
+	}
+
+	listener := bufconn.Listen(1024 * 1024)
+	server := NewServer()
+	go server.Serve(listener)
+
+	dialer := func(context.Context, string) (net.Conn, error) {
+		return listener.Dial()
+	}
+	conn, err := grpc.DialContext(context.Background(), "bufnet", grpc.WithContextDialer(dialer), grpc.WithInsecure())
+	if err != nil {
+		t.Fatalf("Failed to connect to the server; %v", err)
+	}
+
+	return pb.NewScoresClient(conn), func() {
+		conn.Close()
+		server.Stop()
+	}
+}
+
+func TestStudents(t *testing.T) {
+	client, stop := startTestServer(t)
+	defer stop()
+	ctx := context.Background()
+
+	list, err := client.ListStudents(ctx, &pb.ListStudentsRequest{})
+	if err != nil || len(list.Students) != 2 {
+		t.Errorf("Incorrect students returned; have: %v, %v", list, err)
+	}
+
+	student, err := client.GetStudent(ctx, &pb.GetStudentRequest{Id: "test.person1"})
+	if err != nil {
+		t.Errorf("Unable to get the student; %v", err)
+	}
+	if student.Average != 0.7 || len(student.Exams) != 2 || student.ScoreType != "raw" {
+		t.Errorf("Incorrect student returned; have: %v", student)
+	}
+
+	_, err = client.GetStudent(ctx, &pb.GetStudentRequest{Id: "nobody"})
+	have := status.Code(err)
+	want := codes.NotFound
+	if have != want {
+		t.Errorf("Incorrect status for a missing student; have: %v, want: %v", have, want)
+	}
+
+	_, err = client.GetStudent(ctx, &pb.GetStudentRequest{Id: "test.person1", Policy: "does_not_exist"})
+	have = status.Code(err)
+	want = codes.InvalidArgument
+	if have != want {
+		t.Errorf("Incorrect status for an invalid policy; have: %v, want: %v", have, want)
+	}
+}
+
+func TestExams(t *testing.T) {
+	client, stop := startTestServer(t)
+	defer stop()
+	ctx := context.Background()
+
+	list, err := client.ListExams(ctx, &pb.ListExamsRequest{})
+	if err != nil || len(list.Exams) != 2 {
+		t.Errorf("Incorrect exams returned; have: %v, %v", list, err)
+	}
+
+	exam, err := client.GetExam(ctx, &pb.GetExamRequest{Id: 1})
+	if err != nil {
+		t.Errorf("Unable to get the exam; %v", err)
+	}
+	if exam.Average != 0.6 || len(exam.Scores) != 2 || exam.Min != 0.5 || exam.Max != 0.7 {
+		t.Errorf("Incorrect exam returned; have: %v", exam)
+	}
+
+	score, err := client.AddScore(ctx, &pb.AddScoreRequest{Exam: 3, Student: "test.person2", Score: 0.8})
+	if err != nil {
+		t.Errorf("Unable to add the score; %v", err)
+	}
+	if score.Exam != 3 || score.Student != "test.person2" || score.RecordedAt.AsTime().IsZero() {
+		t.Errorf("Incorrect score returned; have: %v", score)
+	}
+
+	// Verify a score is validated like one posted to the REST API
+	for _, req := range []*pb.AddScoreRequest{{Exam: 3}, {Exam: 3, Student: "test.person2", Score: 80}} {
+		_, err = client.AddScore(ctx, req)
+		have := status.Code(err)
+		want := codes.InvalidArgument
+		if have != want {
+			t.Errorf("Incorrect status for an invalid score %v; have: %v, want: %v", req, have, want)
+		}
+	}
+
+	deleted, err := client.DeleteExam(metadata.AppendToOutgoingContext(ctx, requestIDMetadata, "delete-1"), &pb.DeleteExamRequest{Id: 1})
+	if err != nil || deleted.Deleted != 2 {
+		t.Errorf("Incorrect number of scores deleted; have: %v, %v", deleted, err)
+	}
+
+	// The changes are recorded in the audit log with the request id sent by the client
+	entries, _ := audit.List(audit.Filter{Exam: 1, Action: models.AuditExamDeleted})
+	if len(entries) != 2 || entries[0].RequestID != "delete-1" || entries[0].Actor != audit.AnonymousActor || entries[0].IP == "" {
+		t.Errorf("Incorrect audit entries for the deleted exam; have: %+v", entries)
+	}
+	entries, _ = audit.List(audit.Filter{Exam: 3, Action: models.AuditExamAdded})
+	if len(entries) != 1 || entries[0].RequestID == "" {
+		t.Errorf("Incorrect audit entries for the added score; have: %+v", entries)
+	}
+
+	_, err = client.GetExam(ctx, &pb.GetExamRequest{Id: 1})
+	have := status.Code(err)
+	want := codes.NotFound
+	if have != want {
+		t.Errorf("Incorrect status for a deleted exam; have: %v, want: %v", have, want)
+	}
+}
+
+func TestWatchScores(t *testing.T) {
+	client, stop := startTestServer(t)
+	defer stop()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	stream, err := client.WatchScores(ctx, &pb.WatchScoresRequest{Student: "test.person3"})
+	if err != nil {
+		t.Fatalf("Unable to watch the scores; %v", err)
+	}
+
+	// The watch is registered once the call reaches the server, which cannot be observed here, so the scores are recorded until the event arrives
+	done := make(chan bool)
+	defer close(done)
+	go func() {
+		for {
+			select {
+			case <-done:
+				return
+			case <-time.After(10 * time.Millisecond):
+				_ = db.UpsertRow(config.ScoreTable, models.StudentExam{Exam: 2, StudentID: "test.person2", Score: 0.8})
+				_ = db.UpsertRow(config.ScoreTable, models.StudentExam{Exam: 2, StudentID: "test.person3", Score: 0.6})
+			}
+		}
+	}()
+
+	event, err := stream.Recv()
+	if err != nil {
+		t.Fatalf("No event received; %v", err)
+	}
+	if event.Type != models.ScoreUpserted || event.After.GetStudent() != "test.person3" || event.After.GetScore() != 0.6 {
+		t.Errorf("Incorrect event received; have: %v", event)
+	}
+
+	cancel()
+	_, err = stream.Recv()
+	have := status.Code(err)
+	want := codes.Canceled
+	if have != want {
+		t.Errorf("Stream did not end when cancelled; have: %v, want: %v", have, want)
+	}
+}
+
+func TestVisibleStudents(t *testing.T) {
+	client, stop := startTestServer(t)
+	defer stop()
+
+	dir, err := ioutil.TempDir("", "cohorts")
+	if err != nil {
+		t.Fatalf("Failed to create the cohort file")
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "cohorts.json")
+	err = ioutil.WriteFile(path, []byte(`{"math-101": ["test.person1"]}`), 0600)
+	if err != nil {
+		t.Fatalf("Failed to create the cohort file")
+	}
+	err = auth.LoadCohorts(path)
+	if err != nil {
+		t.Fatalf("Failed to load the cohort file; %v", err)
+	}
+
+	// The teacher's cohort only has test.person1, so test.person2 may not appear in any response
+	teacher := auth.Principal{ID: "sub:ms.teacher", Subject: "ms.teacher", Role: auth.RoleTeacher, Cohorts: []string{"math-101"}}
+	ctx := context.WithValue(context.Background(), principalContext, teacher)
+	s := &Server{}
+
+	list, err := s.ListStudents(ctx, &pb.ListStudentsRequest{})
+	if err != nil || len(list.Students) != 1 || list.Students[0] != "test.person1" {
+		t.Errorf("Incorrect students returned; have: %v, %v", list, err)
+	}
+
+	exam, err := s.GetExam(ctx, &pb.GetExamRequest{Id: 1})
+	if err != nil || len(exam.Scores) != 1 || exam.Scores[0].Student != "test.person1" || exam.Average != 0.5 {
+		t.Errorf("Incorrect exam returned; have: %v, %v", exam, err)
+	}
+
+	// Every student is visible to a client that is not limited to its cohorts
+	list, err = client.ListStudents(context.Background(), &pb.ListStudentsRequest{})
+	if err != nil || len(list.Students) != 2 {
+		t.Errorf("Incorrect students returned without a principal; have: %v, %v", list, err)
+	}
+}
@@ -2,10 +2,11 @@ package sse
 
 import (
 	"encoding/json"
-	"github.com/kylegk/sse-rest-server/config"
-	"github.com/kylegk/sse-rest-server/db"
+	"github.com/kylegk/sse-rest-server/anomaly"
+	"github.com/kylegk/sse-rest-server/audit"
 	"github.com/kylegk/sse-rest-server/models"
 	"github.com/r3labs/sse"
+	"log"
 )
 
 // IngestData initializes the connection to the SSE server and populates the data store
@@ -15,14 +16,27 @@ func IngestData(url string) {
 }
 
 // Insert event (score) data into the data store
+// Events that fail the anomaly checks are quarantined instead of being recorded
 func insertScore(msg *sse.Event) {
 	score := models.StudentExam{}
 	err := json.Unmarshal(msg.Data, &score)
 	if err != nil {
-		panic(err)
+		err = anomaly.InspectMalformed(msg.Data, err)
+		if err != nil {
+			log.Println(err)
+		}
+		return
+	}
+
+	quarantined, err := anomaly.Inspect(score)
+	if err != nil {
+		log.Println(err)
+	}
+	if quarantined {
+		return
 	}
 
-	err = db.UpsertRow(config.ScoreTable, score)
+	err = anomaly.Ingest(score, audit.Auditor(models.AuditScoreIngested, audit.Source{Actor: audit.IngestionActor}))
 	if err != nil {
 		panic(err)
 	}
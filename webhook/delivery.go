@@ -0,0 +1,224 @@
+package webhook
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"log"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/kylegk/sse-rest-server/config"
+	"github.com/kylegk/sse-rest-server/db"
+	"github.com/kylegk/sse-rest-server/models"
+)
+
+// Delivery settings; retries back off exponentially from InitialBackoff up to MaxBackoff
+var (
+	MaxAttempts    = 5
+	InitialBackoff = time.Second
+	MaxBackoff     = time.Minute
+	MaxLogEntries  = 100
+	client         = &http.Client{Timeout: 10 * time.Second}
+)
+
+// The number of deliveries that can be waiting for a worker; once it is full new deliveries wait for a worker rather than being dropped
+const queueSize = 1000
+
+// Define the headers sent with every delivery
+const (
+	SignatureHeader = "X-Webhook-Signature"
+	EventHeader     = "X-Webhook-Event"
+	DeliveryHeader  = "X-Webhook-Delivery"
+)
+
+// A single payload waiting to be delivered to a webhook
+type job struct {
+	hook    models.Webhook
+	payload models.WebhookPayload
+	body    []byte
+	attempt int
+}
+
+var (
+	queue     chan job
+	startOnce sync.Once
+
+	// The events of each commit that have not been turned into deliveries yet, in the order they were committed
+	pendingMu   sync.Mutex
+	pendingCond = sync.NewCond(&pendingMu)
+	pending     [][]models.ScoreEvent
+)
+
+// Start subscribes the webhooks to the score events and starts the delivery workers
+func Start(workers int) {
+	startOnce.Do(func() {
+		queue = make(chan job, queueSize)
+		for i := 0; i < workers; i++ {
+			go worker()
+		}
+		go dispatch()
+
+		db.OnScoreChange(enqueue)
+	})
+}
+
+// Sign returns the signature of a payload, which receivers can use to verify the payload was sent by this server
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Hold the events of a commit until they are dispatched
+// This runs inside the commit, so it only records the events and never waits for a delivery
+func enqueue(events []models.ScoreEvent) {
+	pendingMu.Lock()
+	pending = append(pending, events)
+	pendingMu.Unlock()
+
+	pendingCond.Signal()
+}
+
+// Queue a delivery for every webhook matching each of the pending events, in the order they were committed
+// A full queue makes the dispatcher wait for a worker, so a large commit slows the deliveries down rather than losing any
+func dispatch() {
+	for {
+		pendingMu.Lock()
+		for len(pending) == 0 {
+			pendingCond.Wait()
+		}
+		events := pending[0]
+		pending[0] = nil
+		pending = pending[1:]
+		pendingMu.Unlock()
+
+		hooks, err := List()
+		if err != nil {
+			log.Println(err)
+			continue
+		}
+
+		for _, event := range events {
+			for _, hook := range hooks {
+				if !matches(hook, event) {
+					continue
+				}
+
+				payload := models.WebhookPayload{ID: db.NewID(), Event: event.Type, Data: event}
+				body, err := json.Marshal(payload)
+				if err != nil {
+					log.Println(err)
+					continue
+				}
+
+				queue <- job{hook: hook, payload: payload, body: body, attempt: 1}
+			}
+		}
+	}
+}
+
+func worker() {
+	for j := range queue {
+		deliver(j)
+	}
+}
+
+// Attempt a delivery, scheduling a retry if it failed and can be retried
+func deliver(j job) {
+	// Deliveries queued before a webhook was deleted are abandoned
+	if _, err := Get(j.hook.ID); err != nil {
+		return
+	}
+
+	status, err := post(j)
+	message := ""
+	if err != nil {
+		message = err.Error()
+	}
+	record(j, status, message)
+
+	if err == nil && status >= 200 && status < 300 {
+		return
+	}
+	if j.attempt >= MaxAttempts || !retryable(status) {
+		return
+	}
+
+	backoff := InitialBackoff << uint(j.attempt-1)
+	if backoff > MaxBackoff || backoff <= 0 {
+		backoff = MaxBackoff
+	}
+
+	j.attempt++
+	time.AfterFunc(backoff, func() {
+		queue <- j
+	})
+}
+
+// Post the signed payload to the webhook url
+func post(j job) (int, error) {
+	req, err := http.NewRequest("POST", j.hook.URL, bytes.NewReader(j.body))
+	if err != nil {
+		return 0, err
+	}
+
+	req.Header.Set("Content-Type", "application/json; charset=UTF-8")
+	req.Header.Set(SignatureHeader, Sign(j.hook.Secret, j.body))
+	req.Header.Set(EventHeader, j.payload.Event)
+	req.Header.Set(DeliveryHeader, j.payload.ID)
+
+	res, err := client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	res.Body.Close()
+
+	return res.StatusCode, nil
+}
+
+// Network errors, rate limiting and server errors are retried; other client errors will not succeed on a retry
+func retryable(status int) bool {
+	return status == 0 || status == http.StatusTooManyRequests || status >= 500
+}
+
+// Add an attempt to the webhook's delivery log, discarding the oldest entries beyond the log limit
+func record(j job, status int, message string) {
+	delivery := models.WebhookDelivery{
+		ID:         db.NewID(),
+		WebhookID:  j.hook.ID,
+		PayloadID:  j.payload.ID,
+		Event:      j.payload.Event,
+		Attempt:    j.attempt,
+		StatusCode: status,
+		Error:      message,
+		Success:    message == "" && status >= 200 && status < 300,
+		Timestamp:  time.Now().UTC(),
+	}
+
+	err := db.UpsertRow(config.WebhookDeliveryTable, delivery)
+	if err != nil {
+		log.Println(err)
+		return
+	}
+
+	res, err := db.GetRows(config.WebhookDeliveryTable, config.WebhookIdx, j.hook.ID)
+	if err != nil || len(res) <= MaxLogEntries {
+		return
+	}
+
+	sort.Slice(res, func(a, b int) bool {
+		return res[a].(models.WebhookDelivery).Timestamp.Before(res[b].(models.WebhookDelivery).Timestamp)
+	})
+	for _, old := range res[:len(res)-MaxLogEntries] {
+		err = db.DeleteRow(config.WebhookDeliveryTable, old)
+		if err != nil {
+			log.Println(err)
+		}
+	}
+}
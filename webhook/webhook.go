@@ -0,0 +1,174 @@
+package webhook
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/kylegk/sse-rest-server/config"
+	"github.com/kylegk/sse-rest-server/db"
+	"github.com/kylegk/sse-rest-server/models"
+)
+
+// ErrNotFound is returned when a webhook does not exist
+var ErrNotFound = errors.New("webhook not found")
+
+// ErrInvalidURL and ErrInvalidEvent are returned when a webhook cannot be created because its url or one of its events is invalid
+var (
+	ErrInvalidURL   = errors.New("invalid url")
+	ErrInvalidEvent = errors.New("invalid event")
+)
+
+// Create validates and stores a new webhook subscription, generating a secret if one was not provided
+// The auditor, if any, records the new webhook in the audit log
+func Create(hook models.Webhook, auditor db.ResourceAuditor) (models.Webhook, error) {
+	u, err := url.Parse(hook.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return hook, fmt.Errorf("%w: must be an absolute http or https url", ErrInvalidURL)
+	}
+
+	for _, event := range hook.Events {
+		if event != models.ScoreUpserted && event != models.ScoreDeleted {
+			return hook, fmt.Errorf("%w: %s", ErrInvalidEvent, event)
+		}
+	}
+
+	if hook.Secret == "" {
+		hook.Secret = db.NewID() + db.NewID()
+	}
+	hook.ID = db.NewID()
+	hook.CreatedAt = time.Now().UTC()
+
+	var audited db.Auditor
+	if auditor != nil {
+		audited = auditor(hook.ID)
+	}
+	_, err = db.Update(audited, func(txn *db.Txn) error {
+		return txn.UpsertRow(config.WebhookTable, hook)
+	})
+
+	return hook, err
+}
+
+// List retrieves every webhook subscription
+func List() ([]models.Webhook, error) {
+	res, err := db.GetRows(config.WebhookTable, config.IdFld)
+	if err != nil {
+		return nil, err
+	}
+
+	hooks := make([]models.Webhook, 0, len(res))
+	for _, row := range res {
+		hooks = append(hooks, row.(models.Webhook))
+	}
+
+	return hooks, nil
+}
+
+// Get retrieves a single webhook subscription
+func Get(id string) (models.Webhook, error) {
+	res, err := db.GetRows(config.WebhookTable, config.IdFld, id)
+	if err != nil {
+		return models.Webhook{}, err
+	}
+	if len(res) == 0 {
+		return models.Webhook{}, ErrNotFound
+	}
+
+	return res[0].(models.Webhook), nil
+}
+
+// Delete removes a webhook subscription and its delivery log; deliveries already queued are abandoned
+// The auditor, if any, records the delete in the audit log
+func Delete(id string, auditor db.Auditor) error {
+	_, err := db.Update(auditor, func(txn *db.Txn) error {
+		res, err := txn.GetRows(config.WebhookTable, config.IdFld, id)
+		if err != nil {
+			return err
+		}
+		if len(res) == 0 {
+			return ErrNotFound
+		}
+
+		err = txn.DeleteRow(config.WebhookTable, res[0])
+		if err != nil {
+			return err
+		}
+
+		deliveries, err := txn.GetRows(config.WebhookDeliveryTable, config.WebhookIdx, id)
+		if err != nil {
+			return err
+		}
+		for _, delivery := range deliveries {
+			err = txn.DeleteRow(config.WebhookDeliveryTable, delivery)
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+
+	return err
+}
+
+// Deliveries retrieves the delivery log of a webhook
+func Deliveries(id string) ([]models.WebhookDelivery, error) {
+	_, err := Get(id)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := db.GetRows(config.WebhookDeliveryTable, config.WebhookIdx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	deliveries := make([]models.WebhookDelivery, 0, len(res))
+	for _, row := range res {
+		deliveries = append(deliveries, row.(models.WebhookDelivery))
+	}
+
+	return deliveries, nil
+}
+
+// Check whether a webhook's filters match a score event
+func matches(hook models.Webhook, event models.ScoreEvent) bool {
+	score := event.After
+	if score == nil {
+		score = event.Before
+	}
+
+	return matchesEvent(hook.Events, event.Type) && matchesExam(hook.Exams, score.Exam) && matchesStudent(hook.Students, score.StudentID)
+}
+
+func matchesEvent(events []string, event string) bool {
+	for _, e := range events {
+		if e == event {
+			return true
+		}
+	}
+
+	return len(events) == 0
+}
+
+func matchesExam(exams []int, exam int) bool {
+	for _, e := range exams {
+		if e == exam {
+			return true
+		}
+	}
+
+	return len(exams) == 0
+}
+
+func matchesStudent(students []string, student string) bool {
+	for _, s := range students {
+		if s == student {
+			return true
+		}
+	}
+
+	return len(students) == 0
+}
@@ -0,0 +1,200 @@
+package webhook
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/kylegk/sse-rest-server/config"
+	"github.com/kylegk/sse-rest-server/db"
+	"github.com/kylegk/sse-rest-server/models"
+)
+
+// TestCreateInvalid validates that webhooks with an invalid url or event are rejected
+func TestCreateInvalid(t *testing.T) {
+	err := db.InitDB(config.DBSchema)
+	if err != nil {
+		t.Errorf("The database failed to initialize")
+	}
+
+	invalid := []models.Webhook{
+		{URL: "not a url"},
+		{URL: "ftp://example.com/hook"},
+		{URL: "https://example.com/hook", Events: []string{"score.foo"}},
+	}
+
+	for _, hook := range invalid {
+		_, err = Create(hook, nil)
+		if err == nil {
+			t.Errorf("The webhook should have been rejected: %+v", hook)
+		}
+	}
+}
+
+// TestMatches validates the exam, student and event filters
+func TestMatches(t *testing.T) {
+	event := models.ScoreEvent{Type: models.ScoreDeleted, Before: &models.StudentExam{Exam: 1, StudentID: "test"}}
+
+	tests := []struct {
+		hook models.Webhook
+		want bool
+	}{
+		{models.Webhook{}, true},
+		{models.Webhook{Exams: []int{1, 2}, Students: []string{"test"}}, true},
+		{models.Webhook{Exams: []int{2}}, false},
+		{models.Webhook{Students: []string{"other"}}, false},
+		{models.Webhook{Events: []string{models.ScoreUpserted}}, false},
+	}
+
+	for _, test := range tests {
+		have := matches(test.hook, event)
+		if have != test.want {
+			t.Errorf("Incorrect match for %+v; have: %v, want: %v", test.hook, have, test.want)
+		}
+	}
+}
+
+// TestDelivery validates that a signed payload is delivered for a score event, and retried after a server error
+func TestDelivery(t *testing.T) {
+	err := db.InitDB(config.DBSchema)
+	if err != nil {
+		t.Errorf("The database failed to initialize")
+	}
+
+	InitialBackoff = 10 * time.Millisecond
+
+	var mu sync.Mutex
+	attempts := 0
+	done := make(chan bool, 1)
+	var secret string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		body, _ := ioutil.ReadAll(r.Body)
+		if r.Header.Get(SignatureHeader) != Sign(secret, body) {
+			t.Errorf("The payload signature did not match")
+		}
+
+		attempts++
+		if attempts == 1 {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		done <- true
+	}))
+	defer server.Close()
+
+	hook, err := Create(models.Webhook{URL: server.URL, Exams: []int{1}}, nil)
+	if err != nil {
+		t.Fatalf("Failed to create webhook: %v", err)
+	}
+	mu.Lock()
+	secret = hook.Secret
+	mu.Unlock()
+
+	Start(1)
+
+	// A score for another exam should not be delivered
+	err = db.UpsertRow(config.ScoreTable, models.StudentExam{Exam: 2, StudentID: "test", Score: 0.5})
+	if err != nil {
+		t.Errorf("Failed to insert score")
+	}
+	err = db.UpsertRow(config.ScoreTable, models.StudentExam{Exam: 1, StudentID: "test", Score: 0.5})
+	if err != nil {
+		t.Errorf("Failed to insert score")
+	}
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("The payload was not delivered")
+	}
+
+	// The delivery log is written after the response is received
+	var deliveries []models.WebhookDelivery
+	for i := 0; i < 50; i++ {
+		deliveries, err = Deliveries(hook.ID)
+		if err == nil && len(deliveries) == 2 {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	have := len(deliveries)
+	want := 2
+	if have != want {
+		t.Fatalf("Incorrect number of deliveries logged; have: %v, want: %v", have, want)
+	}
+
+	successes := 0
+	for _, delivery := range deliveries {
+		if delivery.Success {
+			successes++
+		}
+	}
+	if successes != 1 {
+		t.Errorf("Exactly one delivery should have succeeded; have: %+v", deliveries)
+	}
+}
+
+// TestDeliveryBacklog validates that no delivery is lost when a commit holds more events than the queue
+func TestDeliveryBacklog(t *testing.T) {
+	err := db.InitDB(config.DBSchema)
+	if err != nil {
+		t.Errorf("The database failed to initialize")
+	}
+
+	var mu sync.Mutex
+	received := map[string]bool{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		payload := models.WebhookPayload{}
+		_ = json.NewDecoder(r.Body).Decode(&payload)
+
+		mu.Lock()
+		received[payload.Data.After.StudentID] = true
+		mu.Unlock()
+
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	_, err = Create(models.Webhook{URL: server.URL, Exams: []int{3}}, nil)
+	if err != nil {
+		t.Fatalf("Failed to create webhook: %v", err)
+	}
+
+	Start(1)
+
+	var scores []models.StudentExam
+	for i := 0; i < queueSize+100; i++ {
+		scores = append(scores, models.StudentExam{Exam: 3, StudentID: fmt.Sprintf("test.person%v", i), Score: 0.5})
+	}
+	_, err = db.UpsertScores(scores, nil)
+	if err != nil {
+		t.Fatalf("Failed to insert scores")
+	}
+
+	have := 0
+	for i := 0; i < 500; i++ {
+		mu.Lock()
+		have = len(received)
+		mu.Unlock()
+		if have == len(scores) {
+			break
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+
+	want := len(scores)
+	if have != want {
+		t.Errorf("Incorrect number of payloads delivered; have: %v, want: %v", have, want)
+	}
+}